@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -259,39 +260,39 @@ func validateAlarm(alarm *AlarmConfig) error {
 }
 
 func generateAlarmComponent(alarm *AlarmConfig) string {
-	var builder strings.Builder
+	var w contentLineWriter
 
-	builder.WriteString("BEGIN:VALARM\r\n")
-	builder.WriteString(fmt.Sprintf("ACTION:%s\r\n", alarm.Action))
-	builder.WriteString(fmt.Sprintf("TRIGGER:%s\r\n", alarm.Trigger))
+	w.begin("VALARM")
+	w.writeProperty("ACTION", nil, string(alarm.Action))
+	w.writeProperty("TRIGGER", nil, alarm.Trigger)
 
 	if alarm.Description != "" {
-		builder.WriteString(fmt.Sprintf("DESCRIPTION:%s\r\n", escapeICalText(alarm.Description)))
+		w.writeText("DESCRIPTION", nil, alarm.Description)
 	}
 
 	if alarm.Summary != "" {
-		builder.WriteString(fmt.Sprintf("SUMMARY:%s\r\n", escapeICalText(alarm.Summary)))
+		w.writeText("SUMMARY", nil, alarm.Summary)
 	}
 
 	if alarm.Duration != "" {
-		builder.WriteString(fmt.Sprintf("DURATION:%s\r\n", alarm.Duration))
+		w.writeProperty("DURATION", nil, alarm.Duration)
 	}
 
 	if alarm.Repeat > 0 {
-		builder.WriteString(fmt.Sprintf("REPEAT:%d\r\n", alarm.Repeat))
+		w.writeProperty("REPEAT", nil, strconv.Itoa(alarm.Repeat))
 	}
 
 	for _, attendee := range alarm.Attendees {
-		builder.WriteString(fmt.Sprintf("ATTENDEE:%s\r\n", attendee))
+		w.writeProperty("ATTENDEE", nil, attendee)
 	}
 
 	if alarm.Attach != "" {
-		builder.WriteString(fmt.Sprintf("ATTACH:%s\r\n", alarm.Attach))
+		w.writeProperty("ATTACH", nil, alarm.Attach)
 	}
 
-	builder.WriteString("END:VALARM\r\n")
+	w.end("VALARM")
 
-	return builder.String()
+	return w.String()
 }
 
 func insertAlarmIntoEvent(icalData string, alarmComponent string) string {
@@ -0,0 +1,293 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// lowestAvailabilityPriority ranks VAVAILABILITY components with PRIORITY 0,
+// meaning undefined, below PRIORITY 9.
+const lowestAvailabilityPriority = 10
+
+// AvailabilityBusyPeriods returns the time between start and end that the
+// VAVAILABILITY components in availability declare unavailable. Within the
+// time a component covers, everything outside its AVAILABLE periods, with
+// recurrences expanded, is busy with the component's BUSYTYPE, by default
+// BUSY-UNAVAILABLE. Where components overlap, the one with the highest
+// priority decides. Time no component covers is left free.
+func AvailabilityBusyPeriods(availability []ParsedAvailability, start, end time.Time) []FreeBusyPeriod {
+	ordered := make([]*ParsedAvailability, len(availability))
+	for i := range availability {
+		ordered[i] = &availability[i]
+	}
+	// Apply the lowest priority first so that higher priorities overwrite it.
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return availabilityRank(ordered[i]) > availabilityRank(ordered[j])
+	})
+
+	var timeline []FreeBusyPeriod
+	for _, avail := range ordered {
+		coverStart, coverEnd := availabilityBounds(avail, start, end)
+		if !coverEnd.After(coverStart) {
+			continue
+		}
+
+		busyType := avail.BusyType
+		if busyType == "" {
+			busyType = FBTypeBusyUnavailable
+		}
+
+		cover := []FreeBusyPeriod{{Start: coverStart, End: coverEnd, FBType: busyType}}
+		busy := subtractPeriods(cover, availablePeriods(avail, coverStart, coverEnd))
+		timeline = append(subtractPeriods(timeline, cover), busy...)
+	}
+
+	return mergeFreeBusyPeriods(timeline, start, end)
+}
+
+func availabilityRank(avail *ParsedAvailability) int {
+	if avail.Priority <= 0 {
+		return lowestAvailabilityPriority
+	}
+	return avail.Priority
+}
+
+// availabilityBounds returns the part of start to end covered by avail.
+func availabilityBounds(avail *ParsedAvailability, start, end time.Time) (time.Time, time.Time) {
+	if avail.DTStart != nil && avail.DTStart.After(start) {
+		start = *avail.DTStart
+	}
+
+	coverEnd := avail.DTEnd
+	if coverEnd == nil && avail.DTStart != nil && avail.Duration != "" {
+		if d, _, err := parseISO8601Duration(avail.Duration); err == nil {
+			t := avail.DTStart.Add(d)
+			coverEnd = &t
+		}
+	}
+	if coverEnd != nil && coverEnd.Before(end) {
+		end = *coverEnd
+	}
+	return start, end
+}
+
+// availablePeriods expands the AVAILABLE subcomponents of avail into the
+// periods that overlap start to end.
+func availablePeriods(avail *ParsedAvailability, start, end time.Time) []FreeBusyPeriod {
+	data := &ParsedCalendarData{}
+	var longest time.Duration
+	for _, available := range avail.Available {
+		event := availableAsEvent(available)
+		if event.DTStart == nil || event.DTEnd == nil {
+			continue
+		}
+		if d := event.DTEnd.Sub(*event.DTStart); d > longest {
+			longest = d
+		}
+		data.Events = append(data.Events, event)
+	}
+
+	expanded, err := ExpandEvents(data, start.Add(-longest), end)
+	if err != nil || expanded == nil {
+		return nil
+	}
+
+	var periods []FreeBusyPeriod
+	for _, event := range expanded.Events {
+		if event.DTStart == nil || event.DTEnd == nil {
+			continue
+		}
+		if event.DTEnd.After(start) && event.DTStart.Before(end) {
+			periods = append(periods, FreeBusyPeriod{Start: *event.DTStart, End: *event.DTEnd, FBType: FBTypeFree})
+		}
+	}
+	return periods
+}
+
+// availableAsEvent converts available to a ParsedEvent so that it can be
+// expanded like an event, resolving DURATION to DTEND.
+func availableAsEvent(available ParsedAvailable) ParsedEvent {
+	event := ParsedEvent{
+		UID:             available.UID,
+		DTStart:         available.DTStart,
+		DTEnd:           available.DTEnd,
+		RecurrenceID:    available.RecurrenceID,
+		RecurrenceRule:  available.RecurrenceRule,
+		RecurrenceDates: available.RecurrenceDates,
+		ExceptionDates:  available.ExceptionDates,
+	}
+	if event.DTEnd == nil && event.DTStart != nil && available.Duration != "" {
+		if d, _, err := parseISO8601Duration(available.Duration); err == nil {
+			t := event.DTStart.Add(d)
+			event.DTEnd = &t
+		}
+	}
+	return event
+}
+
+// subtractPeriods returns periods with every part that overlaps holes removed.
+func subtractPeriods(periods, holes []FreeBusyPeriod) []FreeBusyPeriod {
+	for _, hole := range holes {
+		var remaining []FreeBusyPeriod
+		for _, p := range periods {
+			if !hole.Start.Before(p.End) || !hole.End.After(p.Start) {
+				remaining = append(remaining, p)
+				continue
+			}
+			if p.Start.Before(hole.Start) {
+				remaining = append(remaining, FreeBusyPeriod{Start: p.Start, End: hole.Start, FBType: p.FBType})
+			}
+			if p.End.After(hole.End) {
+				remaining = append(remaining, FreeBusyPeriod{Start: hole.End, End: p.End, FBType: p.FBType})
+			}
+		}
+		periods = remaining
+	}
+	return periods
+}
+
+// FreeBusyQueryWithAvailability works like FreeBusyQuery and additionally
+// reports the time outside availability, typically read with
+// GetCalendarAvailability, as BUSY-UNAVAILABLE.
+func (c *CalDAVClient) FreeBusyQueryWithAvailability(ctx context.Context, calendarHref string, start, end time.Time, availability []ParsedAvailability) ([]FreeBusyPeriod, error) {
+	periods, err := c.FreeBusyQuery(ctx, calendarHref, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(availability) == 0 {
+		return periods, nil
+	}
+	return mergeFreeBusyPeriods(append(periods, AvailabilityBusyPeriods(availability, start, end)...), start, end), nil
+}
+
+// GetCalendarAvailability reads the RFC 7953 calendar-availability property
+// of the schedule inbox at inboxURL. It returns nil when no availability has
+// been declared. Servers known not to support the property, such as iCloud,
+// return an error wrapping ErrAvailabilityUnsupported.
+func (c *CalDAVClient) GetCalendarAvailability(ctx context.Context, inboxURL string) ([]ParsedAvailability, error) {
+	if err := c.requireAvailability("availability.get"); err != nil {
+		return nil, err
+	}
+
+	xmlBody, err := buildPropfindXML([]string{"calendar-availability"})
+	if err != nil {
+		return nil, wrapErrorWithType("availability.build", ErrorTypeInvalidRequest, err)
+	}
+
+	resp, err := c.propfind(ctx, inboxURL, "0", xmlBody)
+	if err != nil {
+		return nil, wrapErrorWithType("availability.request", ErrorTypeNetwork, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 207 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newCalDAVError("availability.get", resp.StatusCode, string(body))
+	}
+
+	msResp, err := parseMultiStatusResponse(resp.Body)
+	if err != nil {
+		return nil, wrapErrorWithType("availability.parse", ErrorTypeInvalidResponse, err)
+	}
+
+	for _, r := range msResp.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Status != 200 || ps.Prop.CalendarAvailability == "" {
+				continue
+			}
+			data, err := ParseICalendar(ps.Prop.CalendarAvailability)
+			if err != nil {
+				return nil, wrapErrorWithType("availability.parse", ErrorTypeInvalidResponse, err)
+			}
+			return data.Availability, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetCalendarAvailability stores availability as the calendar-availability
+// property of the schedule inbox at inboxURL. An empty availability removes
+// the property.
+func (c *CalDAVClient) SetCalendarAvailability(ctx context.Context, inboxURL string, availability []ParsedAvailability) error {
+	if err := c.requireAvailability("availability.set"); err != nil {
+		return err
+	}
+
+	body, err := buildCalendarAvailabilityXML(availability)
+	if err != nil {
+		return wrapErrorWithType("availability.build", ErrorTypeInvalidRequest, err)
+	}
+
+	req, err := c.prepareRequest(ctx, "PROPPATCH", inboxURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	c.setXMLHeaders(req)
+	c.logRequest(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("PROPPATCH request failed: %v", err)
+		return wrapErrorWithType("availability.set", ErrorTypeNetwork, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	c.logResponse(resp)
+
+	respBody, _ := io.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusMultiStatus:
+		msResp, err := parseMultiStatusResponse(bytes.NewReader(respBody))
+		if err != nil {
+			return wrapErrorWithType("availability.parse", ErrorTypeInvalidResponse, err)
+		}
+		for _, r := range msResp.Responses {
+			for _, ps := range r.Propstat {
+				if ps.Status >= 300 {
+					return newCalDAVError("availability.set", ps.Status, string(respBody))
+				}
+			}
+		}
+		return nil
+	default:
+		return newCalDAVError("availability.set", resp.StatusCode, string(respBody))
+	}
+}
+
+func buildCalendarAvailabilityXML(availability []ParsedAvailability) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xmlHeader)
+	buf.WriteString(`<D:propertyupdate xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+
+	if len(availability) == 0 {
+		buf.WriteString(`<D:remove><D:prop><C:calendar-availability/></D:prop></D:remove>`)
+	} else {
+		icalData, err := EncodeICalendar(&ParsedCalendarData{Availability: availability})
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`<D:set><D:prop><C:calendar-availability>`)
+		buf.WriteString(escapeXML(string(icalData)))
+		buf.WriteString(`</C:calendar-availability></D:prop></D:set>`)
+	}
+
+	buf.WriteString(`</D:propertyupdate>`)
+	return buf.Bytes(), nil
+}
+
+// requireAvailability fails with ErrAvailabilityUnsupported when the detected
+// server is known not to support calendar availability.
+func (c *CalDAVClient) requireAvailability(op string) error {
+	if c.serverCompat == nil {
+		return nil
+	}
+	if supported, known := c.serverCompat.Capabilities[CapCalendarAvailability]; known && !supported {
+		return newTypedError(op, ErrorTypeClient, "server does not support calendar availability", ErrAvailabilityUnsupported)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const workingHoursICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Test//Test//EN\r\n" +
+	"BEGIN:VAVAILABILITY\r\n" +
+	"UID:working-hours\r\n" +
+	"DTSTAMP:20240101T000000Z\r\n" +
+	"DTSTART:20240101T000000Z\r\n" +
+	"BEGIN:AVAILABLE\r\n" +
+	"UID:weekdays\r\n" +
+	"DTSTART:20240101T090000Z\r\n" +
+	"DTEND:20240101T170000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR\r\n" +
+	"END:AVAILABLE\r\n" +
+	"END:VAVAILABILITY\r\n" +
+	"BEGIN:VAVAILABILITY\r\n" +
+	"UID:late-monday\r\n" +
+	"DTSTAMP:20240101T000000Z\r\n" +
+	"DTSTART:20240115T170000Z\r\n" +
+	"DURATION:PT3H\r\n" +
+	"PRIORITY:1\r\n" +
+	"BUSYTYPE:BUSY\r\n" +
+	"BEGIN:AVAILABLE\r\n" +
+	"UID:late-monday-1\r\n" +
+	"DTSTART:20240115T170000Z\r\n" +
+	"DURATION:PT2H\r\n" +
+	"END:AVAILABLE\r\n" +
+	"END:VAVAILABILITY\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseWorkingHours(t *testing.T) []ParsedAvailability {
+	t.Helper()
+	data, err := ParseICalendar(workingHoursICS)
+	if err != nil {
+		t.Fatalf("failed to parse availability: %v", err)
+	}
+	if len(data.Availability) != 2 || len(data.Availability[0].Available) != 1 {
+		t.Fatalf("unexpected availability %+v", data.Availability)
+	}
+	return data.Availability
+}
+
+func TestAvailabilityBusyPeriods(t *testing.T) {
+	availability := parseWorkingHours(t)
+
+	// Monday: available 09:00-17:00 and, through the higher-priority
+	// component, 17:00-19:00; 19:00-20:00 takes that component's BUSYTYPE.
+	periods := AvailabilityBusyPeriods(availability, mustParseTime("20240115T000000Z"), mustParseTime("20240116T000000Z"))
+	expected := []FreeBusyPeriod{
+		{Start: mustParseTime("20240115T000000Z"), End: mustParseTime("20240115T090000Z"), FBType: FBTypeBusyUnavailable},
+		{Start: mustParseTime("20240115T190000Z"), End: mustParseTime("20240115T200000Z"), FBType: FBTypeBusy},
+		{Start: mustParseTime("20240115T200000Z"), End: mustParseTime("20240116T000000Z"), FBType: FBTypeBusyUnavailable},
+	}
+	if !reflect.DeepEqual(periods, expected) {
+		t.Errorf("expected %+v, got %+v", expected, periods)
+	}
+
+	// Time before the components start is not covered and stays free, while
+	// Saturday is unavailable all day.
+	periods = AvailabilityBusyPeriods(availability, mustParseTime("20231231T000000Z"), mustParseTime("20240101T000000Z"))
+	if len(periods) != 0 {
+		t.Errorf("expected no busy time before the availability starts, got %+v", periods)
+	}
+	periods = AvailabilityBusyPeriods(availability, mustParseTime("20240120T000000Z"), mustParseTime("20240121T000000Z"))
+	expected = []FreeBusyPeriod{
+		{Start: mustParseTime("20240120T000000Z"), End: mustParseTime("20240121T000000Z"), FBType: FBTypeBusyUnavailable},
+	}
+	if !reflect.DeepEqual(periods, expected) {
+		t.Errorf("expected %+v, got %+v", expected, periods)
+	}
+}
+
+func TestFindFreeSlots_Availability(t *testing.T) {
+	slots, err := FindFreeSlots(SlotRequest{
+		Participants: []SlotParticipant{{Name: "olivia", Availability: parseWorkingHours(t)}},
+		Start:        mustParseTime("20240116T000000Z"),
+		End:          mustParseTime("20240117T000000Z"),
+		Duration:     time.Hour,
+		Step:         time.Hour,
+		MaxResults:   24,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slots) != 8 {
+		t.Fatalf("expected eight slots within 09:00-17:00, got %+v", slots)
+	}
+	for _, slot := range slots {
+		if slot.Start.Before(mustParseTime("20240116T090000Z")) || slot.End.After(mustParseTime("20240116T170000Z")) {
+			t.Errorf("slot %v-%v is outside the declared availability", slot.Start, slot.End)
+		}
+	}
+}
+
+func TestCalendarAvailabilityProperty(t *testing.T) {
+	var patched string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method {
+		case "PROPFIND":
+			if !strings.Contains(string(body), "<C:calendar-availability/>") {
+				t.Errorf("unexpected PROPFIND body %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+				`<D:response><D:href>/inbox/</D:href><D:propstat><D:prop><C:calendar-availability>` +
+				escapeXML(workingHoursICS) +
+				`</C:calendar-availability></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+		case "PROPPATCH":
+			patched = string(body)
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+				`<D:response><D:href>/inbox/</D:href><D:propstat><D:prop><C:calendar-availability/></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+	ctx := context.Background()
+
+	availability, err := client.GetCalendarAvailability(ctx, "/inbox/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(availability, parseWorkingHours(t)) {
+		t.Errorf("unexpected availability %+v", availability)
+	}
+
+	if err := client.SetCalendarAvailability(ctx, "/inbox/", availability); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(patched, "<D:set><D:prop><C:calendar-availability>BEGIN:VCALENDAR") ||
+		!strings.Contains(patched, "BEGIN:VAVAILABILITY") {
+		t.Errorf("unexpected PROPPATCH body %s", patched)
+	}
+
+	if err := client.SetCalendarAvailability(ctx, "/inbox/", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(patched, "<D:remove><D:prop><C:calendar-availability/></D:prop></D:remove>") {
+		t.Errorf("expected the property to be removed, got %s", patched)
+	}
+
+	client.ConfigureForICloud()
+	if _, err := client.GetCalendarAvailability(ctx, "/inbox/"); !errors.Is(err, ErrAvailabilityUnsupported) {
+		t.Errorf("expected ErrAvailabilityUnsupported on iCloud, got %v", err)
+	}
+}
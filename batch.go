@@ -231,6 +231,7 @@ func (cbp *CalendarBatchProcessor) BatchDiscoverCalendars(ctx context.Context, h
 		"current-user-privilege-set",
 		"source",
 		"supported-report-set",
+		"supported-calendar-data",
 		"quota-used-bytes",
 		"quota-available-bytes",
 	}
@@ -3,6 +3,7 @@ package caldav
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -125,6 +126,7 @@ func (c *CalDAVClient) FindCalendars(ctx context.Context, calendarHomePath strin
 		"current-user-privilege-set",
 		"source",
 		"supported-report-set",
+		"supported-calendar-data",
 		"quota-used-bytes",
 		"quota-available-bytes",
 	}
@@ -170,6 +172,70 @@ func (c *CalDAVClient) FindCalendars(ctx context.Context, calendarHomePath strin
 	return calendars, nil
 }
 
+// SupportsCalendarData reports whether the calendar at calendarPath accepts
+// and returns calendar data of contentType, such as ContentTypeXCal, according
+// to its supported-calendar-data property. A calendar without the property
+// supports iCalendar only (RFC 4791 section 5.2.4).
+func (c *CalDAVClient) SupportsCalendarData(ctx context.Context, calendarPath, contentType string) (bool, error) {
+	xmlBody, err := buildPropfindXML([]string{"supported-calendar-data"})
+	if err != nil {
+		return false, wrapErrorWithType("calendar-data.build", ErrorTypeInvalidRequest, err)
+	}
+
+	cacheOp := &CachedOperation{
+		Operation: "supported-calendar-data",
+		Path:      calendarPath,
+		Body:      xmlBody,
+		TTL:       30 * time.Minute,
+	}
+
+	var types []string
+	if cached, found := c.getCachedResponse(ctx, cacheOp); found {
+		types, _ = cached.([]string)
+	} else {
+		resp, err := c.propfind(ctx, calendarPath, "0", xmlBody)
+		if err != nil {
+			return false, wrapError("calendar-data.execute", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode != 207 {
+			body, _ := io.ReadAll(resp.Body)
+			return false, newCalDAVError("calendar-data", resp.StatusCode, string(body))
+		}
+
+		msResp, err := parseMultiStatusResponse(resp.Body)
+		if err != nil {
+			return false, wrapErrorWithType("calendar-data.parse", ErrorTypeInvalidResponse, err)
+		}
+		for _, r := range msResp.Responses {
+			for _, ps := range r.Propstat {
+				if ps.Status == 200 {
+					types = append(types, ps.Prop.SupportedCalendarData...)
+				}
+			}
+		}
+		c.setCachedResponse(cacheOp, types)
+	}
+
+	return supportsContentType(types, contentType), nil
+}
+
+// supportsContentType reports whether contentType, ignoring parameters such
+// as charset, is one of types. Empty types means text/calendar only.
+func supportsContentType(types []string, contentType string) bool {
+	if len(types) == 0 {
+		types = []string{ContentTypeICalendar}
+	}
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	for _, t := range types {
+		if strings.EqualFold(strings.TrimSpace(strings.SplitN(t, ";", 2)[0]), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
 // DiscoverCalendars performs complete calendar discovery for the authenticated user.
 // This is a convenience method that calls FindCurrentUserPrincipal,
 // FindCalendarHomeSet, and FindCalendars in sequence.
@@ -32,12 +32,16 @@ type CalDAVClient struct {
 	autoParsing       bool
 	connectionMetrics *ConnectionMetrics
 	cache             *ResponseCache
+	// serverCompat is set by DetectServerType and ConfigureForICloud.
+	serverCompat *ServerCompatibility
 	// Sync optimization fields
 	etagCache      *ETagCache
 	preferDefaults *PreferHeader
 	batchSize      int
 	deltaStates    map[string]*DeltaSyncState
 	syncMu         sync.RWMutex
+	// syncStore is set by WithSyncStateStore.
+	syncStore SyncStateStore
 }
 
 // NewClient creates a new CalDAV client for iCloud.
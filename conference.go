@@ -0,0 +1,35 @@
+package caldav
+
+import "strings"
+
+// JoinConference picks the conference to offer as a "Join" action for an event
+// or todo with the given CONFERENCE properties: the first with the VIDEO
+// feature, otherwise the first web link, otherwise the first conference. ok is
+// false when there are none.
+func JoinConference(conferences []Conference) (conference Conference, ok bool) {
+	if len(conferences) == 0 {
+		return Conference{}, false
+	}
+
+	for _, c := range conferences {
+		if conferenceHasFeature(c, "VIDEO") {
+			return c, true
+		}
+	}
+	for _, c := range conferences {
+		uri := strings.ToLower(c.URI)
+		if strings.HasPrefix(uri, "https:") || strings.HasPrefix(uri, "http:") {
+			return c, true
+		}
+	}
+	return conferences[0], true
+}
+
+func conferenceHasFeature(c Conference, feature string) bool {
+	for _, f := range c.Features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package caldav
+
+import "testing"
+
+func TestJoinConference(t *testing.T) {
+	phone := Conference{URI: "tel:+1-412-555-0123,,,654321", Features: []string{"PHONE"}}
+	web := Conference{URI: "https://chat.example.com/audio?id=123456", Features: []string{"AUDIO"}}
+	video := Conference{URI: "https://video.example.com/123456", Features: []string{"AUDIO", "VIDEO"}, Label: "Video call"}
+
+	tests := []struct {
+		name        string
+		conferences []Conference
+		want        Conference
+		ok          bool
+	}{
+		{"none", nil, Conference{}, false},
+		{"video preferred", []Conference{phone, web, video}, video, true},
+		{"web link before phone", []Conference{phone, web}, web, true},
+		{"only phone", []Conference{phone}, phone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := JoinConference(tt.conferences)
+			if ok != tt.ok || got.URI != tt.want.URI {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.want.URI, tt.ok, got.URI, ok)
+			}
+		})
+	}
+}
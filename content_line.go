@@ -0,0 +1,164 @@
+package caldav
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the maximum length of a content line, excluding the line
+// break, before it must be folded (RFC 5545 section 3.1).
+const maxLineOctets = 75
+
+// icalParam is a single property parameter. Values may hold several
+// comma-separated entries when the parameter is multi-valued.
+type icalParam struct {
+	Name  string
+	Value string
+}
+
+// contentLineWriter writes iCalendar content lines. Long lines are folded at
+// 75 octets without splitting multi-byte UTF-8 sequences, TEXT values are
+// escaped and parameter values are quoted where required.
+type contentLineWriter struct {
+	buf strings.Builder
+}
+
+func (w *contentLineWriter) begin(component string) {
+	w.writeProperty("BEGIN", nil, component)
+}
+
+func (w *contentLineWriter) end(component string) {
+	w.writeProperty("END", nil, component)
+}
+
+// writeProperty writes a property whose value is already in its iCalendar
+// representation. Parameters with empty values are skipped.
+func (w *contentLineWriter) writeProperty(name string, params []icalParam, value string) {
+	var line strings.Builder
+	line.WriteString(name)
+	for _, param := range params {
+		if param.Value == "" {
+			continue
+		}
+		line.WriteByte(';')
+		line.WriteString(param.Name)
+		line.WriteByte('=')
+		line.WriteString(formatParamValue(param.Name, param.Value))
+	}
+	line.WriteByte(':')
+	line.WriteString(value)
+
+	w.writeFolded(line.String())
+}
+
+// writeICalProperty writes prop as given. Each value of a parameter is quoted
+// on its own, so parameters other than the known multi-valued ones keep all
+// their values.
+func (w *contentLineWriter) writeICalProperty(prop ICalProperty) {
+	var line strings.Builder
+	line.WriteString(prop.Name)
+	for _, param := range prop.Parameters {
+		values := make([]string, len(param.Values))
+		for i, value := range param.Values {
+			values[i] = formatParamValue(param.Name, value)
+		}
+		line.WriteByte(';')
+		line.WriteString(param.Name)
+		line.WriteByte('=')
+		line.WriteString(strings.Join(values, ","))
+	}
+	line.WriteByte(':')
+	line.WriteString(prop.Value)
+
+	w.writeFolded(line.String())
+}
+
+// writeText writes a property with a TEXT value, escaping it first.
+func (w *contentLineWriter) writeText(name string, params []icalParam, text string) {
+	w.writeProperty(name, params, escapeICalText(text))
+}
+
+func (w *contentLineWriter) String() string {
+	return w.buf.String()
+}
+
+func (w *contentLineWriter) writeFolded(line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// Invalid UTF-8 without a rune start to fold at.
+			cut = limit
+		}
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = maxLineOctets - 1
+	}
+	w.buf.WriteString(line)
+	w.buf.WriteString("\r\n")
+}
+
+// multiValuedParams lists parameters whose values are comma-separated lists of
+// quoted values, such as calendar user addresses or RFC 6638 status codes.
+var multiValuedParams = map[string]bool{
+	"MEMBER":          true,
+	"DELEGATED-TO":    true,
+	"DELEGATED-FROM":  true,
+	"SCHEDULE-STATUS": true,
+}
+
+// tokenListParams lists parameters whose values are comma-separated lists of
+// tokens, such as FEATURE=AUDIO,VIDEO.
+var tokenListParams = map[string]bool{
+	"FEATURE": true,
+	"DISPLAY": true,
+}
+
+// formatParamValue encodes a parameter value, quoting it when it contains
+// characters that are not allowed in an unquoted parameter value.
+func formatParamValue(name, value string) string {
+	if multiValuedParams[name] {
+		values := strings.Split(value, ",")
+		for i, v := range values {
+			values[i] = `"` + encodeParamValue(v) + `"`
+		}
+		return strings.Join(values, ",")
+	}
+	if tokenListParams[name] {
+		values := strings.Split(value, ",")
+		for i, v := range values {
+			values[i] = formatParamValue("", v)
+		}
+		return strings.Join(values, ",")
+	}
+
+	encoded := encodeParamValue(value)
+	if strings.ContainsAny(encoded, ":;,") {
+		return `"` + encoded + `"`
+	}
+	return encoded
+}
+
+// encodeParamValue applies RFC 6868 caret encoding so that newlines and double
+// quotes survive inside parameter values.
+func encodeParamValue(value string) string {
+	if !strings.ContainsAny(value, "^\n\"") {
+		return value
+	}
+	return strings.NewReplacer("^", "^^", "\n", "^n", `"`, "^'").Replace(value)
+}
+
+// escapeICalText escapes special characters in iCalendar text values.
+func escapeICalText(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "\r\n", "\\n")
+	text = strings.ReplaceAll(text, "\n", "\\n")
+	text = strings.ReplaceAll(text, ";", "\\;")
+	text = strings.ReplaceAll(text, ",", "\\,")
+	return text
+}
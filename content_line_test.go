@@ -0,0 +1,143 @@
+package caldav
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestContentLineWriter_Folding(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"short ascii", "Team sync"},
+		{"long ascii", strings.Repeat("Quarterly planning review ", 10)},
+		{"japanese", strings.Repeat("会議の議事録を確認してください。", 8)},
+		{"emoji", strings.Repeat("Party 🎉🎂 time ", 12)},
+		{"space at fold", strings.Repeat("a", 66) + "  spaced out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w contentLineWriter
+			w.writeText("SUMMARY", nil, tt.text)
+			output := w.String()
+
+			if !strings.HasSuffix(output, "\r\n") {
+				t.Fatalf("output must end with CRLF: %q", output)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n")
+			for i, line := range lines {
+				if len(line) > maxLineOctets {
+					t.Errorf("line %d is %d octets, exceeds %d", i, len(line), maxLineOctets)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a multi-byte character: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d must start with a space: %q", i, line)
+				}
+			}
+
+			parsed, err := ParseICalendar("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n" + output + "END:VEVENT\r\nEND:VCALENDAR\r\n")
+			if err != nil {
+				t.Fatalf("ParseICalendar failed: %v", err)
+			}
+			if got := parsed.Events[0].Summary; got != tt.text {
+				t.Errorf("unfolded summary mismatch\nexpected: %q\ngot:      %q", tt.text, got)
+			}
+		})
+	}
+}
+
+func TestContentLineWriter_FoldingInvalidUTF8(t *testing.T) {
+	value := strings.Repeat("\x80", 200)
+
+	done := make(chan string, 1)
+	go func() {
+		var w contentLineWriter
+		w.writeProperty("X-BINARY", nil, value)
+		done <- w.String()
+	}()
+
+	select {
+	case output := <-done:
+		lines := strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n")
+		for i, line := range lines {
+			if len(line) > maxLineOctets {
+				t.Errorf("line %d is %d octets, exceeds %d", i, len(line), maxLineOctets)
+			}
+		}
+		if got := strings.ReplaceAll(output, "\r\n ", ""); got != "X-BINARY:"+value+"\r\n" {
+			t.Errorf("unfolded output mismatch: %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("writeProperty did not return for invalid UTF-8")
+	}
+}
+
+func TestContentLineWriter_Parameters(t *testing.T) {
+	var w contentLineWriter
+	w.writeProperty("ATTENDEE", []icalParam{
+		{"CN", "Doe, Jane"},
+		{"ROLE", ""},
+		{"PARTSTAT", "ACCEPTED"},
+	}, "mailto:jane@example.com")
+
+	expected := "ATTENDEE;CN=\"Doe, Jane\";PARTSTAT=ACCEPTED:mailto:jane@example.com\r\n"
+	if got := w.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestEscapeICalText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"a,b;c", "a\\,b\\;c"},
+		{"back\\slash", "back\\\\slash"},
+		{"line1\nline2", "line1\\nline2"},
+		{"line1\r\nline2", "line1\\nline2"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeICalText(tt.input); got != tt.expected {
+			t.Errorf("escapeICalText(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+		if got := unescapeICalText(escapeICalText(tt.input)); got != strings.ReplaceAll(tt.input, "\r\n", "\n") {
+			t.Errorf("unescape round trip of %q: got %q", tt.input, got)
+		}
+	}
+}
+
+func TestFormatParamValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    string
+		value    string
+		expected string
+	}{
+		{"plain", "CN", "John", "John"},
+		{"colon", "DIR", "ldap://host", `"ldap://host"`},
+		{"comma", "CN", "Doe, Jane", `"Doe, Jane"`},
+		{"double quote", "CN", `Jane "JD" Doe`, `Jane ^'JD^' Doe`},
+		{"newline", "X-ADDRESS", "1 Main St\nTown", "1 Main St^nTown"},
+		{"multi-valued", "MEMBER", "mailto:a@x,mailto:b@x", `"mailto:a@x","mailto:b@x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatParamValue(tt.param, tt.value); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+			if got := parseParamValue(formatParamValue(tt.param, tt.value)); got != tt.value {
+				t.Errorf("round trip: expected %q, got %q", tt.value, got)
+			}
+		})
+	}
+}
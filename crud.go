@@ -75,7 +75,8 @@ func (c *CalDAVClient) CreateEventWithContext(ctx context.Context, calendarPath
 
 // UpdateEvent updates an existing event in the specified calendar.
 // The etag parameter should be the ETag from a previous fetch to ensure optimistic locking.
-// Pass an empty string for etag to force update without checking.
+// Pass an empty string for etag to force update without checking. When event
+// carries ParsedData, only the fields changed since it was read are applied.
 func (c *CalDAVClient) UpdateEvent(calendarPath string, event *CalendarObject, etag string) error {
 	return c.UpdateEventWithContext(context.Background(), calendarPath, event, etag)
 }
@@ -208,3 +209,104 @@ func (c *CalDAVClient) DeleteEventByUIDWithContext(ctx context.Context, calendar
 	eventPath := buildEventPath(calendarPath, uid)
 	return c.DeleteEventWithContext(ctx, eventPath)
 }
+
+// putCalendarData writes icalData to an existing resource, guarded by If-Match
+// when etag is set, and returns the new ETag reported by the server.
+func (c *CalDAVClient) putCalendarData(ctx context.Context, eventURL, uid, icalData, etag string) (string, error) {
+	return c.sendCalendarData(ctx, eventURL, uid, icalData, "If-Match", etag)
+}
+
+// createCalendarData writes icalData to a new resource, failing with an
+// EventExistsError when the resource already exists.
+func (c *CalDAVClient) createCalendarData(ctx context.Context, eventURL, uid, icalData string) (string, error) {
+	return c.sendCalendarData(ctx, eventURL, uid, icalData, "If-None-Match", "*")
+}
+
+// deleteCalendarData deletes the resource at eventURL exactly as given,
+// guarded by If-Match when etag is set. Unlike DeleteEventWithETag it reports a
+// missing resource as an EventNotFoundError.
+func (c *CalDAVClient) deleteCalendarData(ctx context.Context, eventURL, uid, etag string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, eventURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", c.authHeader)
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if c.debugHTTP {
+		c.logger.Debug("Deleting calendar resource", "url", eventURL, "uid", uid, "etag", etag)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed:
+		return &ETagMismatchError{Expected: etag}
+	case http.StatusNotFound:
+		return &EventNotFoundError{UID: uid}
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+func (c *CalDAVClient) sendCalendarData(ctx context.Context, eventURL, uid, icalData, precondition, value string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, eventURL, strings.NewReader(icalData))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("Authorization", c.authHeader)
+	if value != "" {
+		req.Header.Set(precondition, value)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if c.debugHTTP {
+		c.logger.Debug("Writing calendar resource", "url", eventURL, "uid", uid, precondition, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed:
+		if precondition == "If-None-Match" {
+			return "", &EventExistsError{UID: uid}
+		}
+		return "", &ETagMismatchError{Expected: value}
+	case http.StatusNotFound:
+		return "", &EventNotFoundError{UID: uid}
+	case http.StatusUnauthorized:
+		return "", ErrUnauthorized
+	case http.StatusForbidden:
+		return "", ErrForbidden
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+}
@@ -84,85 +84,324 @@ func generateICalendar(event *CalendarObject) (string, error) {
 		return "", fmt.Errorf("event cannot be nil")
 	}
 
-	var builder strings.Builder
-	builder.WriteString("BEGIN:VCALENDAR\r\n")
-	builder.WriteString("VERSION:2.0\r\n")
-	builder.WriteString("PRODID:-//go-icloud-caldav//EN\r\n")
-	builder.WriteString("CALSCALE:GREGORIAN\r\n")
-	builder.WriteString("BEGIN:VEVENT\r\n")
-
-	if event.UID != "" {
-		builder.WriteString(fmt.Sprintf("UID:%s\r\n", event.UID))
+	if event.ParsedData != nil && len(event.ParsedData.Events) > 0 {
+		return generateICalendarFromParsed(event)
 	}
 
 	now := time.Now().UTC()
-	builder.WriteString(fmt.Sprintf("DTSTAMP:%s\r\n", formatICalTime(now)))
-
+	created := now
 	if event.Created != nil {
-		builder.WriteString(fmt.Sprintf("CREATED:%s\r\n", formatICalTime(*event.Created)))
-	} else {
-		builder.WriteString(fmt.Sprintf("CREATED:%s\r\n", formatICalTime(now)))
+		created = *event.Created
 	}
-
+	lastModified := now
 	if event.LastModified != nil {
-		builder.WriteString(fmt.Sprintf("LAST-MODIFIED:%s\r\n", formatICalTime(*event.LastModified)))
-	} else {
-		builder.WriteString(fmt.Sprintf("LAST-MODIFIED:%s\r\n", formatICalTime(now)))
+		lastModified = *event.LastModified
 	}
 
-	if event.StartTime != nil {
-		builder.WriteString(fmt.Sprintf("DTSTART:%s\r\n", formatICalTime(*event.StartTime)))
+	endTime := event.EndTime
+	if endTime == nil && event.StartTime != nil {
+		defaultEnd := event.StartTime.Add(time.Hour)
+		if event.AllDay {
+			defaultEnd = event.StartTime.AddDate(0, 0, 1)
+		}
+		endTime = &defaultEnd
 	}
 
-	if event.EndTime != nil {
-		builder.WriteString(fmt.Sprintf("DTEND:%s\r\n", formatICalTime(*event.EndTime)))
-	} else if event.StartTime != nil {
-		endTime := event.StartTime.Add(time.Hour)
-		builder.WriteString(fmt.Sprintf("DTEND:%s\r\n", formatICalTime(endTime)))
+	var w icalEncoder
+	w.begin("VCALENDAR")
+	w.writeProperty("VERSION", nil, "2.0")
+	w.writeProperty("PRODID", nil, defaultProdID)
+	w.writeProperty("CALSCALE", nil, "GREGORIAN")
+
+	if err := w.writeMissingTimeZones(&ParsedCalendarData{Events: []ParsedEvent{{
+		DTStart:         event.StartTime,
+		DTEnd:           endTime,
+		AllDay:          event.AllDay,
+		Floating:        event.Floating,
+		RecurrenceID:    event.RecurrenceID,
+		ExceptionDates:  event.ExceptionDates,
+		RecurrenceDates: event.RecurrenceDates,
+	}}}); err != nil {
+		return "", fmt.Errorf("generating VTIMEZONE: %w", err)
 	}
 
+	w.begin("VEVENT")
+
+	if event.UID != "" {
+		w.writeProperty("UID", nil, event.UID)
+	}
+
+	w.writeProperty("DTSTAMP", nil, formatICalTime(now))
+	w.writeProperty("CREATED", nil, formatICalTime(created))
+	w.writeProperty("LAST-MODIFIED", nil, formatICalTime(lastModified))
+
+	form := eventTimeForm(event.AllDay, event.Floating)
+	w.writeDateTime("DTSTART", event.StartTime, form)
+	w.writeDateTime("DTEND", endTime, form)
+	w.writeDateTime("RECURRENCE-ID", event.RecurrenceID, form)
+
+	if event.RecurrenceRule != "" {
+		w.writeProperty("RRULE", nil, event.RecurrenceRule)
+	}
+	w.writeTimeList("RDATE", event.RecurrenceDates, form)
+	w.writeTimeList("EXDATE", event.ExceptionDates, form)
+
 	if event.Summary != "" {
-		builder.WriteString(fmt.Sprintf("SUMMARY:%s\r\n", escapeICalText(event.Summary)))
+		w.writeText("SUMMARY", nil, event.Summary)
 	}
 
 	if event.Description != "" {
-		builder.WriteString(fmt.Sprintf("DESCRIPTION:%s\r\n", escapeICalText(event.Description)))
+		w.writeText("DESCRIPTION", nil, event.Description)
 	}
 
 	if event.Location != "" {
-		builder.WriteString(fmt.Sprintf("LOCATION:%s\r\n", escapeICalText(event.Location)))
+		w.writeText("LOCATION", nil, event.Location)
 	}
 
 	if event.Status != "" {
-		builder.WriteString(fmt.Sprintf("STATUS:%s\r\n", event.Status))
+		w.writeProperty("STATUS", nil, event.Status)
 	} else {
-		builder.WriteString("STATUS:CONFIRMED\r\n")
+		w.writeProperty("STATUS", nil, "CONFIRMED")
 	}
 
 	if event.Organizer != "" {
-		builder.WriteString(fmt.Sprintf("ORGANIZER:%s\r\n", event.Organizer))
+		w.writeProperty("ORGANIZER", nil, event.Organizer)
 	}
 
 	for _, attendee := range event.Attendees {
-		builder.WriteString(fmt.Sprintf("ATTENDEE:%s\r\n", attendee))
+		w.writeProperty("ATTENDEE", nil, attendee)
+	}
+
+	w.end("VEVENT")
+	w.end("VCALENDAR")
+
+	return w.String(), nil
+}
+
+// generateICalendarFromParsed applies the CalendarObject fields the caller
+// changed to the master VEVENT of its ParsedData and encodes the whole
+// calendar, so that properties, components and overrides written by other
+// clients are preserved on update. ParsedData is authoritative: the simple
+// fields of a CalendarObject are read without unfolding or unescaping, so
+// only those that differ from what was read from CalendarData are applied.
+func generateICalendarFromParsed(event *CalendarObject) (string, error) {
+	data := *event.ParsedData
+	data.Events = append([]ParsedEvent(nil), event.ParsedData.Events...)
+
+	master := findMasterEvent(data.Events, event.UID)
+	if master == nil {
+		master = &data.Events[0]
+	}
+
+	var base *CalendarObject
+	if event.CalendarData != "" {
+		base = lineParsedCalendarObject(event.CalendarData)
+	}
+	applyCalendarObjectChanges(event, base, master, false)
+
+	encoded, err := EncodeICalendar(&data)
+	if err != nil {
+		return "", err
 	}
+	return string(encoded), nil
+}
 
-	builder.WriteString("END:VEVENT\r\n")
-	builder.WriteString("END:VCALENDAR\r\n")
+// findMasterEvent returns the VEVENT without a RECURRENCE-ID for uid, or nil.
+func findMasterEvent(events []ParsedEvent, uid string) *ParsedEvent {
+	for i := range events {
+		if events[i].RecurrenceID == nil && (uid == "" || events[i].UID == uid) {
+			return &events[i]
+		}
+	}
+	return nil
+}
 
-	return builder.String(), nil
+// lineParsedCalendarObject returns the CalendarObject fields the line parser
+// reads from data, as they were when the caller received the object.
+func lineParsedCalendarObject(data string) *CalendarObject {
+	var obj CalendarObject
+	parseCalendarData(&obj, data)
+	return &obj
+}
+
+// applyCalendarObjectChanges applies the fields of event the caller changed
+// to target. base is event as the line parser read it from the stored data,
+// and a field counts as changed when it differs from base; with setOnly,
+// fields left empty are not changes either. A nil base means there is no
+// stored data, so only fields that are set apply and Attendees is taken as
+// the complete list.
+func applyCalendarObjectChanges(event, base *CalendarObject, target *ParsedEvent, setOnly bool) {
+	completeAttendees := base == nil
+	if base == nil {
+		base = &CalendarObject{}
+		setOnly = true
+	}
+
+	now := time.Now().UTC()
+	if event.UID != "" {
+		target.UID = event.UID
+	}
+	target.DTStamp = &now
+
+	text := func(value, baseValue string, field *string) {
+		if value != baseValue && (value != "" || !setOnly) {
+			*field = value
+		}
+	}
+	text(event.Summary, base.Summary, &target.Summary)
+	text(event.Description, base.Description, &target.Description)
+	text(event.Location, base.Location, &target.Location)
+	text(event.Status, base.Status, &target.Status)
+	text(event.Class, base.Class, &target.Class)
+	text(event.Transparency, base.Transparency, &target.Transparency)
+	text(event.URL, base.URL, &target.URL)
+	text(event.RecurrenceRule, base.RecurrenceRule, &target.RecurrenceRule)
+
+	if event.Priority != base.Priority && (event.Priority != 0 || !setOnly) {
+		target.Priority = event.Priority
+	}
+
+	times := func(value, baseValue []time.Time, field *[]time.Time) {
+		if !sameTimeList(value, baseValue) && (len(value) > 0 || !setOnly) {
+			*field = value
+		}
+	}
+	times(event.ExceptionDates, base.ExceptionDates, &target.ExceptionDates)
+	times(event.RecurrenceDates, base.RecurrenceDates, &target.RecurrenceDates)
+
+	if !sameStringList(event.Categories, base.Categories) && (len(event.Categories) > 0 || !setOnly) {
+		target.Categories = event.Categories
+	}
+
+	if event.StartTime != nil && (!sameTimePtr(event.StartTime, base.StartTime) ||
+		event.AllDay != base.AllDay || event.Floating != base.Floating) {
+		target.DTStart = event.StartTime
+		target.AllDay = event.AllDay
+		target.Floating = event.Floating
+	}
+	if event.EndTime != nil && !sameTimePtr(event.EndTime, base.EndTime) {
+		target.DTEnd = event.EndTime
+		target.Duration = ""
+	}
+	if event.LastModified != nil && !sameTimePtr(event.LastModified, base.LastModified) {
+		target.LastModified = event.LastModified
+	}
+	if event.Created != nil && !sameTimePtr(event.Created, base.Created) {
+		target.Created = event.Created
+	}
+
+	if event.Organizer != "" && !sameCalendarAddress(event.Organizer, base.Organizer) &&
+		!sameCalendarAddress(event.Organizer, target.Organizer.Value) {
+		target.Organizer = ParsedOrganizer{Value: event.Organizer}
+	}
+
+	switch {
+	case completeAttendees:
+		if len(event.Attendees) > 0 {
+			target.Attendees = mergeAttendees(event.Attendees, target.Attendees)
+		}
+	case !sameStringList(event.Attendees, base.Attendees) && (len(event.Attendees) > 0 || !setOnly):
+		target.Attendees = updateAttendees(target.Attendees, base.Attendees, event.Attendees)
+	}
+
+	if len(event.CustomProperties) > 0 {
+		custom := make(map[string]string, len(target.CustomProperties)+len(event.CustomProperties))
+		for k, v := range target.CustomProperties {
+			custom[k] = v
+		}
+		for k, v := range event.CustomProperties {
+			custom[k] = v
+		}
+		target.CustomProperties = custom
+	}
+}
+
+// updateAttendees applies the difference between the addresses the caller
+// was given, before, and those it returned, after, to existing. Attendees the
+// line parser did not read, such as those with parameters, are kept.
+func updateAttendees(existing []ParsedAttendee, before, after []string) []ParsedAttendee {
+	contains := func(addresses []string, address string) bool {
+		for _, a := range addresses {
+			if sameCalendarAddress(a, address) {
+				return true
+			}
+		}
+		return false
+	}
+
+	updated := make([]ParsedAttendee, 0, len(existing)+len(after))
+	var kept []string
+	for _, att := range existing {
+		if contains(before, att.Value) && !contains(after, att.Value) {
+			continue
+		}
+		updated = append(updated, att)
+		kept = append(kept, att.Value)
+	}
+	for _, address := range after {
+		if contains(kept, address) {
+			continue
+		}
+		value := address
+		if !strings.Contains(value, ":") {
+			value = "mailto:" + value
+		}
+		updated = append(updated, ParsedAttendee{Value: value})
+		kept = append(kept, value)
+	}
+	return updated
+}
+
+func sameStringList(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeAttendees keeps the parsed attendee (with all its parameters) for every
+// address still present in addresses, and adds plain entries for new addresses.
+func mergeAttendees(addresses []string, existing []ParsedAttendee) []ParsedAttendee {
+	merged := make([]ParsedAttendee, 0, len(addresses))
+	for _, address := range addresses {
+		found := false
+		for _, att := range existing {
+			if sameCalendarAddress(address, att.Value) {
+				merged = append(merged, att)
+				found = true
+				break
+			}
+		}
+		if !found {
+			value := address
+			if !strings.Contains(value, ":") {
+				value = "mailto:" + value
+			}
+			merged = append(merged, ParsedAttendee{Value: value})
+		}
+	}
+	return merged
+}
+
+// sameCalendarAddress compares two calendar user addresses, ignoring case and
+// an optional mailto: scheme.
+func sameCalendarAddress(a, b string) bool {
+	return strings.EqualFold(stripMailto(a), stripMailto(b))
+}
+
+func stripMailto(address string) string {
+	if strings.HasPrefix(strings.ToLower(address), "mailto:") {
+		return address[7:]
+	}
+	return address
 }
 
 // formatICalTime formats a time.Time value in iCalendar format (YYYYMMDDTHHMMSSZ).
 func formatICalTime(t time.Time) string {
 	return t.UTC().Format("20060102T150405Z")
 }
-
-// escapeICalText escapes special characters in iCalendar text values.
-func escapeICalText(text string) string {
-	text = strings.ReplaceAll(text, "\\", "\\\\")
-	text = strings.ReplaceAll(text, "\n", "\\n")
-	text = strings.ReplaceAll(text, ";", "\\;")
-	text = strings.ReplaceAll(text, ",", "\\,")
-	return text
-}
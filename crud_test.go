@@ -544,6 +544,173 @@ func TestGenerateICalendar(t *testing.T) {
 	}
 }
 
+func TestGenerateICalendarPreservesParsedData(t *testing.T) {
+	parsed, err := ParseICalendar(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Other Client//EN
+BEGIN:VEVENT
+UID:preserve-uid
+DTSTART:20240115T100000Z
+DTEND:20240115T110000Z
+SUMMARY:Original
+ATTENDEE;PARTSTAT=ACCEPTED;CN=Keep Me:mailto:keep@example.com
+ATTENDEE:mailto:drop@example.com
+X-OTHER-CLIENT:opaque
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT10M
+END:VALARM
+END:VEVENT
+END:VCALENDAR`)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	event := &CalendarObject{
+		UID:        "preserve-uid",
+		Summary:    "Updated",
+		StartTime:  parsed.Events[0].DTStart,
+		EndTime:    parsed.Events[0].DTEnd,
+		Attendees:  []string{"keep@example.com", "new@example.com"},
+		ParsedData: parsed,
+	}
+
+	ical, err := generateICalendar(event)
+	if err != nil {
+		t.Fatalf("generateICalendar failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"PRODID:-//Other Client//EN",
+		"SUMMARY:Updated",
+		"ATTENDEE;PARTSTAT=ACCEPTED;CN=Keep Me:mailto:keep@example.com",
+		"ATTENDEE:mailto:new@example.com",
+		"X-OTHER-CLIENT:opaque",
+		"BEGIN:VALARM",
+	} {
+		if !strings.Contains(ical, want) {
+			t.Errorf("expected output to contain %q\n%s", want, ical)
+		}
+	}
+	if strings.Contains(ical, "drop@example.com") {
+		t.Error("removed attendee should not be written")
+	}
+	if parsed.Events[0].Summary != "Original" {
+		t.Error("generateICalendar must not modify the parsed data in place")
+	}
+}
+
+func TestGenerateICalendarAppliesOnlyChangedFields(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//Other Client//EN\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:lunch\r\n" +
+		"DTSTART:20240115T120000Z\r\n" +
+		"DTEND:20240115T130000Z\r\n" +
+		"SUMMARY:Lunch\\, then talk\r\n" +
+		"DESCRIPTION:Bring the quarterly figures and the notes from last week's plan\r\n" +
+		" ning session\r\n" +
+		"LOCATION:Room 1\r\n" +
+		"ATTENDEE;PARTSTAT=ACCEPTED:mailto:keep@example.com\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"ACTION:DISPLAY\r\n" +
+		"DESCRIPTION:Reminder\r\n" +
+		"TRIGGER:-PT10M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	parsed, err := ParseICalendar(ics)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	// The object as a query returns it, with the caller's changes on top.
+	event := &CalendarObject{CalendarData: ics, ParsedData: parsed}
+	parseCalendarData(event, ics)
+	event.Location = "Room 2"
+	event.Attendees = append(event.Attendees, "new@example.com")
+
+	ical, err := generateICalendar(event)
+	if err != nil {
+		t.Fatalf("generateICalendar failed: %v", err)
+	}
+	updated, err := ParseICalendar(ical)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	got := updated.Events[0]
+	want := parsed.Events[0]
+	if got.Summary != want.Summary || got.Description != want.Description {
+		t.Errorf("expected summary %q and description %q to be kept, got %q and %q", want.Summary, want.Description, got.Summary, got.Description)
+	}
+	if got.Location != "Room 2" {
+		t.Errorf("expected the changed location, got %q", got.Location)
+	}
+	if len(got.Attendees) != 2 || got.Attendees[0].PartStat != "ACCEPTED" || got.Attendees[1].Value != "mailto:new@example.com" {
+		t.Errorf("unexpected attendees %+v", got.Attendees)
+	}
+	if len(got.Alarms) != 1 || got.Alarms[0].Description != "Reminder" {
+		t.Errorf("unexpected alarms %+v", got.Alarms)
+	}
+}
+
+func TestGenerateICalendarTimeZoneAndRecurrence(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	event := &CalendarObject{
+		UID:            "tz-recurring",
+		Summary:        "Weekly sync",
+		StartTime:      timePtrForCrud(time.Date(2024, 3, 4, 9, 0, 0, 0, london)),
+		RecurrenceRule: "FREQ=WEEKLY;BYDAY=MO",
+		ExceptionDates: []time.Time{time.Date(2024, 4, 1, 9, 0, 0, 0, london)},
+	}
+
+	ical, err := generateICalendar(event)
+	if err != nil {
+		t.Fatalf("generateICalendar failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+		"DTSTART;TZID=Europe/London:20240304T090000\r\n",
+		"DTEND;TZID=Europe/London:20240304T100000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n",
+		"EXDATE;TZID=Europe/London:20240401T090000\r\n",
+	} {
+		if !strings.Contains(ical, want) {
+			t.Errorf("expected output to contain %q\n%s", want, ical)
+		}
+	}
+}
+
+func TestGenerateICalendarAllDay(t *testing.T) {
+	event := &CalendarObject{
+		UID:       "holiday",
+		Summary:   "Holiday",
+		StartTime: timePtrForCrud(time.Date(2024, 12, 25, 0, 0, 0, 0, time.Local)),
+		AllDay:    true,
+	}
+
+	ical, err := generateICalendar(event)
+	if err != nil {
+		t.Fatalf("generateICalendar failed: %v", err)
+	}
+
+	for _, want := range []string{"DTSTART;VALUE=DATE:20241225\r\n", "DTEND;VALUE=DATE:20241226\r\n"} {
+		if !strings.Contains(ical, want) {
+			t.Errorf("expected output to contain %q\n%s", want, ical)
+		}
+	}
+	if strings.Contains(ical, "VTIMEZONE") {
+		t.Errorf("all-day events should not carry a VTIMEZONE\n%s", ical)
+	}
+}
+
 func TestBuildEventURL(t *testing.T) {
 	tests := []struct {
 		name         string
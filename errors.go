@@ -32,26 +32,30 @@ const (
 )
 
 var (
-	ErrAuthentication        = errors.New("authentication failed")
-	ErrNotFound              = errors.New("resource not found")
-	ErrPreconditionFailed    = errors.New("precondition failed")
-	ErrInvalidResponse       = errors.New("invalid server response")
-	ErrTimeout               = errors.New("request timeout")
-	ErrCanceled              = errors.New("request canceled")
-	ErrInvalidXML            = errors.New("invalid XML")
-	ErrNoCalendars           = errors.New("no calendars found")
-	ErrInvalidTimeRange      = errors.New("invalid time range")
-	ErrNetwork               = errors.New("network error")
-	ErrRateLimit             = errors.New("rate limit exceeded")
-	ErrServerError           = errors.New("server error")
-	ErrInvalidRequest        = errors.New("invalid request")
-	ErrPermission            = errors.New("permission denied")
-	ErrConflict              = errors.New("resource conflict")
-	ErrValidation            = errors.New("validation failed")
-	ErrCalendarAlreadyExists = errors.New("calendar already exists")
-	ErrCalendarNotFound      = errors.New("calendar not found")
-	ErrUnauthorized          = errors.New("unauthorized")
-	ErrForbidden             = errors.New("forbidden")
+	ErrAuthentication          = errors.New("authentication failed")
+	ErrNotFound                = errors.New("resource not found")
+	ErrPreconditionFailed      = errors.New("precondition failed")
+	ErrInvalidResponse         = errors.New("invalid server response")
+	ErrTimeout                 = errors.New("request timeout")
+	ErrCanceled                = errors.New("request canceled")
+	ErrInvalidXML              = errors.New("invalid XML")
+	ErrNoCalendars             = errors.New("no calendars found")
+	ErrInvalidTimeRange        = errors.New("invalid time range")
+	ErrNetwork                 = errors.New("network error")
+	ErrRateLimit               = errors.New("rate limit exceeded")
+	ErrServerError             = errors.New("server error")
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrPermission              = errors.New("permission denied")
+	ErrConflict                = errors.New("resource conflict")
+	ErrValidation              = errors.New("validation failed")
+	ErrCalendarAlreadyExists   = errors.New("calendar already exists")
+	ErrCalendarNotFound        = errors.New("calendar not found")
+	ErrUnauthorized            = errors.New("unauthorized")
+	ErrForbidden               = errors.New("forbidden")
+	ErrStaleITIPMessage        = errors.New("iTIP message is older than the stored event")
+	ErrSchedulingUnsupported   = errors.New("server does not support CalDAV scheduling")
+	ErrComponentUnsupported    = errors.New("calendar does not support the component type")
+	ErrAvailabilityUnsupported = errors.New("server does not support calendar availability")
 )
 
 type CalDAVError struct {
@@ -258,6 +262,7 @@ var errorTypeMap = []struct {
 	{ErrPermission, ErrorTypePermission},
 	{ErrConflict, ErrorTypeConflict},
 	{ErrValidation, ErrorTypeValidation},
+	{ErrStaleITIPMessage, ErrorTypeConflict},
 }
 
 func inferErrorType(err error) ErrorType {
@@ -11,8 +11,23 @@ import (
 // QueryCalendar performs a REPORT request on a calendar with a custom query.
 // The calendarPath should be a calendar URL obtained from FindCalendars.
 // Returns matching calendar objects (events, todos, etc.).
+//
+// When query.Expand is set and the server is known not to support expansion,
+// or returns recurring events unexpanded, the events are expanded locally.
+// Likewise query.ContentType falls back to iCalendar when the calendar does
+// not advertise it.
 func (c *CalDAVClient) QueryCalendar(ctx context.Context, calendarPath string, query CalendarQuery) ([]CalendarObject, error) {
-	xmlBody, err := buildCalendarQueryXML(query)
+	request := query
+	if query.Expand != nil && !c.supportsServerExpand() {
+		request.Expand = nil
+	}
+	if query.ContentType != "" {
+		if supported, err := c.SupportsCalendarData(ctx, calendarPath, query.ContentType); err != nil || !supported {
+			request.ContentType = ""
+		}
+	}
+
+	xmlBody, err := buildCalendarQueryXML(request)
 	if err != nil {
 		return nil, wrapErrorWithType("query.build", ErrorTypeInvalidRequest, err)
 	}
@@ -35,9 +50,67 @@ func (c *CalDAVClient) QueryCalendar(ctx context.Context, calendarPath string, q
 
 	objects := extractCalendarObjectsFromResponseWithOptions(msResp, c.autoParsing)
 
+	if query.Expand != nil {
+		expandCalendarObjects(objects, query.Expand.Start, query.Expand.End)
+	}
+
 	return objects, nil
 }
 
+func (c *CalDAVClient) supportsServerExpand() bool {
+	if c.serverCompat == nil {
+		return true
+	}
+	return c.serverCompat.Capabilities[CapCalendarExpand]
+}
+
+// expandCalendarObjects replaces recurring events in objects with their
+// instances between start and end, as a server would for <C:expand>.
+// Objects without a recurrence rule are left untouched.
+func expandCalendarObjects(objects []CalendarObject, start, end time.Time) {
+	for i := range objects {
+		obj := &objects[i]
+		data := obj.ParsedData
+		if data == nil {
+			parsed, err := ParseICalendar(obj.CalendarData)
+			if err != nil {
+				continue
+			}
+			data = parsed
+		}
+		if !hasRecurringEvent(data) {
+			continue
+		}
+
+		expanded, err := ExpandEvents(data, start, end)
+		if err != nil {
+			continue
+		}
+		for j := range expanded.Events {
+			instance := &expanded.Events[j]
+			instance.RecurrenceRule = ""
+			instance.ExceptionRule = ""
+			instance.RecurrenceDates = nil
+			instance.ExceptionDates = nil
+		}
+		encoded, err := EncodeICalendar(expanded)
+		if err != nil {
+			continue
+		}
+		obj.CalendarData = string(encoded)
+		obj.ParsedData = expanded
+	}
+}
+
+func hasRecurringEvent(data *ParsedCalendarData) bool {
+	for _, event := range data.Events {
+		if event.RecurrenceID == nil && event.RecurrenceRule != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // GetRecentEvents retrieves events within a specified number of days before and after today.
 // For example, days=7 returns events from 7 days ago to 7 days in the future.
 func (c *CalDAVClient) GetRecentEvents(ctx context.Context, calendarPath string, days int) ([]CalendarObject, error) {
@@ -570,8 +643,8 @@ func buildAdvancedCompFilterXML(builder *strings.Builder, cf Filter, query Advan
 
 	if cf.TimeRange != nil {
 		fmt.Fprintf(builder, `<C:time-range start="%s" end="%s"/>`,
-			cf.TimeRange.Start.Format("20060102T150405Z"),
-			cf.TimeRange.End.Format("20060102T150405Z"))
+			formatTimeForCalDAV(cf.TimeRange.Start),
+			formatTimeForCalDAV(cf.TimeRange.End))
 	}
 
 	for _, propFilter := range cf.Props {
@@ -2,6 +2,7 @@ package caldav
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -1273,3 +1274,70 @@ func TestBuildComplexQuery(t *testing.T) {
 		t.Errorf("expected 2 prop filters, got %d", len(eventFilter.Props))
 	}
 }
+
+func TestQueryCalendar_Expand(t *testing.T) {
+	recurringData := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\n" +
+		"BEGIN:VEVENT\r\nUID:daily\r\nSUMMARY:Standup\r\n" +
+		"DTSTART:20250113T090000Z\r\nDTEND:20250113T091500Z\r\nRRULE:FREQ=DAILY;COUNT=10\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	tests := []struct {
+		name         string
+		configure    func(*CalDAVClient)
+		expectExpand bool
+	}{
+		{name: "server expansion", configure: func(*CalDAVClient) {}, expectExpand: true},
+		{name: "local fallback for iCloud", configure: (*CalDAVClient).ConfigureForICloud, expectExpand: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if got := strings.Contains(string(body), "<C:expand "); got != tt.expectExpand {
+					t.Errorf("expected <C:expand> in request: %v, got body %s", tt.expectExpand, body)
+				}
+				// The server ignores expand and returns the master event either way.
+				w.WriteHeader(207)
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:response><D:href>/cal/daily.ics</D:href>
+<D:propstat><D:status>HTTP/1.1 200 OK</D:status><D:prop><D:getetag>"1"</D:getetag>
+<C:calendar-data>` + recurringData + `</C:calendar-data></D:prop></D:propstat></D:response></D:multistatus>`))
+			}))
+			defer server.Close()
+
+			client := NewClient("testuser", "testpass")
+			tt.configure(client)
+			client.baseURL = server.URL
+
+			window := &TimeRange{
+				Start: time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC),
+			}
+			objects, err := client.QueryCalendar(context.Background(), "/cal/", CalendarQuery{
+				Properties: []string{"getetag", "calendar-data"},
+				TimeRange:  window,
+				Expand:     window,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(objects) != 1 || objects[0].ParsedData == nil {
+				t.Fatalf("expected one parsed object, got %+v", objects)
+			}
+
+			events := objects[0].ParsedData.Events
+			if len(events) != 3 {
+				t.Fatalf("expected 3 instances, got %d", len(events))
+			}
+			for _, event := range events {
+				if event.RecurrenceRule != "" || event.RecurrenceID == nil {
+					t.Errorf("expected an expanded instance, got %+v", event)
+				}
+			}
+			if strings.Contains(objects[0].CalendarData, "RRULE") {
+				t.Errorf("expected calendar data without RRULE, got %s", objects[0].CalendarData)
+			}
+		})
+	}
+}
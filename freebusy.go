@@ -0,0 +1,199 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+)
+
+// FreeBusy types defined by RFC 5545.
+const (
+	FBTypeFree            = "FREE"
+	FBTypeBusy            = "BUSY"
+	FBTypeBusyUnavailable = "BUSY-UNAVAILABLE"
+	FBTypeBusyTentative   = "BUSY-TENTATIVE"
+)
+
+// FreeBusyQuery returns the busy time in the calendar at calendarHref between
+// start and end. Periods of the same FBTYPE are merged where they overlap or
+// touch, clipped to the range and sorted by start time; FREE periods are
+// omitted.
+//
+// The RFC 4791 free-busy-query report is used when the server supports it.
+// Servers that reject the report, such as iCloud, are answered from a
+// time-range calendar-query instead: recurring events are expanded, and events
+// marked TRANSP:TRANSPARENT or STATUS:CANCELLED are ignored.
+func (c *CalDAVClient) FreeBusyQuery(ctx context.Context, calendarHref string, start, end time.Time) ([]FreeBusyPeriod, error) {
+	if !end.After(start) {
+		return nil, newTypedError("freebusy", ErrorTypeValidation, "end must be after start", nil)
+	}
+
+	resp, err := c.report(ctx, calendarHref, buildFreeBusyQueryXML(start, end))
+	if err != nil {
+		return nil, wrapError("freebusy.execute", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, wrapErrorWithType("freebusy.read", ErrorTypeNetwork, err)
+	}
+
+	switch {
+	case resp.StatusCode == 200:
+		data, err := ParseICalendar(string(body))
+		if err != nil {
+			return nil, wrapErrorWithType("freebusy.parse", ErrorTypeInvalidResponse, err)
+		}
+		var periods []FreeBusyPeriod
+		for _, fb := range data.FreeBusy {
+			periods = append(periods, fb.FreeBusy...)
+		}
+		return mergeFreeBusyPeriods(periods, start, end), nil
+	case freeBusyReportUnsupported(resp.StatusCode):
+		c.logger.Debug("free-busy-query not supported (status %d), computing from events", resp.StatusCode)
+		return c.freeBusyFromEvents(ctx, calendarHref, start, end)
+	default:
+		return nil, newCalDAVError("freebusy", resp.StatusCode, string(body))
+	}
+}
+
+func freeBusyReportUnsupported(statusCode int) bool {
+	switch statusCode {
+	case 400, 403, 405, 415, 422, 501:
+		return true
+	}
+	return false
+}
+
+// freeBusyFromEvents computes busy periods from the events in the calendar.
+func (c *CalDAVClient) freeBusyFromEvents(ctx context.Context, calendarHref string, start, end time.Time) ([]FreeBusyPeriod, error) {
+	objects, err := c.GetEventsByTimeRange(ctx, calendarHref, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []FreeBusyPeriod
+	for _, obj := range objects {
+		data := obj.ParsedData
+		if data == nil {
+			if data, err = ParseICalendar(obj.CalendarData); err != nil {
+				c.logger.Warn("skipping %s in free/busy: %v", obj.Href, err)
+				continue
+			}
+		}
+		periods = append(periods, eventBusyPeriods(data, start, end)...)
+	}
+
+	return mergeFreeBusyPeriods(periods, start, end), nil
+}
+
+// eventBusyPeriods returns a busy period for every occurrence in data that
+// overlaps start to end. All-day and floating times are taken in start's
+// location.
+func eventBusyPeriods(data *ParsedCalendarData, start, end time.Time) []FreeBusyPeriod {
+	// Occurrences are selected by start time, so widen the window enough to
+	// catch ones that began earlier and wall-clock times in any zone.
+	var longest time.Duration
+	for i := range data.Events {
+		if d := eventDuration(&data.Events[i]); d > longest {
+			longest = d
+		}
+	}
+	expanded, err := ExpandEvents(data, start.Add(-longest-24*time.Hour), end.Add(24*time.Hour))
+	if err != nil || expanded == nil {
+		return nil
+	}
+
+	var periods []FreeBusyPeriod
+	for i := range expanded.Events {
+		event := &expanded.Events[i]
+		if event.DTStart == nil || event.Transparency == "TRANSPARENT" || event.Status == "CANCELLED" {
+			continue
+		}
+
+		busyStart := *event.DTStart
+		busyEnd := busyStart.Add(eventDuration(event))
+		if event.AllDay || event.Floating {
+			busyStart = wallClockIn(busyStart, start.Location())
+			busyEnd = wallClockIn(busyEnd, start.Location())
+		}
+		if !busyEnd.After(busyStart) || !busyEnd.After(start) || !busyStart.Before(end) {
+			continue
+		}
+
+		fbType := FBTypeBusy
+		if event.Status == "TENTATIVE" {
+			fbType = FBTypeBusyTentative
+		}
+		periods = append(periods, FreeBusyPeriod{Start: busyStart, End: busyEnd, FBType: fbType})
+	}
+	return periods
+}
+
+// eventDuration returns the length of an event from DTEND or DURATION. An
+// all-day event without either lasts one day; other events last no time.
+func eventDuration(event *ParsedEvent) time.Duration {
+	if event.DTStart == nil {
+		return 0
+	}
+	if event.DTEnd != nil {
+		return event.DTEnd.Sub(*event.DTStart)
+	}
+	if event.Duration != "" {
+		if d, _, err := parseISO8601Duration(event.Duration); err == nil {
+			return d
+		}
+	}
+	if event.AllDay {
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// mergeFreeBusyPeriods clips periods to start and end, drops FREE periods and
+// merges overlapping or adjacent periods of the same FBTYPE.
+func mergeFreeBusyPeriods(periods []FreeBusyPeriod, start, end time.Time) []FreeBusyPeriod {
+	byType := make(map[string][]FreeBusyPeriod)
+	for _, p := range periods {
+		if p.FBType == "" {
+			p.FBType = FBTypeBusy
+		}
+		if p.FBType == FBTypeFree {
+			continue
+		}
+		if p.Start.Before(start) {
+			p.Start = start
+		}
+		if p.End.After(end) {
+			p.End = end
+		}
+		if !p.End.After(p.Start) {
+			continue
+		}
+		byType[p.FBType] = append(byType[p.FBType], p)
+	}
+
+	merged := make([]FreeBusyPeriod, 0, len(periods))
+	for _, fbType := range sortedKeys(byType) {
+		list := byType[fbType]
+		sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+
+		current := list[0]
+		for _, p := range list[1:] {
+			if !p.Start.After(current.End) {
+				if p.End.After(current.End) {
+					current.End = p.End
+				}
+				continue
+			}
+			merged = append(merged, current)
+			current = p
+		}
+		merged = append(merged, current)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Start.Before(merged[j].Start) })
+	return merged
+}
@@ -0,0 +1,118 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFreeBusyQuery_Report(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `<C:free-busy-query`) ||
+			!strings.Contains(string(body), `<C:time-range start="20250115T000000Z" end="20250116T000000Z"/>`) {
+			t.Errorf("unexpected request body: %s", body)
+		}
+
+		w.Header().Set("Content-Type", "text/calendar")
+		_, _ = w.Write([]byte("BEGIN:VCALENDAR\r\n" +
+			"VERSION:2.0\r\n" +
+			"PRODID:-//Test//Test//EN\r\n" +
+			"BEGIN:VFREEBUSY\r\n" +
+			"DTSTART:20250115T000000Z\r\n" +
+			"DTEND:20250116T000000Z\r\n" +
+			"FREEBUSY:20250115T090000Z/20250115T100000Z,20250115T093000Z/PT1H\r\n" +
+			"FREEBUSY;FBTYPE=BUSY-TENTATIVE:20250115T140000Z/20250115T150000Z\r\n" +
+			"FREEBUSY;FBTYPE=FREE:20250115T160000Z/20250115T170000Z\r\n" +
+			"END:VFREEBUSY\r\n" +
+			"END:VCALENDAR\r\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	periods, err := client.FreeBusyQuery(context.Background(), "/cal/", start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []FreeBusyPeriod{
+		{Start: mustParseTime("20250115T090000Z"), End: mustParseTime("20250115T103000Z"), FBType: FBTypeBusy},
+		{Start: mustParseTime("20250115T140000Z"), End: mustParseTime("20250115T150000Z"), FBType: FBTypeBusyTentative},
+	}
+	if !reflect.DeepEqual(periods, expected) {
+		t.Errorf("expected %+v, got %+v", expected, periods)
+	}
+}
+
+func TestFreeBusyQuery_FallbackToEvents(t *testing.T) {
+	events := []string{
+		"UID:opaque\r\nDTSTART:20250115T090000Z\r\nDTEND:20250115T100000Z\r\n",
+		"UID:transparent\r\nDTSTART:20250115T110000Z\r\nDTEND:20250115T120000Z\r\nTRANSP:TRANSPARENT\r\n",
+		"UID:cancelled\r\nDTSTART:20250115T120000Z\r\nDTEND:20250115T130000Z\r\nSTATUS:CANCELLED\r\n",
+		"UID:tentative\r\nDTSTART:20250115T140000Z\r\nDURATION:PT30M\r\nSTATUS:TENTATIVE\r\n",
+		"UID:daily\r\nDTSTART:20250110T093000Z\r\nDTEND:20250110T103000Z\r\nRRULE:FREQ=DAILY\r\n",
+		"UID:overnight\r\nDTSTART:20250114T220000Z\r\nDTEND:20250115T010000Z\r\n",
+	}
+
+	var reports []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "<C:free-busy-query") {
+			reports = append(reports, "free-busy-query")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		reports = append(reports, "calendar-query")
+
+		var sb strings.Builder
+		sb.WriteString(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+		for _, event := range events {
+			sb.WriteString(`<D:response><D:href>/cal/e.ics</D:href><D:propstat><D:status>HTTP/1.1 200 OK</D:status><D:prop><D:getetag>"1"</D:getetag><C:calendar-data>`)
+			sb.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\nBEGIN:VEVENT\r\n" + event + "END:VEVENT\r\nEND:VCALENDAR\r\n")
+			sb.WriteString(`</C:calendar-data></D:prop></D:propstat></D:response>`)
+		}
+		sb.WriteString(`</D:multistatus>`)
+		w.WriteHeader(207)
+		_, _ = w.Write([]byte(sb.String()))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	periods, err := client.FreeBusyQuery(context.Background(), "/cal/", start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(reports, []string{"free-busy-query", "calendar-query"}) {
+		t.Errorf("expected a report followed by a calendar-query, got %v", reports)
+	}
+
+	expected := []FreeBusyPeriod{
+		{Start: mustParseTime("20250115T000000Z"), End: mustParseTime("20250115T010000Z"), FBType: FBTypeBusy},
+		{Start: mustParseTime("20250115T090000Z"), End: mustParseTime("20250115T103000Z"), FBType: FBTypeBusy},
+		{Start: mustParseTime("20250115T140000Z"), End: mustParseTime("20250115T143000Z"), FBType: FBTypeBusyTentative},
+	}
+	if !reflect.DeepEqual(periods, expected) {
+		t.Errorf("expected %+v, got %+v", expected, periods)
+	}
+}
+
+func TestFreeBusyQuery_InvalidRange(t *testing.T) {
+	client := NewClient("testuser", "testpass")
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	if _, err := client.FreeBusyQuery(context.Background(), "/cal/", start, start); !IsValidationError(err) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,843 @@
+package caldav
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultProdID = "-//go-icloud-caldav//EN"
+
+// EncodeICalendar serializes ParsedCalendarData into iCalendar (RFC 5545) format.
+// Every component and property understood by ParseICalendar is written back,
+// so a parse, encode and parse round trip yields the same ParsedCalendarData.
+func EncodeICalendar(data *ParsedCalendarData) ([]byte, error) {
+	if data == nil {
+		return nil, newTypedError("ical.encode", ErrorTypeValidation, "calendar data cannot be nil", nil)
+	}
+
+	enc := &icalEncoder{}
+	enc.begin("VCALENDAR")
+
+	version := data.Version
+	if version == "" {
+		version = "2.0"
+	}
+	prodID := data.ProdID
+	if prodID == "" {
+		prodID = defaultProdID
+	}
+
+	enc.writeProperty("VERSION", nil, version)
+	enc.writeProperty("PRODID", nil, prodID)
+	if data.CalScale != "" {
+		enc.writeProperty("CALSCALE", nil, data.CalScale)
+	}
+	if data.Method != "" {
+		enc.writeProperty("METHOD", nil, data.Method)
+	}
+	enc.writeTextIfSet("NAME", data.Name)
+	enc.writeTextIfSet("DESCRIPTION", data.Description)
+	enc.writeString("COLOR", data.Color)
+	if data.RefreshInterval != "" {
+		enc.writeProperty("REFRESH-INTERVAL", []icalParam{{"VALUE", "DURATION"}}, data.RefreshInterval)
+	}
+	enc.writeImages(data.Images)
+	enc.writeCustomProperties(data.CustomProperties, data.ExtraProperties)
+
+	for i := range data.TimeZones {
+		enc.writeTimeZone(&data.TimeZones[i])
+	}
+	if err := enc.writeMissingTimeZones(data); err != nil {
+		return nil, err
+	}
+	for i := range data.Events {
+		enc.writeEvent(&data.Events[i])
+	}
+	for i := range data.Todos {
+		enc.writeTodo(&data.Todos[i])
+	}
+	for i := range data.Journals {
+		enc.writeJournal(&data.Journals[i])
+	}
+	for i := range data.Availability {
+		enc.writeAvailability(&data.Availability[i])
+	}
+	for i := range data.FreeBusy {
+		enc.writeFreeBusy(&data.FreeBusy[i])
+	}
+	// Alarms that were not nested in an event or todo are written at calendar
+	// level, mirroring where ParseICalendar found them.
+	for i := range data.Alarms {
+		enc.writeAlarm(&data.Alarms[i])
+	}
+
+	enc.end("VCALENDAR")
+
+	return []byte(enc.String()), nil
+}
+
+type icalEncoder struct {
+	contentLineWriter
+}
+
+// writeTime writes a property whose value must be in UTC, such as DTSTAMP.
+func (e *icalEncoder) writeTime(name string, t *time.Time) {
+	if t != nil {
+		e.writeProperty(name, nil, formatICalTime(*t))
+	}
+}
+
+// timeForm selects how a DATE or DATE-TIME value is written.
+type timeForm int
+
+const (
+	formDateTime timeForm = iota // UTC, or local time with a TZID
+	formDate                     // VALUE=DATE
+	formFloating                 // local time without a time zone
+)
+
+// eventTimeForm returns the form of an event's start, end and recurrence
+// times, all of which follow DTSTART, and of a todo's DTSTART and DUE.
+func eventTimeForm(allDay, floating bool) timeForm {
+	switch {
+	case allDay:
+		return formDate
+	case floating:
+		return formFloating
+	default:
+		return formDateTime
+	}
+}
+
+// writeDateTime writes a DATE or DATE-TIME property. In formDateTime the time
+// zone of t is kept as a TZID parameter when it has an IANA name.
+func (e *icalEncoder) writeDateTime(name string, t *time.Time, form timeForm) {
+	if t != nil {
+		value, params := formatICalDateTime(*t, form)
+		e.writeProperty(name, params, value)
+	}
+}
+
+// writeRecurrenceID writes RECURRENCE-ID with its optional RANGE parameter.
+func (e *icalEncoder) writeRecurrenceID(t *time.Time, recurrenceRange string, form timeForm) {
+	if t != nil {
+		value, params := formatICalDateTime(*t, form)
+		params = append(params, icalParam{"RANGE", recurrenceRange})
+		e.writeProperty("RECURRENCE-ID", params, value)
+	}
+}
+
+// writeTimeList writes a multi-valued DATE-TIME property such as EXDATE. Values
+// in different time zones are written on separate lines.
+func (e *icalEncoder) writeTimeList(name string, times []time.Time, form timeForm) {
+	var values []string
+	var params []icalParam
+	for i, t := range times {
+		value, p := formatICalDateTime(t, form)
+		if i > 0 && !sameParams(p, params) {
+			e.writeProperty(name, params, strings.Join(values, ","))
+			values = nil
+		}
+		values = append(values, value)
+		params = p
+	}
+	if len(values) > 0 {
+		e.writeProperty(name, params, strings.Join(values, ","))
+	}
+}
+
+// writeMissingTimeZones generates a VTIMEZONE from Go's tzdata for every TZID
+// referenced by the calendar that it does not already define.
+func (e *icalEncoder) writeMissingTimeZones(data *ParsedCalendarData) error {
+	defined := make(map[string]bool, len(data.TimeZones))
+	for _, tz := range data.TimeZones {
+		defined[tz.TZID] = true
+	}
+
+	earliest := make(map[string]time.Time)
+	locations := make(map[string]*time.Location)
+	note := func(t *time.Time) {
+		if t == nil {
+			return
+		}
+		tzid := tzidForLocation(t.Location())
+		if tzid == "" || defined[tzid] {
+			return
+		}
+		if first, ok := earliest[tzid]; !ok || t.Before(first) {
+			earliest[tzid] = *t
+			locations[tzid] = t.Location()
+		}
+	}
+
+	for i := range data.Events {
+		event := &data.Events[i]
+		if event.AllDay || event.Floating {
+			continue
+		}
+		note(event.DTStart)
+		note(event.DTEnd)
+		note(event.RecurrenceID)
+		for j := range event.RecurrenceDates {
+			note(&event.RecurrenceDates[j])
+		}
+		for j := range event.ExceptionDates {
+			note(&event.ExceptionDates[j])
+		}
+	}
+	for i := range data.Todos {
+		todo := &data.Todos[i]
+		if todo.AllDay || todo.Floating {
+			continue
+		}
+		note(todo.DTStart)
+		note(todo.Due)
+	}
+	for i := range data.Journals {
+		if !data.Journals[i].AllDay {
+			note(data.Journals[i].DTStart)
+		}
+	}
+	for i := range data.Availability {
+		avail := &data.Availability[i]
+		note(avail.DTStart)
+		note(avail.DTEnd)
+		for j := range avail.Available {
+			available := &avail.Available[j]
+			note(available.DTStart)
+			note(available.DTEnd)
+			note(available.RecurrenceID)
+			for k := range available.RecurrenceDates {
+				note(&available.RecurrenceDates[k])
+			}
+			for k := range available.ExceptionDates {
+				note(&available.ExceptionDates[k])
+			}
+		}
+	}
+
+	tzids := make([]string, 0, len(earliest))
+	for tzid := range earliest {
+		tzids = append(tzids, tzid)
+	}
+	sort.Strings(tzids)
+
+	for _, tzid := range tzids {
+		tz, err := GenerateVTimeZone(locations[tzid], earliest[tzid])
+		if err != nil {
+			return err
+		}
+		e.writeTimeZone(tz)
+	}
+
+	return nil
+}
+
+func (e *icalEncoder) writeInt(name string, value int) {
+	if value != 0 {
+		e.writeProperty(name, nil, strconv.Itoa(value))
+	}
+}
+
+func (e *icalEncoder) writeString(name, value string) {
+	if value != "" {
+		e.writeProperty(name, nil, value)
+	}
+}
+
+func (e *icalEncoder) writeTextIfSet(name, value string) {
+	if value != "" {
+		e.writeText(name, nil, value)
+	}
+}
+
+// writeLocalizedText writes a TEXT property with its LANGUAGE parameter.
+func (e *icalEncoder) writeLocalizedText(name, value, language string) {
+	if value != "" {
+		e.writeText(name, []icalParam{{"LANGUAGE", language}}, value)
+	}
+}
+
+func (e *icalEncoder) writeTextList(name string, values []string) {
+	for _, value := range values {
+		e.writeText(name, nil, value)
+	}
+}
+
+func (e *icalEncoder) writeCategories(categories []string) {
+	e.writeJoinedText("CATEGORIES", categories)
+}
+
+// writeJoinedText writes a single property whose value is a comma-separated
+// list of TEXT values.
+func (e *icalEncoder) writeJoinedText(name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeICalText(value)
+	}
+	e.writeProperty(name, nil, strings.Join(escaped, ","))
+}
+
+// writeCustomProperties writes extra in its original order and then the
+// names that only props has. A name whose value in props differs from its last
+// occurrence in extra was changed through CustomProperties and is written once
+// with the new value; a name missing from props was removed. With nil props,
+// extra is written as is.
+func (e *icalEncoder) writeCustomProperties(props map[string]string, extra []ICalProperty) {
+	last := make(map[string]string, len(extra))
+	for _, prop := range extra {
+		last[prop.Name] = prop.Value
+	}
+
+	changed := make(map[string]bool)
+	for _, prop := range extra {
+		value, ok := props[prop.Name]
+		switch {
+		case props == nil || ok && value == last[prop.Name]:
+			e.writeICalProperty(prop)
+		case ok && !changed[prop.Name]:
+			e.writeProperty(prop.Name, nil, value)
+			changed[prop.Name] = true
+		}
+	}
+
+	for _, name := range sortedKeys(props) {
+		if _, seen := last[name]; !seen {
+			e.writeProperty(name, nil, props[name])
+		}
+	}
+}
+
+func (e *icalEncoder) writeEvent(event *ParsedEvent) {
+	e.begin("VEVENT")
+
+	e.writeString("UID", event.UID)
+	e.writeTime("DTSTAMP", event.DTStamp)
+	form := eventTimeForm(event.AllDay, event.Floating)
+	e.writeDateTime("DTSTART", event.DTStart, form)
+	e.writeDateTime("DTEND", event.DTEnd, form)
+	e.writeString("DURATION", event.Duration)
+	e.writeRecurrenceID(event.RecurrenceID, event.RecurrenceRange, form)
+	e.writeTime("CREATED", event.Created)
+	e.writeTime("LAST-MODIFIED", event.LastModified)
+	e.writeInt("SEQUENCE", event.Sequence)
+	e.writeLocalizedText("SUMMARY", event.Summary, event.SummaryLanguage)
+	e.writeLocalizedText("DESCRIPTION", event.Description, event.DescriptionLanguage)
+	e.writeTextIfSet("LOCATION", event.Location)
+	if event.GeoLocation != nil {
+		e.writeProperty("GEO", nil, formatGeo(event.GeoLocation))
+	}
+	if event.StructuredLocation != nil {
+		e.writeAppleLocation(event.StructuredLocation)
+	}
+	e.writeString("STATUS", event.Status)
+	e.writeString("TRANSP", event.Transparency)
+	e.writeString("CLASS", event.Class)
+	e.writeInt("PRIORITY", event.Priority)
+	e.writeString("URL", event.URL)
+	e.writeString("COLOR", event.Color)
+
+	e.writeOrganizer(event.Organizer)
+	for _, attendee := range event.Attendees {
+		e.writeAttendee(attendee)
+	}
+
+	e.writeCategories(event.Categories)
+	e.writeString("RRULE", event.RecurrenceRule)
+	e.writeString("EXRULE", event.ExceptionRule)
+	e.writeTimeList("RDATE", event.RecurrenceDates, form)
+	e.writeTimeList("EXDATE", event.ExceptionDates, form)
+
+	e.writeRelatedTo(event.RelatedTo)
+	e.writeAttachments(event.Attachments)
+	e.writeImages(event.Images)
+	e.writeConferences(event.Conferences)
+	e.writeTextList("CONTACT", event.Contacts)
+	e.writeTextList("COMMENT", event.Comments)
+	e.writeRequestStatus(event.RequestStatus)
+	e.writeCustomProperties(event.CustomProperties, event.ExtraProperties)
+
+	for i := range event.Locations {
+		e.writeLocation(&event.Locations[i])
+	}
+	for i := range event.Participants {
+		e.writeParticipant(&event.Participants[i])
+	}
+	for i := range event.Alarms {
+		e.writeAlarm(&event.Alarms[i])
+	}
+
+	e.end("VEVENT")
+}
+
+func (e *icalEncoder) writeTodo(todo *ParsedTodo) {
+	e.begin("VTODO")
+
+	e.writeString("UID", todo.UID)
+	e.writeTime("DTSTAMP", todo.DTStamp)
+	form := eventTimeForm(todo.AllDay, todo.Floating)
+	e.writeDateTime("DTSTART", todo.DTStart, form)
+	e.writeDateTime("DUE", todo.Due, form)
+	e.writeTime("COMPLETED", todo.Completed)
+	e.writeTime("CREATED", todo.Created)
+	e.writeTime("LAST-MODIFIED", todo.LastModified)
+	e.writeInt("SEQUENCE", todo.Sequence)
+	e.writeLocalizedText("SUMMARY", todo.Summary, todo.SummaryLanguage)
+	e.writeLocalizedText("DESCRIPTION", todo.Description, todo.DescriptionLanguage)
+	e.writeString("STATUS", todo.Status)
+	e.writeInt("PERCENT-COMPLETE", todo.PercentComplete)
+	e.writeInt("PRIORITY", todo.Priority)
+	e.writeString("CLASS", todo.Class)
+	e.writeString("URL", todo.URL)
+	e.writeString("COLOR", todo.Color)
+
+	e.writeCategories(todo.Categories)
+	e.writeRelatedTo(todo.RelatedTo)
+	e.writeAttachments(todo.Attachments)
+	e.writeImages(todo.Images)
+	e.writeConferences(todo.Conferences)
+	e.writeTextList("CONTACT", todo.Contacts)
+	e.writeTextList("COMMENT", todo.Comments)
+	e.writeRequestStatus(todo.RequestStatus)
+	e.writeCustomProperties(todo.CustomProperties, todo.ExtraProperties)
+
+	for i := range todo.Alarms {
+		e.writeAlarm(&todo.Alarms[i])
+	}
+
+	e.end("VTODO")
+}
+
+func (e *icalEncoder) writeJournal(journal *ParsedJournal) {
+	e.begin("VJOURNAL")
+
+	e.writeString("UID", journal.UID)
+	e.writeTime("DTSTAMP", journal.DTStamp)
+	e.writeDateTime("DTSTART", journal.DTStart, eventTimeForm(journal.AllDay, false))
+	e.writeTime("CREATED", journal.Created)
+	e.writeTime("LAST-MODIFIED", journal.LastModified)
+	e.writeInt("SEQUENCE", journal.Sequence)
+	e.writeLocalizedText("SUMMARY", journal.Summary, journal.SummaryLanguage)
+	for _, description := range journal.Descriptions {
+		e.writeText("DESCRIPTION", []icalParam{{"LANGUAGE", journal.DescriptionLanguage}}, description)
+	}
+	e.writeString("STATUS", journal.Status)
+	e.writeString("CLASS", journal.Class)
+	e.writeString("URL", journal.URL)
+
+	e.writeCategories(journal.Categories)
+	e.writeRelatedTo(journal.RelatedTo)
+	e.writeAttachments(journal.Attachments)
+	e.writeTextList("COMMENT", journal.Comments)
+	e.writeCustomProperties(journal.CustomProperties, journal.ExtraProperties)
+
+	e.end("VJOURNAL")
+}
+
+func (e *icalEncoder) writeAvailability(avail *ParsedAvailability) {
+	e.begin("VAVAILABILITY")
+
+	e.writeString("UID", avail.UID)
+	e.writeTime("DTSTAMP", avail.DTStamp)
+	e.writeDateTime("DTSTART", avail.DTStart, formDateTime)
+	e.writeDateTime("DTEND", avail.DTEnd, formDateTime)
+	e.writeString("DURATION", avail.Duration)
+	e.writeString("BUSYTYPE", avail.BusyType)
+	e.writeInt("PRIORITY", avail.Priority)
+	e.writeTime("CREATED", avail.Created)
+	e.writeTime("LAST-MODIFIED", avail.LastModified)
+	e.writeInt("SEQUENCE", avail.Sequence)
+	e.writeTextIfSet("SUMMARY", avail.Summary)
+	e.writeTextIfSet("DESCRIPTION", avail.Description)
+	e.writeTextIfSet("LOCATION", avail.Location)
+	e.writeOrganizer(avail.Organizer)
+	e.writeString("CLASS", avail.Class)
+	e.writeString("URL", avail.URL)
+	e.writeCategories(avail.Categories)
+	e.writeCustomProperties(avail.CustomProperties, avail.ExtraProperties)
+
+	for i := range avail.Available {
+		e.writeAvailable(&avail.Available[i])
+	}
+
+	e.end("VAVAILABILITY")
+}
+
+func (e *icalEncoder) writeAvailable(available *ParsedAvailable) {
+	e.begin("AVAILABLE")
+
+	e.writeString("UID", available.UID)
+	e.writeTime("DTSTAMP", available.DTStamp)
+	e.writeDateTime("DTSTART", available.DTStart, formDateTime)
+	e.writeDateTime("DTEND", available.DTEnd, formDateTime)
+	e.writeString("DURATION", available.Duration)
+	e.writeRecurrenceID(available.RecurrenceID, "", formDateTime)
+	e.writeString("RRULE", available.RecurrenceRule)
+	e.writeTimeList("RDATE", available.RecurrenceDates, formDateTime)
+	e.writeTimeList("EXDATE", available.ExceptionDates, formDateTime)
+	e.writeTime("CREATED", available.Created)
+	e.writeTime("LAST-MODIFIED", available.LastModified)
+	e.writeTextIfSet("SUMMARY", available.Summary)
+	e.writeTextIfSet("DESCRIPTION", available.Description)
+	e.writeTextIfSet("LOCATION", available.Location)
+	e.writeCategories(available.Categories)
+	e.writeCustomProperties(available.CustomProperties, available.ExtraProperties)
+
+	e.end("AVAILABLE")
+}
+
+func (e *icalEncoder) writeFreeBusy(fb *ParsedFreeBusy) {
+	e.begin("VFREEBUSY")
+
+	e.writeString("UID", fb.UID)
+	e.writeTime("DTSTAMP", fb.DTStamp)
+	e.writeTime("DTSTART", fb.DTStart)
+	e.writeTime("DTEND", fb.DTEnd)
+	e.writeOrganizer(fb.Organizer)
+	for _, attendee := range fb.Attendees {
+		e.writeAttendee(attendee)
+	}
+
+	for _, period := range fb.FreeBusy {
+		var params []icalParam
+		if period.FBType != "" && period.FBType != "BUSY" {
+			params = append(params, icalParam{"FBTYPE", period.FBType})
+		}
+		e.writeProperty("FREEBUSY", params, formatICalTime(period.Start)+"/"+formatICalTime(period.End))
+	}
+
+	e.writeCustomProperties(fb.CustomProperties, fb.ExtraProperties)
+	e.end("VFREEBUSY")
+}
+
+func (e *icalEncoder) writeTimeZone(tz *ParsedTimeZone) {
+	e.begin("VTIMEZONE")
+
+	e.writeString("TZID", tz.TZID)
+	e.writeCustomProperties(tz.CustomProperties, tz.ExtraProperties)
+	e.writeTimeZoneComponent("STANDARD", &tz.StandardTime)
+	e.writeTimeZoneComponent("DAYLIGHT", &tz.DaylightTime)
+
+	e.end("VTIMEZONE")
+}
+
+func (e *icalEncoder) writeTimeZoneComponent(name string, comp *ParsedTimeZoneComponent) {
+	if comp.DTStart == nil && comp.TZOffsetFrom == "" && comp.TZOffsetTo == "" {
+		return
+	}
+
+	e.begin(name)
+
+	// Observance times in a VTIMEZONE are always local times without a UTC designator.
+	if comp.DTStart != nil {
+		e.writeProperty("DTSTART", nil, formatLocalICalTime(*comp.DTStart))
+	}
+	e.writeString("TZOFFSETFROM", comp.TZOffsetFrom)
+	e.writeString("TZOFFSETTO", comp.TZOffsetTo)
+	e.writeTextIfSet("TZNAME", comp.TZName)
+	e.writeString("RRULE", comp.RecurrenceRule)
+	for _, rdate := range comp.RecurrenceDates {
+		e.writeProperty("RDATE", nil, formatLocalICalTime(rdate))
+	}
+	for _, exdate := range comp.ExceptionDates {
+		e.writeProperty("EXDATE", nil, formatLocalICalTime(exdate))
+	}
+	e.writeTextList("COMMENT", comp.Comment)
+	e.writeCustomProperties(comp.CustomProperties, comp.ExtraProperties)
+
+	e.end(name)
+}
+
+func (e *icalEncoder) writeAlarm(alarm *ParsedAlarm) {
+	e.begin("VALARM")
+
+	e.writeString("ACTION", alarm.Action)
+	if alarm.Trigger != "" {
+		var params []icalParam
+		if !isDurationValue(alarm.Trigger) {
+			params = append(params, icalParam{"VALUE", "DATE-TIME"})
+		} else if alarm.TriggerRelated != "" {
+			params = append(params, icalParam{"RELATED", alarm.TriggerRelated})
+		}
+		e.writeProperty("TRIGGER", params, alarm.Trigger)
+	}
+	e.writeString("DURATION", alarm.Duration)
+	e.writeInt("REPEAT", alarm.Repeat)
+	e.writeTextIfSet("SUMMARY", alarm.Summary)
+	e.writeTextIfSet("DESCRIPTION", alarm.Description)
+	for _, attendee := range alarm.Attendees {
+		e.writeAttendee(attendee)
+	}
+	e.writeCustomProperties(alarm.CustomProperties, alarm.ExtraProperties)
+
+	e.end("VALARM")
+}
+
+// writeAppleLocation writes location as an X-APPLE-STRUCTURED-LOCATION
+// property, the form iCloud uses.
+func (e *icalEncoder) writeAppleLocation(location *ParsedLocation) {
+	params := []icalParam{
+		{"VALUE", "URI"},
+		{"X-ADDRESS", location.Address},
+	}
+	if location.Radius != 0 {
+		params = append(params, icalParam{"X-APPLE-RADIUS", strconv.FormatFloat(location.Radius, 'f', -1, 64)})
+	}
+	params = append(params, icalParam{"X-TITLE", location.Name})
+	params = appendCustomParams(params, location.CustomParams)
+
+	value := ""
+	if location.Geo != nil {
+		value = formatGeoURI(location.Geo)
+	}
+	e.writeProperty("X-APPLE-STRUCTURED-LOCATION", params, value)
+}
+
+func (e *icalEncoder) writeLocation(location *ParsedLocation) {
+	e.begin("VLOCATION")
+
+	e.writeString("UID", location.UID)
+	e.writeTextIfSet("NAME", location.Name)
+	e.writeTextIfSet("DESCRIPTION", location.Description)
+	e.writeJoinedText("LOCATION-TYPE", location.LocationType)
+	if location.Geo != nil {
+		e.writeProperty("GEO", nil, formatGeo(location.Geo))
+	}
+	e.writeString("URL", location.URL)
+	e.writeCustomProperties(location.CustomProperties, location.ExtraProperties)
+
+	e.end("VLOCATION")
+}
+
+func (e *icalEncoder) writeParticipant(participant *ParsedParticipant) {
+	e.begin("PARTICIPANT")
+
+	e.writeString("UID", participant.UID)
+	e.writeString("PARTICIPANT-TYPE", participant.ParticipantType)
+	e.writeString("CALENDAR-ADDRESS", participant.CalendarAddress)
+	e.writeTextIfSet("SUMMARY", participant.Summary)
+	e.writeTextIfSet("DESCRIPTION", participant.Description)
+	if participant.Geo != nil {
+		e.writeProperty("GEO", nil, formatGeo(participant.Geo))
+	}
+	e.writeString("URL", participant.URL)
+	e.writeCustomProperties(participant.CustomProperties, participant.ExtraProperties)
+
+	for i := range participant.Locations {
+		e.writeLocation(&participant.Locations[i])
+	}
+
+	e.end("PARTICIPANT")
+}
+
+func (e *icalEncoder) writeOrganizer(org ParsedOrganizer) {
+	if org.Value == "" {
+		return
+	}
+
+	params := []icalParam{
+		{"CN", org.CN},
+		{"EMAIL", redundantEmail(org.Value, org.Email)},
+		{"DIR", org.Dir},
+		{"SENT-BY", org.SentBy},
+	}
+	params = appendCustomParams(params, org.CustomParams)
+
+	e.writeProperty("ORGANIZER", params, org.Value)
+}
+
+func (e *icalEncoder) writeAttendee(att ParsedAttendee) {
+	if att.Value == "" {
+		return
+	}
+
+	params := []icalParam{
+		{"CUTYPE", att.CUType},
+		{"ROLE", att.Role},
+		{"PARTSTAT", att.PartStat},
+	}
+	if att.RSVP {
+		params = append(params, icalParam{"RSVP", "TRUE"})
+	}
+	params = append(params,
+		icalParam{"CN", att.CN},
+		icalParam{"EMAIL", redundantEmail(att.Value, att.Email)},
+		icalParam{"MEMBER", att.Member},
+		icalParam{"DELEGATED-TO", att.DelegatedTo},
+		icalParam{"DELEGATED-FROM", att.DelegatedFrom},
+		icalParam{"DIR", att.Dir},
+		icalParam{"SENT-BY", att.SentBy},
+		icalParam{"SCHEDULE-AGENT", att.ScheduleAgent},
+		icalParam{"SCHEDULE-STATUS", att.ScheduleStatus},
+	)
+	params = appendCustomParams(params, att.CustomParams)
+
+	e.writeProperty("ATTENDEE", params, att.Value)
+}
+
+func (e *icalEncoder) writeRelatedTo(related []RelatedEvent) {
+	for _, rel := range related {
+		e.writeProperty("RELATED-TO", []icalParam{{"RELTYPE", rel.RelationType}}, rel.UID)
+	}
+}
+
+func (e *icalEncoder) writeAttachments(attachments []Attachment) {
+	for _, attachment := range attachments {
+		var params []icalParam
+		value := attachment.URI
+
+		if attachment.URI == "" {
+			encoding := attachment.Encoding
+			if encoding == "" {
+				encoding = "BASE64"
+			}
+			params = append(params, icalParam{"ENCODING", encoding}, icalParam{"VALUE", "BINARY"})
+			value = attachment.Value
+		}
+
+		params = append(params, icalParam{"FMTTYPE", attachment.FormatType}, icalParam{"FILENAME", attachment.Filename})
+		if attachment.Size > 0 {
+			params = append(params, icalParam{"SIZE", strconv.Itoa(attachment.Size)})
+		}
+		params = appendCustomParams(params, attachment.CustomParams)
+
+		e.writeProperty("ATTACH", params, value)
+	}
+}
+
+func (e *icalEncoder) writeImages(images []Image) {
+	for _, image := range images {
+		params, value := imageParams(image)
+		e.writeProperty("IMAGE", params, value)
+	}
+}
+
+func (e *icalEncoder) writeConferences(conferences []Conference) {
+	for _, conference := range conferences {
+		e.writeProperty("CONFERENCE", conferenceParams(conference), conference.URI)
+	}
+}
+
+// imageParams returns the parameters and value of the IMAGE property for
+// image.
+func imageParams(image Image) ([]icalParam, string) {
+	params := []icalParam{{"VALUE", "URI"}}
+	value := image.URI
+
+	if image.URI == "" {
+		encoding := image.Encoding
+		if encoding == "" {
+			encoding = "BASE64"
+		}
+		params = []icalParam{{"ENCODING", encoding}, {"VALUE", "BINARY"}}
+		value = image.Value
+	}
+
+	params = append(params,
+		icalParam{"DISPLAY", strings.Join(image.Display, ",")},
+		icalParam{"FMTTYPE", image.FormatType},
+		icalParam{"ALTREP", image.AltRep},
+	)
+	return appendCustomParams(params, image.CustomParams), value
+}
+
+// conferenceParams returns the parameters of the CONFERENCE property for
+// conference. VALUE=URI is required by RFC 7986.
+func conferenceParams(conference Conference) []icalParam {
+	params := []icalParam{
+		{"VALUE", "URI"},
+		{"FEATURE", strings.Join(conference.Features, ",")},
+		{"LABEL", conference.Label},
+	}
+	return appendCustomParams(params, conference.CustomParams)
+}
+
+func (e *icalEncoder) writeRequestStatus(statuses []RequestStatus) {
+	for _, rs := range statuses {
+		value := rs.Code
+		if rs.Description != "" || rs.ExtraData != "" {
+			value += ";" + escapeICalText(rs.Description)
+		}
+		if rs.ExtraData != "" {
+			value += ";" + escapeICalText(rs.ExtraData)
+		}
+		e.writeProperty("REQUEST-STATUS", nil, value)
+	}
+}
+
+func appendCustomParams(params []icalParam, custom map[string]string) []icalParam {
+	for _, name := range sortedKeys(custom) {
+		params = append(params, icalParam{name, custom[name]})
+	}
+	return params
+}
+
+// redundantEmail returns the EMAIL parameter to write, or an empty string when
+// it merely repeats the mailto: address of the property value.
+func redundantEmail(value, email string) string {
+	if strings.HasPrefix(strings.ToLower(value), "mailto:") && value[7:] == email {
+		return ""
+	}
+	return email
+}
+
+func formatGeo(geo *GeoLocation) string {
+	return strconv.FormatFloat(geo.Latitude, 'f', -1, 64) + ";" + strconv.FormatFloat(geo.Longitude, 'f', -1, 64)
+}
+
+// formatGeoURI formats geo as an RFC 5870 geo: URI.
+func formatGeoURI(geo *GeoLocation) string {
+	return "geo:" + strconv.FormatFloat(geo.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(geo.Longitude, 'f', -1, 64)
+}
+
+// formatICalDateTime formats a DATE or DATE-TIME value. Dates and floating
+// times use the wall clock of t. Other times in a named time zone are written
+// as local time with a TZID parameter, everything else in UTC.
+func formatICalDateTime(t time.Time, form timeForm) (string, []icalParam) {
+	switch form {
+	case formDate:
+		return t.Format("20060102"), []icalParam{{"VALUE", "DATE"}}
+	case formFloating:
+		return formatLocalICalTime(t), nil
+	}
+	if tzid := tzidForLocation(t.Location()); tzid != "" {
+		return formatLocalICalTime(t), []icalParam{{"TZID", tzid}}
+	}
+	return formatICalTime(t), nil
+}
+
+func sameParams(a, b []icalParam) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLocalICalTime formats a time without converting it to UTC or adding a UTC designator.
+func formatLocalICalTime(t time.Time) string {
+	return t.Format("20060102T150405")
+}
+
+func isDurationValue(value string) bool {
+	value = strings.TrimLeft(value, "+-")
+	return strings.HasPrefix(value, "P")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,489 @@
+package caldav
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const roundTripICalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Round Trip//EN
+CALSCALE:GREGORIAN
+METHOD:REQUEST
+NAME:Work
+DESCRIPTION:Team calendar\, shared
+COLOR:steelblue
+REFRESH-INTERVAL;VALUE=DURATION:PT1H
+IMAGE;VALUE=URI;DISPLAY=BADGE;FMTTYPE=image/png:https://example.com/calendar.png
+X-WR-CALNAME:Work
+BEGIN:VTIMEZONE
+TZID:Europe/London
+BEGIN:DAYLIGHT
+DTSTART:19810329T010000
+TZOFFSETFROM:+0000
+TZOFFSETTO:+0100
+TZNAME:BST
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+BEGIN:STANDARD
+DTSTART:19961027T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0000
+TZNAME:GMT
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:round-trip-1
+DTSTAMP:20240101T120000Z
+DTSTART:20240115T100000Z
+DTEND:20240115T110000Z
+CREATED:20240101T090000Z
+LAST-MODIFIED:20240102T090000Z
+SEQUENCE:2
+SUMMARY;LANGUAGE=en-GB:Planning\, budget\; review
+DESCRIPTION:Line one\nLine two with a backslash \\ here
+LOCATION:Room 1
+GEO:51.5074;-0.1278
+X-APPLE-STRUCTURED-LOCATION;VALUE=URI;X-ADDRESS="1 Main St, London";X-APPLE-RADIUS=70.5;X-TITLE=Room 1;X-APPLE-REFERENCEFRAME=1:geo:51.5074,-0.1278
+STATUS:CONFIRMED
+TRANSP:OPAQUE
+CLASS:PRIVATE
+PRIORITY:3
+URL:https://example.com/meeting
+COLOR:turquoise
+ORGANIZER;CN="Doe, Jane";SENT-BY="mailto:assistant@example.com":mailto:jane@example.com
+ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;RSVP=TRUE;CN=John Smith;X-NUM-GUESTS=0:mailto:john@example.com
+ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=NEEDS-ACTION;DELEGATED-FROM="mailto:a@example.com","mailto:b@example.com";DIR="ldap://example.com/o=Example":mailto:c@example.com
+CATEGORIES:Work,Finance\,Budget
+RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10
+RDATE:20240301T100000Z,20240308T100000Z
+EXDATE:20240122T100000Z
+RELATED-TO;RELTYPE=SIBLING:other-uid
+ATTACH;FMTTYPE=application/pdf;X-APPLE-FILENAME=agenda.pdf:https://example.com/agenda.pdf
+ATTACH;ENCODING=BASE64;VALUE=BINARY;FMTTYPE=text/plain:SGVsbG8=
+IMAGE;VALUE=URI;DISPLAY=BADGE,THUMBNAIL;FMTTYPE=image/png:https://example.com/party.png
+CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO;LABEL="Join: Video":https://video.example.com/123456
+CONFERENCE;VALUE=URI;FEATURE=PHONE,MODERATOR;LABEL=Moderator dial-in:tel:+1-412-555-0123,,,654321
+CONTACT:Jane Doe\, +44 20 7946 0000
+COMMENT:First comment
+COMMENT:Second comment
+REQUEST-STATUS:2.0;Success
+REQUEST-STATUS:3.1;Invalid property value;DTSTART:96-Apr-01
+X-APPLE-TRAVEL-ADVISORY-BEHAVIOR:AUTOMATIC
+X-ZOOM-ID;X-TYPE=meeting:123
+X-LABELS;X-SET=a,"b;c":work
+X-ZOOM-ID;X-TYPE=webinar:456
+BEGIN:VLOCATION
+UID:loc-1
+NAME:Car park
+LOCATION-TYPE:parking,public
+GEO:51.5080;-0.1280
+URL:https://example.com/parking
+X-CAPACITY:40
+END:VLOCATION
+BEGIN:PARTICIPANT
+UID:participant-1
+PARTICIPANT-TYPE:SPEAKER
+CALENDAR-ADDRESS:mailto:speaker@example.com
+SUMMARY:Keynote\, day one
+BEGIN:VLOCATION
+UID:loc-2
+NAME:Green room
+END:VLOCATION
+END:PARTICIPANT
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Reminder\, soon
+X-WR-ALARMUID:alarm-1
+END:VALARM
+BEGIN:VALARM
+ACTION:EMAIL
+TRIGGER;VALUE=DATE-TIME:20240115T090000Z
+SUMMARY:Email reminder
+DESCRIPTION:Don't forget
+ATTENDEE:mailto:john@example.com
+END:VALARM
+END:VEVENT
+BEGIN:VTODO
+UID:todo-1
+DTSTAMP:20240101T120000Z
+DUE:20240120T170000Z
+SUMMARY:Prepare slides
+DESCRIPTION;LANGUAGE=de:Für das Planungstreffen
+STATUS:IN-PROCESS
+PERCENT-COMPLETE:40
+PRIORITY:1
+CATEGORIES:Work
+RELATED-TO:round-trip-1
+COLOR:red
+CONFERENCE;VALUE=URI;FEATURE=CHAT:xmpp:chat@example.com
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER;RELATED=END:-PT1H
+DESCRIPTION:Slides due
+END:VALARM
+END:VTODO
+BEGIN:VJOURNAL
+UID:journal-1
+DTSTAMP:20240115T120000Z
+DTSTART;VALUE=DATE:20240115
+SUMMARY:Planning notes
+DESCRIPTION:Agreed the budget\, pending sign-off
+DESCRIPTION:Follow up next week
+STATUS:FINAL
+CATEGORIES:Work,Notes
+ATTACH:https://example.com/whiteboard.png
+RELATED-TO:round-trip-1
+END:VJOURNAL
+BEGIN:VAVAILABILITY
+UID:avail-1
+DTSTAMP:20240101T120000Z
+DTSTART;TZID=Europe/London:20240101T000000
+BUSYTYPE:BUSY-UNAVAILABLE
+PRIORITY:1
+SUMMARY:Working hours
+BEGIN:AVAILABLE
+UID:avail-1-weekdays
+DTSTAMP:20240101T120000Z
+DTSTART;TZID=Europe/London:20240101T090000
+DTEND;TZID=Europe/London:20240101T170000
+RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR
+EXDATE;TZID=Europe/London:20240102T090000
+SUMMARY:Office
+END:AVAILABLE
+END:VAVAILABILITY
+BEGIN:VFREEBUSY
+UID:fb-1
+DTSTAMP:20240101T120000Z
+DTSTART:20240115T000000Z
+DTEND:20240116T000000Z
+ORGANIZER:mailto:jane@example.com
+FREEBUSY:20240115T100000Z/20240115T110000Z
+FREEBUSY;FBTYPE=BUSY-TENTATIVE:20240115T140000Z/20240115T150000Z
+END:VFREEBUSY
+END:VCALENDAR`
+
+func TestEncodeICalendar_RoundTrip(t *testing.T) {
+	original, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	encoded, err := EncodeICalendar(original)
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+
+	reparsed, err := ParseICalendar(string(encoded))
+	if err != nil {
+		t.Fatalf("ParseICalendar of encoded data failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(original, reparsed) {
+		t.Errorf("round trip mismatch\noriginal: %+v\nreparsed: %+v\nencoded:\n%s", original, reparsed, encoded)
+	}
+}
+
+func TestEncodeICalendar_ParsedValues(t *testing.T) {
+	parsed, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	event := parsed.Events[0]
+	if event.Summary != "Planning, budget; review" {
+		t.Errorf("Summary not unescaped: %q", event.Summary)
+	}
+	if event.Description != "Line one\nLine two with a backslash \\ here" {
+		t.Errorf("Description not unescaped: %q", event.Description)
+	}
+	if event.Organizer.CN != "Doe, Jane" {
+		t.Errorf("Organizer CN: got %q", event.Organizer.CN)
+	}
+	if event.Organizer.SentBy != "mailto:assistant@example.com" {
+		t.Errorf("Organizer SENT-BY: got %q", event.Organizer.SentBy)
+	}
+	if got := event.Attendees[1].DelegatedFrom; got != "mailto:a@example.com,mailto:b@example.com" {
+		t.Errorf("DelegatedFrom: got %q", got)
+	}
+	if got := event.Attendees[1].Dir; got != "ldap://example.com/o=Example" {
+		t.Errorf("Dir: got %q", got)
+	}
+	if want := []string{"Work", "Finance,Budget"}; !reflect.DeepEqual(event.Categories, want) {
+		t.Errorf("Categories: expected %v, got %v", want, event.Categories)
+	}
+	if rs := event.RequestStatus[1]; rs.ExtraData != "DTSTART:96-Apr-01" {
+		t.Errorf("RequestStatus extra data: got %q", rs.ExtraData)
+	}
+	if len(parsed.Todos[0].Alarms) != 1 {
+		t.Errorf("expected todo alarm to be nested in the todo, got %d", len(parsed.Todos[0].Alarms))
+	}
+	if len(parsed.Alarms) != 0 {
+		t.Errorf("expected no calendar-level alarms, got %d", len(parsed.Alarms))
+	}
+}
+
+func TestEncodeICalendar_Output(t *testing.T) {
+	parsed, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	encoded, err := EncodeICalendar(parsed)
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+	output := strings.ReplaceAll(string(encoded), "\r\n ", "")
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"METHOD:REQUEST\r\n",
+		"X-WR-CALNAME:Work\r\n",
+		"DTSTART:19810329T010000\r\n",
+		"SUMMARY;LANGUAGE=en-GB:Planning\\, budget\\; review\r\n",
+		"DESCRIPTION;LANGUAGE=de:Für das Planungstreffen\r\n",
+		"X-ZOOM-ID;X-TYPE=meeting:123\r\nX-LABELS;X-SET=a,\"b;c\":work\r\nX-ZOOM-ID;X-TYPE=webinar:456\r\n",
+		"GEO:51.5074;-0.1278\r\n",
+		`ORGANIZER;CN="Doe, Jane";SENT-BY="mailto:assistant@example.com":mailto:jane@example.com`,
+		`DELEGATED-FROM="mailto:a@example.com","mailto:b@example.com"`,
+		"RSVP=TRUE",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10\r\n",
+		"EXDATE:20240122T100000Z\r\n",
+		"ATTACH;ENCODING=BASE64;VALUE=BINARY;FMTTYPE=text/plain:SGVsbG8=\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n",
+		"COLOR:turquoise\r\n",
+		`X-APPLE-STRUCTURED-LOCATION;VALUE=URI;X-ADDRESS="1 Main St, London";X-APPLE-RADIUS=70.5;X-TITLE=Room 1;X-APPLE-REFERENCEFRAME=1:geo:51.5074,-0.1278`,
+		"LOCATION-TYPE:parking,public\r\n",
+		"BEGIN:PARTICIPANT\r\n",
+		"IMAGE;VALUE=URI;DISPLAY=BADGE,THUMBNAIL;FMTTYPE=image/png:https://example.com/party.png\r\n",
+		`CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO;LABEL="Join: Video":https://video.example.com/123456`,
+		"TRIGGER;VALUE=DATE-TIME:20240115T090000Z\r\n",
+		"TRIGGER;RELATED=END:-PT1H\r\n",
+		"FREEBUSY;FBTYPE=BUSY-TENTATIVE:20240115T140000Z/20240115T150000Z\r\n",
+		"END:VCALENDAR\r\n",
+	}
+
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("encoded output missing %q\n%s", want, output)
+		}
+	}
+}
+
+func TestEncodeICalendar_CustomPropertyEdits(t *testing.T) {
+	parsed, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	event := &parsed.Events[0]
+	if got := event.CustomProperties["X-ZOOM-ID"]; got != "456" {
+		t.Errorf("CustomProperties should hold the last X-ZOOM-ID, got %q", got)
+	}
+	event.CustomProperties["X-ZOOM-ID"] = "789"
+	delete(event.CustomProperties, "X-LABELS")
+	event.CustomProperties["X-NEW"] = "added"
+
+	encoded, err := EncodeICalendar(parsed)
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+	output := string(encoded)
+
+	if strings.Count(output, "X-ZOOM-ID") != 1 || !strings.Contains(output, "X-ZOOM-ID:789\r\n") {
+		t.Errorf("expected a single changed X-ZOOM-ID\n%s", output)
+	}
+	if strings.Contains(output, "X-LABELS") {
+		t.Errorf("expected deleted X-LABELS to be dropped\n%s", output)
+	}
+	if !strings.Contains(output, "X-NEW:added\r\n") {
+		t.Errorf("expected X-NEW to be written\n%s", output)
+	}
+}
+
+func TestEncodeICalendar_Defaults(t *testing.T) {
+	encoded, err := EncodeICalendar(&ParsedCalendarData{
+		Events: []ParsedEvent{{UID: "minimal", Summary: "Minimal"}},
+	})
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+
+	output := string(encoded)
+	for _, want := range []string{"VERSION:2.0\r\n", "PRODID:" + defaultProdID + "\r\n", "UID:minimal\r\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("encoded output missing %q", want)
+		}
+	}
+}
+
+func TestEncodeICalendar_Nil(t *testing.T) {
+	if _, err := EncodeICalendar(nil); err == nil {
+		t.Error("expected error for nil calendar data")
+	}
+}
+
+func TestEncodeICalendar_TimeZones(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, london)
+	end := start.Add(time.Hour)
+	data := &ParsedCalendarData{
+		Events: []ParsedEvent{{UID: "tz-event", DTStart: &start, DTEnd: &end, Summary: "Stand-up"}},
+	}
+
+	encoded, err := EncodeICalendar(data)
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+
+	output := string(encoded)
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\n",
+		"DTSTART;TZID=Europe/London:20240701T090000\r\n",
+		"DTEND;TZID=Europe/London:20240701T100000\r\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("encoded output missing %q\n%s", want, output)
+		}
+	}
+	if strings.Index(output, "BEGIN:VTIMEZONE") > strings.Index(output, "BEGIN:VEVENT") {
+		t.Error("expected VTIMEZONE before VEVENT")
+	}
+
+	reparsed, err := ParseICalendar(output)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	got := reparsed.Events[0].DTStart
+	if got == nil || !got.Equal(start) || got.Location().String() != "Europe/London" {
+		t.Errorf("expected DTSTART %v in Europe/London, got %v", start, got)
+	}
+}
+
+func TestParseICalendar_TZIDFromVTimeZone(t *testing.T) {
+	data := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Custom Eastern
+BEGIN:STANDARD
+DTSTART:20201101T020000
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:20200308T020000
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:custom-tz
+DTSTART;TZID="Custom Eastern":20240701T090000
+END:VEVENT
+END:VCALENDAR`
+
+	parsed, err := ParseICalendar(data)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	want := time.Date(2024, 7, 1, 13, 0, 0, 0, time.UTC)
+	if got := parsed.Events[0].DTStart; got == nil || !got.Equal(want) {
+		t.Errorf("expected DTSTART %v, got %v", want, got)
+	}
+}
+
+func TestEncodeICalendar_AllDayAndFloating(t *testing.T) {
+	data := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//All Day//EN
+BEGIN:VEVENT
+UID:birthday
+DTSTART;VALUE=DATE:20240310
+DTEND;VALUE=DATE:20240311
+RRULE:FREQ=YEARLY
+EXDATE;VALUE=DATE:20260310
+SUMMARY:Birthday
+END:VEVENT
+BEGIN:VEVENT
+UID:alarm-clock
+DTSTART:20240310T070000
+DTEND:20240310T071500
+SUMMARY:Wake up
+END:VEVENT
+BEGIN:VTODO
+UID:tax-return
+DTSTART;VALUE=DATE:20240401
+DUE;VALUE=DATE:20240415
+SUMMARY:File tax return
+END:VTODO
+BEGIN:VTODO
+UID:water-plants
+DUE:20240310T180000
+SUMMARY:Water plants
+END:VTODO
+END:VCALENDAR`
+
+	parsed, err := ParseICalendar(data)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	birthday, alarm := parsed.Events[0], parsed.Events[1]
+	if !birthday.AllDay || birthday.Floating {
+		t.Errorf("expected all-day event, got AllDay=%v Floating=%v", birthday.AllDay, birthday.Floating)
+	}
+	if want := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC); !birthday.DTStart.Equal(want) {
+		t.Errorf("expected DTSTART %v, got %v", want, birthday.DTStart)
+	}
+	if alarm.AllDay || !alarm.Floating {
+		t.Errorf("expected floating event, got AllDay=%v Floating=%v", alarm.AllDay, alarm.Floating)
+	}
+	tax, plants := parsed.Todos[0], parsed.Todos[1]
+	if !tax.AllDay || tax.Floating {
+		t.Errorf("expected all-day todo, got AllDay=%v Floating=%v", tax.AllDay, tax.Floating)
+	}
+	if plants.AllDay || !plants.Floating {
+		t.Errorf("expected floating todo, got AllDay=%v Floating=%v", plants.AllDay, plants.Floating)
+	}
+
+	encoded, err := EncodeICalendar(parsed)
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+
+	output := string(encoded)
+	for _, want := range []string{
+		"DTSTART;VALUE=DATE:20240310\r\n",
+		"DTEND;VALUE=DATE:20240311\r\n",
+		"EXDATE;VALUE=DATE:20260310\r\n",
+		"DTSTART:20240310T070000\r\n",
+		"DTEND:20240310T071500\r\n",
+		"DTSTART;VALUE=DATE:20240401\r\n",
+		"DUE;VALUE=DATE:20240415\r\n",
+		"DUE:20240310T180000\r\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("encoded output missing %q\n%s", want, output)
+		}
+	}
+
+	reparsed, err := ParseICalendar(output)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, reparsed) {
+		t.Errorf("round trip mismatch\noriginal: %+v\nreparsed: %+v", parsed, reparsed)
+	}
+}
@@ -3,6 +3,7 @@ package caldav
 import (
 	"bufio"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,6 +15,8 @@ func ParseICalendar(data string) (*ParsedCalendarData, error) {
 		result: &ParsedCalendarData{
 			Events:           make([]ParsedEvent, 0),
 			Todos:            make([]ParsedTodo, 0),
+			Journals:         make([]ParsedJournal, 0),
+			Availability:     make([]ParsedAvailability, 0),
 			FreeBusy:         make([]ParsedFreeBusy, 0),
 			TimeZones:        make([]ParsedTimeZone, 0),
 			Alarms:           make([]ParsedAlarm, 0),
@@ -29,17 +32,30 @@ type icalParser struct {
 	result             *ParsedCalendarData
 	currentEvent       *ParsedEvent
 	currentTodo        *ParsedTodo
+	currentJournal     *ParsedJournal
+	currentAvail       *ParsedAvailability
+	currentAvailable   *ParsedAvailable
 	currentFreeBusy    *ParsedFreeBusy
 	currentTimeZone    *ParsedTimeZone
 	currentTZComponent *ParsedTimeZoneComponent
 	currentAlarm       *ParsedAlarm
+	currentLocation    *ParsedLocation
+	currentParticipant *ParsedParticipant
 	inEvent            bool
 	inTodo             bool
+	inJournal          bool
+	inAvailability     bool
+	inAvailable        bool
 	inFreeBusy         bool
 	inTimeZone         bool
 	inTZStandard       bool
 	inTZDaylight       bool
 	inAlarm            bool
+	inLocation         bool
+	inParticipant      bool
+	// lineParams holds the parameters of the current line in order, for
+	// properties kept in ExtraProperties.
+	lineParams []ICalParameter
 }
 
 func (p *icalParser) parse() (*ParsedCalendarData, error) {
@@ -62,7 +78,8 @@ func (p *icalParser) parse() (*ParsedCalendarData, error) {
 		for p.scanner.Scan() {
 			nextLine := p.scanner.Text()
 			if len(nextLine) > 0 && (nextLine[0] == ' ' || nextLine[0] == '\t') {
-				line += strings.TrimLeft(nextLine, " \t")
+				// Only the single folding whitespace character is removed
+				line += nextLine[1:]
 			} else {
 				currentLine = nextLine
 				break
@@ -86,7 +103,7 @@ func (p *icalParser) parse() (*ParsedCalendarData, error) {
 }
 
 func (p *icalParser) parseLine(line string) error {
-	colonIndex := strings.Index(line, ":")
+	colonIndex := findValueSeparator(line)
 	if colonIndex == -1 {
 		return nil // Skip malformed lines
 	}
@@ -118,20 +135,153 @@ func (p *icalParser) parseLine(line string) error {
 }
 
 func (p *icalParser) parseProperty(propertyPart string) (string, map[string]string) {
-	parts := strings.Split(propertyPart, ";")
+	parts := splitOutsideQuotes(propertyPart, ';')
 	property := parts[0]
 	params := make(map[string]string)
+	p.lineParams = nil
 
 	for i := 1; i < len(parts); i++ {
 		paramParts := strings.SplitN(parts[i], "=", 2)
 		if len(paramParts) == 2 {
-			params[paramParts[0]] = strings.Trim(paramParts[1], "\"")
+			params[paramParts[0]] = parseParamValue(paramParts[1])
+			p.lineParams = append(p.lineParams, ICalParameter{
+				Name:   paramParts[0],
+				Values: parseParamValues(paramParts[1]),
+			})
 		}
 	}
 
 	return property, params
 }
 
+// addCustomProperty records a property the current component has no field
+// for: props keeps its last value and extra every occurrence in order.
+func (p *icalParser) addCustomProperty(props map[string]string, extra *[]ICalProperty, property, value string) {
+	props[property] = value
+	*extra = append(*extra, ICalProperty{Name: property, Parameters: p.lineParams, Value: value})
+}
+
+// findValueSeparator returns the index of the colon separating the property
+// name and parameters from the value, ignoring colons inside quoted parameters.
+func findValueSeparator(line string) int {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ':':
+			if !inQuotes {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside quoted strings.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseParamValue unquotes a parameter value. Multi-valued parameters such as
+// MEMBER="a","b" are joined with commas.
+func parseParamValue(raw string) string {
+	return strings.Join(parseParamValues(raw), ",")
+}
+
+// parseParamValues splits a parameter value into its unquoted values.
+func parseParamValues(raw string) []string {
+	values := splitOutsideQuotes(raw, ',')
+	for i, v := range values {
+		values[i] = decodeParamValue(strings.Trim(v, "\""))
+	}
+	return values
+}
+
+// decodeParamValue reverses RFC 6868 caret encoding of parameter values.
+func decodeParamValue(value string) string {
+	if !strings.Contains(value, "^") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '^' && i+1 < len(value) {
+			switch value[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\'':
+				b.WriteByte('"')
+				i++
+				continue
+			case '^':
+				b.WriteByte('^')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// unescapeICalText reverses the TEXT value escaping described in RFC 5545 section 3.3.11.
+func unescapeICalText(text string) string {
+	if !strings.Contains(text, "\\") {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			switch text[i+1] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			case '\\', ';', ',':
+				b.WriteByte(text[i+1])
+			default:
+				b.WriteByte(text[i])
+				continue
+			}
+			i++
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+// splitEscaped splits an escaped TEXT list on unescaped separators and unescapes each part.
+func splitEscaped(value string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == sep {
+			parts = append(parts, unescapeICalText(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, unescapeICalText(value[start:]))
+}
+
 func (p *icalParser) handleBegin(component string) {
 	switch component {
 	case "VEVENT":
@@ -157,8 +307,36 @@ func (p *icalParser) handleBegin(component string) {
 			Contacts:         make([]string, 0),
 			Comments:         make([]string, 0),
 			RequestStatus:    make([]RequestStatus, 0),
+			Alarms:           make([]ParsedAlarm, 0),
 			CustomProperties: make(map[string]string),
 		}
+	case "VJOURNAL":
+		p.inJournal = true
+		p.currentJournal = &ParsedJournal{
+			Descriptions:     make([]string, 0),
+			Categories:       make([]string, 0),
+			RelatedTo:        make([]RelatedEvent, 0),
+			Attachments:      make([]Attachment, 0),
+			Comments:         make([]string, 0),
+			CustomProperties: make(map[string]string),
+		}
+	case "VAVAILABILITY":
+		p.inAvailability = true
+		p.currentAvail = &ParsedAvailability{
+			Categories:       make([]string, 0),
+			Available:        make([]ParsedAvailable, 0),
+			CustomProperties: make(map[string]string),
+		}
+	case "AVAILABLE":
+		if p.inAvailability {
+			p.inAvailable = true
+			p.currentAvailable = &ParsedAvailable{
+				RecurrenceDates:  make([]time.Time, 0),
+				ExceptionDates:   make([]time.Time, 0),
+				Categories:       make([]string, 0),
+				CustomProperties: make(map[string]string),
+			}
+		}
 	case "VFREEBUSY":
 		p.inFreeBusy = true
 		p.currentFreeBusy = &ParsedFreeBusy{
@@ -187,6 +365,22 @@ func (p *icalParser) handleBegin(component string) {
 			p.inTZDaylight = true
 			p.currentTZComponent = &p.currentTimeZone.DaylightTime
 		}
+	case "VLOCATION":
+		if (p.inEvent || p.inParticipant) && !p.inAlarm {
+			p.inLocation = true
+			p.currentLocation = &ParsedLocation{
+				LocationType:     make([]string, 0),
+				CustomProperties: make(map[string]string),
+			}
+		}
+	case "PARTICIPANT":
+		if p.inEvent && !p.inAlarm {
+			p.inParticipant = true
+			p.currentParticipant = &ParsedParticipant{
+				Locations:        make([]ParsedLocation, 0),
+				CustomProperties: make(map[string]string),
+			}
+		}
 	case "VALARM":
 		p.inAlarm = true
 		p.currentAlarm = &ParsedAlarm{
@@ -198,13 +392,18 @@ func (p *icalParser) handleBegin(component string) {
 
 func (p *icalParser) handleEnd(component string) {
 	handlers := map[string]func(){
-		"VEVENT":    p.handleEndEvent,
-		"VTODO":     p.handleEndTodo,
-		"VFREEBUSY": p.handleEndFreeBusy,
-		"VTIMEZONE": p.handleEndTimeZone,
-		"STANDARD":  p.handleEndStandard,
-		"DAYLIGHT":  p.handleEndDaylight,
-		"VALARM":    p.handleEndAlarm,
+		"VEVENT":        p.handleEndEvent,
+		"VTODO":         p.handleEndTodo,
+		"VJOURNAL":      p.handleEndJournal,
+		"VAVAILABILITY": p.handleEndAvailability,
+		"AVAILABLE":     p.handleEndAvailable,
+		"VFREEBUSY":     p.handleEndFreeBusy,
+		"VTIMEZONE":     p.handleEndTimeZone,
+		"STANDARD":      p.handleEndStandard,
+		"DAYLIGHT":      p.handleEndDaylight,
+		"VALARM":        p.handleEndAlarm,
+		"VLOCATION":     p.handleEndLocation,
+		"PARTICIPANT":   p.handleEndParticipant,
 	}
 
 	if handler, ok := handlers[component]; ok {
@@ -228,6 +427,30 @@ func (p *icalParser) handleEndTodo() {
 	}
 }
 
+func (p *icalParser) handleEndJournal() {
+	if p.inJournal && p.currentJournal != nil {
+		p.result.Journals = append(p.result.Journals, *p.currentJournal)
+		p.currentJournal = nil
+		p.inJournal = false
+	}
+}
+
+func (p *icalParser) handleEndAvailability() {
+	if p.inAvailability && p.currentAvail != nil {
+		p.result.Availability = append(p.result.Availability, *p.currentAvail)
+		p.currentAvail = nil
+		p.inAvailability = false
+	}
+}
+
+func (p *icalParser) handleEndAvailable() {
+	if p.inAvailable && p.currentAvailable != nil {
+		p.currentAvail.Available = append(p.currentAvail.Available, *p.currentAvailable)
+		p.currentAvailable = nil
+		p.inAvailable = false
+	}
+}
+
 func (p *icalParser) handleEndFreeBusy() {
 	if p.inFreeBusy && p.currentFreeBusy != nil {
 		p.result.FreeBusy = append(p.result.FreeBusy, *p.currentFreeBusy)
@@ -262,6 +485,8 @@ func (p *icalParser) handleEndAlarm() {
 	if p.inAlarm && p.currentAlarm != nil {
 		if p.inEvent && p.currentEvent != nil {
 			p.currentEvent.Alarms = append(p.currentEvent.Alarms, *p.currentAlarm)
+		} else if p.inTodo && p.currentTodo != nil {
+			p.currentTodo.Alarms = append(p.currentTodo.Alarms, *p.currentAlarm)
 		} else {
 			p.result.Alarms = append(p.result.Alarms, *p.currentAlarm)
 		}
@@ -270,14 +495,46 @@ func (p *icalParser) handleEndAlarm() {
 	}
 }
 
+func (p *icalParser) handleEndLocation() {
+	if p.inLocation && p.currentLocation != nil {
+		if p.inParticipant && p.currentParticipant != nil {
+			p.currentParticipant.Locations = append(p.currentParticipant.Locations, *p.currentLocation)
+		} else if p.inEvent && p.currentEvent != nil {
+			p.currentEvent.Locations = append(p.currentEvent.Locations, *p.currentLocation)
+		}
+		p.currentLocation = nil
+		p.inLocation = false
+	}
+}
+
+func (p *icalParser) handleEndParticipant() {
+	if p.inParticipant && p.currentParticipant != nil {
+		if p.inEvent && p.currentEvent != nil {
+			p.currentEvent.Participants = append(p.currentEvent.Participants, *p.currentParticipant)
+		}
+		p.currentParticipant = nil
+		p.inParticipant = false
+	}
+}
+
 func (p *icalParser) handleProperty(property, value string, params map[string]string) {
-	// Handle alarms first since they can be nested in events
-	if p.inAlarm && p.currentAlarm != nil {
+	// Handle subcomponents first since they are nested in events
+	if p.inLocation && p.currentLocation != nil {
+		p.handleLocationProperty(property, value, params)
+	} else if p.inParticipant && p.currentParticipant != nil {
+		p.handleParticipantProperty(property, value, params)
+	} else if p.inAlarm && p.currentAlarm != nil {
 		p.handleAlarmProperty(property, value, params)
 	} else if p.inEvent && p.currentEvent != nil {
 		p.handleEventProperty(property, value, params)
 	} else if p.inTodo && p.currentTodo != nil {
 		p.handleTodoProperty(property, value, params)
+	} else if p.inJournal && p.currentJournal != nil {
+		p.handleJournalProperty(property, value, params)
+	} else if p.inAvailable && p.currentAvailable != nil {
+		p.handleAvailableProperty(property, value, params)
+	} else if p.inAvailability && p.currentAvail != nil {
+		p.handleAvailabilityProperty(property, value, params)
 	} else if p.inFreeBusy && p.currentFreeBusy != nil {
 		p.handleFreeBusyProperty(property, value, params)
 	} else if p.inTimeZone && p.currentTimeZone != nil {
@@ -287,12 +544,35 @@ func (p *icalParser) handleProperty(property, value string, params map[string]st
 			p.handleTimeZoneProperty(property, value, params)
 		}
 	} else {
+		p.handleCalendarProperty(property, value, params)
+	}
+}
+
+func (p *icalParser) handleCalendarProperty(property, value string, params map[string]string) {
+	switch property {
+	case "NAME":
+		p.result.Name = unescapeICalText(value)
+	case "DESCRIPTION":
+		p.result.Description = unescapeICalText(value)
+	case "COLOR":
+		p.result.Color = value
+	case "REFRESH-INTERVAL":
+		p.result.RefreshInterval = value
+	case "IMAGE":
+		p.result.Images = append(p.result.Images, p.parseImage(value, params))
+	default:
 		// Global custom properties
-		p.result.CustomProperties[property] = value
+		p.addCustomProperty(p.result.CustomProperties, &p.result.ExtraProperties, property, value)
 	}
 }
 
 func (p *icalParser) handleEventProperty(property, value string, params map[string]string) {
+	switch property {
+	case "SUMMARY":
+		p.currentEvent.SummaryLanguage = params["LANGUAGE"]
+	case "DESCRIPTION":
+		p.currentEvent.DescriptionLanguage = params["LANGUAGE"]
+	}
 	if p.handleStringProperties(property, value) {
 		return
 	}
@@ -305,11 +585,11 @@ func (p *icalParser) handleEventProperty(property, value string, params map[stri
 	if p.handleSpecialProperties(property, value, params) {
 		return
 	}
-	p.currentEvent.CustomProperties[property] = value
+	p.addCustomProperty(p.currentEvent.CustomProperties, &p.currentEvent.ExtraProperties, property, value)
 }
 
 func (p *icalParser) handleStringProperties(property, value string) bool {
-	stringProps := []string{"UID", "DURATION", "SUMMARY", "LOCATION", "STATUS", "TRANSP", "CLASS", "URL", "RRULE", "EXRULE"}
+	stringProps := []string{"UID", "DURATION", "SUMMARY", "LOCATION", "STATUS", "TRANSP", "CLASS", "URL", "COLOR", "RRULE", "EXRULE"}
 	for _, prop := range stringProps {
 		if property == prop {
 			p.setEventStringProperty(property, value)
@@ -333,17 +613,21 @@ func (p *icalParser) handleTimeProperties(property, value string, params map[str
 func (p *icalParser) handleListProperties(property, value string, params map[string]string) bool {
 	switch property {
 	case "CATEGORIES":
-		p.currentEvent.Categories = append(p.currentEvent.Categories, strings.Split(value, ",")...)
+		p.currentEvent.Categories = append(p.currentEvent.Categories, splitEscaped(value, ',')...)
 	case "ATTENDEE":
 		p.currentEvent.Attendees = append(p.currentEvent.Attendees, p.parseAttendee(value, params))
 	case "RELATED-TO":
 		p.currentEvent.RelatedTo = append(p.currentEvent.RelatedTo, p.parseRelatedTo(value, params))
 	case "ATTACH":
 		p.currentEvent.Attachments = append(p.currentEvent.Attachments, p.parseAttachment(value, params))
+	case "IMAGE":
+		p.currentEvent.Images = append(p.currentEvent.Images, p.parseImage(value, params))
+	case "CONFERENCE":
+		p.currentEvent.Conferences = append(p.currentEvent.Conferences, p.parseConference(value, params))
 	case "CONTACT":
-		p.currentEvent.Contacts = append(p.currentEvent.Contacts, value)
+		p.currentEvent.Contacts = append(p.currentEvent.Contacts, unescapeICalText(value))
 	case "COMMENT":
-		p.currentEvent.Comments = append(p.currentEvent.Comments, value)
+		p.currentEvent.Comments = append(p.currentEvent.Comments, unescapeICalText(value))
 	case "REQUEST-STATUS":
 		p.currentEvent.RequestStatus = append(p.currentEvent.RequestStatus, p.parseRequestStatus(value))
 	default:
@@ -366,6 +650,8 @@ func (p *icalParser) handleSpecialProperties(property, value string, params map[
 		p.setEventIntProperty(property, value)
 	case "GEO":
 		p.currentEvent.GeoLocation = p.parseGeo(value)
+	case "X-APPLE-STRUCTURED-LOCATION":
+		p.currentEvent.StructuredLocation = p.parseAppleLocation(value, params)
 	default:
 		return false
 	}
@@ -379,9 +665,9 @@ func (p *icalParser) setEventStringProperty(property, value string) {
 	case "DURATION":
 		p.currentEvent.Duration = value
 	case "SUMMARY":
-		p.currentEvent.Summary = value
+		p.currentEvent.Summary = unescapeICalText(value)
 	case "LOCATION":
-		p.currentEvent.Location = value
+		p.currentEvent.Location = unescapeICalText(value)
 	case "STATUS":
 		p.currentEvent.Status = value
 	case "TRANSP":
@@ -390,6 +676,8 @@ func (p *icalParser) setEventStringProperty(property, value string) {
 		p.currentEvent.Class = value
 	case "URL":
 		p.currentEvent.URL = value
+	case "COLOR":
+		p.currentEvent.Color = value
 	case "RRULE":
 		p.currentEvent.RecurrenceRule = value
 	case "EXRULE":
@@ -408,10 +696,13 @@ func (p *icalParser) setEventTimeProperty(property, value string, params map[str
 		p.currentEvent.DTStamp = t
 	case "DTSTART":
 		p.currentEvent.DTStart = t
+		p.currentEvent.AllDay = isDateValue(value, params)
+		p.currentEvent.Floating = isFloatingValue(value, params)
 	case "DTEND":
 		p.currentEvent.DTEnd = t
 	case "RECURRENCE-ID":
 		p.currentEvent.RecurrenceID = t
+		p.currentEvent.RecurrenceRange = params["RANGE"]
 	case "CREATED":
 		p.currentEvent.Created = t
 	case "LAST-MODIFIED":
@@ -429,6 +720,7 @@ func (p *icalParser) setEventIntProperty(property, value string) {
 }
 
 func (p *icalParser) appendEventDescription(value string) {
+	value = unescapeICalText(value)
 	if p.currentEvent.Description == "" {
 		p.currentEvent.Description = value
 	} else {
@@ -450,6 +742,10 @@ func (p *icalParser) handleTodoProperty(property, value string, params map[strin
 		if t := p.parseTime(value, params); t != nil {
 			*timePtr = t
 		}
+		if property == "DTSTART" || property == "DUE" {
+			p.currentTodo.AllDay = isDateValue(value, params)
+			p.currentTodo.Floating = isFloatingValue(value, params)
+		}
 		return
 	}
 
@@ -460,9 +756,18 @@ func (p *icalParser) handleTodoProperty(property, value string, params map[strin
 		"STATUS":      &p.currentTodo.Status,
 		"CLASS":       &p.currentTodo.Class,
 		"URL":         &p.currentTodo.URL,
+		"COLOR":       &p.currentTodo.Color,
 	}
 
 	if strPtr, ok := stringProperties[property]; ok {
+		switch property {
+		case "SUMMARY":
+			value = unescapeICalText(value)
+			p.currentTodo.SummaryLanguage = params["LANGUAGE"]
+		case "DESCRIPTION":
+			value = unescapeICalText(value)
+			p.currentTodo.DescriptionLanguage = params["LANGUAGE"]
+		}
 		*strPtr = value
 		return
 	}
@@ -480,19 +785,202 @@ func (p *icalParser) handleTodoProperty(property, value string, params map[strin
 
 	switch property {
 	case "CATEGORIES":
-		p.currentTodo.Categories = append(p.currentTodo.Categories, strings.Split(value, ",")...)
+		p.currentTodo.Categories = append(p.currentTodo.Categories, splitEscaped(value, ',')...)
 	case "RELATED-TO":
 		p.currentTodo.RelatedTo = append(p.currentTodo.RelatedTo, p.parseRelatedTo(value, params))
 	case "ATTACH":
 		p.currentTodo.Attachments = append(p.currentTodo.Attachments, p.parseAttachment(value, params))
+	case "IMAGE":
+		p.currentTodo.Images = append(p.currentTodo.Images, p.parseImage(value, params))
+	case "CONFERENCE":
+		p.currentTodo.Conferences = append(p.currentTodo.Conferences, p.parseConference(value, params))
 	case "CONTACT":
-		p.currentTodo.Contacts = append(p.currentTodo.Contacts, value)
+		p.currentTodo.Contacts = append(p.currentTodo.Contacts, unescapeICalText(value))
 	case "COMMENT":
-		p.currentTodo.Comments = append(p.currentTodo.Comments, value)
+		p.currentTodo.Comments = append(p.currentTodo.Comments, unescapeICalText(value))
 	case "REQUEST-STATUS":
 		p.currentTodo.RequestStatus = append(p.currentTodo.RequestStatus, p.parseRequestStatus(value))
 	default:
-		p.currentTodo.CustomProperties[property] = value
+		p.addCustomProperty(p.currentTodo.CustomProperties, &p.currentTodo.ExtraProperties, property, value)
+	}
+}
+
+func (p *icalParser) handleJournalProperty(property, value string, params map[string]string) {
+	journal := p.currentJournal
+	timeProperties := map[string]**time.Time{
+		"DTSTAMP":       &journal.DTStamp,
+		"CREATED":       &journal.Created,
+		"LAST-MODIFIED": &journal.LastModified,
+	}
+
+	if timePtr, ok := timeProperties[property]; ok {
+		if t := p.parseTime(value, params); t != nil {
+			*timePtr = t
+		}
+		return
+	}
+
+	switch property {
+	case "UID":
+		journal.UID = value
+	case "DTSTART":
+		journal.DTStart = p.parseTime(value, params)
+		journal.AllDay = isDateValue(value, params)
+	case "SUMMARY":
+		journal.Summary = unescapeICalText(value)
+		journal.SummaryLanguage = params["LANGUAGE"]
+	case "DESCRIPTION":
+		journal.Descriptions = append(journal.Descriptions, unescapeICalText(value))
+		journal.DescriptionLanguage = params["LANGUAGE"]
+	case "STATUS":
+		journal.Status = value
+	case "CLASS":
+		journal.Class = value
+	case "URL":
+		journal.URL = value
+	case "SEQUENCE":
+		journal.Sequence = p.parseInt(value)
+	case "CATEGORIES":
+		journal.Categories = append(journal.Categories, splitEscaped(value, ',')...)
+	case "RELATED-TO":
+		journal.RelatedTo = append(journal.RelatedTo, p.parseRelatedTo(value, params))
+	case "ATTACH":
+		journal.Attachments = append(journal.Attachments, p.parseAttachment(value, params))
+	case "COMMENT":
+		journal.Comments = append(journal.Comments, unescapeICalText(value))
+	default:
+		p.addCustomProperty(journal.CustomProperties, &journal.ExtraProperties, property, value)
+	}
+}
+
+func (p *icalParser) handleAvailabilityProperty(property, value string, params map[string]string) {
+	avail := p.currentAvail
+	timeProperties := map[string]**time.Time{
+		"DTSTAMP":       &avail.DTStamp,
+		"DTSTART":       &avail.DTStart,
+		"DTEND":         &avail.DTEnd,
+		"CREATED":       &avail.Created,
+		"LAST-MODIFIED": &avail.LastModified,
+	}
+
+	if timePtr, ok := timeProperties[property]; ok {
+		if t := p.parseTime(value, params); t != nil {
+			*timePtr = t
+		}
+		return
+	}
+
+	switch property {
+	case "UID":
+		avail.UID = value
+	case "DURATION":
+		avail.Duration = value
+	case "BUSYTYPE":
+		avail.BusyType = strings.ToUpper(value)
+	case "PRIORITY":
+		avail.Priority = p.parseInt(value)
+	case "SEQUENCE":
+		avail.Sequence = p.parseInt(value)
+	case "SUMMARY":
+		avail.Summary = unescapeICalText(value)
+	case "DESCRIPTION":
+		avail.Description = unescapeICalText(value)
+	case "LOCATION":
+		avail.Location = unescapeICalText(value)
+	case "ORGANIZER":
+		avail.Organizer = p.parseOrganizer(value, params)
+	case "CLASS":
+		avail.Class = value
+	case "URL":
+		avail.URL = value
+	case "CATEGORIES":
+		avail.Categories = append(avail.Categories, splitEscaped(value, ',')...)
+	default:
+		p.addCustomProperty(avail.CustomProperties, &avail.ExtraProperties, property, value)
+	}
+}
+
+func (p *icalParser) handleAvailableProperty(property, value string, params map[string]string) {
+	available := p.currentAvailable
+	timeProperties := map[string]**time.Time{
+		"DTSTAMP":       &available.DTStamp,
+		"DTSTART":       &available.DTStart,
+		"DTEND":         &available.DTEnd,
+		"RECURRENCE-ID": &available.RecurrenceID,
+		"CREATED":       &available.Created,
+		"LAST-MODIFIED": &available.LastModified,
+	}
+
+	if timePtr, ok := timeProperties[property]; ok {
+		if t := p.parseTime(value, params); t != nil {
+			*timePtr = t
+		}
+		return
+	}
+
+	switch property {
+	case "UID":
+		available.UID = value
+	case "DURATION":
+		available.Duration = value
+	case "RRULE":
+		available.RecurrenceRule = value
+	case "RDATE":
+		available.RecurrenceDates = append(available.RecurrenceDates, p.parseTimeDates(value, params)...)
+	case "EXDATE":
+		available.ExceptionDates = append(available.ExceptionDates, p.parseTimeDates(value, params)...)
+	case "SUMMARY":
+		available.Summary = unescapeICalText(value)
+	case "DESCRIPTION":
+		available.Description = unescapeICalText(value)
+	case "LOCATION":
+		available.Location = unescapeICalText(value)
+	case "CATEGORIES":
+		available.Categories = append(available.Categories, splitEscaped(value, ',')...)
+	default:
+		p.addCustomProperty(available.CustomProperties, &available.ExtraProperties, property, value)
+	}
+}
+
+func (p *icalParser) handleLocationProperty(property, value string, params map[string]string) {
+	location := p.currentLocation
+	switch property {
+	case "UID":
+		location.UID = value
+	case "NAME":
+		location.Name = unescapeICalText(value)
+	case "DESCRIPTION":
+		location.Description = unescapeICalText(value)
+	case "LOCATION-TYPE":
+		location.LocationType = append(location.LocationType, splitEscaped(value, ',')...)
+	case "GEO":
+		location.Geo = p.parseGeo(value)
+	case "URL":
+		location.URL = value
+	default:
+		p.addCustomProperty(location.CustomProperties, &location.ExtraProperties, property, value)
+	}
+}
+
+func (p *icalParser) handleParticipantProperty(property, value string, params map[string]string) {
+	participant := p.currentParticipant
+	switch property {
+	case "UID":
+		participant.UID = value
+	case "PARTICIPANT-TYPE":
+		participant.ParticipantType = value
+	case "CALENDAR-ADDRESS":
+		participant.CalendarAddress = value
+	case "SUMMARY":
+		participant.Summary = unescapeICalText(value)
+	case "DESCRIPTION":
+		participant.Description = unescapeICalText(value)
+	case "GEO":
+		participant.Geo = p.parseGeo(value)
+	case "URL":
+		participant.URL = value
+	default:
+		p.addCustomProperty(participant.CustomProperties, &participant.ExtraProperties, property, value)
 	}
 }
 
@@ -517,11 +1005,13 @@ func (p *icalParser) handleFreeBusyProperty(property, value string, params map[s
 	case "ATTENDEE":
 		p.currentFreeBusy.Attendees = append(p.currentFreeBusy.Attendees, p.parseAttendee(value, params))
 	case "FREEBUSY":
-		if fb := p.parseFreeBusyPeriod(value, params); fb != nil {
-			p.currentFreeBusy.FreeBusy = append(p.currentFreeBusy.FreeBusy, *fb)
+		for _, period := range strings.Split(value, ",") {
+			if fb := p.parseFreeBusyPeriod(strings.TrimSpace(period), params); fb != nil {
+				p.currentFreeBusy.FreeBusy = append(p.currentFreeBusy.FreeBusy, *fb)
+			}
 		}
 	default:
-		p.currentFreeBusy.CustomProperties[property] = value
+		p.addCustomProperty(p.currentFreeBusy.CustomProperties, &p.currentFreeBusy.ExtraProperties, property, value)
 	}
 }
 
@@ -530,7 +1020,7 @@ func (p *icalParser) handleTimeZoneProperty(property, value string, params map[s
 	case "TZID":
 		p.currentTimeZone.TZID = value
 	default:
-		p.currentTimeZone.CustomProperties[property] = value
+		p.addCustomProperty(p.currentTimeZone.CustomProperties, &p.currentTimeZone.ExtraProperties, property, value)
 	}
 }
 
@@ -545,7 +1035,7 @@ func (p *icalParser) handleTimeZoneComponentProperty(property, value string, par
 	case "TZOFFSETTO":
 		p.currentTZComponent.TZOffsetTo = value
 	case "TZNAME":
-		p.currentTZComponent.TZName = value
+		p.currentTZComponent.TZName = unescapeICalText(value)
 	case "RRULE":
 		p.currentTZComponent.RecurrenceRule = value
 	case "RDATE":
@@ -555,12 +1045,12 @@ func (p *icalParser) handleTimeZoneComponentProperty(property, value string, par
 		dates := p.parseTimeDates(value, params)
 		p.currentTZComponent.ExceptionDates = append(p.currentTZComponent.ExceptionDates, dates...)
 	case "COMMENT":
-		p.currentTZComponent.Comment = append(p.currentTZComponent.Comment, value)
+		p.currentTZComponent.Comment = append(p.currentTZComponent.Comment, unescapeICalText(value))
 	default:
 		if p.currentTZComponent.CustomProperties == nil {
 			p.currentTZComponent.CustomProperties = make(map[string]string)
 		}
-		p.currentTZComponent.CustomProperties[property] = value
+		p.addCustomProperty(p.currentTZComponent.CustomProperties, &p.currentTZComponent.ExtraProperties, property, value)
 	}
 }
 
@@ -570,23 +1060,37 @@ func (p *icalParser) handleAlarmProperty(property, value string, params map[stri
 		p.currentAlarm.Action = value
 	case "TRIGGER":
 		p.currentAlarm.Trigger = value
+		p.currentAlarm.TriggerRelated = strings.ToUpper(params["RELATED"])
 	case "DURATION":
 		p.currentAlarm.Duration = value
 	case "REPEAT":
 		p.currentAlarm.Repeat = p.parseInt(value)
 	case "DESCRIPTION":
-		p.currentAlarm.Description = value
+		p.currentAlarm.Description = unescapeICalText(value)
 	case "SUMMARY":
-		p.currentAlarm.Summary = value
+		p.currentAlarm.Summary = unescapeICalText(value)
 	case "ATTENDEE":
 		p.currentAlarm.Attendees = append(p.currentAlarm.Attendees, p.parseAttendee(value, params))
 	default:
-		p.currentAlarm.CustomProperties[property] = value
+		p.addCustomProperty(p.currentAlarm.CustomProperties, &p.currentAlarm.ExtraProperties, property, value)
 	}
 }
 
 func (p *icalParser) parseTime(value string, params map[string]string) *time.Time {
-	return ParseCalDAVTimeWithParams(value, params)
+	t := ParseCalDAVTimeWithParams(value, params)
+	if t == nil || params["TZID"] == "" || t.Location() != time.UTC || strings.HasSuffix(value, "Z") {
+		return t
+	}
+
+	// The TZID is not known to Go's tzdata; fall back to a VTIMEZONE defined
+	// earlier in the same calendar.
+	for _, tz := range p.result.TimeZones {
+		if tz.TZID == params["TZID"] {
+			utc := CreateTimeZoneInfo(tz).ConvertToUTC(*t)
+			return &utc
+		}
+	}
+	return t
 }
 
 func (p *icalParser) parseInt(value string) int {
@@ -654,6 +1158,10 @@ func (p *icalParser) parseAttendee(value string, params map[string]string) Parse
 			att.Dir = val
 		case "SENT-BY":
 			att.SentBy = val
+		case "SCHEDULE-AGENT":
+			att.ScheduleAgent = val
+		case "SCHEDULE-STATUS":
+			att.ScheduleStatus = val
 		default:
 			att.CustomParams[key] = val
 		}
@@ -687,6 +1195,59 @@ func (p *icalParser) parseGeo(value string) *GeoLocation {
 	}
 }
 
+// parseGeoURI parses an RFC 5870 geo: URI such as geo:37.33,-122.03;u=35,
+// ignoring any altitude and URI parameters.
+func (p *icalParser) parseGeoURI(value string) *GeoLocation {
+	if len(value) < 4 || !strings.EqualFold(value[:4], "geo:") {
+		return nil
+	}
+	coords := value[4:]
+	if i := strings.IndexByte(coords, ';'); i >= 0 {
+		coords = coords[:i]
+	}
+
+	parts := strings.Split(coords, ",")
+	if len(parts) < 2 {
+		return nil
+	}
+	lat, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil
+	}
+	lon, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil
+	}
+
+	return &GeoLocation{
+		Latitude:  lat,
+		Longitude: lon,
+	}
+}
+
+// parseAppleLocation parses iCloud's X-APPLE-STRUCTURED-LOCATION property,
+// whose value is a geo: URI described by X-TITLE, X-ADDRESS and
+// X-APPLE-RADIUS parameters.
+func (p *icalParser) parseAppleLocation(value string, params map[string]string) *ParsedLocation {
+	location := &ParsedLocation{
+		Name:         params["X-TITLE"],
+		Address:      params["X-ADDRESS"],
+		Geo:          p.parseGeoURI(value),
+		CustomParams: make(map[string]string),
+	}
+	if radius, err := strconv.ParseFloat(params["X-APPLE-RADIUS"], 64); err == nil {
+		location.Radius = radius
+	}
+
+	for key, val := range params {
+		if key != "VALUE" && key != "X-TITLE" && key != "X-ADDRESS" && key != "X-APPLE-RADIUS" {
+			location.CustomParams[key] = val
+		}
+	}
+
+	return location
+}
+
 func (p *icalParser) parseFreeBusyPeriod(value string, params map[string]string) *FreeBusyPeriod {
 	parts := strings.Split(value, "/")
 	if len(parts) != 2 {
@@ -695,6 +1256,12 @@ func (p *icalParser) parseFreeBusyPeriod(value string, params map[string]string)
 
 	start := p.parseTime(parts[0], nil)
 	end := p.parseTime(parts[1], nil)
+	if start != nil && end == nil && strings.HasPrefix(parts[1], "P") {
+		if d, _, err := parseISO8601Duration(parts[1]); err == nil {
+			e := start.Add(d)
+			end = &e
+		}
+	}
 
 	if start == nil || end == nil {
 		return nil
@@ -779,8 +1346,58 @@ func (p *icalParser) parseAttachment(value string, params map[string]string) Att
 	return attachment
 }
 
+func (p *icalParser) parseImage(value string, params map[string]string) Image {
+	image := Image{
+		FormatType:   params["FMTTYPE"],
+		Display:      splitParamList(params["DISPLAY"]),
+		AltRep:       params["ALTREP"],
+		CustomParams: make(map[string]string),
+	}
+
+	if params["VALUE"] == "BINARY" {
+		image.Value = value
+		image.Encoding = params["ENCODING"]
+	} else {
+		image.URI = value
+	}
+
+	for key, val := range params {
+		if key != "VALUE" && key != "ENCODING" && key != "FMTTYPE" && key != "DISPLAY" && key != "ALTREP" {
+			image.CustomParams[key] = val
+		}
+	}
+
+	return image
+}
+
+func (p *icalParser) parseConference(value string, params map[string]string) Conference {
+	conference := Conference{
+		URI:          value,
+		Features:     splitParamList(params["FEATURE"]),
+		Label:        params["LABEL"],
+		CustomParams: make(map[string]string),
+	}
+
+	for key, val := range params {
+		if key != "VALUE" && key != "FEATURE" && key != "LABEL" {
+			conference.CustomParams[key] = val
+		}
+	}
+
+	return conference
+}
+
+// splitParamList splits a multi-valued parameter such as FEATURE=AUDIO,VIDEO
+// into its values.
+func splitParamList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 func (p *icalParser) parseRequestStatus(value string) RequestStatus {
-	parts := strings.SplitN(value, ";", 3)
+	parts := splitEscaped(value, ';')
 	status := RequestStatus{
 		Code: parts[0],
 	}
@@ -790,7 +1407,7 @@ func (p *icalParser) parseRequestStatus(value string) RequestStatus {
 	}
 
 	if len(parts) > 2 {
-		status.ExtraData = parts[2]
+		status.ExtraData = strings.Join(parts[2:], ";")
 	}
 
 	return status
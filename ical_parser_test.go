@@ -274,6 +274,61 @@ END:VCALENDAR`
 	}
 }
 
+func TestParseICalendar_Journal(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VJOURNAL
+UID:journal-001
+DTSTAMP:20240115T120000Z
+DTSTART;VALUE=DATE:20240115
+SUMMARY:Standup notes
+DESCRIPTION:Sam is blocked on review
+DESCRIPTION:Kim ships on Friday\, pending QA
+STATUS:DRAFT
+CATEGORIES:Notes,Standup
+ATTACH;FMTTYPE=image/png:https://example.com/board.png
+X-NOTES-SOURCE:app
+END:VJOURNAL
+END:VCALENDAR`
+
+	parsed, err := ParseICalendar(icalData)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	if len(parsed.Journals) != 1 {
+		t.Fatalf("Expected 1 journal, got %d", len(parsed.Journals))
+	}
+
+	journal := parsed.Journals[0]
+
+	if journal.UID != "journal-001" || journal.Summary != "Standup notes" || journal.Status != "DRAFT" {
+		t.Errorf("unexpected journal %+v", journal)
+	}
+
+	expectedStart := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if journal.DTStart == nil || !journal.DTStart.Equal(expectedStart) || !journal.AllDay {
+		t.Errorf("DTStart: expected all-day %v, got %v (all-day %v)", expectedStart, journal.DTStart, journal.AllDay)
+	}
+
+	expectedDescriptions := []string{"Sam is blocked on review", "Kim ships on Friday, pending QA"}
+	if !reflect.DeepEqual(journal.Descriptions, expectedDescriptions) {
+		t.Errorf("Descriptions: expected %v, got %v", expectedDescriptions, journal.Descriptions)
+	}
+
+	if !reflect.DeepEqual(journal.Categories, []string{"Notes", "Standup"}) {
+		t.Errorf("Categories: unexpected %v", journal.Categories)
+	}
+
+	if len(journal.Attachments) != 1 || journal.Attachments[0].URI != "https://example.com/board.png" {
+		t.Errorf("Attachments: unexpected %+v", journal.Attachments)
+	}
+
+	if journal.CustomProperties["X-NOTES-SOURCE"] != "app" {
+		t.Errorf("CustomProperties: unexpected %v", journal.CustomProperties)
+	}
+}
+
 func TestParseICalendar_EventWithAlarm(t *testing.T) {
 	icalData := `BEGIN:VCALENDAR
 VERSION:2.0
@@ -855,6 +910,16 @@ func TestParseFreeBusyPeriod(t *testing.T) {
 				FBType: "BUSY",
 			},
 		},
+		{
+			name:   "Period with duration",
+			value:  "20240115T140000Z/PT1H30M",
+			params: map[string]string{"FBTYPE": "BUSY-TENTATIVE"},
+			expectedFB: FreeBusyPeriod{
+				Start:  mustParseTime("20240115T140000Z"),
+				End:    mustParseTime("20240115T153000Z"),
+				FBType: "BUSY-TENTATIVE",
+			},
+		},
 		{
 			name:      "Invalid format - no slash",
 			value:     "20240115T090000Z",
@@ -1677,6 +1742,156 @@ END:VCALENDAR`
 	}
 }
 
+func TestParseICalendar_ExtendedProperties(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+NAME:Holidays
+DESCRIPTION:Public holidays\, UK
+COLOR:crimson
+REFRESH-INTERVAL;VALUE=DURATION:P1W
+BEGIN:VEVENT
+UID:event-with-conference
+DTSTART:20240115T100000Z
+SUMMARY:Standup
+COLOR:turquoise
+IMAGE;VALUE=URI;DISPLAY=BADGE;FMTTYPE=image/png;ALTREP="https://example.com/badge.html":https://example.com/badge.png
+IMAGE;ENCODING=BASE64;VALUE=BINARY;FMTTYPE=image/png:iVBORw0KGgo=
+CONFERENCE;VALUE=URI;FEATURE=PHONE;LABEL=Dial-in:tel:+1-412-555-0123,,,654321
+CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO;LABEL="Join, video";X-PROVIDER=zoom:https://video.example.com/123456
+END:VEVENT
+END:VCALENDAR`
+
+	parsed, err := ParseICalendar(icalData)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	if parsed.Name != "Holidays" || parsed.Description != "Public holidays, UK" ||
+		parsed.Color != "crimson" || parsed.RefreshInterval != "P1W" {
+		t.Errorf("unexpected calendar properties %+v", parsed)
+	}
+	if len(parsed.CustomProperties) != 0 {
+		t.Errorf("expected no custom properties, got %v", parsed.CustomProperties)
+	}
+
+	event := parsed.Events[0]
+	if event.Color != "turquoise" {
+		t.Errorf("Color: expected 'turquoise', got %q", event.Color)
+	}
+	if len(event.CustomProperties) != 0 {
+		t.Errorf("expected no custom properties, got %v", event.CustomProperties)
+	}
+
+	if len(event.Images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(event.Images))
+	}
+	if image := event.Images[0]; image.URI != "https://example.com/badge.png" || image.FormatType != "image/png" ||
+		!reflect.DeepEqual(image.Display, []string{"BADGE"}) || image.AltRep != "https://example.com/badge.html" {
+		t.Errorf("unexpected image %+v", image)
+	}
+	if image := event.Images[1]; image.URI != "" || image.Value != "iVBORw0KGgo=" || image.Encoding != "BASE64" {
+		t.Errorf("unexpected binary image %+v", image)
+	}
+
+	if len(event.Conferences) != 2 {
+		t.Fatalf("expected 2 conferences, got %d", len(event.Conferences))
+	}
+	if conf := event.Conferences[0]; conf.URI != "tel:+1-412-555-0123,,,654321" || conf.Label != "Dial-in" ||
+		!reflect.DeepEqual(conf.Features, []string{"PHONE"}) {
+		t.Errorf("unexpected conference %+v", conf)
+	}
+	conf := event.Conferences[1]
+	if conf.URI != "https://video.example.com/123456" || conf.Label != "Join, video" ||
+		!reflect.DeepEqual(conf.Features, []string{"AUDIO", "VIDEO"}) || conf.CustomParams["X-PROVIDER"] != "zoom" {
+		t.Errorf("unexpected conference %+v", conf)
+	}
+	if join, ok := JoinConference(event.Conferences); !ok || join.URI != conf.URI {
+		t.Errorf("expected to join %q, got %q", conf.URI, join.URI)
+	}
+}
+
+func TestParseICalendar_StructuredLocations(t *testing.T) {
+	icalData := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:event-with-locations\r\n" +
+		"DTSTART:20240115T100000Z\r\n" +
+		"LOCATION:Apple Park\\n1 Apple Park Way\r\n" +
+		"X-APPLE-STRUCTURED-LOCATION;VALUE=URI;X-ADDRESS=1 Apple Park Way\\nCupertino\r\n" +
+		" ;X-APPLE-MAPKIT-HANDLE=CAES;X-APPLE-RADIUS=141.17;X-APPLE-REFERENCEFRAME=1;X-TI\r\n" +
+		" TLE=Apple Park:geo:37.334900,-122.009020\r\n" +
+		"BEGIN:VLOCATION\r\n" +
+		"UID:loc-1\r\n" +
+		"NAME:Visitor parking\r\n" +
+		"DESCRIPTION:Level 2\r\n" +
+		"LOCATION-TYPE:parking\r\n" +
+		"GEO:37.3318;-122.0302\r\n" +
+		"END:VLOCATION\r\n" +
+		"BEGIN:PARTICIPANT\r\n" +
+		"UID:participant-1\r\n" +
+		"PARTICIPANT-TYPE:SPEAKER\r\n" +
+		"CALENDAR-ADDRESS:mailto:speaker@example.com\r\n" +
+		"SUMMARY:Keynote\r\n" +
+		"X-BIO:Engineer\r\n" +
+		"BEGIN:VLOCATION\r\n" +
+		"UID:loc-2\r\n" +
+		"NAME:Green room\r\n" +
+		"END:VLOCATION\r\n" +
+		"END:PARTICIPANT\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	parsed, err := ParseICalendar(icalData)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	event := parsed.Events[0]
+
+	if event.UID != "event-with-locations" {
+		t.Errorf("subcomponent UIDs must not replace the event UID, got %q", event.UID)
+	}
+	if len(event.CustomProperties) != 0 {
+		t.Errorf("expected no custom properties, got %v", event.CustomProperties)
+	}
+
+	apple := event.StructuredLocation
+	if apple == nil {
+		t.Fatal("expected an Apple structured location")
+	}
+	if apple.Name != "Apple Park" || apple.Address != "1 Apple Park Way\\nCupertino" || apple.Radius != 141.17 {
+		t.Errorf("unexpected structured location %+v", apple)
+	}
+	if apple.Geo == nil || apple.Geo.Latitude != 37.3349 || apple.Geo.Longitude != -122.00902 {
+		t.Errorf("unexpected coordinates %+v", apple.Geo)
+	}
+	if apple.CustomParams["X-APPLE-REFERENCEFRAME"] != "1" || apple.CustomParams["X-APPLE-MAPKIT-HANDLE"] != "CAES" {
+		t.Errorf("unexpected custom parameters %v", apple.CustomParams)
+	}
+
+	if len(event.Locations) != 1 {
+		t.Fatalf("expected 1 location, got %d", len(event.Locations))
+	}
+	location := event.Locations[0]
+	if location.UID != "loc-1" || location.Name != "Visitor parking" || location.Description != "Level 2" ||
+		!reflect.DeepEqual(location.LocationType, []string{"parking"}) ||
+		location.Geo == nil || location.Geo.Latitude != 37.3318 {
+		t.Errorf("unexpected location %+v", location)
+	}
+
+	if len(event.Participants) != 1 {
+		t.Fatalf("expected 1 participant, got %d", len(event.Participants))
+	}
+	participant := event.Participants[0]
+	if participant.UID != "participant-1" || participant.ParticipantType != "SPEAKER" ||
+		participant.CalendarAddress != "mailto:speaker@example.com" || participant.Summary != "Keynote" ||
+		participant.CustomProperties["X-BIO"] != "Engineer" {
+		t.Errorf("unexpected participant %+v", participant)
+	}
+	if len(participant.Locations) != 1 || participant.Locations[0].Name != "Green room" {
+		t.Errorf("expected the participant's location, got %+v", participant.Locations)
+	}
+}
+
 func TestParseEventMetadata_ContactsAndComments(t *testing.T) {
 	icalData := `BEGIN:VCALENDAR
 VERSION:2.0
@@ -26,6 +26,10 @@ const (
 	CapVJournal                 ServerCapability = "vjournal"
 	CapVResource                ServerCapability = "vresource"
 	CapVAvailability            ServerCapability = "vavailability"
+
+	// CapCalendarExpand is not advertised in the DAV header; it records whether
+	// the server honours <C:expand> in calendar-data requests.
+	CapCalendarExpand ServerCapability = "calendar-expand"
 )
 
 type ServerType string
@@ -83,6 +87,7 @@ func (c *CalDAVClient) DetectServerType(ctx context.Context) (*ServerCompatibili
 		c.populateGenericCapabilities(compat, davHeader)
 	}
 
+	c.serverCompat = compat
 	return compat, nil
 }
 
@@ -104,12 +109,14 @@ func (c *CalDAVClient) populateICloudCapabilities(compat *ServerCompatibility, d
 	compat.Capabilities[CapVJournal] = false
 	compat.Capabilities[CapVResource] = false
 	compat.Capabilities[CapVAvailability] = false
+	compat.Capabilities[CapCalendarExpand] = false
 }
 
 func (c *CalDAVClient) populateGoogleCapabilities(compat *ServerCompatibility, davHeader string) {
 	compat.Capabilities[CapCalendarAccess] = true
 	compat.Capabilities[CapCalendarProxy] = true
 	compat.Capabilities[CapCalendarQueryExtended] = true
+	compat.Capabilities[CapCalendarExpand] = true
 
 	compat.Capabilities[CapCalendarSchedule] = false
 	compat.Capabilities[CapCalendarManagedAttach] = false
@@ -123,6 +130,8 @@ func (c *CalDAVClient) populateNextcloudCapabilities(compat *ServerCompatibility
 	compat.Capabilities[CapCalendarQueryExtended] = true
 	compat.Capabilities[CapCalendarSchedule] = true
 	compat.Capabilities[CapCalendarAutoSchedule] = true
+	compat.Capabilities[CapCalendarExpand] = true
+	compat.Capabilities[CapVJournal] = true
 
 	compat.Capabilities[CapCalendarManagedAttach] = false
 	compat.Capabilities[CapVResource] = false
@@ -138,6 +147,7 @@ func (c *CalDAVClient) populateGenericCapabilities(compat *ServerCompatibility,
 	compat.Capabilities[CapCalendarManagedAttach] = strings.Contains(davHeader, "calendar-managed-attachments")
 	compat.Capabilities[CapCalendarNoInstance] = strings.Contains(davHeader, "calendar-no-instance")
 	compat.Capabilities[CapInboxAvailability] = strings.Contains(davHeader, "inbox-availability")
+	compat.Capabilities[CapCalendarExpand] = compat.Capabilities[CapCalendarAccess]
 }
 
 func (c *CalDAVClient) IsICloudServer(ctx context.Context) (bool, error) {
@@ -171,6 +181,13 @@ func (c *CalDAVClient) GetServerCompatibility(ctx context.Context) (*ServerCompa
 func (c *CalDAVClient) ConfigureForICloud() {
 	c.SetTimeout(30)
 
+	compat := &ServerCompatibility{
+		Type:         ServerTypeICloud,
+		Capabilities: make(map[ServerCapability]bool),
+	}
+	c.populateICloudCapabilities(compat, "")
+	c.serverCompat = compat
+
 	if !strings.HasSuffix(c.baseURL, "/") {
 		c.baseURL = c.baseURL + "/"
 	}
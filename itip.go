@@ -0,0 +1,603 @@
+package caldav
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// iTIP methods defined by RFC 5546.
+const (
+	ITIPMethodPublish        = "PUBLISH"
+	ITIPMethodRequest        = "REQUEST"
+	ITIPMethodReply          = "REPLY"
+	ITIPMethodAdd            = "ADD"
+	ITIPMethodCancel         = "CANCEL"
+	ITIPMethodRefresh        = "REFRESH"
+	ITIPMethodCounter        = "COUNTER"
+	ITIPMethodDeclineCounter = "DECLINECOUNTER"
+)
+
+// Participation statuses used in iTIP replies.
+const (
+	PartStatNeedsAction = "NEEDS-ACTION"
+	PartStatAccepted    = "ACCEPTED"
+	PartStatDeclined    = "DECLINED"
+	PartStatTentative   = "TENTATIVE"
+	PartStatDelegated   = "DELEGATED"
+)
+
+// ITIPMessage is an iTIP scheduling message and the calendar user addresses
+// it should be delivered to.
+type ITIPMessage struct {
+	Method     string
+	Recipients []string
+	Calendar   *ParsedCalendarData
+}
+
+// Encode returns the message as iCalendar data.
+func (m *ITIPMessage) Encode() ([]byte, error) {
+	return EncodeICalendar(m.Calendar)
+}
+
+// NewITIPRequest builds a METHOD:REQUEST inviting the attendees of event uid
+// in data, including its overrides, addressed to every attendee other than
+// the organizer.
+func NewITIPRequest(data *ParsedCalendarData, uid string) (*ITIPMessage, error) {
+	events, err := schedulingComponents(data, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	stampEvents(events, time.Now().UTC())
+	return &ITIPMessage{
+		Method:     ITIPMethodRequest,
+		Recipients: attendeeRecipients(events),
+		Calendar:   newITIPCalendar(ITIPMethodRequest, events, data.TimeZones),
+	}, nil
+}
+
+// NewITIPUpdate prepares the messages for a change from previous to updated,
+// both copies of the organizer's resource holding event uid. Each component
+// of updated gets a new DTSTAMP, and its SEQUENCE is incremented when the
+// change is significant as defined by RFC 5546 section 2.1.4 (start, end,
+// duration or recurrence). It returns a REQUEST for the current attendees and,
+// when attendees were removed, a CANCEL addressed to them.
+func NewITIPUpdate(previous, updated *ParsedCalendarData, uid string) ([]*ITIPMessage, error) {
+	before, err := schedulingComponents(previous, uid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := schedulingComponents(updated, uid); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	oldMaster := findMasterEvent(before, uid)
+	for i := range updated.Events {
+		event := &updated.Events[i]
+		if event.UID != uid {
+			continue
+		}
+		event.DTStamp = &now
+
+		old := findOverride(before, event.RecurrenceID)
+		switch {
+		case old != nil && isSignificantChange(old, event):
+			event.Sequence = maxInt(event.Sequence, old.Sequence+1)
+		case old != nil:
+			event.Sequence = maxInt(event.Sequence, old.Sequence)
+		case oldMaster != nil:
+			event.Sequence = maxInt(event.Sequence, oldMaster.Sequence+1)
+		}
+	}
+
+	request, err := NewITIPRequest(updated, uid)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*ITIPMessage{request}
+
+	var removed []string
+	for _, address := range attendeeRecipients(before) {
+		if !containsCalendarAddress(request.Recipients, address) {
+			removed = append(removed, address)
+		}
+	}
+	if len(removed) > 0 && oldMaster != nil {
+		cancel := *oldMaster
+		cancel.Status = "CANCELLED"
+		if master := findMasterEvent(updated.Events, uid); master != nil {
+			cancel.Sequence = master.Sequence
+		}
+		cancel.Attendees = filterAttendees(oldMaster.Attendees, removed)
+		cancel.DTStamp = &now
+		messages = append(messages, &ITIPMessage{
+			Method:     ITIPMethodCancel,
+			Recipients: removed,
+			Calendar:   newITIPCalendar(ITIPMethodCancel, []ParsedEvent{cancel}, previous.TimeZones),
+		})
+	}
+
+	return messages, nil
+}
+
+// NewITIPCancel builds a METHOD:CANCEL for event uid addressed to all of its
+// attendees. The components in data are marked STATUS:CANCELLED with an
+// incremented SEQUENCE, so the organizer's copy can be written back as is.
+func NewITIPCancel(data *ParsedCalendarData, uid string) (*ITIPMessage, error) {
+	if _, err := schedulingComponents(data, uid); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for i := range data.Events {
+		if data.Events[i].UID == uid {
+			data.Events[i].Status = "CANCELLED"
+			data.Events[i].Sequence++
+			data.Events[i].DTStamp = &now
+		}
+	}
+
+	events, _ := schedulingComponents(data, uid)
+	return &ITIPMessage{
+		Method:     ITIPMethodCancel,
+		Recipients: attendeeRecipients(events),
+		Calendar:   newITIPCalendar(ITIPMethodCancel, events, data.TimeZones),
+	}, nil
+}
+
+// NewITIPReply builds the METHOD:REPLY in which attendee answers the
+// invitation event with partStat, addressed to the organizer. event may be
+// the master or a single overridden occurrence.
+func NewITIPReply(event *ParsedEvent, attendee, partStat string) (*ITIPMessage, error) {
+	if err := validateSchedulingEvent(event); err != nil {
+		return nil, err
+	}
+	replying := findAttendee(event.Attendees, attendee)
+	if replying == nil {
+		return nil, newTypedError("itip.reply", ErrorTypeValidation, fmt.Sprintf("%s is not an attendee of event %s", attendee, event.UID), nil)
+	}
+
+	answer := *replying
+	answer.PartStat = strings.ToUpper(partStat)
+	answer.RSVP = false
+
+	now := time.Now().UTC()
+	reply := ParsedEvent{
+		UID:          event.UID,
+		DTStamp:      &now,
+		DTStart:      event.DTStart,
+		DTEnd:        event.DTEnd,
+		AllDay:       event.AllDay,
+		Floating:     event.Floating,
+		Duration:     event.Duration,
+		Summary:      event.Summary,
+		Organizer:    event.Organizer,
+		Attendees:    []ParsedAttendee{answer},
+		RecurrenceID: event.RecurrenceID,
+		Sequence:     event.Sequence,
+	}
+
+	return &ITIPMessage{
+		Method:     ITIPMethodReply,
+		Recipients: []string{event.Organizer.Value},
+		Calendar:   newITIPCalendar(ITIPMethodReply, []ParsedEvent{reply}, nil),
+	}, nil
+}
+
+// NewITIPCounter builds a METHOD:COUNTER in which attendee proposes the
+// changes in proposed, a modified copy of the invitation, to the organizer.
+func NewITIPCounter(proposed *ParsedEvent, attendee string) (*ITIPMessage, error) {
+	if err := validateSchedulingEvent(proposed); err != nil {
+		return nil, err
+	}
+	if findAttendee(proposed.Attendees, attendee) == nil {
+		return nil, newTypedError("itip.counter", ErrorTypeValidation, fmt.Sprintf("%s is not an attendee of event %s", attendee, proposed.UID), nil)
+	}
+
+	counter := *proposed
+	now := time.Now().UTC()
+	counter.DTStamp = &now
+
+	return &ITIPMessage{
+		Method:     ITIPMethodCounter,
+		Recipients: []string{proposed.Organizer.Value},
+		Calendar:   newITIPCalendar(ITIPMethodCounter, []ParsedEvent{counter}, nil),
+	}, nil
+}
+
+// NewITIPDeclineCounter builds the METHOD:DECLINECOUNTER with which the
+// organizer rejects attendee's counter proposal for event.
+func NewITIPDeclineCounter(event *ParsedEvent, attendee string) (*ITIPMessage, error) {
+	if err := validateSchedulingEvent(event); err != nil {
+		return nil, err
+	}
+	countering := findAttendee(event.Attendees, attendee)
+	if countering == nil {
+		return nil, newTypedError("itip.declinecounter", ErrorTypeValidation, fmt.Sprintf("%s is not an attendee of event %s", attendee, event.UID), nil)
+	}
+
+	now := time.Now().UTC()
+	decline := ParsedEvent{
+		UID:          event.UID,
+		DTStamp:      &now,
+		Organizer:    event.Organizer,
+		Attendees:    []ParsedAttendee{*countering},
+		RecurrenceID: event.RecurrenceID,
+		Sequence:     event.Sequence,
+	}
+
+	return &ITIPMessage{
+		Method:     ITIPMethodDeclineCounter,
+		Recipients: []string{countering.Value},
+		Calendar:   newITIPCalendar(ITIPMethodDeclineCounter, []ParsedEvent{decline}, nil),
+	}, nil
+}
+
+// ApplyITIPReply records the participation statuses in reply on the
+// organizer's copy of the event in data. A reply for a single occurrence of a
+// recurring event creates an override for it when there is none. Replies with
+// a lower SEQUENCE than the stored event return ErrStaleITIPMessage and leave
+// data unchanged.
+func ApplyITIPReply(data *ParsedCalendarData, reply *ParsedCalendarData) error {
+	if err := checkITIPMethod(data, reply, ITIPMethodReply); err != nil {
+		return err
+	}
+	if err := checkITIPTargets(data, reply); err != nil {
+		return err
+	}
+
+	for _, answer := range reply.Events {
+		index, err := itipTarget(data, &answer)
+		if err != nil {
+			return err
+		}
+
+		target := &data.Events[index]
+		for _, attendee := range answer.Attendees {
+			existing := findAttendee(target.Attendees, attendee.Value)
+			if existing == nil {
+				target.Attendees = append(target.Attendees, attendee)
+				continue
+			}
+			existing.PartStat = attendee.PartStat
+			existing.RSVP = false
+			if attendee.DelegatedTo != "" {
+				existing.DelegatedTo = attendee.DelegatedTo
+			}
+		}
+	}
+
+	return nil
+}
+
+// ApplyITIPRequest stores the invitation in request in data, the attendee's
+// copy of the event. A request holding the master event replaces every
+// component of the event; one holding only occurrences replaces or adds those
+// overrides. Requests with a lower SEQUENCE than the stored event return
+// ErrStaleITIPMessage and leave data unchanged.
+func ApplyITIPRequest(data *ParsedCalendarData, request *ParsedCalendarData) error {
+	if err := checkITIPMethod(data, request, ITIPMethodRequest); err != nil {
+		return err
+	}
+
+	for _, uid := range itipUIDs(request) {
+		incoming := eventsWithUID(request.Events, uid)
+		for _, event := range incoming {
+			if stored := findOverride(eventsWithUID(data.Events, uid), event.RecurrenceID); stored != nil && event.Sequence < stored.Sequence {
+				return ErrStaleITIPMessage
+			}
+		}
+	}
+
+	for _, uid := range itipUIDs(request) {
+		incoming := eventsWithUID(request.Events, uid)
+		replaceAll := findMasterEvent(incoming, uid) != nil
+
+		kept := data.Events[:0]
+		for _, event := range data.Events {
+			if event.UID == uid && (replaceAll || findOverride(incoming, event.RecurrenceID) != nil) {
+				continue
+			}
+			kept = append(kept, event)
+		}
+		data.Events = append(kept, incoming...)
+	}
+
+	for _, tz := range request.TimeZones {
+		if !hasTimeZone(data.TimeZones, tz.TZID) {
+			data.TimeZones = append(data.TimeZones, tz)
+		}
+	}
+	return nil
+}
+
+// ApplyITIPCancel records the cancellation in cancel on data, the attendee's
+// copy of the event. Cancelling the master marks every component of the
+// event STATUS:CANCELLED; cancelling an occurrence marks only its override,
+// which is created if needed. Cancellations with a lower SEQUENCE than the
+// stored event return ErrStaleITIPMessage and leave data unchanged.
+func ApplyITIPCancel(data *ParsedCalendarData, cancel *ParsedCalendarData) error {
+	if err := checkITIPMethod(data, cancel, ITIPMethodCancel); err != nil {
+		return err
+	}
+	if err := checkITIPTargets(data, cancel); err != nil {
+		return err
+	}
+
+	for _, event := range cancel.Events {
+		index, err := itipTarget(data, &event)
+		if err != nil {
+			return err
+		}
+
+		if event.RecurrenceID != nil {
+			data.Events[index].Status = "CANCELLED"
+			data.Events[index].Sequence = event.Sequence
+			continue
+		}
+		for i := range data.Events {
+			if data.Events[i].UID == event.UID {
+				data.Events[i].Status = "CANCELLED"
+				data.Events[i].Sequence = maxInt(data.Events[i].Sequence, event.Sequence)
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkITIPMethod(data, message *ParsedCalendarData, method string) error {
+	if data == nil || message == nil {
+		return newTypedError("itip.apply", ErrorTypeValidation, "calendar data cannot be nil", nil)
+	}
+	if !strings.EqualFold(message.Method, method) {
+		return newTypedError("itip.apply", ErrorTypeValidation, fmt.Sprintf("expected METHOD:%s, got %q", method, message.Method), nil)
+	}
+	return nil
+}
+
+// itipUIDs returns the UIDs of the events in message in order of appearance.
+func itipUIDs(message *ParsedCalendarData) []string {
+	var uids []string
+	for _, event := range message.Events {
+		if !containsUID(uids, event.UID) {
+			uids = append(uids, event.UID)
+		}
+	}
+	return uids
+}
+
+func containsUID(uids []string, uid string) bool {
+	for _, u := range uids {
+		if u == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func eventsWithUID(events []ParsedEvent, uid string) []ParsedEvent {
+	var matching []ParsedEvent
+	for _, event := range events {
+		if event.UID == uid {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+func hasTimeZone(timeZones []ParsedTimeZone, tzid string) bool {
+	for _, tz := range timeZones {
+		if tz.TZID == tzid {
+			return true
+		}
+	}
+	return false
+}
+
+// checkITIPTargets checks every component of message with findITIPTarget
+// before any of them is applied, so that a stale or unknown component leaves
+// data unchanged.
+func checkITIPTargets(data, message *ParsedCalendarData) error {
+	for i := range message.Events {
+		if _, _, err := findITIPTarget(data, &message.Events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findITIPTarget returns the index in data of the component addressed by the
+// iTIP component event, or -1 and the index of the master when it addresses
+// an occurrence that has no override. Components older than the stored one
+// return ErrStaleITIPMessage.
+func findITIPTarget(data *ParsedCalendarData, event *ParsedEvent) (int, int, error) {
+	master := -1
+	for i := range data.Events {
+		stored := &data.Events[i]
+		if stored.UID != event.UID {
+			continue
+		}
+		if stored.RecurrenceID == nil {
+			master = i
+		}
+		if sameTimePtr(stored.RecurrenceID, event.RecurrenceID) {
+			if event.Sequence < stored.Sequence {
+				return -1, -1, ErrStaleITIPMessage
+			}
+			return i, master, nil
+		}
+	}
+
+	if master == -1 {
+		return -1, -1, &EventNotFoundError{UID: event.UID}
+	}
+	if event.Sequence < data.Events[master].Sequence {
+		return -1, -1, ErrStaleITIPMessage
+	}
+	return -1, master, nil
+}
+
+// itipTarget returns the index in data of the component addressed by the
+// iTIP component event, adding an override when it addresses an occurrence
+// that has none.
+func itipTarget(data *ParsedCalendarData, event *ParsedEvent) (int, error) {
+	index, master, err := findITIPTarget(data, event)
+	if err != nil || index != -1 {
+		return index, err
+	}
+
+	recurrenceID := *event.RecurrenceID
+	if start := data.Events[master].DTStart; start != nil && !data.Events[master].AllDay && !data.Events[master].Floating {
+		recurrenceID = recurrenceID.In(start.Location())
+	}
+	data.Events = append(data.Events, newRecurrenceOverride(data.Events[master], recurrenceID))
+	return len(data.Events) - 1, nil
+}
+
+// schedulingComponents returns copies of the components of event uid in data,
+// checking that the event has an organizer and attendees.
+func schedulingComponents(data *ParsedCalendarData, uid string) ([]ParsedEvent, error) {
+	if data == nil {
+		return nil, newTypedError("itip", ErrorTypeValidation, "calendar data cannot be nil", nil)
+	}
+
+	var events []ParsedEvent
+	for _, event := range data.Events {
+		if event.UID == uid {
+			event.Attendees = append([]ParsedAttendee(nil), event.Attendees...)
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, &EventNotFoundError{UID: uid}
+	}
+	if err := validateSchedulingEvent(&events[0]); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func validateSchedulingEvent(event *ParsedEvent) error {
+	if event == nil {
+		return newTypedError("itip", ErrorTypeValidation, "event cannot be nil", nil)
+	}
+	if event.Organizer.Value == "" {
+		return newTypedError("itip", ErrorTypeValidation, fmt.Sprintf("event %s has no organizer", event.UID), nil)
+	}
+	if len(event.Attendees) == 0 {
+		return newTypedError("itip", ErrorTypeValidation, fmt.Sprintf("event %s has no attendees", event.UID), nil)
+	}
+	return nil
+}
+
+func newITIPCalendar(method string, events []ParsedEvent, timeZones []ParsedTimeZone) *ParsedCalendarData {
+	return &ParsedCalendarData{
+		Version:   "2.0",
+		ProdID:    defaultProdID,
+		Method:    method,
+		Events:    events,
+		TimeZones: timeZones,
+	}
+}
+
+func stampEvents(events []ParsedEvent, now time.Time) {
+	for i := range events {
+		events[i].DTStamp = &now
+	}
+}
+
+// attendeeRecipients lists the addresses of the attendees of events, once
+// each, leaving out the organizer.
+func attendeeRecipients(events []ParsedEvent) []string {
+	var recipients []string
+	for _, event := range events {
+		for _, attendee := range event.Attendees {
+			if sameCalendarAddress(attendee.Value, event.Organizer.Value) || containsCalendarAddress(recipients, attendee.Value) {
+				continue
+			}
+			recipients = append(recipients, attendee.Value)
+		}
+	}
+	return recipients
+}
+
+func containsCalendarAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if sameCalendarAddress(a, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func findAttendee(attendees []ParsedAttendee, address string) *ParsedAttendee {
+	for i := range attendees {
+		if sameCalendarAddress(attendees[i].Value, address) {
+			return &attendees[i]
+		}
+	}
+	return nil
+}
+
+func filterAttendees(attendees []ParsedAttendee, addresses []string) []ParsedAttendee {
+	var filtered []ParsedAttendee
+	for _, attendee := range attendees {
+		if containsCalendarAddress(addresses, attendee.Value) {
+			filtered = append(filtered, attendee)
+		}
+	}
+	return filtered
+}
+
+// findOverride returns the component in events with the given RECURRENCE-ID,
+// or the master when recurrenceID is nil.
+func findOverride(events []ParsedEvent, recurrenceID *time.Time) *ParsedEvent {
+	for i := range events {
+		if sameTimePtr(events[i].RecurrenceID, recurrenceID) {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+// isSignificantChange reports whether updated changes the time or recurrence
+// of old, which requires attendees to respond again.
+func isSignificantChange(old, updated *ParsedEvent) bool {
+	return !sameTimePtr(old.DTStart, updated.DTStart) ||
+		!sameTimePtr(old.DTEnd, updated.DTEnd) ||
+		old.Duration != updated.Duration ||
+		old.AllDay != updated.AllDay ||
+		old.RecurrenceRule != updated.RecurrenceRule ||
+		old.ExceptionRule != updated.ExceptionRule ||
+		!sameTimeList(old.RecurrenceDates, updated.RecurrenceDates) ||
+		!sameTimeList(old.ExceptionDates, updated.ExceptionDates)
+}
+
+func sameTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func sameTimeList(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,347 @@
+package caldav
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const meetingICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Test//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meeting-1\r\n" +
+	"DTSTAMP:20250101T000000Z\r\n" +
+	"DTSTART:20250115T140000Z\r\n" +
+	"DTEND:20250115T150000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"SUMMARY:Planning\r\n" +
+	"SEQUENCE:2\r\n" +
+	"ORGANIZER;CN=Olivia:mailto:olivia@example.com\r\n" +
+	"ATTENDEE;PARTSTAT=ACCEPTED:mailto:olivia@example.com\r\n" +
+	"ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:sam@example.com\r\n" +
+	"ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:kim@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseMeeting(t *testing.T) *ParsedCalendarData {
+	t.Helper()
+	data, err := ParseICalendar(meetingICS)
+	if err != nil {
+		t.Fatalf("failed to parse meeting: %v", err)
+	}
+	return data
+}
+
+func TestNewITIPRequest(t *testing.T) {
+	data := parseMeeting(t)
+
+	msg, err := NewITIPRequest(data, "meeting-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if msg.Method != ITIPMethodRequest || msg.Calendar.Method != ITIPMethodRequest {
+		t.Errorf("expected METHOD:REQUEST, got %s/%s", msg.Method, msg.Calendar.Method)
+	}
+	expected := []string{"mailto:sam@example.com", "mailto:kim@example.com"}
+	if strings.Join(msg.Recipients, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected recipients %v, got %v", expected, msg.Recipients)
+	}
+
+	encoded, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	for _, want := range []string{"METHOD:REQUEST\r\n", "SEQUENCE:2\r\n", "RRULE:FREQ=WEEKLY;COUNT=4\r\n"} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("expected %q in %s", want, encoded)
+		}
+	}
+	if strings.Contains(string(encoded), "DTSTAMP:20250101T000000Z") {
+		t.Error("expected a new DTSTAMP")
+	}
+}
+
+func TestNewITIPRequest_Validation(t *testing.T) {
+	data := parseMeeting(t)
+	data.Events[0].Attendees = nil
+
+	if _, err := NewITIPRequest(data, "meeting-1"); !IsValidationError(err) {
+		t.Errorf("expected a validation error for an event without attendees, got %v", err)
+	}
+
+	var notFound *EventNotFoundError
+	if _, err := NewITIPRequest(parseMeeting(t), "other"); !errors.As(err, &notFound) {
+		t.Errorf("expected EventNotFoundError, got %v", err)
+	}
+}
+
+func TestNewITIPUpdate(t *testing.T) {
+	t.Run("minor change keeps SEQUENCE", func(t *testing.T) {
+		previous, updated := parseMeeting(t), parseMeeting(t)
+		updated.Events[0].Summary = "Planning (agenda attached)"
+
+		messages, err := NewITIPUpdate(previous, updated, "meeting-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Method != ITIPMethodRequest {
+			t.Fatalf("expected a single REQUEST, got %+v", messages)
+		}
+		if updated.Events[0].Sequence != 2 {
+			t.Errorf("expected SEQUENCE 2, got %d", updated.Events[0].Sequence)
+		}
+	})
+
+	t.Run("rescheduling bumps SEQUENCE and cancels removed attendees", func(t *testing.T) {
+		previous, updated := parseMeeting(t), parseMeeting(t)
+		master := &updated.Events[0]
+		start := master.DTStart.Add(time.Hour)
+		end := master.DTEnd.Add(time.Hour)
+		master.DTStart, master.DTEnd = &start, &end
+		master.Attendees = master.Attendees[:2]
+
+		messages, err := NewITIPUpdate(previous, updated, "meeting-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if master.Sequence != 3 {
+			t.Errorf("expected SEQUENCE 3, got %d", master.Sequence)
+		}
+		if master.DTStamp == nil || master.DTStamp.Year() < 2025 {
+			t.Errorf("expected DTSTAMP to be refreshed, got %v", master.DTStamp)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("expected REQUEST and CANCEL, got %d messages", len(messages))
+		}
+
+		request, cancel := messages[0], messages[1]
+		if len(request.Recipients) != 1 || request.Recipients[0] != "mailto:sam@example.com" {
+			t.Errorf("expected REQUEST to sam, got %v", request.Recipients)
+		}
+		if cancel.Method != ITIPMethodCancel || len(cancel.Recipients) != 1 || cancel.Recipients[0] != "mailto:kim@example.com" {
+			t.Errorf("expected CANCEL to kim, got %s %v", cancel.Method, cancel.Recipients)
+		}
+		cancelled := cancel.Calendar.Events[0]
+		if cancelled.Status != "CANCELLED" || cancelled.Sequence != 3 || len(cancelled.Attendees) != 1 {
+			t.Errorf("unexpected CANCEL component: %+v", cancelled)
+		}
+	})
+}
+
+func TestNewITIPCancel(t *testing.T) {
+	data := parseMeeting(t)
+
+	msg, err := NewITIPCancel(data, "meeting-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Method != ITIPMethodCancel || len(msg.Recipients) != 2 {
+		t.Errorf("expected CANCEL to both attendees, got %s %v", msg.Method, msg.Recipients)
+	}
+	if data.Events[0].Status != "CANCELLED" || data.Events[0].Sequence != 3 {
+		t.Errorf("expected the organizer's copy to be cancelled with SEQUENCE 3, got %s %d", data.Events[0].Status, data.Events[0].Sequence)
+	}
+}
+
+func TestNewITIPReplyAndApply(t *testing.T) {
+	organizerCopy := parseMeeting(t)
+	attendeeCopy := parseMeeting(t)
+
+	msg, err := NewITIPReply(&attendeeCopy.Events[0], "sam@example.com", "accepted")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Method != ITIPMethodReply || len(msg.Recipients) != 1 || msg.Recipients[0] != "mailto:olivia@example.com" {
+		t.Errorf("expected REPLY to the organizer, got %s %v", msg.Method, msg.Recipients)
+	}
+
+	encoded, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	if !strings.Contains(string(encoded), "ATTENDEE;PARTSTAT=ACCEPTED:mailto:sam@example.com\r\n") ||
+		strings.Contains(string(encoded), "kim@example.com") {
+		t.Errorf("expected only sam's answer in %s", encoded)
+	}
+
+	reply, err := ParseICalendar(string(encoded))
+	if err != nil {
+		t.Fatalf("failed to parse reply: %v", err)
+	}
+	if err := ApplyITIPReply(organizerCopy, reply); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sam := findAttendee(organizerCopy.Events[0].Attendees, "sam@example.com")
+	if sam == nil || sam.PartStat != PartStatAccepted || sam.RSVP {
+		t.Errorf("expected sam to have accepted, got %+v", sam)
+	}
+	if kim := findAttendee(organizerCopy.Events[0].Attendees, "kim@example.com"); kim.PartStat != PartStatNeedsAction {
+		t.Errorf("expected kim to be unchanged, got %+v", kim)
+	}
+
+	if _, err := NewITIPReply(&attendeeCopy.Events[0], "nobody@example.com", PartStatAccepted); !IsValidationError(err) {
+		t.Errorf("expected a validation error for a non-attendee, got %v", err)
+	}
+}
+
+func TestApplyITIPReply_Occurrence(t *testing.T) {
+	organizerCopy := parseMeeting(t)
+
+	occurrence := newRecurrenceOverride(parseMeeting(t).Events[0], time.Date(2025, 1, 22, 14, 0, 0, 0, time.UTC))
+	msg, err := NewITIPReply(&occurrence, "kim@example.com", PartStatDeclined)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ApplyITIPReply(organizerCopy, msg.Calendar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(organizerCopy.Events) != 2 {
+		t.Fatalf("expected an override to be added, got %d events", len(organizerCopy.Events))
+	}
+
+	master, override := organizerCopy.Events[0], organizerCopy.Events[1]
+	if findAttendee(master.Attendees, "kim@example.com").PartStat != PartStatNeedsAction {
+		t.Error("expected the master to be unchanged")
+	}
+	if override.RecurrenceID == nil || !override.RecurrenceID.Equal(time.Date(2025, 1, 22, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected RECURRENCE-ID %v", override.RecurrenceID)
+	}
+	if findAttendee(override.Attendees, "kim@example.com").PartStat != PartStatDeclined {
+		t.Error("expected kim to have declined the occurrence")
+	}
+}
+
+func TestApplyITIPReply_Stale(t *testing.T) {
+	organizerCopy := parseMeeting(t)
+	msg, err := NewITIPReply(&parseMeeting(t).Events[0], "sam@example.com", PartStatAccepted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	organizerCopy.Events[0].Sequence = 3
+
+	if err := ApplyITIPReply(organizerCopy, msg.Calendar); !errors.Is(err, ErrStaleITIPMessage) {
+		t.Errorf("expected ErrStaleITIPMessage, got %v", err)
+	}
+	if findAttendee(organizerCopy.Events[0].Attendees, "sam@example.com").PartStat != PartStatNeedsAction {
+		t.Error("expected a stale reply to be ignored")
+	}
+
+	request, _ := NewITIPRequest(parseMeeting(t), "meeting-1")
+	if err := ApplyITIPReply(organizerCopy, request.Calendar); !IsValidationError(err) {
+		t.Errorf("expected a validation error for a REQUEST, got %v", err)
+	}
+}
+
+func TestApplyITIP_StaleComponentLeavesDataUnchanged(t *testing.T) {
+	newStored := func() *ParsedCalendarData {
+		data := parseMeeting(t)
+		override := newRecurrenceOverride(data.Events[0], time.Date(2025, 1, 29, 14, 0, 0, 0, time.UTC))
+		override.Sequence = 5
+		data.Events = append(data.Events, override)
+		return data
+	}
+	// A valid reply for the series and for a new occurrence, followed by a
+	// stale one for the rescheduled occurrence.
+	var components []ParsedEvent
+	for _, recurrenceID := range []*time.Time{nil, timePtr(time.Date(2025, 1, 22, 14, 0, 0, 0, time.UTC)), timePtr(time.Date(2025, 1, 29, 14, 0, 0, 0, time.UTC))} {
+		event := parseMeeting(t).Events[0]
+		if recurrenceID != nil {
+			event = newRecurrenceOverride(event, *recurrenceID)
+		}
+		msg, err := NewITIPReply(&event, "sam@example.com", PartStatAccepted)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		components = append(components, msg.Calendar.Events...)
+	}
+
+	stored := newStored()
+	reply := newITIPCalendar(ITIPMethodReply, components, nil)
+	if err := ApplyITIPReply(stored, reply); !errors.Is(err, ErrStaleITIPMessage) {
+		t.Fatalf("expected ErrStaleITIPMessage, got %v", err)
+	}
+	if !reflect.DeepEqual(stored, newStored()) {
+		t.Errorf("expected a stale reply to leave data unchanged, got %+v", stored.Events)
+	}
+
+	stored = newStored()
+	cancel := newITIPCalendar(ITIPMethodCancel, components, nil)
+	if err := ApplyITIPCancel(stored, cancel); !errors.Is(err, ErrStaleITIPMessage) {
+		t.Fatalf("expected ErrStaleITIPMessage, got %v", err)
+	}
+	if !reflect.DeepEqual(stored, newStored()) {
+		t.Errorf("expected a stale cancel to leave data unchanged, got %+v", stored.Events)
+	}
+}
+
+func TestApplyITIPRequestAndCancel(t *testing.T) {
+	attendeeCopy := &ParsedCalendarData{Version: "2.0"}
+	request, err := NewITIPRequest(parseMeeting(t), "meeting-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ApplyITIPRequest(attendeeCopy, request.Calendar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(attendeeCopy.Events) != 1 || attendeeCopy.Events[0].Summary != "Planning" {
+		t.Fatalf("expected the invitation to be stored, got %+v", attendeeCopy.Events)
+	}
+
+	updated := parseMeeting(t)
+	updated.Events[0].Summary = "Planning (moved)"
+	updated.Events[0].Sequence = 3
+	update, _ := NewITIPRequest(updated, "meeting-1")
+	if err := ApplyITIPRequest(attendeeCopy, update.Calendar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(attendeeCopy.Events) != 1 || attendeeCopy.Events[0].Summary != "Planning (moved)" {
+		t.Errorf("expected the update to replace the event, got %+v", attendeeCopy.Events)
+	}
+
+	if err := ApplyITIPRequest(attendeeCopy, request.Calendar); !errors.Is(err, ErrStaleITIPMessage) {
+		t.Errorf("expected ErrStaleITIPMessage for the original request, got %v", err)
+	}
+
+	cancel, _ := NewITIPCancel(updated, "meeting-1")
+	if err := ApplyITIPCancel(attendeeCopy, cancel.Calendar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attendeeCopy.Events[0].Status != "CANCELLED" || attendeeCopy.Events[0].Sequence != 4 {
+		t.Errorf("expected the event to be cancelled with SEQUENCE 4, got %s %d", attendeeCopy.Events[0].Status, attendeeCopy.Events[0].Sequence)
+	}
+}
+
+func TestNewITIPCounterAndDecline(t *testing.T) {
+	proposed := parseMeeting(t).Events[0]
+	start := proposed.DTStart.Add(24 * time.Hour)
+	proposed.DTStart = &start
+
+	counter, err := NewITIPCounter(&proposed, "sam@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counter.Method != ITIPMethodCounter || counter.Recipients[0] != "mailto:olivia@example.com" {
+		t.Errorf("expected COUNTER to the organizer, got %s %v", counter.Method, counter.Recipients)
+	}
+	if !counter.Calendar.Events[0].DTStart.Equal(start) {
+		t.Errorf("expected the proposed start, got %v", counter.Calendar.Events[0].DTStart)
+	}
+
+	decline, err := NewITIPDeclineCounter(&parseMeeting(t).Events[0], "sam@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decline.Method != ITIPMethodDeclineCounter || decline.Recipients[0] != "mailto:sam@example.com" {
+		t.Errorf("expected DECLINECOUNTER to sam, got %s %v", decline.Method, decline.Recipients)
+	}
+	if event := decline.Calendar.Events[0]; event.Sequence != 2 || len(event.Attendees) != 1 {
+		t.Errorf("unexpected DECLINECOUNTER component: %+v", event)
+	}
+}
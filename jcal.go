@@ -0,0 +1,575 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// icalValueTypes maps properties to their default value type (RFC 5545 section
+// 3.8 and RFC 7986, 7953 and 9073) as named in jCal. Properties not listed
+// here have the jCal type "unknown".
+var icalValueTypes = map[string]string{
+	"DTSTAMP":          "date-time",
+	"DTSTART":          "date-time",
+	"DTEND":            "date-time",
+	"DUE":              "date-time",
+	"RECURRENCE-ID":    "date-time",
+	"COMPLETED":        "date-time",
+	"CREATED":          "date-time",
+	"LAST-MODIFIED":    "date-time",
+	"RDATE":            "date-time",
+	"EXDATE":           "date-time",
+	"DURATION":         "duration",
+	"TRIGGER":          "duration",
+	"REFRESH-INTERVAL": "duration",
+	"FREEBUSY":         "period",
+	"RRULE":            "recur",
+	"EXRULE":           "recur",
+	"TZOFFSETFROM":     "utc-offset",
+	"TZOFFSETTO":       "utc-offset",
+	"SEQUENCE":         "integer",
+	"PRIORITY":         "integer",
+	"PERCENT-COMPLETE": "integer",
+	"REPEAT":           "integer",
+	"GEO":              "float",
+	"URL":              "uri",
+	"TZURL":            "uri",
+	"ATTACH":           "uri",
+	"IMAGE":            "uri",
+	"CONFERENCE":       "uri",
+	"SOURCE":           "uri",
+	"ORGANIZER":        "cal-address",
+	"ATTENDEE":         "cal-address",
+	"CALENDAR-ADDRESS": "cal-address",
+	"VERSION":          "text",
+	"PRODID":           "text",
+	"CALSCALE":         "text",
+	"METHOD":           "text",
+	"UID":              "text",
+	"SUMMARY":          "text",
+	"DESCRIPTION":      "text",
+	"LOCATION":         "text",
+	"STATUS":           "text",
+	"TRANSP":           "text",
+	"CLASS":            "text",
+	"CATEGORIES":       "text",
+	"RESOURCES":        "text",
+	"COMMENT":          "text",
+	"CONTACT":          "text",
+	"RELATED-TO":       "text",
+	"REQUEST-STATUS":   "text",
+	"ACTION":           "text",
+	"TZID":             "text",
+	"TZNAME":           "text",
+	"NAME":             "text",
+	"COLOR":            "text",
+	"BUSYTYPE":         "text",
+	"LOCATION-TYPE":    "text",
+	"PARTICIPANT-TYPE": "text",
+}
+
+// listValuedProperties lists properties whose comma-separated values become
+// separate jCal values.
+var listValuedProperties = map[string]bool{
+	"CATEGORIES":    true,
+	"RESOURCES":     true,
+	"LOCATION-TYPE": true,
+	"RDATE":         true,
+	"EXDATE":        true,
+	"FREEBUSY":      true,
+}
+
+// recurIntegerParts lists the RRULE parts whose values are jCal integers.
+var recurIntegerParts = map[string]bool{
+	"count":      true,
+	"interval":   true,
+	"bysecond":   true,
+	"byminute":   true,
+	"byhour":     true,
+	"bymonthday": true,
+	"byyearday":  true,
+	"byweekno":   true,
+	"bymonth":    true,
+	"bysetpos":   true,
+}
+
+// MarshalJCal encodes data as jCal (RFC 7265). The calendar is serialized
+// with EncodeICalendar first, so the JSON carries exactly the properties and
+// parameters of the iCalendar form, each value typed the way ParseICalendar
+// reads it.
+func MarshalJCal(data *ParsedCalendarData) ([]byte, error) {
+	icalData, err := EncodeICalendar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	component, err := icalToJCal(string(icalData))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(component)
+}
+
+// UnmarshalJCal decodes a jCal (RFC 7265) vcalendar by converting it to
+// iCalendar and parsing that with ParseICalendar, so JSON and iCalendar input
+// yield the same ParsedCalendarData.
+func UnmarshalJCal(data []byte) (*ParsedCalendarData, error) {
+	var component []json.RawMessage
+	if err := json.Unmarshal(data, &component); err != nil {
+		return nil, newTypedError("jcal.unmarshal", ErrorTypeValidation, "invalid jCal JSON", err)
+	}
+	var name string
+	if len(component) == 0 || json.Unmarshal(component[0], &name) != nil || name != "vcalendar" {
+		return nil, newTypedError("jcal.unmarshal", ErrorTypeValidation, "top-level component must be vcalendar", nil)
+	}
+
+	var w contentLineWriter
+	if err := writeJCalComponent(&w, component); err != nil {
+		return nil, err
+	}
+	return ParseICalendar(w.String())
+}
+
+// icalValueType returns the jCal type of a property value: the VALUE
+// parameter when present, otherwise the property's default type, with DATE
+// and DATE-TIME told apart as ParseICalendar does.
+func icalValueType(name string, params map[string]string, value string) string {
+	if valueType := params["VALUE"]; valueType != "" {
+		return strings.ToLower(valueType)
+	}
+
+	valueType, ok := icalValueTypes[name]
+	if !ok {
+		return "unknown"
+	}
+	switch {
+	case valueType == "date-time" && isDateValue(value, params):
+		return "date"
+	case name == "TRIGGER" && !isDurationValue(value):
+		return "date-time"
+	}
+	return valueType
+}
+
+// icalToJCal converts iCalendar text to a jCal component tree.
+func icalToJCal(icalData string) ([]interface{}, error) {
+	type frame struct {
+		name       string
+		properties []interface{}
+		components []interface{}
+	}
+
+	var (
+		parser icalParser
+		stack  []*frame
+		root   []interface{}
+	)
+
+	unfolded := strings.NewReplacer("\r\n ", "", "\r\n\t", "").Replace(icalData)
+	for _, line := range strings.Split(unfolded, "\r\n") {
+		sep := findValueSeparator(line)
+		if sep == -1 {
+			continue
+		}
+		name, params := parser.parseProperty(line[:sep])
+		value := line[sep+1:]
+
+		switch name {
+		case "BEGIN":
+			stack = append(stack, &frame{name: value, properties: []interface{}{}, components: []interface{}{}})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != value {
+				return nil, newTypedError("jcal.marshal", ErrorTypeValidation, fmt.Sprintf("unbalanced END:%s", value), nil)
+			}
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			component := []interface{}{strings.ToLower(top.name), top.properties, top.components}
+			if len(stack) == 0 {
+				root = component
+			} else {
+				parent := stack[len(stack)-1]
+				parent.components = append(parent.components, component)
+			}
+		default:
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			top.properties = append(top.properties, jcalProperty(name, params, parser.lineParams, value))
+		}
+	}
+
+	if root == nil || len(stack) != 0 {
+		return nil, newTypedError("jcal.marshal", ErrorTypeValidation, "incomplete iCalendar data", nil)
+	}
+	return root, nil
+}
+
+// jcalProperty converts a property to jCal. lineParams holds the parameters
+// with their values split, so that any parameter with several values becomes
+// an array.
+func jcalProperty(name string, params map[string]string, lineParams []ICalParameter, value string) []interface{} {
+	valueType := icalValueType(name, params, value)
+
+	jparams := make(map[string]interface{}, len(lineParams))
+	for _, param := range lineParams {
+		if param.Name == "VALUE" {
+			continue
+		}
+		if multiValuedParams[param.Name] || tokenListParams[param.Name] || len(param.Values) > 1 {
+			jparams[strings.ToLower(param.Name)] = param.Values
+		} else {
+			jparams[strings.ToLower(param.Name)] = param.Values[0]
+		}
+	}
+
+	property := []interface{}{strings.ToLower(name), jparams, valueType}
+	switch {
+	case name == "GEO" && valueType == "float":
+		var coords []interface{}
+		for _, part := range strings.Split(value, ";") {
+			coords = append(coords, jcalValue("float", part))
+		}
+		return append(property, coords)
+	case name == "REQUEST-STATUS" && valueType == "text":
+		var parts []interface{}
+		for _, part := range splitEscaped(value, ';') {
+			parts = append(parts, part)
+		}
+		return append(property, parts)
+	case listValuedProperties[name] && valueType == "text":
+		for _, part := range splitEscaped(value, ',') {
+			property = append(property, part)
+		}
+		return property
+	case listValuedProperties[name]:
+		for _, part := range strings.Split(value, ",") {
+			property = append(property, jcalValue(valueType, part))
+		}
+		return property
+	}
+	return append(property, jcalValue(valueType, value))
+}
+
+// jcalValue converts a single iCalendar value to its jCal representation.
+func jcalValue(valueType, value string) interface{} {
+	switch valueType {
+	case "text":
+		return unescapeICalText(value)
+	case "date":
+		if len(value) == 8 {
+			return value[:4] + "-" + value[4:6] + "-" + value[6:]
+		}
+	case "date-time":
+		return jcalDateTime(value)
+	case "period":
+		parts := strings.SplitN(value, "/", 2)
+		for i, part := range parts {
+			if !isDurationValue(part) {
+				parts[i] = jcalDateTime(part)
+			}
+		}
+		return strings.Join(parts, "/")
+	case "utc-offset":
+		if len(value) >= 5 {
+			offset := value[:3] + ":" + value[3:5]
+			if len(value) == 7 {
+				offset += ":" + value[5:]
+			}
+			return offset
+		}
+	case "integer":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		return strings.EqualFold(value, "TRUE")
+	case "recur":
+		return newJCalRecur(value)
+	}
+	return value
+}
+
+// jcalDateTime formats 20240115T100000Z as 2024-01-15T10:00:00Z.
+func jcalDateTime(value string) string {
+	if len(value) < 15 || value[8] != 'T' {
+		return value
+	}
+	return value[:4] + "-" + value[4:6] + "-" + value[6:8] + "T" +
+		value[9:11] + ":" + value[11:13] + ":" + value[13:]
+}
+
+// jcalRecur is a RECUR value as a jCal object. Its parts keep their
+// iCalendar order, which encoding/json would lose with a map.
+type jcalRecur []jcalRecurPart
+
+type jcalRecurPart struct {
+	name   string
+	values []interface{}
+}
+
+func (r jcalRecur) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, part := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(part.name)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{} = part.values
+		if len(part.values) == 1 {
+			value = part.values[0]
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func newJCalRecur(value string) jcalRecur {
+	var recur jcalRecur
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(kv[0])
+
+		var values []interface{}
+		for _, v := range strings.Split(kv[1], ",") {
+			switch {
+			case key == "until" && len(v) == 8:
+				values = append(values, jcalValue("date", v))
+			case key == "until":
+				values = append(values, jcalDateTime(v))
+			case recurIntegerParts[key]:
+				values = append(values, jcalValue("integer", v))
+			default:
+				values = append(values, v)
+			}
+		}
+		recur = append(recur, jcalRecurPart{name: key, values: values})
+	}
+	return recur
+}
+
+func writeJCalComponent(w *contentLineWriter, component []json.RawMessage) error {
+	var name string
+	if len(component) != 3 || json.Unmarshal(component[0], &name) != nil {
+		return newTypedError("jcal.unmarshal", ErrorTypeValidation, "component must be [name, properties, components]", nil)
+	}
+	var properties, components [][]json.RawMessage
+	if err := json.Unmarshal(component[1], &properties); err != nil {
+		return newTypedError("jcal.unmarshal", ErrorTypeValidation, fmt.Sprintf("invalid properties of %s", name), err)
+	}
+	if err := json.Unmarshal(component[2], &components); err != nil {
+		return newTypedError("jcal.unmarshal", ErrorTypeValidation, fmt.Sprintf("invalid subcomponents of %s", name), err)
+	}
+
+	w.begin(strings.ToUpper(name))
+	for _, property := range properties {
+		if err := writeJCalProperty(w, property); err != nil {
+			return err
+		}
+	}
+	for _, sub := range components {
+		if err := writeJCalComponent(w, sub); err != nil {
+			return err
+		}
+	}
+	w.end(strings.ToUpper(name))
+	return nil
+}
+
+func writeJCalProperty(w *contentLineWriter, property []json.RawMessage) error {
+	var (
+		name, valueType string
+		jparams         map[string]interface{}
+	)
+	if len(property) < 4 ||
+		json.Unmarshal(property[0], &name) != nil ||
+		json.Unmarshal(property[1], &jparams) != nil || jparams == nil ||
+		json.Unmarshal(property[2], &valueType) != nil {
+		return newTypedError("jcal.unmarshal", ErrorTypeValidation, "property must be [name, parameters, type, value...]", nil)
+	}
+	name = strings.ToUpper(name)
+
+	var params []ICalParameter
+	if valueParam, ok := icalValueParam(name, valueType); ok {
+		params = append(params, ICalParameter{Name: valueParam.Name, Values: []string{valueParam.Value}})
+	}
+	for _, key := range sortedKeys(jparams) {
+		values, err := jcalParamValues(jparams[key])
+		if err != nil {
+			return newTypedError("jcal.unmarshal", ErrorTypeValidation, fmt.Sprintf("invalid parameter %s of %s", key, name), err)
+		}
+		params = append(params, ICalParameter{Name: strings.ToUpper(key), Values: values})
+	}
+
+	values := make([]string, 0, len(property)-3)
+	for _, raw := range property[3:] {
+		var (
+			value string
+			err   error
+		)
+		if valueType == "recur" {
+			value, err = icalRecurFromJCal(raw)
+		} else {
+			var v interface{}
+			if err = decodeJCalValue(raw, &v); err == nil {
+				value, err = icalValueFromJCal(valueType, v)
+			}
+		}
+		if err != nil {
+			return newTypedError("jcal.unmarshal", ErrorTypeValidation, fmt.Sprintf("invalid value of %s", name), err)
+		}
+		values = append(values, value)
+	}
+
+	w.writeICalProperty(ICalProperty{Name: name, Parameters: params, Value: strings.Join(values, ",")})
+	return nil
+}
+
+// icalValueParam returns the VALUE parameter a property of the given value
+// type needs, which is none for the property's default type and for unknown
+// values.
+func icalValueParam(name, valueType string) (icalParam, bool) {
+	if defaultType, ok := icalValueTypes[name]; valueType == "unknown" || (ok && valueType == defaultType) {
+		return icalParam{}, false
+	}
+	return icalParam{"VALUE", strings.ToUpper(valueType)}, true
+}
+
+// decodeJCalValue decodes raw into v, keeping numbers as json.Number so that
+// integers and floats are written back exactly as given.
+func decodeJCalValue(raw json.RawMessage, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func jcalParamValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, part := range v {
+			s, ok := part.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %T in parameter list", part)
+			}
+			parts[i] = s
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("unexpected parameter value %T", value)
+	}
+}
+
+// icalValueFromJCal converts a jCal value back to its iCalendar form.
+// Structured values, such as GEO or REQUEST-STATUS, are arrays whose parts are
+// joined with semicolons.
+func icalValueFromJCal(valueType string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, part := range v {
+			s, err := icalValueFromJCal(valueType, part)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ";"), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case string:
+		return icalStringFromJCal(valueType, v), nil
+	default:
+		return "", fmt.Errorf("unexpected value %T", value)
+	}
+}
+
+func icalStringFromJCal(valueType, value string) string {
+	switch valueType {
+	case "text":
+		return escapeICalText(value)
+	case "date", "date-time":
+		return strings.NewReplacer("-", "", ":", "").Replace(value)
+	case "period":
+		parts := strings.SplitN(value, "/", 2)
+		for i, part := range parts {
+			if !isDurationValue(part) {
+				parts[i] = strings.NewReplacer("-", "", ":", "").Replace(part)
+			}
+		}
+		return strings.Join(parts, "/")
+	case "utc-offset":
+		return strings.ReplaceAll(value, ":", "")
+	}
+	return value
+}
+
+// icalRecurFromJCal converts a jCal recur object to an RRULE value, reading
+// the object's members in order so that the rule keeps its part order.
+func icalRecurFromJCal(raw json.RawMessage) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return "", fmt.Errorf("recur value must be an object")
+	}
+
+	var parts []string
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		key, _ := tok.(string)
+
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return "", err
+		}
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+
+		strs := make([]string, len(values))
+		for i, v := range values {
+			s, err := icalValueFromJCal("recur", v)
+			if err != nil {
+				return "", err
+			}
+			if strings.EqualFold(key, "until") {
+				s = icalStringFromJCal("date-time", s)
+			}
+			strs[i] = s
+		}
+		parts = append(parts, strings.ToUpper(key)+"="+strings.Join(strs, ","))
+	}
+	return strings.Join(parts, ";"), nil
+}
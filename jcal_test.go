@@ -0,0 +1,171 @@
+package caldav
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJCal_RoundTrip(t *testing.T) {
+	original, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	encoded, err := MarshalJCal(original)
+	if err != nil {
+		t.Fatalf("MarshalJCal failed: %v", err)
+	}
+	if !json.Valid(encoded) {
+		t.Fatalf("MarshalJCal produced invalid JSON: %s", encoded)
+	}
+
+	decoded, err := UnmarshalJCal(encoded)
+	if err != nil {
+		t.Fatalf("UnmarshalJCal failed: %v", err)
+	}
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("round trip mismatch\noriginal: %+v\ndecoded:  %+v\njCal: %s", original, decoded, encoded)
+	}
+}
+
+func TestMarshalJCal_Values(t *testing.T) {
+	parsed, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	encoded, err := MarshalJCal(parsed)
+	if err != nil {
+		t.Fatalf("MarshalJCal failed: %v", err)
+	}
+	output := string(encoded)
+
+	expected := []string{
+		`["vcalendar",[["version",{},"text","2.0"]`,
+		`["dtstart",{},"date-time","2024-01-15T10:00:00Z"]`,
+		`["dtstart",{"tzid":"Europe/London"},"date-time","2024-01-01T00:00:00"]`,
+		`["dtstart",{},"date","2024-01-15"]`,
+		`["summary",{"language":"en-GB"},"text","Planning, budget; review"]`,
+		`["x-labels",{"x-set":["a","b;c"]},"unknown","work"]`,
+		`["sequence",{},"integer",2]`,
+		`["geo",{},"float",[51.5074,-0.1278]]`,
+		`["tzoffsetfrom",{},"utc-offset","+00:00"]`,
+		`["rrule",{},"recur",{"freq":"WEEKLY","byday":"MO","count":10}]`,
+		`["rdate",{},"date-time","2024-03-01T10:00:00Z","2024-03-08T10:00:00Z"]`,
+		`["categories",{},"text","Work","Finance,Budget"]`,
+		`"delegated-from":["mailto:a@example.com","mailto:b@example.com"]`,
+		`["request-status",{},"text",["3.1","Invalid property value","DTSTART:96-Apr-01"]]`,
+		`["attach",{"encoding":"BASE64","fmttype":"text/plain"},"binary","SGVsbG8="]`,
+		`["trigger",{},"date-time","2024-01-15T09:00:00Z"]`,
+		`["trigger",{},"duration","-PT15M"]`,
+		`["conference",{"feature":["AUDIO","VIDEO"],"label":"Join: Video"},"uri","https://video.example.com/123456"]`,
+		`["freebusy",{"fbtype":"BUSY-TENTATIVE"},"period","2024-01-15T14:00:00Z/2024-01-15T15:00:00Z"]`,
+		`["x-apple-travel-advisory-behavior",{},"unknown","AUTOMATIC"]`,
+		`["x-apple-structured-location",{`,
+		`"uri","geo:51.5074,-0.1278"]`,
+		`["vlocation",[["uid",{},"text","loc-1"]`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("jCal output missing %s\n%s", want, output)
+		}
+	}
+}
+
+func TestUnmarshalJCal(t *testing.T) {
+	// Adapted from the examples in RFC 7265 section 4 and appendix B.
+	input := `["vcalendar",
+	  [
+	    ["version", {}, "text", "2.0"],
+	    ["prodid", {}, "text", "-//Example Inc.//Example Calendar//EN"]
+	  ],
+	  [
+	    ["vevent",
+	      [
+	        ["uid", {}, "text", "2@example.com"],
+	        ["dtstamp", {}, "date-time", "2008-02-05T19:12:24Z"],
+	        ["dtstart", {"tzid": "America/New_York"}, "date-time", "2008-02-11T12:00:00"],
+	        ["dtend", {}, "date", "2008-02-12"],
+	        ["summary", {}, "text", "Lunch; with\nfriends"],
+	        ["categories", {}, "text", "Food", "Social, fun"],
+	        ["rrule", {}, "recur", {"freq": "WEEKLY", "byday": ["MO", "WE"], "until": "2008-03-01T00:00:00Z"}],
+	        ["exdate", {}, "date-time", "2008-02-13T17:00:00Z", "2008-02-18T17:00:00Z"],
+	        ["geo", {}, "float", [37.386013, -122.082932]],
+	        ["priority", {}, "integer", 5],
+	        ["attendee", {"partstat": "ACCEPTED", "delegated-from": ["mailto:a@example.com", "mailto:b@example.com"]}, "cal-address", "mailto:c@example.com"],
+	        ["request-status", {}, "text", ["2.0", "Success"]],
+	        ["x-custom", {}, "unknown", "raw;value"]
+	      ],
+	      [
+	        ["valarm", [["action", {}, "text", "DISPLAY"], ["trigger", {}, "duration", "-PT15M"]], []]
+	      ]
+	    ]
+	  ]
+	]`
+
+	parsed, err := UnmarshalJCal([]byte(input))
+	if err != nil {
+		t.Fatalf("UnmarshalJCal failed: %v", err)
+	}
+	if parsed.ProdID != "-//Example Inc.//Example Calendar//EN" || len(parsed.Events) != 1 {
+		t.Fatalf("unexpected calendar %+v", parsed)
+	}
+
+	event := parsed.Events[0]
+	if event.UID != "2@example.com" || event.Summary != "Lunch; with\nfriends" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.DTStart == nil || event.DTStart.Location().String() != "America/New_York" || event.DTStart.Hour() != 12 {
+		t.Errorf("unexpected DTSTART %v", event.DTStart)
+	}
+	if event.DTEnd == nil || event.DTEnd.Day() != 12 {
+		t.Errorf("unexpected DTEND %v", event.DTEnd)
+	}
+	if want := []string{"Food", "Social, fun"}; !reflect.DeepEqual(event.Categories, want) {
+		t.Errorf("expected categories %v, got %v", want, event.Categories)
+	}
+	if event.RecurrenceRule != "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20080301T000000Z" {
+		t.Errorf("unexpected RRULE %q", event.RecurrenceRule)
+	}
+	if len(event.ExceptionDates) != 2 {
+		t.Errorf("expected 2 exception dates, got %v", event.ExceptionDates)
+	}
+	if event.GeoLocation == nil || event.GeoLocation.Latitude != 37.386013 || event.Priority != 5 {
+		t.Errorf("unexpected GEO %v or PRIORITY %d", event.GeoLocation, event.Priority)
+	}
+	if len(event.Attendees) != 1 || event.Attendees[0].DelegatedFrom != "mailto:a@example.com,mailto:b@example.com" {
+		t.Errorf("unexpected attendees %+v", event.Attendees)
+	}
+	if len(event.RequestStatus) != 1 || event.RequestStatus[0].Description != "Success" {
+		t.Errorf("unexpected request status %+v", event.RequestStatus)
+	}
+	if event.CustomProperties["X-CUSTOM"] != "raw;value" {
+		t.Errorf("expected unknown values to pass through, got %q", event.CustomProperties["X-CUSTOM"])
+	}
+	if len(event.Alarms) != 1 || event.Alarms[0].Trigger != "-PT15M" {
+		t.Errorf("unexpected alarms %+v", event.Alarms)
+	}
+}
+
+func TestUnmarshalJCal_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not JSON", `{`},
+		{"not a vcalendar", `["vevent", [], []]`},
+		{"missing subcomponents", `["vcalendar", []]`},
+		{"property without value", `["vcalendar", [["version", {}, "text"]], []]`},
+		{"invalid parameters", `["vcalendar", [["version", [], "text", "2.0"]], []]`},
+		{"invalid parameter value", `["vcalendar", [["prodid", {"x-n": 1}, "text", "x"]], []]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalJCal([]byte(tt.input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
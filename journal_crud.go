@@ -0,0 +1,191 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CreateJournal stores journal, such as meeting notes, as a new VJOURNAL in
+// the calendar at calendarPath. UID, DTSTAMP, CREATED and LAST-MODIFIED are
+// filled in when unset. Calendars whose supported-calendar-component-set
+// excludes VJOURNAL, and servers known not to support journals such as
+// iCloud, return an error wrapping ErrComponentUnsupported.
+func (c *CalDAVClient) CreateJournal(ctx context.Context, calendarPath string, journal *ParsedJournal) error {
+	if err := validateJournal(journal); err != nil {
+		return fmt.Errorf("validating journal: %w", err)
+	}
+	if err := c.requireComponent(ctx, "journal.create", calendarPath, "VJOURNAL"); err != nil {
+		return err
+	}
+
+	if journal.UID == "" {
+		journal.UID = generateUID()
+	}
+
+	now := time.Now().UTC()
+	if journal.DTStamp == nil {
+		journal.DTStamp = &now
+	}
+	if journal.Created == nil {
+		journal.Created = &now
+	}
+	if journal.LastModified == nil {
+		journal.LastModified = &now
+	}
+
+	icalData, err := EncodeICalendar(&ParsedCalendarData{Journals: []ParsedJournal{*journal}})
+	if err != nil {
+		return fmt.Errorf("generating iCalendar data: %w", err)
+	}
+
+	_, err = c.createCalendarData(ctx, buildEventURL(c.baseURL, calendarPath, journal.UID), journal.UID, string(icalData))
+	return err
+}
+
+// UpdateJournal replaces the stored journal with the same UID, guarded by
+// If-Match when etag is set. LAST-MODIFIED and SEQUENCE are updated.
+func (c *CalDAVClient) UpdateJournal(ctx context.Context, calendarPath string, journal *ParsedJournal, etag string) error {
+	if err := validateJournal(journal); err != nil {
+		return fmt.Errorf("validating journal: %w", err)
+	}
+
+	if journal.UID == "" {
+		return fmt.Errorf("UID is required for update")
+	}
+
+	now := time.Now().UTC()
+	journal.DTStamp = &now
+	journal.LastModified = &now
+	journal.Sequence++
+
+	icalData, err := EncodeICalendar(&ParsedCalendarData{Journals: []ParsedJournal{*journal}})
+	if err != nil {
+		return fmt.Errorf("generating iCalendar data: %w", err)
+	}
+
+	_, err = c.putCalendarData(ctx, buildEventURL(c.baseURL, calendarPath, journal.UID), journal.UID, string(icalData), etag)
+	return err
+}
+
+// DeleteJournal deletes the journal at journalPath, guarded by If-Match when
+// etag is set.
+func (c *CalDAVClient) DeleteJournal(ctx context.Context, journalPath string, etag string) error {
+	return c.DeleteEventWithETag(ctx, journalPath, etag)
+}
+
+// GetJournal fetches the journal at journalPath and returns it with its ETag.
+func (c *CalDAVClient) GetJournal(ctx context.Context, journalPath string) (*ParsedJournal, string, error) {
+	resource, etag, err := c.GetEventByPath(ctx, journalPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	parsedICal, err := ParseICalendar(resource.CalendarData)
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing iCalendar: %w", err)
+	}
+
+	if len(parsedICal.Journals) == 0 {
+		return nil, "", fmt.Errorf("no VJOURNAL found in response")
+	}
+
+	return &parsedICal.Journals[0], etag, nil
+}
+
+// GetJournals returns every journal in the calendar at calendarPath.
+func (c *CalDAVClient) GetJournals(ctx context.Context, calendarPath string) ([]ParsedJournal, error) {
+	objects, err := c.QueryCalendar(ctx, calendarPath, CalendarQuery{
+		Properties: []string{"getetag", "calendar-data"},
+		Filter: Filter{
+			Component:   "VCALENDAR",
+			CompFilters: []Filter{{Component: "VJOURNAL"}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var journals []ParsedJournal
+	for _, obj := range objects {
+		parsedICal, err := ParseICalendar(obj.CalendarData)
+		if err != nil {
+			continue
+		}
+		journals = append(journals, parsedICal.Journals...)
+	}
+
+	return journals, nil
+}
+
+func validateJournal(journal *ParsedJournal) error {
+	if journal == nil {
+		return fmt.Errorf("journal cannot be nil")
+	}
+
+	validStatuses := map[string]bool{
+		"DRAFT":     true,
+		"FINAL":     true,
+		"CANCELLED": true,
+	}
+
+	if journal.Status != "" && !validStatuses[journal.Status] {
+		return fmt.Errorf("invalid status: %s", journal.Status)
+	}
+
+	return nil
+}
+
+// requireComponent fails with ErrComponentUnsupported when the server is known
+// not to support component or the supported-calendar-component-set of the
+// calendar at calendarPath excludes it. A calendar that does not report the
+// property accepts every component.
+func (c *CalDAVClient) requireComponent(ctx context.Context, op, calendarPath, component string) error {
+	if component == "VJOURNAL" && c.serverCompat != nil {
+		if supported, known := c.serverCompat.Capabilities[CapVJournal]; known && !supported {
+			return componentUnsupportedError(op, component)
+		}
+	}
+
+	xmlBody, err := buildPropfindXML([]string{"supported-calendar-component-set"})
+	if err != nil {
+		return wrapErrorWithType(op, ErrorTypeInvalidRequest, err)
+	}
+
+	resp, err := c.propfind(ctx, calendarPath, "0", xmlBody)
+	if err != nil {
+		return wrapErrorWithType(op, ErrorTypeNetwork, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 207 {
+		body, _ := io.ReadAll(resp.Body)
+		return newCalDAVError(op, resp.StatusCode, string(body))
+	}
+
+	msResp, err := parseMultiStatusResponse(resp.Body)
+	if err != nil {
+		return wrapErrorWithType(op, ErrorTypeInvalidResponse, err)
+	}
+
+	for _, r := range msResp.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Status != 200 || len(ps.Prop.SupportedCalendarComponentSet) == 0 {
+				continue
+			}
+			for _, comp := range ps.Prop.SupportedCalendarComponentSet {
+				if strings.EqualFold(comp, component) {
+					return nil
+				}
+			}
+			return componentUnsupportedError(op, component)
+		}
+	}
+	return nil
+}
+
+func componentUnsupportedError(op, component string) error {
+	return newTypedError(op, ErrorTypeClient, fmt.Sprintf("calendar does not support %s", component), ErrComponentUnsupported)
+}
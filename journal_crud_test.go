@@ -0,0 +1,159 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func supportedComponentsResponse(components ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+		`<D:response><D:href>/cal/</D:href><D:propstat><D:prop><C:supported-calendar-component-set>`)
+	for _, comp := range components {
+		sb.WriteString(`<C:comp name="` + comp + `"/>`)
+	}
+	sb.WriteString(`</C:supported-calendar-component-set></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`)
+	return sb.String()
+}
+
+func TestCreateJournal(t *testing.T) {
+	var stored string
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PROPFIND":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(supportedComponentsResponse("VEVENT", "VTODO", "VJOURNAL")))
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "*" {
+				t.Errorf("expected If-None-Match: *, got %q", r.Header.Get("If-None-Match"))
+			}
+			body, _ := io.ReadAll(r.Body)
+			stored = string(body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	})
+	defer server.Close()
+
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	journal := &ParsedJournal{
+		DTStart:      &day,
+		AllDay:       true,
+		Summary:      "Planning notes",
+		Descriptions: []string{"Budget agreed", "Follow up on hiring"},
+		Status:       "FINAL",
+		Categories:   []string{"Meetings"},
+	}
+	if err := client.CreateJournal(context.Background(), "/cal/", journal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if journal.UID == "" || journal.DTStamp == nil || journal.Created == nil {
+		t.Errorf("expected UID and timestamps to be set, got %+v", journal)
+	}
+	for _, want := range []string{
+		"BEGIN:VJOURNAL",
+		"DTSTART;VALUE=DATE:20250115",
+		"DESCRIPTION:Budget agreed",
+		"DESCRIPTION:Follow up on hiring",
+		"STATUS:FINAL",
+	} {
+		if !strings.Contains(stored, want) {
+			t.Errorf("stored journal missing %q:\n%s", want, stored)
+		}
+	}
+}
+
+func TestCreateJournal_Unsupported(t *testing.T) {
+	puts := 0
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			puts++
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(supportedComponentsResponse("VEVENT")))
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	if err := client.CreateJournal(ctx, "/cal/", &ParsedJournal{Summary: "Notes"}); !errors.Is(err, ErrComponentUnsupported) {
+		t.Errorf("expected ErrComponentUnsupported for an event-only calendar, got %v", err)
+	}
+
+	client.ConfigureForICloud()
+	client.SetBaseURL(server.URL)
+	if err := client.CreateJournal(ctx, "/cal/", &ParsedJournal{Summary: "Notes"}); !errors.Is(err, ErrComponentUnsupported) {
+		t.Errorf("expected ErrComponentUnsupported on iCloud, got %v", err)
+	}
+
+	if err := client.CreateJournal(ctx, "/cal/", &ParsedJournal{Status: "DONE"}); err == nil {
+		t.Error("expected a validation error for an invalid status")
+	}
+	if puts != 0 {
+		t.Errorf("expected no PUT, got %d", puts)
+	}
+}
+
+func TestUpdateAndDeleteJournal(t *testing.T) {
+	var methods []string
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Header.Get("If-Match") != `"1"` {
+			t.Errorf("expected If-Match \"1\", got %q", r.Header.Get("If-Match"))
+		}
+		if r.URL.Path != "/cal/journal-1.ics" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	journal := &ParsedJournal{UID: "journal-1", Summary: "Notes", Sequence: 1}
+	if err := client.UpdateJournal(ctx, "/cal/", journal, `"1"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if journal.Sequence != 2 || journal.LastModified == nil {
+		t.Errorf("expected SEQUENCE and LAST-MODIFIED to be updated, got %+v", journal)
+	}
+	if err := client.UpdateJournal(ctx, "/cal/", &ParsedJournal{Summary: "Notes"}, ""); err == nil {
+		t.Error("expected an error when updating without a UID")
+	}
+
+	if err := client.DeleteJournal(ctx, "/cal/journal-1.ics", `"1"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(methods, ",") != "PUT,DELETE" {
+		t.Errorf("unexpected requests %v", methods)
+	}
+}
+
+func TestGetJournals(t *testing.T) {
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `<C:comp-filter name="VJOURNAL"`) {
+			t.Errorf("expected a VJOURNAL comp-filter, got %s", body)
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+			`<D:response><D:href>/cal/journal-1.ics</D:href><D:propstat><D:prop><D:getetag>"1"</D:getetag><C:calendar-data>` +
+			"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VJOURNAL\r\nUID:journal-1\r\nDTSTART;VALUE=DATE:20250115\r\n" +
+			"SUMMARY:Standup\r\nDESCRIPTION:All good\r\nEND:VJOURNAL\r\nEND:VCALENDAR\r\n" +
+			`</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+	})
+	defer server.Close()
+
+	journals, err := client.GetJournals(context.Background(), "/cal/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(journals) != 1 || journals[0].UID != "journal-1" || journals[0].Descriptions[0] != "All good" {
+		t.Errorf("unexpected journals %+v", journals)
+	}
+}
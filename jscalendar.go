@@ -0,0 +1,1535 @@
+package caldav
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	jsLocalDateTimeLayout = "2006-01-02T15:04:05"
+	jsUTCDateTimeLayout   = "2006-01-02T15:04:05Z"
+	// jsMainLocationID is the Location holding LOCATION and GEO.
+	jsMainLocationID = "1"
+)
+
+// JSPatch is a JSCalendar PatchObject (RFC 8984 section 1.4.9). Keys are
+// paths of property names separated by "/", relative to the patched object;
+// a value replaces the property and nil removes it.
+type JSPatch map[string]interface{}
+
+// JSEvent is a JSCalendar Event object (RFC 8984 section 5.1).
+type JSEvent struct {
+	Type          string             `json:"@type"`
+	UID           string             `json:"uid"`
+	Created       string             `json:"created,omitempty"`
+	Updated       string             `json:"updated,omitempty"`
+	Sequence      int                `json:"sequence,omitempty"`
+	Title         string             `json:"title,omitempty"`
+	Description   string             `json:"description,omitempty"`
+	Locale        string             `json:"locale,omitempty"`
+	Localizations map[string]JSPatch `json:"localizations,omitempty"`
+	// Start is a LocalDateTime in TimeZone; no TimeZone means floating time.
+	Start           string `json:"start,omitempty"`
+	TimeZone        string `json:"timeZone,omitempty"`
+	Duration        string `json:"duration,omitempty"`
+	ShowWithoutTime bool   `json:"showWithoutTime,omitempty"`
+	// RecurrenceID identifies a single overridden instance that is stored
+	// without its series.
+	RecurrenceID            string                       `json:"recurrenceId,omitempty"`
+	RecurrenceRules         []JSRecurrenceRule           `json:"recurrenceRules,omitempty"`
+	ExcludedRecurrenceRules []JSRecurrenceRule           `json:"excludedRecurrenceRules,omitempty"`
+	RecurrenceOverrides     map[string]JSPatch           `json:"recurrenceOverrides,omitempty"`
+	Status                  string                       `json:"status,omitempty"`
+	FreeBusyStatus          string                       `json:"freeBusyStatus,omitempty"`
+	Privacy                 string                       `json:"privacy,omitempty"`
+	Priority                int                          `json:"priority,omitempty"`
+	Color                   string                       `json:"color,omitempty"`
+	Keywords                map[string]bool              `json:"keywords,omitempty"`
+	Links                   map[string]JSLink            `json:"links,omitempty"`
+	Locations               map[string]JSLocation        `json:"locations,omitempty"`
+	VirtualLocations        map[string]JSVirtualLocation `json:"virtualLocations,omitempty"`
+	RelatedTo               map[string]JSRelation        `json:"relatedTo,omitempty"`
+	ReplyTo                 map[string]string            `json:"replyTo,omitempty"`
+	Participants            map[string]JSParticipant     `json:"participants,omitempty"`
+	Alerts                  map[string]JSAlert           `json:"alerts,omitempty"`
+}
+
+// JSTask is a JSCalendar Task object (RFC 8984 section 5.2).
+type JSTask struct {
+	Type          string             `json:"@type"`
+	UID           string             `json:"uid"`
+	Created       string             `json:"created,omitempty"`
+	Updated       string             `json:"updated,omitempty"`
+	Sequence      int                `json:"sequence,omitempty"`
+	Title         string             `json:"title,omitempty"`
+	Description   string             `json:"description,omitempty"`
+	Locale        string             `json:"locale,omitempty"`
+	Localizations map[string]JSPatch `json:"localizations,omitempty"`
+	// Start and Due are LocalDateTimes in TimeZone; no TimeZone means
+	// floating time.
+	Start            string                       `json:"start,omitempty"`
+	Due              string                       `json:"due,omitempty"`
+	TimeZone         string                       `json:"timeZone,omitempty"`
+	ShowWithoutTime  bool                         `json:"showWithoutTime,omitempty"`
+	Progress         string                       `json:"progress,omitempty"`
+	ProgressUpdated  string                       `json:"progressUpdated,omitempty"`
+	PercentComplete  int                          `json:"percentComplete,omitempty"`
+	Privacy          string                       `json:"privacy,omitempty"`
+	Priority         int                          `json:"priority,omitempty"`
+	Color            string                       `json:"color,omitempty"`
+	Keywords         map[string]bool              `json:"keywords,omitempty"`
+	Links            map[string]JSLink            `json:"links,omitempty"`
+	VirtualLocations map[string]JSVirtualLocation `json:"virtualLocations,omitempty"`
+	RelatedTo        map[string]JSRelation        `json:"relatedTo,omitempty"`
+	Alerts           map[string]JSAlert           `json:"alerts,omitempty"`
+}
+
+// JSRecurrenceRule is a JSCalendar RecurrenceRule, the counterpart of RRULE.
+type JSRecurrenceRule struct {
+	Type           string   `json:"@type"`
+	Frequency      string   `json:"frequency"`
+	Interval       int      `json:"interval,omitempty"`
+	FirstDayOfWeek string   `json:"firstDayOfWeek,omitempty"`
+	ByDay          []JSNDay `json:"byDay,omitempty"`
+	ByMonthDay     []int    `json:"byMonthDay,omitempty"`
+	ByMonth        []string `json:"byMonth,omitempty"`
+	ByYearDay      []int    `json:"byYearDay,omitempty"`
+	ByWeekNo       []int    `json:"byWeekNo,omitempty"`
+	ByHour         []int    `json:"byHour,omitempty"`
+	ByMinute       []int    `json:"byMinute,omitempty"`
+	BySecond       []int    `json:"bySecond,omitempty"`
+	BySetPosition  []int    `json:"bySetPosition,omitempty"`
+	Count          int      `json:"count,omitempty"`
+	// Until is a LocalDateTime in the time zone of the event.
+	Until string `json:"until,omitempty"`
+}
+
+// JSNDay is a weekday in a recurrence rule, optionally the nth of the period.
+type JSNDay struct {
+	Type        string `json:"@type"`
+	Day         string `json:"day"`
+	NthOfPeriod int    `json:"nthOfPeriod,omitempty"`
+}
+
+// JSParticipant is a JSCalendar Participant, built from ATTENDEE and
+// ORGANIZER.
+type JSParticipant struct {
+	Type                string            `json:"@type"`
+	Name                string            `json:"name,omitempty"`
+	Email               string            `json:"email,omitempty"`
+	SendTo              map[string]string `json:"sendTo,omitempty"`
+	Kind                string            `json:"kind,omitempty"`
+	Roles               map[string]bool   `json:"roles"`
+	ParticipationStatus string            `json:"participationStatus,omitempty"`
+	ExpectReply         bool              `json:"expectReply,omitempty"`
+	ScheduleAgent       string            `json:"scheduleAgent,omitempty"`
+	ScheduleStatus      []string          `json:"scheduleStatus,omitempty"`
+	// DelegatedTo, DelegatedFrom and MemberOf hold participant ids.
+	DelegatedTo   map[string]bool `json:"delegatedTo,omitempty"`
+	DelegatedFrom map[string]bool `json:"delegatedFrom,omitempty"`
+	MemberOf      map[string]bool `json:"memberOf,omitempty"`
+}
+
+// JSAlert is a JSCalendar Alert, the counterpart of VALARM.
+type JSAlert struct {
+	Type    string    `json:"@type"`
+	Trigger JSTrigger `json:"trigger"`
+	Action  string    `json:"action,omitempty"`
+}
+
+// JSTrigger is an OffsetTrigger, with Offset and RelativeTo, or an
+// AbsoluteTrigger, with When.
+type JSTrigger struct {
+	Type       string `json:"@type"`
+	Offset     string `json:"offset,omitempty"`
+	RelativeTo string `json:"relativeTo,omitempty"`
+	When       string `json:"when,omitempty"`
+}
+
+// JSLink is a JSCalendar Link, used for URL, ATTACH and IMAGE.
+type JSLink struct {
+	Type        string `json:"@type"`
+	Href        string `json:"href"`
+	ContentType string `json:"contentType,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	Rel         string `json:"rel,omitempty"`
+	Display     string `json:"display,omitempty"`
+	Title       string `json:"title,omitempty"`
+}
+
+// JSLocation is a JSCalendar Location.
+type JSLocation struct {
+	Type          string          `json:"@type"`
+	Name          string          `json:"name,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	LocationTypes map[string]bool `json:"locationTypes,omitempty"`
+	Coordinates   string          `json:"coordinates,omitempty"`
+}
+
+// JSVirtualLocation is a JSCalendar VirtualLocation, used for CONFERENCE.
+type JSVirtualLocation struct {
+	Type     string          `json:"@type"`
+	Name     string          `json:"name,omitempty"`
+	URI      string          `json:"uri"`
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// JSRelation is a JSCalendar Relation, used for RELATED-TO.
+type JSRelation struct {
+	Type     string          `json:"@type"`
+	Relation map[string]bool `json:"relation,omitempty"`
+}
+
+// CalendarToJSCalendar converts the events and todos in data to JSCalendar.
+// Events sharing a UID become one JSEvent whose recurrenceOverrides hold the
+// overridden instances; an override without its master is returned on its own
+// with RecurrenceID set.
+func CalendarToJSCalendar(data *ParsedCalendarData) ([]*JSEvent, []*JSTask, error) {
+	if data == nil {
+		return nil, nil, nil
+	}
+
+	var order []string
+	masters := make(map[string]*ParsedEvent)
+	exceptions := make(map[string]map[string]*ParsedEvent)
+	for i := range data.Events {
+		event := &data.Events[i]
+		if _, seen := exceptions[event.UID]; !seen {
+			order = append(order, event.UID)
+			exceptions[event.UID] = make(map[string]*ParsedEvent)
+		}
+		if event.RecurrenceID == nil {
+			masters[event.UID] = event
+		} else {
+			exceptions[event.UID][formatICalTime(*event.RecurrenceID)] = event
+		}
+	}
+
+	var events []*JSEvent
+	for _, uid := range order {
+		if master, ok := masters[uid]; ok {
+			obj, err := EventToJSCalendar(*master, exceptions[uid])
+			if err != nil {
+				return nil, nil, err
+			}
+			events = append(events, obj)
+			continue
+		}
+		for _, key := range sortedKeys(exceptions[uid]) {
+			obj, err := EventToJSCalendar(*exceptions[uid][key], nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			events = append(events, obj)
+		}
+	}
+
+	var tasks []*JSTask
+	for _, todo := range data.Todos {
+		obj, err := TodoToJSCalendar(todo)
+		if err != nil {
+			return nil, nil, err
+		}
+		tasks = append(tasks, obj)
+	}
+	return events, tasks, nil
+}
+
+// EventToJSCalendar converts event and its overridden instances to a
+// JSCalendar Event. exceptions has the shape ExpandEventWithExceptions takes:
+// instances keyed by their RECURRENCE-ID formatted with formatICalTime, such
+// as "20240115T100000Z". Each becomes a recurrenceOverrides patch holding
+// only what differs from the regular occurrence; EXDATEs become excluded
+// overrides and RDATEs empty ones. A DURATION is kept as is, otherwise the
+// duration runs to DTEND.
+//
+// JSCalendar has no place for the following, so they are dropped and do not
+// survive a round trip through JSCalendarToEvent:
+//   - DTSTAMP, COMMENT, CONTACT, REQUEST-STATUS, RECURRENCE-ID;RANGE, the
+//     LANGUAGE parameters of SUMMARY and DESCRIPTION, and CustomProperties
+//   - the DIR, SENT-BY and custom parameters of ORGANIZER and ATTENDEE, and
+//     DELEGATED-TO, DELEGATED-FROM and MEMBER addresses that are not
+//     themselves attendees
+//   - VALARM DESCRIPTION, SUMMARY, DURATION, REPEAT and attendees, and actions
+//     other than DISPLAY and EMAIL, which become display alerts
+//   - X-APPLE-STRUCTURED-LOCATION, whose coordinates only are kept, and
+//     RFC 9073 PARTICIPANT components
+//   - the ALTREP and custom parameters of IMAGE, ATTACH and CONFERENCE, and
+//     all but the first IMAGE DISPLAY value
+//
+// Other properties change form: CATEGORIES, LOCATION-TYPE and FEATURE values
+// come back sorted, an ATTENDEE without ROLE comes back as REQ-PARTICIPANT,
+// RRULE parts are written in a fixed order, and DURATION becomes DTEND.
+func EventToJSCalendar(event ParsedEvent, exceptions map[string]*ParsedEvent) (*JSEvent, error) {
+	obj, loc, err := eventToJS(event)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.RecurrenceID != nil {
+		obj.RecurrenceID = jsLocalDateTime(*event.RecurrenceID, loc)
+	}
+
+	for _, rule := range []struct {
+		value string
+		rules *[]JSRecurrenceRule
+	}{
+		{event.RecurrenceRule, &obj.RecurrenceRules},
+		{event.ExceptionRule, &obj.ExcludedRecurrenceRules},
+	} {
+		if rule.value == "" {
+			continue
+		}
+		rrule, err := ParseRRule(rule.value)
+		if err != nil {
+			return nil, wrapErrorWithType("jscalendar.event", ErrorTypeValidation, err)
+		}
+		*rule.rules = append(*rule.rules, recurrenceRuleToJS(rrule, loc))
+	}
+
+	overrides := make(map[string]JSPatch)
+	for _, t := range event.RecurrenceDates {
+		overrides[jsLocalDateTime(t, loc)] = JSPatch{}
+	}
+	for _, t := range event.ExceptionDates {
+		overrides[jsLocalDateTime(t, loc)] = JSPatch{"excluded": true}
+	}
+	for _, key := range sortedKeys(exceptions) {
+		exception := exceptions[key]
+		if exception == nil || exception.RecurrenceID == nil {
+			continue
+		}
+		patch, err := eventOverridePatch(event, *exception)
+		if err != nil {
+			return nil, err
+		}
+		overrides[jsLocalDateTime(*exception.RecurrenceID, loc)] = patch
+	}
+	if len(overrides) > 0 {
+		obj.RecurrenceOverrides = overrides
+	}
+
+	return obj, nil
+}
+
+// JSCalendarToEvent converts a JSCalendar Event back to iCalendar. It returns
+// the master event and its overridden instances keyed as
+// ExpandEventWithExceptions expects. See EventToJSCalendar for what does not
+// round-trip; in addition, localizations are not applied (see
+// LocalizeJSEvent) and patches to properties this package does not map are
+// ignored.
+func JSCalendarToEvent(obj *JSEvent) (ParsedEvent, map[string]*ParsedEvent, error) {
+	if obj == nil {
+		return ParsedEvent{}, nil, newTypedError("jscalendar.event", ErrorTypeValidation, "event is nil", nil)
+	}
+
+	event, loc, err := eventFromJS(obj)
+	if err != nil {
+		return ParsedEvent{}, nil, err
+	}
+
+	if obj.RecurrenceID != "" {
+		if event.RecurrenceID, err = jsParseDateTime(obj.RecurrenceID, loc, "recurrenceId"); err != nil {
+			return ParsedEvent{}, nil, err
+		}
+	}
+
+	form := eventTimeForm(event.AllDay, event.Floating)
+	var rrules, exrules []string
+	for _, rule := range obj.RecurrenceRules {
+		rrules = append(rrules, recurrenceRuleFromJS(rule, loc, form))
+	}
+	for _, rule := range obj.ExcludedRecurrenceRules {
+		exrules = append(exrules, recurrenceRuleFromJS(rule, loc, form))
+	}
+	// ParsedEvent holds a single rule of each kind.
+	if len(rrules) > 0 {
+		event.RecurrenceRule = rrules[0]
+	}
+	if len(exrules) > 0 {
+		event.ExceptionRule = exrules[0]
+	}
+
+	var exceptions map[string]*ParsedEvent
+	for _, key := range sortedKeys(obj.RecurrenceOverrides) {
+		patch := obj.RecurrenceOverrides[key]
+		recurrenceID, err := jsParseDateTime(key, loc, "recurrenceOverrides")
+		if err != nil {
+			return ParsedEvent{}, nil, err
+		}
+
+		switch {
+		case patch["excluded"] == true:
+			event.ExceptionDates = append(event.ExceptionDates, *recurrenceID)
+		case len(patch) == 0:
+			event.RecurrenceDates = append(event.RecurrenceDates, *recurrenceID)
+		default:
+			exception, err := eventFromOverride(obj, key, patch)
+			if err != nil {
+				return ParsedEvent{}, nil, err
+			}
+			exception.RecurrenceID = recurrenceID
+			if exceptions == nil {
+				exceptions = make(map[string]*ParsedEvent)
+			}
+			exceptions[formatICalTime(*recurrenceID)] = exception
+		}
+	}
+
+	return event, exceptions, nil
+}
+
+// TodoToJSCalendar converts todo to a JSCalendar Task. DTSTART and DUE are
+// written in the time zone of DUE, or of DTSTART without DUE, and COMPLETED
+// becomes progressUpdated. Dates and floating times have no time zone, and
+// dates set showWithoutTime. Properties that do not round-trip are those
+// listed for EventToJSCalendar.
+func TodoToJSCalendar(todo ParsedTodo) (*JSTask, error) {
+	if todo.UID == "" {
+		return nil, newTypedError("jscalendar.task", ErrorTypeValidation, "todo has no UID", nil)
+	}
+
+	var loc *time.Location
+	switch {
+	case todo.AllDay || todo.Floating:
+		loc = time.UTC
+	case todo.Due != nil:
+		loc = todo.Due.Location()
+	case todo.DTStart != nil:
+		loc = todo.DTStart.Location()
+	}
+
+	obj := &JSTask{
+		Type:             "Task",
+		UID:              todo.UID,
+		Created:          jsUTCDateTime(todo.Created),
+		Updated:          jsUTCDateTime(todo.LastModified),
+		Sequence:         todo.Sequence,
+		Title:            todo.Summary,
+		Description:      todo.Description,
+		Progress:         strings.ToLower(todo.Status),
+		ProgressUpdated:  jsUTCDateTime(todo.Completed),
+		PercentComplete:  todo.PercentComplete,
+		Privacy:          privacyToJS(todo.Class),
+		Priority:         todo.Priority,
+		Color:            todo.Color,
+		Keywords:         keywordsToJS(todo.Categories),
+		Links:            linksToJS(todo.URL, todo.Attachments, todo.Images),
+		VirtualLocations: virtualLocationsToJS(todo.Conferences),
+		RelatedTo:        relatedToJS(todo.RelatedTo),
+		Alerts:           alertsToJS(todo.Alarms),
+		ShowWithoutTime:  todo.AllDay,
+	}
+	if loc != nil && !todo.AllDay && !todo.Floating {
+		obj.TimeZone = jsTimeZoneName(loc)
+		loc = jsLocation(loc)
+	}
+	if todo.DTStart != nil {
+		obj.Start = jsLocalDateTime(*todo.DTStart, loc)
+	}
+	if todo.Due != nil {
+		obj.Due = jsLocalDateTime(*todo.Due, loc)
+	}
+	return obj, nil
+}
+
+// JSCalendarToTodo converts a JSCalendar Task back to a VTODO. See
+// TodoToJSCalendar for what does not round-trip.
+func JSCalendarToTodo(obj *JSTask) (ParsedTodo, error) {
+	if obj == nil {
+		return ParsedTodo{}, newTypedError("jscalendar.task", ErrorTypeValidation, "task is nil", nil)
+	}
+	if obj.UID == "" {
+		return ParsedTodo{}, newTypedError("jscalendar.task", ErrorTypeValidation, "task has no uid", nil)
+	}
+
+	loc, err := jsLoadTimeZone(obj.TimeZone)
+	if err != nil {
+		return ParsedTodo{}, err
+	}
+	if obj.ShowWithoutTime {
+		loc = time.UTC
+	}
+
+	todo := ParsedTodo{
+		UID:             obj.UID,
+		AllDay:          obj.ShowWithoutTime,
+		Floating:        obj.TimeZone == "" && !obj.ShowWithoutTime && (obj.Start != "" || obj.Due != ""),
+		Sequence:        obj.Sequence,
+		Summary:         obj.Title,
+		Description:     obj.Description,
+		Status:          strings.ToUpper(obj.Progress),
+		PercentComplete: obj.PercentComplete,
+		Priority:        obj.Priority,
+		Class:           privacyFromJS(obj.Privacy),
+		Color:           obj.Color,
+		Categories:      keywordsFromJS(obj.Keywords),
+		Conferences:     virtualLocationsFromJS(obj.VirtualLocations),
+		RelatedTo:       relatedFromJS(obj.RelatedTo),
+		Alarms:          alertsFromJS(obj.Alerts, obj.Title),
+	}
+	todo.URL, todo.Attachments, todo.Images = linksFromJS(obj.Links)
+
+	for _, field := range []struct {
+		name  string
+		value string
+		local bool
+		dst   **time.Time
+	}{
+		{"start", obj.Start, true, &todo.DTStart},
+		{"due", obj.Due, true, &todo.Due},
+		{"created", obj.Created, false, &todo.Created},
+		{"updated", obj.Updated, false, &todo.LastModified},
+		{"progressUpdated", obj.ProgressUpdated, false, &todo.Completed},
+	} {
+		if field.value == "" {
+			continue
+		}
+		fieldLoc := time.UTC
+		if field.local {
+			fieldLoc = loc
+		}
+		if *field.dst, err = jsParseDateTime(field.value, fieldLoc, field.name); err != nil {
+			return ParsedTodo{}, err
+		}
+	}
+	return todo, nil
+}
+
+// LocalizeJSEvent returns a copy of obj with its localization for language,
+// such as "de", applied. obj is returned unchanged when it has none.
+func LocalizeJSEvent(obj *JSEvent, language string) (*JSEvent, error) {
+	if obj == nil {
+		return nil, newTypedError("jscalendar.localize", ErrorTypeValidation, "event is nil", nil)
+	}
+	localized := &JSEvent{}
+	if err := localizeJS(obj, obj.Localizations[language], localized); err != nil {
+		return nil, err
+	}
+	localized.Locale = language
+	return localized, nil
+}
+
+// LocalizeJSTask returns a copy of obj with its localization for language
+// applied, like LocalizeJSEvent.
+func LocalizeJSTask(obj *JSTask, language string) (*JSTask, error) {
+	if obj == nil {
+		return nil, newTypedError("jscalendar.localize", ErrorTypeValidation, "task is nil", nil)
+	}
+	localized := &JSTask{}
+	if err := localizeJS(obj, obj.Localizations[language], localized); err != nil {
+		return nil, err
+	}
+	localized.Locale = language
+	return localized, nil
+}
+
+func localizeJS(obj interface{}, patch JSPatch, out interface{}) error {
+	doc, err := jsToMap(obj)
+	if err != nil {
+		return err
+	}
+	if err := applyJSPatch(doc, patch); err != nil {
+		return err
+	}
+	delete(doc, "localizations")
+	return jsFromMap(doc, out)
+}
+
+// eventToJS converts the properties of event shared by masters and
+// overridden instances. It returns the location start times are written in.
+func eventToJS(event ParsedEvent) (*JSEvent, *time.Location, error) {
+	if event.UID == "" {
+		return nil, nil, newTypedError("jscalendar.event", ErrorTypeValidation, "event has no UID", nil)
+	}
+	if event.DTStart == nil {
+		return nil, nil, newTypedError("jscalendar.event", ErrorTypeValidation, "event has no DTSTART", nil)
+	}
+
+	obj := &JSEvent{
+		Type:             "Event",
+		UID:              event.UID,
+		Created:          jsUTCDateTime(event.Created),
+		Updated:          jsUTCDateTime(event.LastModified),
+		Sequence:         event.Sequence,
+		Title:            event.Summary,
+		Description:      event.Description,
+		ShowWithoutTime:  event.AllDay,
+		Status:           strings.ToLower(event.Status),
+		FreeBusyStatus:   freeBusyStatusToJS(event.Transparency),
+		Privacy:          privacyToJS(event.Class),
+		Priority:         event.Priority,
+		Color:            event.Color,
+		Keywords:         keywordsToJS(event.Categories),
+		Links:            linksToJS(event.URL, event.Attachments, event.Images),
+		Locations:        locationsToJS(event),
+		VirtualLocations: virtualLocationsToJS(event.Conferences),
+		RelatedTo:        relatedToJS(event.RelatedTo),
+		Alerts:           alertsToJS(event.Alarms),
+	}
+	obj.Participants, obj.ReplyTo = participantsToJS(event.Organizer, event.Attendees)
+
+	// Dates and floating times have no time zone and keep their wall clock.
+	loc := time.UTC
+	if !event.AllDay && !event.Floating {
+		obj.TimeZone = jsTimeZoneName(event.DTStart.Location())
+		loc = jsLocation(event.DTStart.Location())
+	}
+	obj.Start = jsLocalDateTime(*event.DTStart, loc)
+
+	switch {
+	case event.DTEnd != nil && event.AllDay:
+		start, end := event.DTStart.In(loc), event.DTEnd.In(loc)
+		days := int(time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC).
+			Sub(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+		obj.Duration = fmt.Sprintf("P%dD", days)
+	case event.DTEnd != nil:
+		obj.Duration = formatJSDuration(event.DTEnd.Sub(*event.DTStart))
+	case event.Duration != "":
+		obj.Duration = event.Duration
+	}
+
+	return obj, loc, nil
+}
+
+// eventFromJS converts the properties shared by masters and overridden
+// instances back. It returns the location of the start time.
+func eventFromJS(obj *JSEvent) (ParsedEvent, *time.Location, error) {
+	if obj.UID == "" {
+		return ParsedEvent{}, nil, newTypedError("jscalendar.event", ErrorTypeValidation, "event has no uid", nil)
+	}
+	if obj.Start == "" {
+		return ParsedEvent{}, nil, newTypedError("jscalendar.event", ErrorTypeValidation, "event has no start", nil)
+	}
+
+	loc, err := jsLoadTimeZone(obj.TimeZone)
+	if err != nil {
+		return ParsedEvent{}, nil, err
+	}
+	if obj.ShowWithoutTime {
+		loc = time.UTC
+	}
+
+	event := ParsedEvent{
+		UID:          obj.UID,
+		AllDay:       obj.ShowWithoutTime,
+		Floating:     obj.TimeZone == "" && !obj.ShowWithoutTime,
+		Summary:      obj.Title,
+		Description:  obj.Description,
+		Status:       strings.ToUpper(obj.Status),
+		Transparency: freeBusyStatusFromJS(obj.FreeBusyStatus),
+		Categories:   keywordsFromJS(obj.Keywords),
+		RelatedTo:    relatedFromJS(obj.RelatedTo),
+		Sequence:     obj.Sequence,
+		Priority:     obj.Priority,
+		Class:        privacyFromJS(obj.Privacy),
+		Color:        obj.Color,
+		Conferences:  virtualLocationsFromJS(obj.VirtualLocations),
+		Alarms:       alertsFromJS(obj.Alerts, obj.Title),
+	}
+	event.URL, event.Attachments, event.Images = linksFromJS(obj.Links)
+	event.Location, event.GeoLocation, event.Locations = locationsFromJS(obj.Locations)
+	event.Organizer, event.Attendees = participantsFromJS(obj.Participants, obj.ReplyTo)
+
+	if event.DTStart, err = jsParseDateTime(obj.Start, loc, "start"); err != nil {
+		return ParsedEvent{}, nil, err
+	}
+	if event.Created, err = jsParseOptionalUTC(obj.Created, "created"); err != nil {
+		return ParsedEvent{}, nil, err
+	}
+	if event.LastModified, err = jsParseOptionalUTC(obj.Updated, "updated"); err != nil {
+		return ParsedEvent{}, nil, err
+	}
+
+	if obj.Duration != "" {
+		end, err := jsAddDuration(*event.DTStart, obj.Duration, event.AllDay)
+		if err != nil {
+			return ParsedEvent{}, nil, err
+		}
+		event.DTEnd = &end
+	}
+
+	return event, loc, nil
+}
+
+// eventOverridePatch returns the patch turning the regular occurrence of
+// master at exception's RECURRENCE-ID into exception.
+func eventOverridePatch(master, exception ParsedEvent) (JSPatch, error) {
+	occurrence := jsOccurrence(master, *exception.RecurrenceID)
+
+	base, _, err := eventToJS(occurrence)
+	if err != nil {
+		return nil, err
+	}
+	exception.RecurrenceID = nil
+	override, _, err := eventToJS(exception)
+	if err != nil {
+		return nil, err
+	}
+
+	baseDoc, err := jsToMap(base)
+	if err != nil {
+		return nil, err
+	}
+	overrideDoc, err := jsToMap(override)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := JSPatch{}
+	for key, value := range overrideDoc {
+		if !jsEqual(baseDoc[key], value) {
+			patch[key] = value
+		}
+	}
+	for key := range baseDoc {
+		if _, ok := overrideDoc[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch, nil
+}
+
+// eventFromOverride applies patch to the occurrence of obj identified by
+// recurrenceID and converts the result.
+func eventFromOverride(obj *JSEvent, recurrenceID string, patch JSPatch) (*ParsedEvent, error) {
+	base := *obj
+	base.Start = recurrenceID
+	base.RecurrenceID = ""
+	base.RecurrenceRules = nil
+	base.ExcludedRecurrenceRules = nil
+	base.RecurrenceOverrides = nil
+
+	doc, err := jsToMap(&base)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyJSPatch(doc, patch); err != nil {
+		return nil, err
+	}
+
+	var patched JSEvent
+	if err := jsFromMap(doc, &patched); err != nil {
+		return nil, err
+	}
+	event, _, err := eventFromJS(&patched)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// jsOccurrence returns the regular occurrence of master starting at start.
+func jsOccurrence(master ParsedEvent, start time.Time) ParsedEvent {
+	occurrence := master
+	occurrence.RecurrenceRule = ""
+	occurrence.ExceptionRule = ""
+	occurrence.RecurrenceDates = nil
+	occurrence.ExceptionDates = nil
+	occurrence.DTStart = &start
+	if master.DTEnd != nil {
+		end := start.Add(master.DTEnd.Sub(*master.DTStart))
+		occurrence.DTEnd = &end
+	}
+	return occurrence
+}
+
+// applyJSPatch applies patch to doc, the JSON object form of a JSCalendar
+// object, creating intermediate objects as needed.
+func applyJSPatch(doc map[string]interface{}, patch JSPatch) error {
+	for _, path := range sortedKeys(patch) {
+		segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		target := doc
+		for _, segment := range segments[:len(segments)-1] {
+			segment = jsUnescapePointer(segment)
+			next, ok := target[segment].(map[string]interface{})
+			if !ok {
+				if target[segment] != nil {
+					return newTypedError("jscalendar.patch", ErrorTypeValidation, "cannot patch into "+path, nil)
+				}
+				next = make(map[string]interface{})
+				target[segment] = next
+			}
+			target = next
+		}
+
+		last := jsUnescapePointer(segments[len(segments)-1])
+		if value := patch[path]; value == nil {
+			delete(target, last)
+		} else {
+			target[last] = value
+		}
+	}
+	return nil
+}
+
+func jsUnescapePointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
+
+func jsToMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, wrapErrorWithType("jscalendar.encode", ErrorTypeValidation, err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, wrapErrorWithType("jscalendar.encode", ErrorTypeValidation, err)
+	}
+	return doc, nil
+}
+
+func jsFromMap(doc map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return wrapErrorWithType("jscalendar.decode", ErrorTypeValidation, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return wrapErrorWithType("jscalendar.decode", ErrorTypeValidation, err)
+	}
+	return nil
+}
+
+func jsEqual(a, b interface{}) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
+
+// jsTimeZoneName returns the IANA name of loc, which JSCalendar requires.
+func jsTimeZoneName(loc *time.Location) string {
+	if loc == time.UTC || loc == time.Local || loc.String() == "UTC" {
+		return "Etc/UTC"
+	}
+	return loc.String()
+}
+
+// jsLocation returns the location times in loc are written in, converting
+// the process-local time zone, which has no portable name, to UTC.
+func jsLocation(loc *time.Location) *time.Location {
+	if loc == time.Local {
+		return time.UTC
+	}
+	return loc
+}
+
+func jsLoadTimeZone(name string) (*time.Location, error) {
+	switch name {
+	case "", "UTC", "Etc/UTC":
+		return time.UTC, nil
+	}
+	loc, err := LoadLocationFromTZID(name)
+	if err != nil {
+		return nil, newTypedError("jscalendar.timezone", ErrorTypeValidation, "unknown time zone "+name, err)
+	}
+	return loc, nil
+}
+
+func jsLocalDateTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(jsLocalDateTimeLayout)
+}
+
+func jsUTCDateTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(jsUTCDateTimeLayout)
+}
+
+// jsParseDateTime parses a LocalDateTime in loc or a UTCDateTime.
+func jsParseDateTime(value string, loc *time.Location, field string) (*time.Time, error) {
+	layout := jsLocalDateTimeLayout
+	if strings.HasSuffix(value, "Z") {
+		layout, loc = jsUTCDateTimeLayout, time.UTC
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return nil, newTypedError("jscalendar.time", ErrorTypeValidation, fmt.Sprintf("invalid %s %q", field, value), err)
+	}
+	return &t, nil
+}
+
+func jsParseOptionalUTC(value, field string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return jsParseDateTime(value, time.UTC, field)
+}
+
+// formatJSDuration formats d in hours, minutes and seconds, which unlike
+// days are exact across daylight saving transitions.
+func formatJSDuration(d time.Duration) string {
+	if d <= 0 {
+		return "PT0S"
+	}
+	result := "PT"
+	if hours := int(d / time.Hour); hours > 0 {
+		result += strconv.Itoa(hours) + "H"
+	}
+	if minutes := int(d/time.Minute) % 60; minutes > 0 {
+		result += strconv.Itoa(minutes) + "M"
+	}
+	if seconds := int(d/time.Second) % 60; seconds > 0 {
+		result += strconv.Itoa(seconds) + "S"
+	}
+	return result
+}
+
+// jsAddDuration adds the duration value to start. Days and weeks are
+// calendar days, as RFC 8984 requires.
+func jsAddDuration(start time.Time, value string, allDay bool) (time.Time, error) {
+	invalid := newTypedError("jscalendar.duration", ErrorTypeValidation, "invalid duration "+value, nil)
+
+	datePart, timePart := strings.TrimPrefix(value, "P"), ""
+	if len(datePart) == len(value) {
+		return time.Time{}, invalid
+	}
+	if i := strings.IndexByte(datePart, 'T'); i >= 0 {
+		datePart, timePart = datePart[:i], datePart[i:]
+	}
+
+	days := 0
+	for datePart != "" {
+		i := strings.IndexAny(datePart, "DW")
+		if i <= 0 {
+			return time.Time{}, invalid
+		}
+		n, err := strconv.Atoi(datePart[:i])
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		if datePart[i] == 'W' {
+			n *= 7
+		}
+		days += n
+		datePart = datePart[i+1:]
+	}
+
+	end := start.AddDate(0, 0, days)
+	if timePart != "" {
+		d, _, err := parseISO8601Duration("P" + timePart)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		if allDay && d != 0 {
+			return time.Time{}, invalid
+		}
+		end = end.Add(d)
+	}
+	return end, nil
+}
+
+func freeBusyStatusToJS(transparency string) string {
+	switch strings.ToUpper(transparency) {
+	case "OPAQUE":
+		return "busy"
+	case "TRANSPARENT":
+		return "free"
+	}
+	return ""
+}
+
+func freeBusyStatusFromJS(status string) string {
+	switch status {
+	case "":
+		return ""
+	case "free":
+		return "TRANSPARENT"
+	}
+	return "OPAQUE"
+}
+
+func privacyToJS(class string) string {
+	if strings.EqualFold(class, "CONFIDENTIAL") {
+		return "secret"
+	}
+	return strings.ToLower(class)
+}
+
+func privacyFromJS(privacy string) string {
+	if privacy == "secret" {
+		return "CONFIDENTIAL"
+	}
+	return strings.ToUpper(privacy)
+}
+
+func keywordsToJS(categories []string) map[string]bool {
+	if len(categories) == 0 {
+		return nil
+	}
+	keywords := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		keywords[category] = true
+	}
+	return keywords
+}
+
+func keywordsFromJS(keywords map[string]bool) []string {
+	var categories []string
+	for _, keyword := range sortedKeys(keywords) {
+		if keywords[keyword] {
+			categories = append(categories, keyword)
+		}
+	}
+	return categories
+}
+
+// jsIDs returns the keys of m in numeric order where they are numbers, so
+// that ids generated by this package keep their order.
+func jsIDs[V any](m map[string]V) []string {
+	ids := sortedKeys(m)
+	sort.SliceStable(ids, func(i, j int) bool {
+		a, aErr := strconv.Atoi(ids[i])
+		b, bErr := strconv.Atoi(ids[j])
+		if aErr == nil && bErr == nil {
+			return a < b
+		}
+		return aErr == nil && bErr != nil
+	})
+	return ids
+}
+
+// linksToJS maps URL, ATTACH and IMAGE to links with the relations
+// "describedby", "enclosure" and "icon". Inline data becomes a data: URI.
+func linksToJS(url string, attachments []Attachment, images []Image) map[string]JSLink {
+	links := make(map[string]JSLink)
+	add := func(link JSLink) {
+		link.Type = "Link"
+		links[strconv.Itoa(len(links)+1)] = link
+	}
+
+	if url != "" {
+		add(JSLink{Href: url, Rel: "describedby"})
+	}
+	for _, a := range attachments {
+		add(JSLink{
+			Href:        jsLinkHref(a.URI, a.Value, a.FormatType),
+			ContentType: a.FormatType,
+			Size:        a.Size,
+			Rel:         "enclosure",
+			Title:       a.Filename,
+		})
+	}
+	for _, image := range images {
+		link := JSLink{Href: jsLinkHref(image.URI, image.Value, image.FormatType), ContentType: image.FormatType, Rel: "icon"}
+		if len(image.Display) > 0 {
+			link.Display = strings.ToLower(image.Display[0])
+		}
+		add(link)
+	}
+
+	if len(links) == 0 {
+		return nil
+	}
+	return links
+}
+
+func jsLinkHref(uri, data, contentType string) string {
+	if uri != "" {
+		return uri
+	}
+	return "data:" + contentType + ";base64," + data
+}
+
+func linksFromJS(links map[string]JSLink) (string, []Attachment, []Image) {
+	var url string
+	var attachments []Attachment
+	var images []Image
+	for _, id := range jsIDs(links) {
+		link := links[id]
+		uri, data := link.Href, ""
+		if strings.HasPrefix(uri, "data:") {
+			if i := strings.Index(uri, ";base64,"); i >= 0 {
+				uri, data = "", uri[i+len(";base64,"):]
+			}
+		}
+		encoding := ""
+		if data != "" {
+			encoding = "BASE64"
+		}
+
+		switch {
+		case link.Rel == "describedby" && url == "":
+			url = link.Href
+		case link.Rel == "icon":
+			image := Image{URI: uri, Encoding: encoding, Value: data, FormatType: link.ContentType}
+			if link.Display != "" {
+				image.Display = []string{strings.ToUpper(link.Display)}
+			}
+			images = append(images, image)
+		default:
+			attachments = append(attachments, Attachment{
+				URI:        uri,
+				Encoding:   encoding,
+				Value:      data,
+				FormatType: link.ContentType,
+				Size:       link.Size,
+				Filename:   link.Title,
+			})
+		}
+	}
+	return url, attachments, images
+}
+
+// locationsToJS maps LOCATION and the event's coordinates to the location
+// with id "1" and each VLOCATION to a location keyed by its UID.
+func locationsToJS(event ParsedEvent) map[string]JSLocation {
+	locations := make(map[string]JSLocation)
+
+	main := JSLocation{Type: "Location", Name: event.Location}
+	if geo, ok := EventCoordinates(&event); ok {
+		main.Coordinates = formatGeoURI(&geo)
+	}
+	if main.Name != "" || main.Coordinates != "" {
+		locations[jsMainLocationID] = main
+	}
+
+	for i, vloc := range event.Locations {
+		id := vloc.UID
+		if id == "" {
+			id = fmt.Sprintf("location-%d", i+1)
+		}
+		location := JSLocation{Type: "Location", Name: vloc.Name, Description: vloc.Description}
+		if len(vloc.LocationType) > 0 {
+			location.LocationTypes = make(map[string]bool, len(vloc.LocationType))
+			for _, t := range vloc.LocationType {
+				location.LocationTypes[t] = true
+			}
+		}
+		if vloc.Geo != nil {
+			location.Coordinates = formatGeoURI(vloc.Geo)
+		}
+		locations[id] = location
+	}
+
+	if len(locations) == 0 {
+		return nil
+	}
+	return locations
+}
+
+// locationsFromJS returns the location with id "1", or else the first, as
+// LOCATION and GEO and the others as VLOCATIONs.
+func locationsFromJS(locations map[string]JSLocation) (string, *GeoLocation, []ParsedLocation) {
+	mainID := jsMainLocationID
+	if _, ok := locations[mainID]; !ok {
+		if ids := jsIDs(locations); len(ids) > 0 {
+			mainID = ids[0]
+		}
+	}
+
+	var parser icalParser
+	var name string
+	var geo *GeoLocation
+	var vlocations []ParsedLocation
+	for _, id := range jsIDs(locations) {
+		location := locations[id]
+		if id == mainID {
+			name, geo = location.Name, parser.parseGeoURI(location.Coordinates)
+			continue
+		}
+		vlocations = append(vlocations, ParsedLocation{
+			UID:          id,
+			Name:         location.Name,
+			Description:  location.Description,
+			LocationType: sortedTrueKeys(location.LocationTypes),
+			Geo:          parser.parseGeoURI(location.Coordinates),
+		})
+	}
+	return name, geo, vlocations
+}
+
+func sortedTrueKeys(m map[string]bool) []string {
+	var keys []string
+	for _, key := range sortedKeys(m) {
+		if m[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func virtualLocationsToJS(conferences []Conference) map[string]JSVirtualLocation {
+	if len(conferences) == 0 {
+		return nil
+	}
+	locations := make(map[string]JSVirtualLocation, len(conferences))
+	for i, conference := range conferences {
+		location := JSVirtualLocation{Type: "VirtualLocation", Name: conference.Label, URI: conference.URI}
+		if len(conference.Features) > 0 {
+			location.Features = make(map[string]bool, len(conference.Features))
+			for _, feature := range conference.Features {
+				location.Features[strings.ToLower(feature)] = true
+			}
+		}
+		locations[strconv.Itoa(i+1)] = location
+	}
+	return locations
+}
+
+func virtualLocationsFromJS(locations map[string]JSVirtualLocation) []Conference {
+	var conferences []Conference
+	for _, id := range jsIDs(locations) {
+		location := locations[id]
+		conference := Conference{URI: location.URI, Label: location.Name}
+		for _, feature := range sortedTrueKeys(location.Features) {
+			conference.Features = append(conference.Features, strings.ToUpper(feature))
+		}
+		conferences = append(conferences, conference)
+	}
+	return conferences
+}
+
+func relatedToJS(related []RelatedEvent) map[string]JSRelation {
+	if len(related) == 0 {
+		return nil
+	}
+	relations := make(map[string]JSRelation, len(related))
+	for _, r := range related {
+		relation := JSRelation{Type: "Relation"}
+		if r.RelationType != "" {
+			relation.Relation = map[string]bool{strings.ToLower(r.RelationType): true}
+		}
+		relations[r.UID] = relation
+	}
+	return relations
+}
+
+func relatedFromJS(relations map[string]JSRelation) []RelatedEvent {
+	var related []RelatedEvent
+	for _, uid := range sortedKeys(relations) {
+		r := RelatedEvent{UID: uid}
+		if types := sortedTrueKeys(relations[uid].Relation); len(types) > 0 {
+			r.RelationType = strings.ToUpper(types[0])
+		}
+		related = append(related, r)
+	}
+	return related
+}
+
+// participantsToJS maps attendees and the organizer to participants. An
+// organizer who is also an attendee becomes one participant with the
+// "owner" role.
+func participantsToJS(organizer ParsedOrganizer, attendees []ParsedAttendee) (map[string]JSParticipant, map[string]string) {
+	participants := make(map[string]JSParticipant)
+	ids := make(map[string]string)
+	for i, attendee := range attendees {
+		ids[strings.ToLower(attendee.Value)] = strconv.Itoa(i + 1)
+	}
+	idsOf := func(addresses string) map[string]bool {
+		var set map[string]bool
+		for _, address := range strings.Split(addresses, ",") {
+			if id, ok := ids[strings.ToLower(strings.TrimSpace(address))]; ok {
+				if set == nil {
+					set = make(map[string]bool)
+				}
+				set[id] = true
+			}
+		}
+		return set
+	}
+
+	for i, attendee := range attendees {
+		p := JSParticipant{
+			Type:                "Participant",
+			Name:                attendee.CN,
+			Email:               attendee.Email,
+			Kind:                participantKindToJS(attendee.CUType),
+			Roles:               participantRolesToJS(attendee.Role),
+			ParticipationStatus: strings.ToLower(attendee.PartStat),
+			ExpectReply:         attendee.RSVP,
+			ScheduleAgent:       strings.ToLower(attendee.ScheduleAgent),
+			DelegatedTo:         idsOf(attendee.DelegatedTo),
+			DelegatedFrom:       idsOf(attendee.DelegatedFrom),
+			MemberOf:            idsOf(attendee.Member),
+		}
+		if attendee.Value != "" {
+			p.SendTo = map[string]string{"imip": attendee.Value}
+		}
+		if attendee.ScheduleStatus != "" {
+			p.ScheduleStatus = strings.Split(attendee.ScheduleStatus, ",")
+		}
+		participants[strconv.Itoa(i+1)] = p
+	}
+
+	if organizer.Value == "" {
+		if len(participants) == 0 {
+			return nil, nil
+		}
+		return participants, nil
+	}
+
+	if id, ok := ids[strings.ToLower(organizer.Value)]; ok {
+		participants[id].Roles["owner"] = true
+	} else {
+		participants[strconv.Itoa(len(attendees)+1)] = JSParticipant{
+			Type:   "Participant",
+			Name:   organizer.CN,
+			Email:  organizer.Email,
+			SendTo: map[string]string{"imip": organizer.Value},
+			Roles:  map[string]bool{"owner": true},
+		}
+	}
+	return participants, map[string]string{"imip": organizer.Value}
+}
+
+func participantsFromJS(participants map[string]JSParticipant, replyTo map[string]string) (ParsedOrganizer, []ParsedAttendee) {
+	var organizer ParsedOrganizer
+	addresses := make(map[string]string, len(participants))
+	for id, p := range participants {
+		addresses[id] = jsParticipantAddress(p)
+	}
+	addressesOf := func(ids map[string]bool) string {
+		var list []string
+		for _, id := range jsIDs(ids) {
+			if ids[id] && addresses[id] != "" {
+				list = append(list, addresses[id])
+			}
+		}
+		return strings.Join(list, ",")
+	}
+
+	var attendees []ParsedAttendee
+	for _, id := range jsIDs(participants) {
+		p := participants[id]
+		if p.Roles["owner"] && organizer.Value == "" {
+			organizer = ParsedOrganizer{Value: addresses[id], CN: p.Name, Email: p.Email}
+		}
+
+		role := participantRoleFromJS(p.Roles)
+		if role == "" {
+			continue
+		}
+		attendees = append(attendees, ParsedAttendee{
+			Value:          addresses[id],
+			CN:             p.Name,
+			Email:          p.Email,
+			Role:           role,
+			PartStat:       strings.ToUpper(p.ParticipationStatus),
+			RSVP:           p.ExpectReply,
+			CUType:         participantKindFromJS(p.Kind),
+			Member:         addressesOf(p.MemberOf),
+			DelegatedTo:    addressesOf(p.DelegatedTo),
+			DelegatedFrom:  addressesOf(p.DelegatedFrom),
+			ScheduleAgent:  strings.ToUpper(p.ScheduleAgent),
+			ScheduleStatus: strings.Join(p.ScheduleStatus, ","),
+		})
+	}
+
+	if organizer.Value == "" && replyTo["imip"] != "" {
+		organizer.Value = replyTo["imip"]
+	}
+	return organizer, attendees
+}
+
+func jsParticipantAddress(p JSParticipant) string {
+	if address := p.SendTo["imip"]; address != "" {
+		return address
+	}
+	if p.Email != "" {
+		return "mailto:" + p.Email
+	}
+	return ""
+}
+
+func participantRolesToJS(role string) map[string]bool {
+	switch strings.ToUpper(role) {
+	case "CHAIR":
+		return map[string]bool{"attendee": true, "chair": true}
+	case "OPT-PARTICIPANT":
+		return map[string]bool{"attendee": true, "optional": true}
+	case "NON-PARTICIPANT":
+		return map[string]bool{"informational": true}
+	}
+	return map[string]bool{"attendee": true}
+}
+
+// participantRoleFromJS returns the ROLE of a participant, or "" when the
+// participant does not attend, such as an organizer who is only the owner.
+func participantRoleFromJS(roles map[string]bool) string {
+	switch {
+	case roles["chair"]:
+		return "CHAIR"
+	case roles["optional"]:
+		return "OPT-PARTICIPANT"
+	case roles["attendee"]:
+		return "REQ-PARTICIPANT"
+	case roles["informational"]:
+		return "NON-PARTICIPANT"
+	}
+	return ""
+}
+
+func participantKindToJS(cuType string) string {
+	switch strings.ToUpper(cuType) {
+	case "", "UNKNOWN":
+		return ""
+	case "ROOM":
+		return "location"
+	}
+	return strings.ToLower(cuType)
+}
+
+func participantKindFromJS(kind string) string {
+	if kind == "location" {
+		return "ROOM"
+	}
+	return strings.ToUpper(kind)
+}
+
+// alertsToJS maps alarms to alerts. A relative TRIGGER becomes an
+// OffsetTrigger relative to the start, or to the end with RELATED=END, and an
+// absolute one an AbsoluteTrigger.
+func alertsToJS(alarms []ParsedAlarm) map[string]JSAlert {
+	if len(alarms) == 0 {
+		return nil
+	}
+	alerts := make(map[string]JSAlert, len(alarms))
+	for i, alarm := range alarms {
+		alert := JSAlert{Type: "Alert", Action: "display"}
+		if strings.EqualFold(alarm.Action, "EMAIL") {
+			alert.Action = "email"
+		}
+		if isDurationValue(alarm.Trigger) {
+			alert.Trigger = JSTrigger{Type: "OffsetTrigger", Offset: alarm.Trigger}
+			if strings.EqualFold(alarm.TriggerRelated, "END") {
+				alert.Trigger.RelativeTo = "end"
+			}
+		} else if t, err := ParseCalDAVTime(alarm.Trigger); err == nil {
+			alert.Trigger = JSTrigger{Type: "AbsoluteTrigger", When: jsUTCDateTime(&t)}
+		}
+		alerts[strconv.Itoa(i+1)] = alert
+	}
+	return alerts
+}
+
+// alertsFromJS maps alerts back to alarms, which take description as the
+// DESCRIPTION a DISPLAY alarm requires.
+func alertsFromJS(alerts map[string]JSAlert, description string) []ParsedAlarm {
+	var alarms []ParsedAlarm
+	for _, id := range jsIDs(alerts) {
+		alert := alerts[id]
+		alarm := ParsedAlarm{Action: "DISPLAY", Description: description}
+		if alert.Action == "email" {
+			alarm.Action = "EMAIL"
+		}
+		switch alert.Trigger.Type {
+		case "OffsetTrigger":
+			alarm.Trigger = alert.Trigger.Offset
+			if alert.Trigger.RelativeTo == "end" {
+				alarm.TriggerRelated = "END"
+			}
+		case "AbsoluteTrigger":
+			if t, err := jsParseDateTime(alert.Trigger.When, time.UTC, "when"); err == nil {
+				alarm.Trigger = CreateAbsoluteAlarmTrigger(*t)
+			}
+		}
+		if alarm.Trigger == "" {
+			continue
+		}
+		alarms = append(alarms, alarm)
+	}
+	return alarms
+}
+
+func recurrenceRuleToJS(rule *RRule, loc *time.Location) JSRecurrenceRule {
+	js := JSRecurrenceRule{
+		Type:           "RecurrenceRule",
+		Frequency:      strings.ToLower(rule.Freq),
+		FirstDayOfWeek: strings.ToLower(rule.WeekStart),
+		ByMonthDay:     rule.ByMonthDay,
+		ByYearDay:      rule.ByYearDay,
+		ByWeekNo:       rule.ByWeekNo,
+		ByHour:         rule.ByHour,
+		ByMinute:       rule.ByMinute,
+		BySecond:       rule.BySecond,
+		BySetPosition:  rule.BySetPos,
+		Count:          rule.Count,
+	}
+	if rule.Interval > 1 {
+		js.Interval = rule.Interval
+	}
+	for _, day := range rule.ByDay {
+		if len(day) < 2 {
+			continue
+		}
+		nday := JSNDay{Type: "NDay", Day: strings.ToLower(day[len(day)-2:])}
+		nday.NthOfPeriod, _ = strconv.Atoi(strings.TrimPrefix(day[:len(day)-2], "+"))
+		js.ByDay = append(js.ByDay, nday)
+	}
+	for _, month := range rule.ByMonth {
+		js.ByMonth = append(js.ByMonth, strconv.Itoa(month))
+	}
+	if rule.Until != nil {
+		js.Until = jsLocalDateTime(*rule.Until, loc)
+	}
+	return js
+}
+
+// recurrenceRuleFromJS formats rule as an RRULE value whose UNTIL takes the
+// form of the event's DTSTART, in UTC unless it is a date or floating.
+func recurrenceRuleFromJS(rule JSRecurrenceRule, loc *time.Location, form timeForm) string {
+	parts := []string{"FREQ=" + strings.ToUpper(rule.Frequency)}
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if rule.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+	if rule.Until != "" {
+		if until, err := time.ParseInLocation(jsLocalDateTimeLayout, rule.Until, loc); err == nil {
+			switch form {
+			case formDate:
+				parts = append(parts, "UNTIL="+until.Format("20060102"))
+			case formFloating:
+				parts = append(parts, "UNTIL="+formatLocalICalTime(until))
+			default:
+				parts = append(parts, "UNTIL="+formatICalTime(until))
+			}
+		}
+	}
+
+	var days []string
+	for _, day := range rule.ByDay {
+		value := strings.ToUpper(day.Day)
+		if day.NthOfPeriod != 0 {
+			value = strconv.Itoa(day.NthOfPeriod) + value
+		}
+		days = append(days, value)
+	}
+
+	for _, list := range []struct {
+		name   string
+		values []string
+	}{
+		{"BYMONTH", rule.ByMonth},
+		{"BYWEEKNO", jsIntStrings(rule.ByWeekNo)},
+		{"BYYEARDAY", jsIntStrings(rule.ByYearDay)},
+		{"BYMONTHDAY", jsIntStrings(rule.ByMonthDay)},
+		{"BYDAY", days},
+		{"BYHOUR", jsIntStrings(rule.ByHour)},
+		{"BYMINUTE", jsIntStrings(rule.ByMinute)},
+		{"BYSECOND", jsIntStrings(rule.BySecond)},
+		{"BYSETPOS", jsIntStrings(rule.BySetPosition)},
+	} {
+		if len(list.values) > 0 {
+			parts = append(parts, list.name+"="+strings.Join(list.values, ","))
+		}
+	}
+	if rule.FirstDayOfWeek != "" {
+		parts = append(parts, "WKST="+strings.ToUpper(rule.FirstDayOfWeek))
+	}
+	return strings.Join(parts, ";")
+}
+
+func jsIntStrings(values []int) []string {
+	var s []string
+	for _, v := range values {
+		s = append(s, strconv.Itoa(v))
+	}
+	return s
+}
@@ -0,0 +1,492 @@
+package caldav
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const jsCalendarSeriesICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Test//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"DTSTAMP:20240101T000000Z\r\n" +
+	"DTSTART;TZID=Europe/London:20240108T093000\r\n" +
+	"DTEND;TZID=Europe/London:20240108T094500\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"LOCATION:Room 1\r\n" +
+	"GEO:51.5074;-0.1278\r\n" +
+	"STATUS:CONFIRMED\r\n" +
+	"TRANSP:OPAQUE\r\n" +
+	"CLASS:CONFIDENTIAL\r\n" +
+	"CATEGORIES:Work,Daily\r\n" +
+	"URL:https://example.com/standup\r\n" +
+	"CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO;LABEL=Join:https://video.example.com/1\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4;BYDAY=MO,-1FR\r\n" +
+	"EXDATE;TZID=Europe/London:20240115T093000\r\n" +
+	"ORGANIZER;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Alice;ROLE=CHAIR;PARTSTAT=ACCEPTED:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Bob;ROLE=OPT-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE;DELEGATED-TO=\"mailto:carol@example.com\":mailto:bob@example.com\r\n" +
+	"ATTENDEE;CN=Carol;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;DELEGATED-FROM=\"mailto:bob@example.com\":mailto:carol@example.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:Standup\r\n" +
+	"TRIGGER:-PT10M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"DTSTAMP:20240101T000000Z\r\n" +
+	"RECURRENCE-ID;TZID=Europe/London:20240122T093000\r\n" +
+	"DTSTART;TZID=Europe/London:20240122T100000\r\n" +
+	"DTEND;TZID=Europe/London:20240122T101500\r\n" +
+	"SUMMARY:Standup (moved)\r\n" +
+	"LOCATION:Room 1\r\n" +
+	"GEO:51.5074;-0.1278\r\n" +
+	"STATUS:CONFIRMED\r\n" +
+	"TRANSP:OPAQUE\r\n" +
+	"CLASS:CONFIDENTIAL\r\n" +
+	"CATEGORIES:Work,Daily\r\n" +
+	"URL:https://example.com/standup\r\n" +
+	"CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO;LABEL=Join:https://video.example.com/1\r\n" +
+	"ORGANIZER;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Alice;ROLE=CHAIR;PARTSTAT=ACCEPTED:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Bob;ROLE=OPT-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE;DELEGATED-TO=\"mailto:carol@example.com\":mailto:bob@example.com\r\n" +
+	"ATTENDEE;CN=Carol;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;DELEGATED-FROM=\"mailto:bob@example.com\":mailto:carol@example.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:Standup\r\n" +
+	"TRIGGER:-PT10M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:todo@example.com\r\n" +
+	"DTSTAMP:20240101T000000Z\r\n" +
+	"DTSTART:20240110T090000Z\r\n" +
+	"DUE:20240112T170000Z\r\n" +
+	"COMPLETED:20240111T120000Z\r\n" +
+	"SUMMARY:Write report\r\n" +
+	"STATUS:COMPLETED\r\n" +
+	"PERCENT-COMPLETE:100\r\n" +
+	"PRIORITY:1\r\n" +
+	"RELATED-TO;RELTYPE=PARENT:project@example.com\r\n" +
+	"ATTACH;FMTTYPE=text/plain;VALUE=BINARY;ENCODING=BASE64:SGVsbG8=\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendarToJSCalendar(t *testing.T) {
+	data, err := ParseICalendar(jsCalendarSeriesICS)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	events, tasks, err := CalendarToJSCalendar(data)
+	if err != nil {
+		t.Fatalf("CalendarToJSCalendar failed: %v", err)
+	}
+	if len(events) != 1 || len(tasks) != 1 {
+		t.Fatalf("expected one event and one task, got %d and %d", len(events), len(tasks))
+	}
+
+	encoded, err := json.Marshal(events[0])
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	output := string(encoded)
+	expected := []string{
+		`"@type":"Event"`,
+		`"start":"2024-01-08T09:30:00","timeZone":"Europe/London","duration":"PT15M"`,
+		`"recurrenceRules":[{"@type":"RecurrenceRule","frequency":"weekly","byDay":[{"@type":"NDay","day":"mo"},{"@type":"NDay","day":"fr","nthOfPeriod":-1}],"count":4}]`,
+		`"2024-01-15T09:30:00":{"excluded":true}`,
+		`"2024-01-22T09:30:00":{"start":"2024-01-22T10:00:00","title":"Standup (moved)"}`,
+		`"status":"confirmed","freeBusyStatus":"busy","privacy":"secret"`,
+		`"keywords":{"Daily":true,"Work":true}`,
+		`"links":{"1":{"@type":"Link","href":"https://example.com/standup","rel":"describedby"}}`,
+		`"locations":{"1":{"@type":"Location","name":"Room 1","coordinates":"geo:51.5074,-0.1278"}}`,
+		`"virtualLocations":{"1":{"@type":"VirtualLocation","name":"Join","uri":"https://video.example.com/1","features":{"audio":true,"video":true}}}`,
+		`"replyTo":{"imip":"mailto:alice@example.com"}`,
+		`"1":{"@type":"Participant","name":"Alice","email":"alice@example.com","sendTo":{"imip":"mailto:alice@example.com"},"roles":{"attendee":true,"chair":true,"owner":true},"participationStatus":"accepted"}`,
+		`"participationStatus":"needs-action","expectReply":true,"delegatedTo":{"3":true}`,
+		`"alerts":{"1":{"@type":"Alert","trigger":{"@type":"OffsetTrigger","offset":"-PT10M"},"action":"display"}}`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("JSCalendar output missing %s\n%s", want, output)
+		}
+	}
+
+	task := tasks[0]
+	if task.Type != "Task" || task.Start != "2024-01-10T09:00:00" || task.Due != "2024-01-12T17:00:00" || task.TimeZone != "Etc/UTC" {
+		t.Errorf("unexpected task times %+v", task)
+	}
+	if task.Progress != "completed" || task.ProgressUpdated != "2024-01-11T12:00:00Z" || task.PercentComplete != 100 {
+		t.Errorf("unexpected task progress %+v", task)
+	}
+	if link := task.Links["1"]; link.Href != "data:text/plain;base64,SGVsbG8=" || link.Rel != "enclosure" {
+		t.Errorf("unexpected task link %+v", task.Links)
+	}
+	if rel := task.RelatedTo["project@example.com"]; !rel.Relation["parent"] {
+		t.Errorf("unexpected task relations %+v", task.RelatedTo)
+	}
+}
+
+func TestJSCalendarEvent_RoundTrip(t *testing.T) {
+	data, err := ParseICalendar(jsCalendarSeriesICS)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	master := data.Events[0]
+	exceptions := map[string]*ParsedEvent{formatICalTime(*data.Events[1].RecurrenceID): &data.Events[1]}
+
+	obj, err := EventToJSCalendar(master, exceptions)
+	if err != nil {
+		t.Fatalf("EventToJSCalendar failed: %v", err)
+	}
+	// Go through JSON as a downstream service would.
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var decoded JSEvent
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	event, gotExceptions, err := JSCalendarToEvent(&decoded)
+	if err != nil {
+		t.Fatalf("JSCalendarToEvent failed: %v", err)
+	}
+
+	if !event.DTStart.Equal(*master.DTStart) || event.DTStart.Location().String() != "Europe/London" || !event.DTEnd.Equal(*master.DTEnd) {
+		t.Errorf("unexpected times %v - %v", event.DTStart, event.DTEnd)
+	}
+	if event.RecurrenceRule != "FREQ=WEEKLY;COUNT=4;BYDAY=MO,-1FR" {
+		t.Errorf("unexpected RRULE %q", event.RecurrenceRule)
+	}
+	if event.Summary != master.Summary || event.Location != master.Location || !reflect.DeepEqual(event.GeoLocation, master.GeoLocation) {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.Status != "CONFIRMED" || event.Transparency != "OPAQUE" || event.Class != "CONFIDENTIAL" || event.URL != master.URL {
+		t.Errorf("unexpected status %q, transparency %q, class %q or URL %q", event.Status, event.Transparency, event.Class, event.URL)
+	}
+	if want := []string{"Daily", "Work"}; !reflect.DeepEqual(event.Categories, want) {
+		t.Errorf("expected categories %v, got %v", want, event.Categories)
+	}
+	if len(event.Conferences) != 1 || !reflect.DeepEqual(event.Conferences[0].Features, []string{"AUDIO", "VIDEO"}) {
+		t.Errorf("unexpected conferences %+v", event.Conferences)
+	}
+	if event.Organizer.Value != "mailto:alice@example.com" || event.Organizer.CN != "Alice" {
+		t.Errorf("unexpected organizer %+v", event.Organizer)
+	}
+	expectedAttendees := []ParsedAttendee{
+		{Value: "mailto:alice@example.com", CN: "Alice", Email: "alice@example.com", Role: "CHAIR", PartStat: "ACCEPTED"},
+		{Value: "mailto:bob@example.com", CN: "Bob", Email: "bob@example.com", Role: "OPT-PARTICIPANT", PartStat: "NEEDS-ACTION", RSVP: true, DelegatedTo: "mailto:carol@example.com"},
+		{Value: "mailto:carol@example.com", CN: "Carol", Email: "carol@example.com", Role: "REQ-PARTICIPANT", PartStat: "ACCEPTED", DelegatedFrom: "mailto:bob@example.com"},
+	}
+	if !reflect.DeepEqual(event.Attendees, expectedAttendees) {
+		t.Errorf("expected attendees %+v, got %+v", expectedAttendees, event.Attendees)
+	}
+	if len(event.Alarms) != 1 || event.Alarms[0].Action != "DISPLAY" || event.Alarms[0].Trigger != "-PT10M" || event.Alarms[0].Description != "Standup" {
+		t.Errorf("unexpected alarms %+v", event.Alarms)
+	}
+	if len(event.ExceptionDates) != 1 || !event.ExceptionDates[0].Equal(master.ExceptionDates[0]) {
+		t.Errorf("unexpected EXDATEs %v", event.ExceptionDates)
+	}
+
+	// Both representations must expand to the same occurrences.
+	start, end := mustParseTime("20240101T000000Z"), mustParseTime("20240201T000000Z")
+	want, err := ExpandEventWithExceptions(master, exceptions, start, end)
+	if err != nil {
+		t.Fatalf("ExpandEventWithExceptions failed: %v", err)
+	}
+	got, err := ExpandEventWithExceptions(event, gotExceptions, start, end)
+	if err != nil {
+		t.Fatalf("ExpandEventWithExceptions failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d occurrences, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !got[i].DTStart.Equal(*want[i].DTStart) || !got[i].DTEnd.Equal(*want[i].DTEnd) || got[i].Summary != want[i].Summary {
+			t.Errorf("occurrence %d: expected %v %q, got %v %q", i, want[i].DTStart, want[i].Summary, got[i].DTStart, got[i].Summary)
+		}
+	}
+}
+
+func TestJSCalendarToEvent(t *testing.T) {
+	// Adapted from the recurring event with overrides in RFC 8984 section 6.5.
+	input := `{
+	  "@type": "Event",
+	  "uid": "a8df6573-0474-496d-8496-033ad45d7fea",
+	  "title": "Calculus I",
+	  "start": "2020-01-08T09:00:00",
+	  "timeZone": "Europe/London",
+	  "duration": "PT1H30M",
+	  "locations": {
+	    "mlab": {"@type": "Location", "title": "Math lab room 1", "name": "Math lab room 1"}
+	  },
+	  "recurrenceRules": [{
+	    "@type": "RecurrenceRule",
+	    "frequency": "weekly",
+	    "until": "2020-06-24T09:00:00"
+	  }],
+	  "recurrenceOverrides": {
+	    "2020-01-07T14:00:00": {"title": "Introduction to Calculus I (optional)"},
+	    "2020-04-01T09:00:00": {"excluded": true},
+	    "2020-06-25T09:00:00": {"title": "Calculus I Exam", "start": "2020-06-25T10:00:00", "duration": "PT2H", "locations/mlab/name": "Exam hall"}
+	  },
+	  "alerts": {
+	    "a": {"@type": "Alert", "trigger": {"@type": "AbsoluteTrigger", "when": "2020-01-08T08:00:00Z"}, "action": "email"}
+	  },
+	  "participants": {
+	    "dG9tQGZvb2Jhci5xlLmNvbQ": {"@type": "Participant", "name": "Tom", "email": "tom@example.com", "roles": {"owner": true}}
+	  }
+	}`
+
+	var obj JSEvent
+	if err := json.Unmarshal([]byte(input), &obj); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	event, exceptions, err := JSCalendarToEvent(&obj)
+	if err != nil {
+		t.Fatalf("JSCalendarToEvent failed: %v", err)
+	}
+
+	london, _ := time.LoadLocation("Europe/London")
+	if want := time.Date(2020, 1, 8, 9, 0, 0, 0, london); !event.DTStart.Equal(want) || !event.DTEnd.Equal(want.Add(90*time.Minute)) {
+		t.Errorf("unexpected times %v - %v", event.DTStart, event.DTEnd)
+	}
+	if event.RecurrenceRule != "FREQ=WEEKLY;UNTIL=20200624T080000Z" {
+		t.Errorf("unexpected RRULE %q", event.RecurrenceRule)
+	}
+	if event.Location != "Math lab room 1" || event.Organizer.Value != "mailto:tom@example.com" || len(event.Attendees) != 0 {
+		t.Errorf("unexpected location %q, organizer %+v or attendees %+v", event.Location, event.Organizer, event.Attendees)
+	}
+	if len(event.Alarms) != 1 || event.Alarms[0].Action != "EMAIL" || event.Alarms[0].Trigger != "20200108T080000Z" {
+		t.Errorf("unexpected alarms %+v", event.Alarms)
+	}
+	if len(event.ExceptionDates) != 1 || !event.ExceptionDates[0].Equal(time.Date(2020, 4, 1, 9, 0, 0, 0, london)) {
+		t.Errorf("unexpected EXDATEs %v", event.ExceptionDates)
+	}
+
+	if len(exceptions) != 2 {
+		t.Fatalf("expected 2 exceptions, got %d", len(exceptions))
+	}
+	exam := exceptions["20200625T080000Z"]
+	if exam == nil {
+		t.Fatalf("missing exam override in %v", exceptions)
+	}
+	if exam.Summary != "Calculus I Exam" || exam.Location != "Exam hall" || exam.RecurrenceID == nil || exam.RecurrenceRule != "" {
+		t.Errorf("unexpected exam override %+v", exam)
+	}
+	if want := time.Date(2020, 6, 25, 10, 0, 0, 0, london); !exam.DTStart.Equal(want) || !exam.DTEnd.Equal(want.Add(2*time.Hour)) {
+		t.Errorf("unexpected exam times %v - %v", exam.DTStart, exam.DTEnd)
+	}
+	if intro := exceptions["20200107T140000Z"]; intro == nil || intro.Summary != "Introduction to Calculus I (optional)" || !intro.DTStart.Equal(*intro.RecurrenceID) {
+		t.Errorf("unexpected intro override %+v", intro)
+	}
+}
+
+func TestJSCalendarEvent_AlertRelativeToEnd(t *testing.T) {
+	start := mustParseTime("20240115T090000Z")
+	end := start.Add(time.Hour)
+	event := ParsedEvent{
+		UID:     "review",
+		Summary: "Review",
+		DTStart: &start,
+		DTEnd:   &end,
+		Alarms: []ParsedAlarm{
+			{Action: "DISPLAY", Description: "Review", Trigger: "-PT5M", TriggerRelated: "END"},
+			{Action: "DISPLAY", Description: "Review", Trigger: "-PT10M"},
+		},
+	}
+
+	obj, err := EventToJSCalendar(event, nil)
+	if err != nil {
+		t.Fatalf("EventToJSCalendar failed: %v", err)
+	}
+	if trigger := obj.Alerts["1"].Trigger; trigger.Offset != "-PT5M" || trigger.RelativeTo != "end" {
+		t.Errorf("expected an offset relative to the end, got %+v", trigger)
+	}
+	if trigger := obj.Alerts["2"].Trigger; trigger.Offset != "-PT10M" || trigger.RelativeTo != "" {
+		t.Errorf("expected an offset relative to the start, got %+v", trigger)
+	}
+
+	back, _, err := JSCalendarToEvent(obj)
+	if err != nil {
+		t.Fatalf("JSCalendarToEvent failed: %v", err)
+	}
+	if len(back.Alarms) != 2 || back.Alarms[0].TriggerRelated != "END" || back.Alarms[1].TriggerRelated != "" {
+		t.Errorf("unexpected alarms %+v", back.Alarms)
+	}
+}
+
+func TestJSCalendarEvent_AllDayAndFloating(t *testing.T) {
+	start, end := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)
+	allDay := ParsedEvent{UID: "holiday", DTStart: &start, DTEnd: &end, AllDay: true, RecurrenceRule: "FREQ=YEARLY;UNTIL=20240301"}
+
+	obj, err := EventToJSCalendar(allDay, nil)
+	if err != nil {
+		t.Fatalf("EventToJSCalendar failed: %v", err)
+	}
+	if obj.Start != "2024-01-15T00:00:00" || obj.TimeZone != "" || !obj.ShowWithoutTime || obj.Duration != "P2D" {
+		t.Errorf("unexpected all-day event %+v", obj)
+	}
+	event, _, err := JSCalendarToEvent(obj)
+	if err != nil {
+		t.Fatalf("JSCalendarToEvent failed: %v", err)
+	}
+	if !event.AllDay || event.Floating || !event.DTStart.Equal(start) || !event.DTEnd.Equal(end) || event.RecurrenceRule != "FREQ=YEARLY;UNTIL=20240301" {
+		t.Errorf("unexpected all-day round trip %+v", event)
+	}
+
+	floating := ParsedEvent{UID: "floating", DTStart: &start, Floating: true, Duration: "PT1H"}
+	obj, err = EventToJSCalendar(floating, nil)
+	if err != nil {
+		t.Fatalf("EventToJSCalendar failed: %v", err)
+	}
+	if obj.TimeZone != "" || obj.ShowWithoutTime || obj.Duration != "PT1H" {
+		t.Errorf("unexpected floating event %+v", obj)
+	}
+	event, _, err = JSCalendarToEvent(obj)
+	if err != nil {
+		t.Fatalf("JSCalendarToEvent failed: %v", err)
+	}
+	if !event.Floating || event.AllDay || !event.DTEnd.Equal(start.Add(time.Hour)) {
+		t.Errorf("unexpected floating round trip %+v", event)
+	}
+}
+
+func TestJSCalendarTodo_RoundTrip(t *testing.T) {
+	data, err := ParseICalendar(jsCalendarSeriesICS)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	original := data.Todos[0]
+
+	obj, err := TodoToJSCalendar(original)
+	if err != nil {
+		t.Fatalf("TodoToJSCalendar failed: %v", err)
+	}
+	todo, err := JSCalendarToTodo(obj)
+	if err != nil {
+		t.Fatalf("JSCalendarToTodo failed: %v", err)
+	}
+
+	// JSCalendar has no DTSTAMP; everything else in the todo survives.
+	todo.DTStamp = original.DTStamp
+	want, err := EncodeICalendar(&ParsedCalendarData{Todos: []ParsedTodo{original}})
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+	got, err := EncodeICalendar(&ParsedCalendarData{Todos: []ParsedTodo{todo}})
+	if err != nil {
+		t.Fatalf("EncodeICalendar failed: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("round trip mismatch\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestJSCalendarTodo_DateAndFloating(t *testing.T) {
+	data, err := ParseICalendar("BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VTODO\r\nUID:dated\r\nDUE;VALUE=DATE:20240415\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:floating\r\nDUE:20240310T180000\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n")
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	for _, original := range data.Todos {
+		obj, err := TodoToJSCalendar(original)
+		if err != nil {
+			t.Fatalf("TodoToJSCalendar failed: %v", err)
+		}
+		if obj.TimeZone != "" || obj.ShowWithoutTime != original.AllDay {
+			t.Errorf("%s: expected no timeZone and showWithoutTime %v, got %q and %v", original.UID, original.AllDay, obj.TimeZone, obj.ShowWithoutTime)
+		}
+		todo, err := JSCalendarToTodo(obj)
+		if err != nil {
+			t.Fatalf("JSCalendarToTodo failed: %v", err)
+		}
+		want, err := EncodeICalendar(&ParsedCalendarData{Todos: []ParsedTodo{original}})
+		if err != nil {
+			t.Fatalf("EncodeICalendar failed: %v", err)
+		}
+		got, err := EncodeICalendar(&ParsedCalendarData{Todos: []ParsedTodo{todo}})
+		if err != nil {
+			t.Fatalf("EncodeICalendar failed: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s: round trip mismatch\nwant:\n%s\ngot:\n%s", original.UID, want, got)
+		}
+	}
+}
+
+func TestLocalizeJSEvent(t *testing.T) {
+	obj := &JSEvent{
+		Type:  "Event",
+		UID:   "localized",
+		Title: "Team lunch",
+		Locations: map[string]JSLocation{
+			"1": {Type: "Location", Name: "Canteen"},
+		},
+		Localizations: map[string]JSPatch{
+			"de": {"title": "Teamessen", "locations/1/name": "Kantine"},
+		},
+	}
+
+	localized, err := LocalizeJSEvent(obj, "de")
+	if err != nil {
+		t.Fatalf("LocalizeJSEvent failed: %v", err)
+	}
+	if localized.Title != "Teamessen" || localized.Locations["1"].Name != "Kantine" || localized.Locale != "de" || localized.Localizations != nil {
+		t.Errorf("unexpected localized event %+v", localized)
+	}
+	if obj.Title != "Team lunch" || obj.Locations["1"].Name != "Canteen" {
+		t.Errorf("LocalizeJSEvent modified its input: %+v", obj)
+	}
+
+	unchanged, err := LocalizeJSEvent(obj, "fr")
+	if err != nil {
+		t.Fatalf("LocalizeJSEvent failed: %v", err)
+	}
+	if unchanged.Title != "Team lunch" {
+		t.Errorf("expected no localization for fr, got %+v", unchanged)
+	}
+}
+
+func TestJSCalendar_Invalid(t *testing.T) {
+	start := mustParseTime("20240115T100000Z")
+	if _, err := EventToJSCalendar(ParsedEvent{UID: "no-start"}, nil); err == nil {
+		t.Error("expected an error for an event without DTSTART")
+	}
+	if _, err := EventToJSCalendar(ParsedEvent{DTStart: &start}, nil); err == nil {
+		t.Error("expected an error for an event without UID")
+	}
+	if _, err := TodoToJSCalendar(ParsedTodo{}); err == nil {
+		t.Error("expected an error for a todo without UID")
+	}
+
+	tests := []struct {
+		name string
+		obj  JSEvent
+	}{
+		{"missing start", JSEvent{UID: "x"}},
+		{"bad start", JSEvent{UID: "x", Start: "yesterday"}},
+		{"unknown time zone", JSEvent{UID: "x", Start: "2024-01-15T10:00:00", TimeZone: "Mars/Olympus_Mons"}},
+		{"bad duration", JSEvent{UID: "x", Start: "2024-01-15T10:00:00", Duration: "1H"}},
+		{"bad override", JSEvent{UID: "x", Start: "2024-01-15T10:00:00", RecurrenceOverrides: map[string]JSPatch{"tomorrow": {"title": "y"}}}},
+		{"bad patch", JSEvent{UID: "x", Start: "2024-01-15T10:00:00", Title: "t", RecurrenceOverrides: map[string]JSPatch{"2024-01-16T10:00:00": {"title/en": "y"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := JSCalendarToEvent(&tt.obj); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package caldav
+
+// EventCoordinates returns the coordinates of event's location without
+// geocoding the free-text LOCATION. Apple's structured location is preferred,
+// then the first VLOCATION with coordinates, then the GEO property. ok is
+// false when none of them has coordinates.
+func EventCoordinates(event *ParsedEvent) (geo GeoLocation, ok bool) {
+	if event == nil {
+		return GeoLocation{}, false
+	}
+	if event.StructuredLocation != nil && event.StructuredLocation.Geo != nil {
+		return *event.StructuredLocation.Geo, true
+	}
+	for _, location := range event.Locations {
+		if location.Geo != nil {
+			return *location.Geo, true
+		}
+	}
+	if event.GeoLocation != nil {
+		return *event.GeoLocation, true
+	}
+	return GeoLocation{}, false
+}
@@ -0,0 +1,44 @@
+package caldav
+
+import "testing"
+
+func TestEventCoordinates(t *testing.T) {
+	apple := &GeoLocation{Latitude: 37.331741, Longitude: -122.030333}
+	vlocation := &GeoLocation{Latitude: 51.5074, Longitude: -0.1278}
+	geo := &GeoLocation{Latitude: 48.8566, Longitude: 2.3522}
+
+	tests := []struct {
+		name  string
+		event *ParsedEvent
+		want  *GeoLocation
+	}{
+		{"nil event", nil, nil},
+		{"no coordinates", &ParsedEvent{Location: "Room 1"}, nil},
+		{"GEO only", &ParsedEvent{GeoLocation: geo}, geo},
+		{"VLOCATION before GEO", &ParsedEvent{
+			GeoLocation: geo,
+			Locations:   []ParsedLocation{{Name: "Lobby"}, {Name: "Office", Geo: vlocation}},
+		}, vlocation},
+		{"Apple structured location first", &ParsedEvent{
+			GeoLocation:        geo,
+			Locations:          []ParsedLocation{{Geo: vlocation}},
+			StructuredLocation: &ParsedLocation{Name: "Apple Park", Geo: apple},
+		}, apple},
+		{"Apple location without coordinates", &ParsedEvent{
+			GeoLocation:        geo,
+			StructuredLocation: &ParsedLocation{Name: "Somewhere"},
+		}, geo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EventCoordinates(tt.event)
+			if ok != (tt.want != nil) {
+				t.Fatalf("expected ok %v, got %v", tt.want != nil, ok)
+			}
+			if ok && got != *tt.want {
+				t.Errorf("expected %+v, got %+v", *tt.want, got)
+			}
+		})
+	}
+}
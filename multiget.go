@@ -0,0 +1,90 @@
+package caldav
+
+import (
+	"context"
+	"io"
+)
+
+// MultiGet fetches the calendar objects at hrefs from the calendar at
+// calendarHref with calendar-multiget REPORTs, sending at most the client's
+// batch size of hrefs per request. props defaults to getetag and
+// calendar-data; iCalendar property names in props limit calendar-data as
+// they do in CalendarQuery.Properties.
+//
+// Hrefs the server reports as missing (or otherwise failed) do not fail the
+// call: the objects that were found are returned together with a
+// *MultiStatusError whose Responses list each failed href and its status.
+func (c *CalDAVClient) MultiGet(ctx context.Context, calendarHref string, hrefs []string, props ...string) ([]CalendarObject, error) {
+	if len(hrefs) == 0 {
+		return nil, nil
+	}
+	if len(props) == 0 {
+		props = []string{"getetag", "calendar-data"}
+	}
+
+	size := c.batchSize
+	if size <= 0 {
+		size = len(hrefs)
+	}
+
+	objects := make([]CalendarObject, 0, len(hrefs))
+	var failed []ErrorResponse
+
+	for start := 0; start < len(hrefs); start += size {
+		end := start + size
+		if end > len(hrefs) {
+			end = len(hrefs)
+		}
+
+		msResp, err := c.multiGetChunk(ctx, calendarHref, hrefs[start:end], props)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, extractCalendarObjectsFromResponseWithOptions(msResp, c.autoParsing)...)
+
+		for _, r := range msResp.Responses {
+			if code := parseStatusCode(r.Status); code != 0 && (code < 200 || code > 299) {
+				failed = append(failed, ErrorResponse{
+					Href:       r.Href,
+					StatusCode: code,
+					Error:      newCalDAVError("multiget", code, r.Href),
+				})
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		multiErr := &MultiStatusError{
+			Op:           "multiget",
+			SuccessCount: len(objects),
+			Responses:    failed,
+		}
+		for _, f := range failed {
+			multiErr.Errors = append(multiErr.Errors, f.Error)
+		}
+		return objects, multiErr
+	}
+
+	return objects, nil
+}
+
+func (c *CalDAVClient) multiGetChunk(ctx context.Context, calendarHref string, hrefs []string, props []string) (*MultiStatusResponse, error) {
+	resp, err := c.report(ctx, calendarHref, buildCalendarMultigetXML(hrefs, props))
+	if err != nil {
+		return nil, wrapError("multiget.execute", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 207 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newCalDAVError("multiget", resp.StatusCode, string(body))
+	}
+
+	msResp, err := parseMultiStatusResponse(resp.Body)
+	if err != nil {
+		return nil, wrapErrorWithType("multiget.parse", ErrorTypeInvalidResponse, err)
+	}
+
+	return msResp, nil
+}
@@ -0,0 +1,133 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newMultiGetServer(t *testing.T, missing map[string]bool, requests *[][]string) *httptest.Server {
+	hrefPattern := regexp.MustCompile(`<D:href>([^<]+)</D:href>`)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "REPORT" {
+			t.Errorf("expected method REPORT, got %s", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "<C:calendar-multiget") {
+			t.Errorf("expected calendar-multiget body, got %s", body)
+		}
+
+		var hrefs []string
+		var sb strings.Builder
+		sb.WriteString(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+		for _, m := range hrefPattern.FindAllStringSubmatch(string(body), -1) {
+			href := m[1]
+			hrefs = append(hrefs, href)
+			if missing[href] {
+				fmt.Fprintf(&sb, `<D:response><D:href>%s</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>`, href)
+				continue
+			}
+			uid := strings.TrimSuffix(href[strings.LastIndex(href, "/")+1:], ".ics")
+			fmt.Fprintf(&sb, `<D:response><D:href>%s</D:href><D:propstat><D:status>HTTP/1.1 200 OK</D:status><D:prop>`+
+				`<D:getetag>"etag-%s"</D:getetag><C:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test//EN
+BEGIN:VEVENT
+UID:%s
+SUMMARY:Event %s
+DTSTART:20250115T140000Z
+END:VEVENT
+END:VCALENDAR</C:calendar-data></D:prop></D:propstat></D:response>`, href, uid, uid, uid)
+		}
+		sb.WriteString(`</D:multistatus>`)
+		*requests = append(*requests, hrefs)
+
+		w.WriteHeader(207)
+		_, _ = w.Write([]byte(sb.String()))
+	}))
+}
+
+func TestMultiGet(t *testing.T) {
+	var requests [][]string
+	server := newMultiGetServer(t, nil, &requests)
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+	client.SetBatchSize(2)
+
+	hrefs := []string{"/cal/a.ics", "/cal/b.ics", "/cal/c.ics"}
+	objects, err := client.MultiGet(context.Background(), "/cal/", hrefs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requests) != 2 || len(requests[0]) != 2 || len(requests[1]) != 1 {
+		t.Fatalf("expected hrefs chunked as [2 1], got %v", requests)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objects))
+	}
+	for i, obj := range objects {
+		if obj.Href != hrefs[i] {
+			t.Errorf("object %d: expected href %s, got %s", i, hrefs[i], obj.Href)
+		}
+		if obj.ETag == "" || obj.CalendarData == "" || obj.UID == "" {
+			t.Errorf("object %d: expected ETag, calendar data and UID, got %+v", i, obj)
+		}
+	}
+}
+
+func TestMultiGet_NotFound(t *testing.T) {
+	var requests [][]string
+	server := newMultiGetServer(t, map[string]bool{"/cal/gone.ics": true}, &requests)
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	objects, err := client.MultiGet(context.Background(), "/cal/", []string{"/cal/a.ics", "/cal/gone.ics"})
+	if len(objects) != 1 || objects[0].UID != "a" {
+		t.Fatalf("expected the found object to be returned, got %+v", objects)
+	}
+
+	var multiErr *MultiStatusError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("expected *MultiStatusError, got %v", err)
+	}
+	if len(multiErr.Responses) != 1 || multiErr.Responses[0].Href != "/cal/gone.ics" || multiErr.Responses[0].StatusCode != 404 {
+		t.Errorf("expected a 404 for /cal/gone.ics, got %+v", multiErr.Responses)
+	}
+	if !IsNotFound(multiErr.Errors[0]) {
+		t.Errorf("expected a not-found error, got %v", multiErr.Errors[0])
+	}
+	if multiErr.AllFailed() {
+		t.Error("expected a partial failure")
+	}
+}
+
+func TestBuildCalendarMultigetXML(t *testing.T) {
+	xml := string(buildCalendarMultigetXML([]string{"/cal/a&b.ics"}, []string{"getetag", "calendar-data"}))
+
+	for _, want := range []string{
+		`<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"`,
+		`<D:prop><D:getetag/><C:calendar-data/></D:prop>`,
+		`<D:href>/cal/a&amp;b.ics</D:href>`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("expected %s in %s", want, xml)
+		}
+	}
+
+	projected := string(buildCalendarMultigetXML([]string{"/cal/a.ics"}, []string{"getetag", "SUMMARY"}))
+	if !strings.Contains(projected, `<C:comp name="VEVENT"><C:prop name="UID"/><C:prop name="RECURRENCE-ID"/><C:prop name="DTSTART"/><C:prop name="SUMMARY"/></C:comp>`) {
+		t.Errorf("expected a VEVENT projection in %s", projected)
+	}
+}
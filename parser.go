@@ -32,7 +32,7 @@ type xmlPropData struct {
 	CalendarOrder                 string                `xml:"calendar-order,omitempty"`
 	GetCTag                       string                `xml:"getctag,omitempty"`
 	GetETag                       string                `xml:"getetag,omitempty"`
-	CalendarData                  string                `xml:"calendar-data,omitempty"`
+	CalendarData                  xmlCalendarData       `xml:"calendar-data,omitempty"`
 	GetContentType                string                `xml:"getcontenttype,omitempty"`
 	CurrentUserPrincipal          xmlHref               `xml:"current-user-principal,omitempty"`
 	CalendarHomeSet               xmlHref               `xml:"calendar-home-set,omitempty"`
@@ -52,6 +52,49 @@ type xmlPropData struct {
 	GetContentLength              string                `xml:"getcontentlength,omitempty"`
 	CreationDate                  string                `xml:"creationdate,omitempty"`
 	GetLastModified               string                `xml:"getlastmodified,omitempty"`
+	ScheduleInboxURL              xmlHref               `xml:"schedule-inbox-URL,omitempty"`
+	ScheduleOutboxURL             xmlHref               `xml:"schedule-outbox-URL,omitempty"`
+	CalendarUserAddressSet        xmlHrefSet            `xml:"calendar-user-address-set,omitempty"`
+	CalendarAvailability          string                `xml:"calendar-availability,omitempty"`
+	SupportedCalendarData         xmlCalendarDataTypes  `xml:"supported-calendar-data,omitempty"`
+}
+
+// xmlCalendarData is a calendar-data element. iCalendar arrives as text; xCal
+// may also arrive as nested elements, which only InnerXML holds.
+type xmlCalendarData struct {
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+// calendarDataText returns the content of a calendar-data element as
+// iCalendar, converting xCal. Data that cannot be converted is returned as
+// received, so that parsing it reports the problem.
+func calendarDataText(data xmlCalendarData) string {
+	text := data.Text
+	if strings.TrimSpace(text) == "" {
+		if !strings.Contains(data.InnerXML, "<") {
+			return text
+		}
+		text = data.InnerXML
+	}
+	if !strings.HasPrefix(strings.TrimSpace(text), "<") {
+		return text
+	}
+
+	icalData, err := xcalToICal([]byte(text))
+	if err != nil {
+		return text
+	}
+	return icalData
+}
+
+type xmlCalendarDataTypes struct {
+	Types []xmlCalendarDataType `xml:"calendar-data"`
+}
+
+type xmlCalendarDataType struct {
+	ContentType string `xml:"content-type,attr"`
+	Version     string `xml:"version,attr"`
 }
 
 type xmlResourceType struct {
@@ -64,6 +107,10 @@ type xmlHref struct {
 	Href string `xml:"href,omitempty"`
 }
 
+type xmlHrefSet struct {
+	Hrefs []string `xml:"href"`
+}
+
 type xmlComponentSet struct {
 	Comps []xmlComp `xml:"comp"`
 }
@@ -282,7 +329,7 @@ func convertPropData(xmlProp xmlPropData) PropstatProp {
 		CalendarColor:        xmlProp.CalendarColor,
 		CTag:                 xmlProp.GetCTag,
 		ETag:                 xmlProp.GetETag,
-		CalendarData:         xmlProp.CalendarData,
+		CalendarData:         calendarDataText(xmlProp.CalendarData),
 		CurrentUserPrincipal: xmlProp.CurrentUserPrincipal.Href,
 		CalendarHomeSet:      xmlProp.CalendarHomeSet.Href,
 		Owner:                xmlProp.Owner.Href,
@@ -293,13 +340,20 @@ func convertPropData(xmlProp xmlPropData) PropstatProp {
 		ContentType:          xmlProp.GetContentType,
 		CreationDate:         xmlProp.CreationDate,
 		LastModified:         xmlProp.GetLastModified,
+		ScheduleInboxURL:     xmlProp.ScheduleInboxURL.Href,
+		ScheduleOutboxURL:    xmlProp.ScheduleOutboxURL.Href,
+		CalendarAvailability: xmlProp.CalendarAvailability,
 	}
+	prop.CalendarUserAddressSet = xmlProp.CalendarUserAddressSet.Hrefs
 
 	parseNumericFields(xmlProp, &prop)
 	prop.ResourceType = parseResourceTypes(xmlProp)
 	prop.SupportedCalendarComponentSet = parseSupportedComponents(xmlProp)
 	prop.CurrentUserPrivilegeSet = parsePrivilegeSet(xmlProp)
 	prop.SupportedReports = parseSupportedReports(xmlProp)
+	for _, dataType := range xmlProp.SupportedCalendarData.Types {
+		prop.SupportedCalendarData = append(prop.SupportedCalendarData, dataType.ContentType)
+	}
 
 	return prop
 }
@@ -336,6 +390,7 @@ func extractCalendarsFromResponse(resp *MultiStatusResponse) []Calendar {
 						CurrentUserPrivilegeSet: ps.Prop.CurrentUserPrivilegeSet,
 						Source:                  ps.Prop.Source,
 						SupportedReports:        ps.Prop.SupportedReports,
+						SupportedCalendarData:   ps.Prop.SupportedCalendarData,
 						Quota: CalendarQuota{
 							QuotaUsedBytes:      ps.Prop.QuotaUsedBytes,
 							QuotaAvailableBytes: ps.Prop.QuotaAvailableBytes,
@@ -457,6 +512,12 @@ func parseCalendarTimeFields(obj *CalendarObject, line string) {
 				if t := parseICalTime(line); t != nil {
 					*tf.field = t
 				}
+				if tf.field == &obj.StartTime {
+					if value, params, ok := splitPropertyLine(line); ok {
+						obj.AllDay = isDateValue(value, params)
+						obj.Floating = isFloatingValue(value, params)
+					}
+				}
 				return
 			}
 		}
@@ -506,3 +567,40 @@ func extractCalendarHomeSetFromResponse(resp *MultiStatusResponse) string {
 	}
 	return ""
 }
+
+type xmlScheduleResponse struct {
+	XMLName   xml.Name                  `xml:"schedule-response"`
+	Responses []xmlScheduleResponseItem `xml:"response"`
+}
+
+type xmlScheduleResponseItem struct {
+	Recipient     xmlHref `xml:"recipient"`
+	RequestStatus string  `xml:"request-status"`
+	CalendarData  string  `xml:"calendar-data"`
+}
+
+func parseScheduleResponse(body io.Reader) ([]ScheduleResponse, error) {
+	var sr xmlScheduleResponse
+	if err := xml.NewDecoder(body).Decode(&sr); err != nil {
+		return nil, wrapErrorWithType("parse.schedule-response", ErrorTypeInvalidResponse, err)
+	}
+
+	responses := make([]ScheduleResponse, 0, len(sr.Responses))
+	for _, item := range sr.Responses {
+		response := ScheduleResponse{
+			Recipient:     strings.TrimSpace(item.Recipient.Href),
+			RequestStatus: strings.TrimSpace(item.RequestStatus),
+			CalendarData:  item.CalendarData,
+		}
+		if item.CalendarData != "" {
+			if data, err := ParseICalendar(item.CalendarData); err == nil {
+				for _, fb := range data.FreeBusy {
+					response.FreeBusy = append(response.FreeBusy, fb.FreeBusy...)
+				}
+			}
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
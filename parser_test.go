@@ -862,6 +862,16 @@ func TestParseICalTime_EdgeCases(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:  "Date value ignores TZID",
+			input: "DTSTART;TZID=America/Los_Angeles;VALUE=DATE:20251225",
+			checkFunc: func(t *testing.T, tm *time.Time) {
+				expected := time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC)
+				if tm == nil || !tm.Equal(expected) {
+					t.Errorf("expected %v, got %v", expected, tm)
+				}
+			},
+		},
 		{
 			name:  "Line without colon",
 			input: "NOTAVALIDLINE",
@@ -883,6 +893,35 @@ func TestParseICalTime_EdgeCases(t *testing.T) {
 	}
 }
 
+func TestParseCalendarData_AllDayAndFloating(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		allDay   bool
+		floating bool
+	}{
+		{"all-day", "DTSTART;VALUE=DATE:20251225", true, false},
+		{"date without VALUE", "DTSTART:20251225", true, false},
+		{"floating", "DTSTART:20251225T090000", false, true},
+		{"utc", "DTSTART:20251225T090000Z", false, false},
+		{"zoned", "DTSTART;TZID=Europe/London:20251225T090000", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj CalendarObject
+			parseCalendarData(&obj, "BEGIN:VEVENT\n"+tt.line+"\nEND:VEVENT")
+			if obj.StartTime == nil {
+				t.Fatal("expected StartTime to be parsed")
+			}
+			if obj.AllDay != tt.allDay || obj.Floating != tt.floating {
+				t.Errorf("expected AllDay=%v Floating=%v, got AllDay=%v Floating=%v",
+					tt.allDay, tt.floating, obj.AllDay, obj.Floating)
+			}
+		})
+	}
+}
+
 func TestReaderErrorHandling(t *testing.T) {
 	errReader := &errorReader{err: io.ErrUnexpectedEOF}
 
@@ -0,0 +1,366 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpdateRecurrenceInstanceAndFollowing changes the occurrence at recurrenceID
+// and every occurrence after it by splitting the series in two.
+func (c *CalDAVClient) UpdateRecurrenceInstanceAndFollowing(calendarPath string, instanceEvent *CalendarObject, recurrenceID time.Time) (*CalendarObject, error) {
+	return c.UpdateRecurrenceInstanceAndFollowingWithContext(context.Background(), calendarPath, instanceEvent, recurrenceID)
+}
+
+// UpdateRecurrenceInstanceAndFollowingWithContext ends the original series
+// before recurrenceID and creates a new series, with its own UID and resource,
+// that starts at instanceEvent.StartTime and carries the fields of
+// instanceEvent. The new series keeps the original RRULE unless instanceEvent
+// sets one, and inherits the EXDATEs and overrides from recurrenceID onwards,
+// shifted by the same amount as the start time. The new series is created
+// first; if the original resource then fails to update, with If-Match, the new
+// series is deleted again. It returns the new series.
+//
+// When recurrenceID is the first occurrence there is nothing to split off, so
+// the whole series is updated in place and returned instead.
+func (c *CalDAVClient) UpdateRecurrenceInstanceAndFollowingWithContext(ctx context.Context, calendarPath string, instanceEvent *CalendarObject, recurrenceID time.Time) (*CalendarObject, error) {
+	if instanceEvent == nil {
+		return nil, fmt.Errorf("instance event cannot be nil")
+	}
+	if instanceEvent.UID == "" {
+		return nil, fmt.Errorf("instance must have the same UID as the recurring event")
+	}
+
+	resource, data, err := c.fetchRecurringResource(ctx, calendarPath, instanceEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	base := lineParsedCalendarObject(resource.CalendarData)
+	if isFirstOccurrence(data, instanceEvent.UID, recurrenceID) {
+		applySeriesChanges(data, instanceEvent.UID, instanceEvent, base, recurrenceID)
+		return c.putSeries(ctx, resource.Href, recurringEventPath(calendarPath, instanceEvent), resource.ETag, false, data)
+	}
+
+	following, err := SplitRecurringSeries(data, instanceEvent.UID, recurrenceID, generateUID())
+	if err != nil {
+		return nil, err
+	}
+	applySeriesChanges(following, following.Events[0].UID, instanceEvent, base, recurrenceID)
+
+	uid := following.Events[0].UID
+	seriesURL := buildEventURL(c.baseURL, calendarPath, uid)
+	series, err := c.putSeries(ctx, seriesURL, buildEventPath(calendarPath, uid), "", true, following)
+	if err != nil {
+		return nil, fmt.Errorf("creating following series: %w", err)
+	}
+
+	if err := c.writeRecurringResource(ctx, resource, data, instanceEvent); err != nil {
+		// Without the truncated original both series would hold the
+		// following occurrences.
+		if deleteErr := c.deleteCalendarData(ctx, seriesURL, uid, series.ETag); deleteErr != nil {
+			return nil, fmt.Errorf("%w; removing the following series %s also failed: %v", err, series.Href, deleteErr)
+		}
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// putSeries writes the recurring event in data to eventURL, either creating it
+// with If-None-Match or replacing it with If-Match etag, and returns it with
+// href.
+func (c *CalDAVClient) putSeries(ctx context.Context, eventURL, href, etag string, create bool, data *ParsedCalendarData) (*CalendarObject, error) {
+	icalData, err := EncodeICalendar(data)
+	if err != nil {
+		return nil, fmt.Errorf("generating iCalendar data: %w", err)
+	}
+
+	var master ParsedEvent
+	for _, event := range data.Events {
+		if event.RecurrenceID == nil {
+			master = event
+			break
+		}
+	}
+
+	if create {
+		etag, err = c.createCalendarData(ctx, eventURL, master.UID, string(icalData))
+	} else {
+		etag, err = c.putCalendarData(ctx, eventURL, master.UID, string(icalData), etag)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &CalendarObject{
+		Href:           href,
+		ETag:           etag,
+		UID:            master.UID,
+		Summary:        master.Summary,
+		Description:    master.Description,
+		Location:       master.Location,
+		StartTime:      master.DTStart,
+		EndTime:        master.DTEnd,
+		AllDay:         master.AllDay,
+		Floating:       master.Floating,
+		RecurrenceRule: master.RecurrenceRule,
+		ExceptionDates: master.ExceptionDates,
+		CalendarData:   string(icalData),
+		ParsedData:     data,
+	}, nil
+}
+
+// DeleteRecurrenceInstanceAndFollowing deletes the occurrence at instanceDate
+// and every occurrence after it by ending the series before it.
+func (c *CalDAVClient) DeleteRecurrenceInstanceAndFollowing(calendarPath string, event *CalendarObject, instanceDate time.Time) error {
+	return c.DeleteRecurrenceInstanceAndFollowingWithContext(context.Background(), calendarPath, event, instanceDate)
+}
+
+// DeleteRecurrenceInstanceAndFollowingWithContext deletes the occurrence at
+// instanceDate and all later occurrences with the provided context. Overrides
+// of the deleted occurrences are removed from the resource. When instanceDate
+// is the first occurrence the whole resource is deleted, with If-Match.
+func (c *CalDAVClient) DeleteRecurrenceInstanceAndFollowingWithContext(ctx context.Context, calendarPath string, event *CalendarObject, instanceDate time.Time) error {
+	if event == nil || event.UID == "" {
+		return fmt.Errorf("event UID is required")
+	}
+
+	resource, data, err := c.fetchRecurringResource(ctx, calendarPath, event)
+	if err != nil {
+		return err
+	}
+
+	if isFirstOccurrence(data, event.UID, instanceDate) {
+		return c.deleteCalendarData(ctx, resource.Href, event.UID, resource.ETag)
+	}
+
+	if _, err := SplitRecurringSeries(data, event.UID, instanceDate, ""); err != nil {
+		return err
+	}
+
+	return c.writeRecurringResource(ctx, resource, data, event)
+}
+
+// UpdateRecurrenceRange writes a RECURRENCE-ID;RANGE=THISANDFUTURE override.
+func (c *CalDAVClient) UpdateRecurrenceRange(calendarPath string, instanceEvent *CalendarObject, recurrenceID time.Time) error {
+	return c.UpdateRecurrenceRangeWithContext(context.Background(), calendarPath, instanceEvent, recurrenceID)
+}
+
+// UpdateRecurrenceRangeWithContext stores instanceEvent as an override with
+// RANGE=THISANDFUTURE in the master resource, which applies the change to
+// recurrenceID and all later occurrences without splitting the series. Only
+// servers that implement the RANGE parameter understand such overrides; iCloud
+// does not, so use UpdateRecurrenceInstanceAndFollowing there.
+func (c *CalDAVClient) UpdateRecurrenceRangeWithContext(ctx context.Context, calendarPath string, instanceEvent *CalendarObject, recurrenceID time.Time) error {
+	if instanceEvent == nil {
+		return fmt.Errorf("instance event cannot be nil")
+	}
+	if instanceEvent.UID == "" {
+		return fmt.Errorf("instance must have the same UID as the recurring event")
+	}
+
+	resource, data, err := c.fetchRecurringResource(ctx, calendarPath, instanceEvent)
+	if err != nil {
+		return err
+	}
+
+	if err := setRecurrenceOverride(data, instanceEvent, lineParsedCalendarObject(resource.CalendarData), recurrenceID, "THISANDFUTURE"); err != nil {
+		return err
+	}
+
+	return c.writeRecurringResource(ctx, resource, data, instanceEvent)
+}
+
+// SplitRecurringSeries ends the recurring event uid in data before the
+// occurrence at splitAt and returns a new calendar holding a series with
+// newUID that continues from that occurrence. The original RRULE is truncated
+// with UNTIL, or its COUNT reduced, and the EXDATEs, RDATEs and overrides from
+// splitAt onwards move to the new series. When newUID is empty the following
+// occurrences are dropped instead.
+func SplitRecurringSeries(data *ParsedCalendarData, uid string, splitAt time.Time, newUID string) (*ParsedCalendarData, error) {
+	if data == nil {
+		return nil, newTypedError("recurrence.split", ErrorTypeValidation, "calendar data cannot be nil", nil)
+	}
+
+	masterIndex := -1
+	for i := range data.Events {
+		if data.Events[i].UID == uid && data.Events[i].RecurrenceID == nil {
+			masterIndex = i
+			break
+		}
+	}
+	if masterIndex == -1 {
+		return nil, fmt.Errorf("recurring event %s not found in calendar resource", uid)
+	}
+
+	master := &data.Events[masterIndex]
+	if master.RecurrenceRule == "" || master.DTStart == nil {
+		return nil, fmt.Errorf("event is not recurring")
+	}
+
+	rule, err := ParseRRule(master.RecurrenceRule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RRULE: %w", err)
+	}
+
+	if !master.AllDay && !master.Floating {
+		splitAt = splitAt.In(master.DTStart.Location())
+	}
+
+	if matches, err := ExpandRRule(rule, master.DTStart, splitAt, splitAt); err != nil || len(matches) == 0 {
+		return nil, fmt.Errorf("%s is not an occurrence of the recurring event", formatICalTime(splitAt))
+	}
+
+	// COUNT counts every generated occurrence, including those removed by
+	// EXDATE, so the occurrences are taken straight from the RRULE.
+	before, err := ExpandRRule(rule, master.DTStart, *master.DTStart, splitAt.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand RRULE: %w", err)
+	}
+	if len(before) == 0 {
+		return nil, fmt.Errorf("cannot split a series at its first occurrence")
+	}
+	if rule.Count > 0 && len(before) >= rule.Count {
+		return nil, fmt.Errorf("%s is not an occurrence of the recurring event", formatICalTime(splitAt))
+	}
+
+	following := *master
+	following.UID = newUID
+	start := splitAt
+	following.DTStart = &start
+	if master.DTEnd != nil {
+		end := start.Add(master.DTEnd.Sub(*master.DTStart))
+		following.DTEnd = &end
+	}
+
+	if rule.Count > 0 {
+		master.RecurrenceRule = withRRuleLimit(master.RecurrenceRule, "COUNT", strconv.Itoa(len(before)))
+		following.RecurrenceRule = withRRuleLimit(following.RecurrenceRule, "COUNT", strconv.Itoa(rule.Count-len(before)))
+	} else {
+		last := before[len(before)-1]
+		master.RecurrenceRule = withRRuleLimit(master.RecurrenceRule, "UNTIL", formatRRuleUntil(last, master.AllDay, master.Floating))
+	}
+
+	master.ExceptionDates, following.ExceptionDates = partitionTimes(master.ExceptionDates, splitAt)
+	master.RecurrenceDates, following.RecurrenceDates = partitionTimes(master.RecurrenceDates, splitAt)
+
+	result := &ParsedCalendarData{
+		Version:   data.Version,
+		ProdID:    data.ProdID,
+		CalScale:  data.CalScale,
+		TimeZones: append([]ParsedTimeZone(nil), data.TimeZones...),
+		Events:    []ParsedEvent{following},
+	}
+
+	kept := data.Events[:0]
+	for _, event := range data.Events {
+		if event.UID == uid && event.RecurrenceID != nil && !event.RecurrenceID.Before(splitAt) {
+			event.UID = newUID
+			result.Events = append(result.Events, event)
+			continue
+		}
+		kept = append(kept, event)
+	}
+	data.Events = kept
+
+	if newUID == "" {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// isFirstOccurrence reports whether at is the DTSTART of the recurring event
+// uid in data, before which a split would leave no occurrences.
+func isFirstOccurrence(data *ParsedCalendarData, uid string, at time.Time) bool {
+	for _, event := range data.Events {
+		if event.UID == uid && event.RecurrenceID == nil {
+			return event.DTStart != nil && event.DTStart.Equal(at)
+		}
+	}
+	return false
+}
+
+// applySeriesChanges applies the fields of instance to the recurring event uid
+// in data, which is either a whole series or the new series created by
+// SplitRecurringSeries starting at from. Only fields instance sets and that
+// differ from base, the resource as the line parser read it, are applied.
+// When the start time moves, EXDATEs, RDATEs and the RECURRENCE-IDs of the
+// overrides move with it.
+func applySeriesChanges(data *ParsedCalendarData, uid string, instance, base *CalendarObject, from time.Time) {
+	var shift time.Duration
+	if instance.StartTime != nil {
+		shift = instance.StartTime.Sub(from)
+	}
+
+	for i := range data.Events {
+		event := &data.Events[i]
+		if event.UID != uid {
+			continue
+		}
+		if event.RecurrenceID != nil {
+			recurrenceID := event.RecurrenceID.Add(shift)
+			event.RecurrenceID = &recurrenceID
+			continue
+		}
+
+		exDates, rDates := event.ExceptionDates, event.RecurrenceDates
+		applyCalendarObjectChanges(instance, base, event, true)
+		event.UID = uid
+		event.RecurrenceID = nil
+		event.ExceptionDates = shiftTimes(exDates, shift)
+		event.RecurrenceDates = shiftTimes(rDates, shift)
+	}
+}
+
+// withRRuleLimit replaces the COUNT and UNTIL parts of rrule with key=value,
+// keeping the other parts as written.
+func withRRuleLimit(rrule, key, value string) string {
+	var parts []string
+	for _, part := range strings.Split(rrule, ";") {
+		name := strings.ToUpper(strings.SplitN(part, "=", 2)[0])
+		if name == "COUNT" || name == "UNTIL" || part == "" {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(append(parts, key+"="+value), ";")
+}
+
+// formatRRuleUntil formats an UNTIL value with the same value type as DTSTART:
+// a DATE for all-day events, a local time for floating events and UTC otherwise.
+func formatRRuleUntil(t time.Time, allDay, floating bool) string {
+	switch {
+	case allDay:
+		return t.Format("20060102")
+	case floating:
+		return formatLocalICalTime(t)
+	default:
+		return formatICalTime(t)
+	}
+}
+
+// partitionTimes splits times into those before splitAt and the rest.
+func partitionTimes(times []time.Time, splitAt time.Time) (before, after []time.Time) {
+	for _, t := range times {
+		if t.Before(splitAt) {
+			before = append(before, t)
+		} else {
+			after = append(after, t)
+		}
+	}
+	return before, after
+}
+
+func shiftTimes(times []time.Time, shift time.Duration) []time.Time {
+	if shift == 0 || len(times) == 0 {
+		return times
+	}
+	shifted := make([]time.Time, len(times))
+	for i, t := range times {
+		shifted[i] = t.Add(shift)
+	}
+	return shifted
+}
@@ -0,0 +1,438 @@
+package caldav
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const splitSeriesICS = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Split//EN
+BEGIN:VEVENT
+UID:standup
+DTSTAMP:20240101T000000Z
+DTSTART:20240101T090000Z
+DTEND:20240101T091500Z
+RRULE:FREQ=DAILY;COUNT=10
+EXDATE:20240103T090000Z,20240107T090000Z
+SUMMARY:Stand-up
+DESCRIPTION:Yesterday\, today\, blockers
+LOCATION:Room 2
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+DTSTAMP:20240101T000000Z
+RECURRENCE-ID:20240102T090000Z
+DTSTART:20240102T100000Z
+DTEND:20240102T101500Z
+SUMMARY:Late stand-up
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+DTSTAMP:20240101T000000Z
+RECURRENCE-ID:20240108T090000Z
+DTSTART:20240108T110000Z
+DTEND:20240108T111500Z
+SUMMARY:Moved stand-up
+END:VEVENT
+END:VCALENDAR`
+
+func TestSplitRecurringSeries_Count(t *testing.T) {
+	data, err := ParseICalendar(splitSeriesICS)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	splitAt := time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)
+	following, err := SplitRecurringSeries(data, "standup", splitAt, "standup-2")
+	if err != nil {
+		t.Fatalf("SplitRecurringSeries failed: %v", err)
+	}
+
+	original := data.Events[0]
+	if original.RecurrenceRule != "FREQ=DAILY;COUNT=4" {
+		t.Errorf("original RRULE: got %q", original.RecurrenceRule)
+	}
+	if len(original.ExceptionDates) != 1 || !original.ExceptionDates[0].Equal(time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("original EXDATEs: got %v", original.ExceptionDates)
+	}
+	if len(data.Events) != 2 {
+		t.Errorf("expected the earlier override to stay with the original, got %d events", len(data.Events))
+	}
+
+	series := following.Events[0]
+	if series.UID != "standup-2" || series.RecurrenceRule != "FREQ=DAILY;COUNT=6" {
+		t.Errorf("unexpected following series: UID %q, RRULE %q", series.UID, series.RecurrenceRule)
+	}
+	if !series.DTStart.Equal(splitAt) || !series.DTEnd.Equal(splitAt.Add(15*time.Minute)) {
+		t.Errorf("unexpected following start/end: %v - %v", series.DTStart, series.DTEnd)
+	}
+	if len(series.ExceptionDates) != 1 || !series.ExceptionDates[0].Equal(time.Date(2024, 1, 7, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("following EXDATEs: got %v", series.ExceptionDates)
+	}
+	if len(following.Events) != 2 || following.Events[1].UID != "standup-2" || following.Events[1].Summary != "Moved stand-up" {
+		t.Errorf("expected the later override to move to the following series, got %+v", following.Events)
+	}
+
+	// Together the two series still describe the same occurrences.
+	rangeStart, rangeEnd := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	before, _ := ExpandEvents(data, rangeStart, rangeEnd)
+	after, _ := ExpandEvents(following, rangeStart, rangeEnd)
+	if got := len(before.Events) + len(after.Events); got != 8 {
+		t.Errorf("expected 8 occurrences across both series, got %d", got)
+	}
+}
+
+func TestSplitRecurringSeries_Until(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, london)
+	data := &ParsedCalendarData{Events: []ParsedEvent{{
+		UID:            "weekly",
+		DTStart:        &start,
+		RecurrenceRule: "FREQ=WEEKLY;BYDAY=MO;UNTIL=20241231T000000Z",
+	}}}
+
+	splitAt := time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+	following, err := SplitRecurringSeries(data, "weekly", splitAt, "weekly-2")
+	if err != nil {
+		t.Fatalf("SplitRecurringSeries failed: %v", err)
+	}
+
+	if got := data.Events[0].RecurrenceRule; got != "FREQ=WEEKLY;BYDAY=MO;UNTIL=20240325T090000Z" {
+		t.Errorf("original RRULE: got %q", got)
+	}
+	series := following.Events[0]
+	if series.RecurrenceRule != "FREQ=WEEKLY;BYDAY=MO;UNTIL=20241231T000000Z" {
+		t.Errorf("following RRULE: got %q", series.RecurrenceRule)
+	}
+	if series.DTStart.Location() != london || series.DTStart.Hour() != 9 {
+		t.Errorf("expected following series to start at 09:00 London time, got %v", series.DTStart)
+	}
+}
+
+func TestSplitRecurringSeries_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		splitAt time.Time
+	}{
+		{"first occurrence", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{"not an occurrence", time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)},
+		{"after the last occurrence", time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseICalendar(splitSeriesICS)
+			if err != nil {
+				t.Fatalf("ParseICalendar failed: %v", err)
+			}
+			if _, err := SplitRecurringSeries(data, "standup", tt.splitAt, "new"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestUpdateRecurrenceInstanceAndFollowing(t *testing.T) {
+	var updated, created string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/cal/standup.ics":
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(splitSeriesICS))
+		case r.Method == http.MethodPut && r.URL.Path == "/cal/standup.ics":
+			if got := r.Header.Get("If-Match"); got != `"v1"` {
+				t.Errorf("expected If-Match \"v1\", got %q", got)
+			}
+			updated = string(body)
+			w.Header().Set("ETag", `"v2"`)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPut:
+			if got := r.Header.Get("If-None-Match"); got != "*" {
+				t.Errorf("expected If-None-Match *, got %q", got)
+			}
+			created = string(body)
+			w.Header().Set("ETag", `"n1"`)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	instance := &CalendarObject{
+		UID:       "standup",
+		Summary:   "Stand-up (new room)",
+		StartTime: timePtr(time.Date(2024, 1, 5, 9, 30, 0, 0, time.UTC)),
+		EndTime:   timePtr(time.Date(2024, 1, 5, 9, 45, 0, 0, time.UTC)),
+	}
+
+	series, err := client.UpdateRecurrenceInstanceAndFollowing("/cal/", instance, time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("UpdateRecurrenceInstanceAndFollowing failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "RRULE:FREQ=DAILY;COUNT=4") {
+		t.Errorf("original series was not truncated:\n%s", updated)
+	}
+	if series.UID == "standup" || series.ETag != `"n1"` || series.Href != "/cal/"+series.UID+".ics" {
+		t.Errorf("unexpected new series: %+v", series)
+	}
+
+	parsed, err := ParseICalendar(created)
+	if err != nil {
+		t.Fatalf("new series does not parse: %v", err)
+	}
+	master := parsed.Events[0]
+	if master.Summary != "Stand-up (new room)" || master.RecurrenceRule != "FREQ=DAILY;COUNT=6" {
+		t.Errorf("unexpected new series master: %q %q", master.Summary, master.RecurrenceRule)
+	}
+	if master.Description != "Yesterday, today, blockers" || master.Location != "Room 2" {
+		t.Errorf("expected fields the instance leaves unset to carry over, got %q and %q", master.Description, master.Location)
+	}
+	if want := time.Date(2024, 1, 7, 9, 30, 0, 0, time.UTC); len(master.ExceptionDates) != 1 || !master.ExceptionDates[0].Equal(want) {
+		t.Errorf("expected EXDATE shifted to %v, got %v", want, master.ExceptionDates)
+	}
+	if want := time.Date(2024, 1, 8, 9, 30, 0, 0, time.UTC); len(parsed.Events) != 2 || !parsed.Events[1].RecurrenceID.Equal(want) {
+		t.Errorf("expected the carried-over override at %v, got %+v", want, parsed.Events)
+	}
+}
+
+func TestUpdateRecurrenceInstanceAndFollowing_RollsBack(t *testing.T) {
+	var created, deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(splitSeriesICS))
+		case r.Method == http.MethodPut && r.URL.Path == "/cal/standup.ics":
+			if created == "" {
+				t.Error("original series was written before the following series was created")
+			}
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case r.Method == http.MethodPut:
+			created = r.URL.Path
+			w.Header().Set("ETag", `"n1"`)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete:
+			if got := r.Header.Get("If-Match"); got != `"n1"` {
+				t.Errorf("expected If-Match \"n1\", got %q", got)
+			}
+			deleted = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	instance := &CalendarObject{UID: "standup", Summary: "Stand-up (new room)"}
+	_, err := client.UpdateRecurrenceInstanceAndFollowing("/cal/", instance, time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC))
+	var mismatch *ETagMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected ETagMismatchError, got %v", err)
+	}
+	if created == "" || deleted != created {
+		t.Errorf("expected the created series %q to be deleted, deleted %q", created, deleted)
+	}
+}
+
+func TestUpdateRecurrenceInstanceAndFollowing_FirstOccurrence(t *testing.T) {
+	var updated string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(splitSeriesICS))
+		case r.Method == http.MethodPut && r.URL.Path == "/cal/standup.ics":
+			if got := r.Header.Get("If-Match"); got != `"v1"` {
+				t.Errorf("expected If-Match \"v1\", got %q", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			updated = string(body)
+			w.Header().Set("ETag", `"v2"`)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	instance := &CalendarObject{
+		UID:       "standup",
+		Summary:   "Stand-up (new room)",
+		StartTime: timePtr(time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)),
+		EndTime:   timePtr(time.Date(2024, 1, 1, 9, 45, 0, 0, time.UTC)),
+	}
+	series, err := client.UpdateRecurrenceInstanceAndFollowing("/cal/", instance, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("UpdateRecurrenceInstanceAndFollowing failed: %v", err)
+	}
+	if series.UID != "standup" || series.ETag != `"v2"` || series.Href != "/cal/standup.ics" {
+		t.Errorf("expected the updated original series, got %+v", series)
+	}
+
+	parsed, err := ParseICalendar(updated)
+	if err != nil {
+		t.Fatalf("written resource does not parse: %v", err)
+	}
+	master := parsed.Events[0]
+	if master.Summary != "Stand-up (new room)" || master.RecurrenceRule != "FREQ=DAILY;COUNT=10" {
+		t.Errorf("unexpected master: %q %q", master.Summary, master.RecurrenceRule)
+	}
+	if want := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC); len(parsed.Events) != 3 || !parsed.Events[1].RecurrenceID.Equal(want) {
+		t.Errorf("expected the overrides shifted to %v, got %+v", want, parsed.Events)
+	}
+}
+
+func TestDeleteRecurrenceInstanceAndFollowing_FirstOccurrence(t *testing.T) {
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(splitSeriesICS))
+		case http.MethodDelete:
+			if r.URL.Path != "/cal/standup.ics" || r.Header.Get("If-Match") != `"v1"` {
+				t.Errorf("unexpected delete of %s with If-Match %q", r.URL.Path, r.Header.Get("If-Match"))
+			}
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	for _, event := range []*CalendarObject{
+		{UID: "standup"},
+		{UID: "standup", Href: "/cal/standup.ics"},
+		{UID: "standup", Href: server.URL + "/cal/standup.ics"},
+	} {
+		deleted = false
+		if err := client.DeleteRecurrenceInstanceAndFollowing("/cal/", event, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("DeleteRecurrenceInstanceAndFollowing with href %q failed: %v", event.Href, err)
+		}
+		if !deleted {
+			t.Errorf("expected the whole series at href %q to be deleted", event.Href)
+		}
+	}
+}
+
+func TestDeleteRecurrenceInstanceAndFollowing(t *testing.T) {
+	var updated string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(splitSeriesICS))
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			updated = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	event := &CalendarObject{UID: "standup"}
+	if err := client.DeleteRecurrenceInstanceAndFollowing("/cal/", event, time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("DeleteRecurrenceInstanceAndFollowing failed: %v", err)
+	}
+
+	parsed, err := ParseICalendar(updated)
+	if err != nil {
+		t.Fatalf("written resource does not parse: %v", err)
+	}
+	if len(parsed.Events) != 2 || parsed.Events[0].RecurrenceRule != "FREQ=DAILY;COUNT=4" {
+		t.Errorf("expected truncated series with its earlier override, got %+v", parsed.Events)
+	}
+}
+
+func TestUpdateRecurrenceRange(t *testing.T) {
+	var updated string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(splitSeriesICS))
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			updated = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	instance := &CalendarObject{
+		UID:       "standup",
+		Summary:   "Stand-up (new room)",
+		StartTime: timePtr(time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)),
+	}
+	if err := client.UpdateRecurrenceRange("/cal/", instance, time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("UpdateRecurrenceRange failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "RECURRENCE-ID;RANGE=THISANDFUTURE:20240105T090000Z\r\n") {
+		t.Errorf("expected a THISANDFUTURE override:\n%s", updated)
+	}
+
+	parsed, err := ParseICalendar(updated)
+	if err != nil {
+		t.Fatalf("written resource does not parse: %v", err)
+	}
+	if got := parsed.Events[len(parsed.Events)-1].RecurrenceRange; got != "THISANDFUTURE" {
+		t.Errorf("expected RecurrenceRange THISANDFUTURE, got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package caldav
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -87,25 +88,167 @@ func (c *CalDAVClient) DeleteRecurrenceInstanceWithContext(ctx context.Context,
 }
 
 // UpdateRecurrenceInstance updates a single occurrence of a recurring event.
-// This creates a detached instance with RECURRENCE-ID.
+// The override is stored as a VEVENT with RECURRENCE-ID alongside the master
+// event in the same calendar resource. Fields instanceEvent leaves empty keep
+// the values of the master or of the existing override.
 func (c *CalDAVClient) UpdateRecurrenceInstance(calendarPath string, instanceEvent *CalendarObject, recurrenceID time.Time) error {
 	return c.UpdateRecurrenceInstanceWithContext(context.Background(), calendarPath, instanceEvent, recurrenceID)
 }
 
-// UpdateRecurrenceInstanceWithContext updates a recurrence instance with the provided context.
+// UpdateRecurrenceInstanceWithContext fetches the resource holding the master
+// event, adds or replaces the override for recurrenceID and writes it back with
+// If-Match, so a concurrent change surfaces as an ETagMismatchError. The
+// resource is read from instanceEvent.Href, or from the UID-based path in
+// calendarPath when Href is empty.
 func (c *CalDAVClient) UpdateRecurrenceInstanceWithContext(ctx context.Context, calendarPath string, instanceEvent *CalendarObject, recurrenceID time.Time) error {
+	if instanceEvent == nil {
+		return fmt.Errorf("instance event cannot be nil")
+	}
 	if instanceEvent.UID == "" {
 		return fmt.Errorf("instance must have the same UID as the recurring event")
 	}
 
-	instanceEvent.RecurrenceID = &recurrenceID
-	instanceEvent.RecurrenceRule = ""
+	resource, data, err := c.fetchRecurringResource(ctx, calendarPath, instanceEvent)
+	if err != nil {
+		return err
+	}
 
-	if instanceEvent.UID == "" {
-		instanceEvent.UID = generateUID()
+	if err := setRecurrenceOverride(data, instanceEvent, lineParsedCalendarObject(resource.CalendarData), recurrenceID, ""); err != nil {
+		return err
+	}
+
+	return c.writeRecurringResource(ctx, resource, data, instanceEvent)
+}
+
+// fetchRecurringResource reads and parses the resource holding the recurring
+// event with event's UID, from event.Href or the UID-based path in calendarPath.
+// The returned CalendarObject carries the resource's URL and ETag.
+func (c *CalDAVClient) fetchRecurringResource(ctx context.Context, calendarPath string, event *CalendarObject) (*CalendarObject, *ParsedCalendarData, error) {
+	resource, _, err := c.GetEventByPath(ctx, recurringEventPath(calendarPath, event))
+	if err != nil {
+		var notFound *EventNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil, &EventNotFoundError{UID: event.UID}
+		}
+		return nil, nil, fmt.Errorf("fetching recurring event: %w", err)
+	}
+
+	data, err := ParseICalendar(resource.CalendarData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing recurring event: %w", err)
+	}
+
+	return resource, data, nil
+}
+
+// recurringEventPath returns the path of the resource holding event.
+func recurringEventPath(calendarPath string, event *CalendarObject) string {
+	if event.Href != "" {
+		return event.Href
+	}
+	return buildEventPath(calendarPath, event.UID)
+}
+
+// writeRecurringResource writes data back to the resource read by
+// fetchRecurringResource, guarded by its ETag, and records the new ETag on event.
+func (c *CalDAVClient) writeRecurringResource(ctx context.Context, resource *CalendarObject, data *ParsedCalendarData, event *CalendarObject) error {
+	icalData, err := EncodeICalendar(data)
+	if err != nil {
+		return fmt.Errorf("generating iCalendar data: %w", err)
+	}
+
+	newETag, err := c.putCalendarData(ctx, resource.Href, event.UID, string(icalData), resource.ETag)
+	if err != nil {
+		return err
+	}
+	if newETag != "" {
+		event.ETag = newETag
+	}
+
+	return nil
+}
+
+// setRecurrenceOverride adds the override described by instance to data, or
+// replaces the existing override with the same RECURRENCE-ID. A new override
+// starts as a copy of the master event so that properties the caller does not
+// model, such as alarms, carry over to the instance. Only the fields instance
+// sets and that differ from base, the resource as the line parser read it,
+// are applied, so an instance holding just the changes leaves the rest alone.
+// recurrenceRange is the RANGE parameter of the RECURRENCE-ID, either empty
+// or "THISANDFUTURE".
+func setRecurrenceOverride(data *ParsedCalendarData, instance, base *CalendarObject, recurrenceID time.Time, recurrenceRange string) error {
+	masterIndex := -1
+	overrideIndex := -1
+	for i := range data.Events {
+		event := &data.Events[i]
+		if event.UID != instance.UID {
+			continue
+		}
+		if event.RecurrenceID == nil {
+			masterIndex = i
+		} else if formatICalTime(*event.RecurrenceID) == formatICalTime(recurrenceID) {
+			overrideIndex = i
+		}
+	}
+
+	if masterIndex == -1 {
+		return fmt.Errorf("recurring event %s not found in calendar resource", instance.UID)
+	}
+	master := data.Events[masterIndex]
+	if master.RecurrenceRule == "" && len(master.RecurrenceDates) == 0 {
+		return fmt.Errorf("event is not recurring")
+	}
+
+	// RECURRENCE-ID must use the same value type and time zone as DTSTART.
+	if master.DTStart != nil && !master.AllDay && !master.Floating {
+		recurrenceID = recurrenceID.In(master.DTStart.Location())
 	}
 
-	return c.CreateEventWithContext(ctx, calendarPath, instanceEvent)
+	var override ParsedEvent
+	if overrideIndex >= 0 {
+		override = data.Events[overrideIndex]
+	} else {
+		override = newRecurrenceOverride(master, recurrenceID)
+	}
+
+	applyCalendarObjectChanges(instance, base, &override, true)
+	override.RecurrenceID = &recurrenceID
+	override.RecurrenceRange = recurrenceRange
+	override.RecurrenceRule = ""
+	override.ExceptionRule = ""
+	override.RecurrenceDates = nil
+	override.ExceptionDates = nil
+
+	instance.RecurrenceID = &recurrenceID
+	instance.RecurrenceRule = ""
+
+	if overrideIndex >= 0 {
+		data.Events[overrideIndex] = override
+	} else {
+		data.Events = append(data.Events, override)
+	}
+
+	return nil
+}
+
+// newRecurrenceOverride returns a copy of master for the occurrence at
+// recurrenceID, keeping the master's duration and dropping its recurrence.
+func newRecurrenceOverride(master ParsedEvent, recurrenceID time.Time) ParsedEvent {
+	override := master
+	start := recurrenceID
+	override.DTStart = &start
+	if master.DTStart != nil && master.DTEnd != nil {
+		end := start.Add(master.DTEnd.Sub(*master.DTStart))
+		override.DTEnd = &end
+	}
+	override.RecurrenceID = &recurrenceID
+	override.RecurrenceRange = ""
+	override.RecurrenceRule = ""
+	override.ExceptionRule = ""
+	override.RecurrenceDates = nil
+	override.ExceptionDates = nil
+	override.Attendees = append([]ParsedAttendee(nil), master.Attendees...)
+	return override
 }
 
 // ExpandRecurringEvent expands a recurring event into individual occurrences.
@@ -120,7 +263,17 @@ func (c *CalDAVClient) ExpandRecurringEvent(event *CalendarObject, start, end ti
 		return nil, fmt.Errorf("failed to parse RRULE: %w", err)
 	}
 
-	occurrences, err := ExpandRRule(rrule, event.StartTime, start, end)
+	// All-day and floating events happen at the same wall-clock time wherever
+	// they are viewed, so they are expanded in the location of the requested
+	// range and the occurrences are returned as wall-clock times in UTC.
+	floating := event.AllDay || event.Floating
+	dtStart := event.StartTime
+	if floating && dtStart != nil {
+		localStart := wallClockIn(*dtStart, start.Location())
+		dtStart = &localStart
+	}
+
+	occurrences, err := ExpandRRule(rrule, dtStart, start, end)
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand RRULE: %w", err)
 	}
@@ -128,8 +281,15 @@ func (c *CalDAVClient) ExpandRecurringEvent(event *CalendarObject, start, end ti
 	var expandedEvents []*CalendarObject
 
 	for _, occurrence := range occurrences {
+		if floating {
+			occurrence = wallClockIn(occurrence, time.UTC)
+		}
+
 		isException := false
 		for _, exDate := range event.ExceptionDates {
+			if floating {
+				exDate = wallClockIn(exDate, time.UTC)
+			}
 			if occurrence.Equal(exDate) {
 				isException = true
 				break
@@ -148,12 +308,15 @@ func (c *CalDAVClient) ExpandRecurringEvent(event *CalendarObject, start, end ti
 		occurrenceCopy := occurrence
 		endTime := occurrenceCopy.Add(duration)
 		instanceEvent := &CalendarObject{
+			Href:             event.Href,
 			UID:              event.UID,
 			Summary:          event.Summary,
 			Description:      event.Description,
 			Location:         event.Location,
 			StartTime:        &occurrenceCopy,
 			EndTime:          &endTime,
+			AllDay:           event.AllDay,
+			Floating:         event.Floating,
 			Organizer:        event.Organizer,
 			Attendees:        event.Attendees,
 			Categories:       event.Categories,
@@ -299,7 +462,7 @@ func BuildRRule(freq string, interval int, count int, until *time.Time, byDay []
 	if count > 0 {
 		parts = append(parts, fmt.Sprintf("COUNT=%d", count))
 	} else if until != nil {
-		parts = append(parts, fmt.Sprintf("UNTIL=%s", until.Format("20060102T150405Z")))
+		parts = append(parts, fmt.Sprintf("UNTIL=%s", formatICalTime(*until)))
 	}
 
 	if len(byDay) > 0 {
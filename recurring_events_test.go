@@ -2,8 +2,11 @@ package caldav
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -578,3 +581,304 @@ func TestCreateRecurringEventWithExceptions(t *testing.T) {
 		t.Errorf("Expected 2 exception dates, got %d", len(event.ExceptionDates))
 	}
 }
+
+func TestExpandRecurringEvent_AcrossDST(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	client := &CalDAVClient{
+		logger: &testLogger{},
+	}
+
+	event := &CalendarObject{
+		UID:            "dst-event",
+		StartTime:      timePtr(time.Date(2024, 3, 28, 9, 0, 0, 0, london)),
+		EndTime:        timePtr(time.Date(2024, 3, 28, 10, 0, 0, 0, london)),
+		RecurrenceRule: "FREQ=DAILY;COUNT=4",
+	}
+
+	occurrences, err := client.ExpandRecurringEvent(event,
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(occurrences) != 4 {
+		t.Fatalf("Expected 4 occurrences, got %d", len(occurrences))
+	}
+	for i, occ := range occurrences {
+		expected := time.Date(2024, 3, 28+i, 9, 0, 0, 0, london)
+		if !occ.StartTime.Equal(expected) {
+			t.Errorf("Occurrence %d: expected start %v, got %v", i, expected, occ.StartTime.In(london))
+		}
+	}
+}
+
+func TestExpandRecurringEvent_AllDay(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	client := &CalDAVClient{
+		logger: &testLogger{},
+	}
+
+	event := &CalendarObject{
+		UID:            "birthday",
+		StartTime:      timePtr(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)),
+		EndTime:        timePtr(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)),
+		AllDay:         true,
+		RecurrenceRule: "FREQ=YEARLY",
+		ExceptionDates: []time.Time{time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// The range is given in local time west of UTC; the birthday must still
+	// fall on 10 March rather than the evening of the 9th.
+	occurrences, err := client.ExpandRecurringEvent(event,
+		time.Date(2024, 1, 1, 0, 0, 0, 0, losAngeles), time.Date(2027, 1, 1, 0, 0, 0, 0, losAngeles))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(occurrences) != 2 {
+		t.Fatalf("Expected 2 occurrences, got %d", len(occurrences))
+	}
+	for i, year := range []int{2024, 2026} {
+		occ := occurrences[i]
+		if !occ.AllDay {
+			t.Errorf("Occurrence %d: expected AllDay", i)
+		}
+		if want := time.Date(year, 3, 10, 0, 0, 0, 0, time.UTC); !occ.StartTime.Equal(want) {
+			t.Errorf("Occurrence %d: expected start %v, got %v", i, want, occ.StartTime)
+		}
+		if want := time.Date(year, 3, 11, 0, 0, 0, 0, time.UTC); !occ.EndTime.Equal(want) {
+			t.Errorf("Occurrence %d: expected end %v, got %v", i, want, occ.EndTime)
+		}
+	}
+}
+
+const recurringMasterICS = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Recurring//EN
+BEGIN:VEVENT
+UID:weekly-sync
+DTSTAMP:20240101T000000Z
+DTSTART:20240101T100000Z
+DTEND:20240101T110000Z
+RRULE:FREQ=WEEKLY;COUNT=4
+SUMMARY:Weekly sync
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT10M
+DESCRIPTION:Weekly sync
+END:VALARM
+END:VEVENT
+END:VCALENDAR`
+
+func newRecurrenceOverrideServer(t *testing.T, resource string, putStatus int, captured *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/calendars/test/weekly-sync.ics" {
+			t.Errorf("unexpected resource path %s", r.URL.Path)
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(resource))
+		case http.MethodPut:
+			if got := r.Header.Get("If-Match"); got != `"v1"` {
+				t.Errorf("expected If-Match \"v1\", got %q", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			*captured = string(body)
+			w.Header().Set("ETag", `"v2"`)
+			w.WriteHeader(putStatus)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+}
+
+func TestUpdateRecurrenceInstance(t *testing.T) {
+	var written string
+	server := newRecurrenceOverrideServer(t, recurringMasterICS, http.StatusNoContent, &written)
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	recurrenceID := time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)
+	instance := &CalendarObject{
+		UID:       "weekly-sync",
+		Summary:   "Weekly sync (moved)",
+		StartTime: timePtr(time.Date(2024, 1, 9, 14, 0, 0, 0, time.UTC)),
+		EndTime:   timePtr(time.Date(2024, 1, 9, 15, 0, 0, 0, time.UTC)),
+	}
+
+	if err := client.UpdateRecurrenceInstance("/calendars/test/", instance, recurrenceID); err != nil {
+		t.Fatalf("UpdateRecurrenceInstance failed: %v", err)
+	}
+	if instance.ETag != `"v2"` {
+		t.Errorf("expected ETag to be updated, got %q", instance.ETag)
+	}
+
+	parsed, err := ParseICalendar(written)
+	if err != nil {
+		t.Fatalf("written resource does not parse: %v", err)
+	}
+	if len(parsed.Events) != 2 {
+		t.Fatalf("expected master and override in one resource, got %d events", len(parsed.Events))
+	}
+
+	override := parsed.Events[1]
+	if override.RecurrenceID == nil || !override.RecurrenceID.Equal(recurrenceID) {
+		t.Errorf("expected RECURRENCE-ID %v, got %v", recurrenceID, override.RecurrenceID)
+	}
+	if override.RecurrenceRule != "" {
+		t.Errorf("override must not carry an RRULE, got %q", override.RecurrenceRule)
+	}
+	if len(override.Alarms) != 1 {
+		t.Errorf("expected the master's alarm to carry over, got %d alarms", len(override.Alarms))
+	}
+	if parsed.Events[0].RecurrenceRule == "" {
+		t.Error("master event lost its RRULE")
+	}
+
+	expanded, err := ExpandEvents(parsed, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ExpandEvents failed: %v", err)
+	}
+	if len(expanded.Events) != 4 {
+		t.Fatalf("expected 4 occurrences, got %d", len(expanded.Events))
+	}
+	if got := expanded.Events[1]; got.Summary != "Weekly sync (moved)" || !got.DTStart.Equal(*instance.StartTime) {
+		t.Errorf("expected the override for the second occurrence, got %q at %v", got.Summary, got.DTStart)
+	}
+}
+
+func TestUpdateRecurrenceInstance_ReplacesOverride(t *testing.T) {
+	resource := strings.Replace(recurringMasterICS, "END:VCALENDAR", `BEGIN:VEVENT
+UID:weekly-sync
+DTSTAMP:20240101T000000Z
+RECURRENCE-ID:20240108T100000Z
+DTSTART:20240108T120000Z
+DTEND:20240108T130000Z
+SUMMARY:First move
+X-KEEP:yes
+END:VEVENT
+END:VCALENDAR`, 1)
+
+	var written string
+	server := newRecurrenceOverrideServer(t, resource, http.StatusNoContent, &written)
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	instance := &CalendarObject{
+		UID:       "weekly-sync",
+		Summary:   "Second move",
+		StartTime: timePtr(time.Date(2024, 1, 8, 16, 0, 0, 0, time.UTC)),
+		EndTime:   timePtr(time.Date(2024, 1, 8, 17, 0, 0, 0, time.UTC)),
+	}
+
+	if err := client.UpdateRecurrenceInstance("/calendars/test/", instance, time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("UpdateRecurrenceInstance failed: %v", err)
+	}
+
+	parsed, err := ParseICalendar(written)
+	if err != nil {
+		t.Fatalf("written resource does not parse: %v", err)
+	}
+	if len(parsed.Events) != 2 {
+		t.Fatalf("expected the override to be replaced, got %d events", len(parsed.Events))
+	}
+	if got := parsed.Events[1]; got.Summary != "Second move" || got.CustomProperties["X-KEEP"] != "yes" {
+		t.Errorf("unexpected override: %+v", got)
+	}
+}
+
+func TestUpdateRecurrenceInstance_KeepsUnsetFields(t *testing.T) {
+	resource := strings.Replace(recurringMasterICS, "SUMMARY:Weekly sync\n", `SUMMARY:Weekly sync\, team
+DESCRIPTION:Agenda in the shared doc
+LOCATION:Room 4
+CATEGORIES:Work,Team
+URL:https://example.com/sync
+`, 1)
+
+	var written string
+	server := newRecurrenceOverrideServer(t, resource, http.StatusNoContent, &written)
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	// Only the new time is set; the summary is the one the line parser read.
+	instance := &CalendarObject{
+		UID:       "weekly-sync",
+		Summary:   `Weekly sync\, team`,
+		StartTime: timePtr(time.Date(2024, 1, 8, 16, 0, 0, 0, time.UTC)),
+		EndTime:   timePtr(time.Date(2024, 1, 8, 17, 0, 0, 0, time.UTC)),
+	}
+	if err := client.UpdateRecurrenceInstance("/calendars/test/", instance, time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("UpdateRecurrenceInstance failed: %v", err)
+	}
+
+	parsed, err := ParseICalendar(written)
+	if err != nil {
+		t.Fatalf("written resource does not parse: %v", err)
+	}
+	if len(parsed.Events) != 2 {
+		t.Fatalf("expected master and override, got %d events", len(parsed.Events))
+	}
+	override := parsed.Events[1]
+	if override.Summary != "Weekly sync, team" || override.Description != "Agenda in the shared doc" || override.Location != "Room 4" {
+		t.Errorf("expected the master's text to carry over, got %q, %q and %q", override.Summary, override.Description, override.Location)
+	}
+	if !reflect.DeepEqual(override.Categories, []string{"Work", "Team"}) || override.URL != "https://example.com/sync" {
+		t.Errorf("expected categories and URL to carry over, got %v and %q", override.Categories, override.URL)
+	}
+	if !override.DTStart.Equal(*instance.StartTime) {
+		t.Errorf("expected the new start time, got %v", override.DTStart)
+	}
+}
+
+func TestUpdateRecurrenceInstance_ETagMismatch(t *testing.T) {
+	var written string
+	server := newRecurrenceOverrideServer(t, recurringMasterICS, http.StatusPreconditionFailed, &written)
+	defer server.Close()
+
+	client := &CalDAVClient{
+		baseURL:    server.URL,
+		authHeader: "Basic dGVzdDp0ZXN0",
+		httpClient: &http.Client{},
+		logger:     &testLogger{},
+	}
+
+	instance := &CalendarObject{
+		UID:       "weekly-sync",
+		Summary:   "Moved",
+		StartTime: timePtr(time.Date(2024, 1, 9, 14, 0, 0, 0, time.UTC)),
+	}
+
+	err := client.UpdateRecurrenceInstance("/calendars/test/", instance, time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC))
+	var mismatch *ETagMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected ETagMismatchError, got %v", err)
+	}
+}
@@ -112,7 +112,7 @@ func buildExclusionMap(event ParsedEvent, end time.Time) map[string]bool {
 	excludeMap := make(map[string]bool)
 
 	for _, exDate := range event.ExceptionDates {
-		excludeMap[exDate.Format("20060102T150405Z")] = true
+		excludeMap[formatICalTime(exDate)] = true
 	}
 
 	if event.ExceptionRule != "" && event.DTStart != nil {
@@ -128,7 +128,7 @@ func buildExclusionMap(event ParsedEvent, end time.Time) map[string]bool {
 				if exRule.Until != nil && current.After(*exRule.Until) {
 					break
 				}
-				excludeMap[current.Format("20060102T150405Z")] = true
+				excludeMap[formatICalTime(current)] = true
 				current = nextOccurrence(current, exRule)
 			}
 		}
@@ -200,7 +200,7 @@ func checkRegularByDay(current time.Time, byDayParts []ByDayPart) bool {
 }
 
 func addOccurrenceIfValid(state *expandEventState, expandedTime time.Time) {
-	key := expandedTime.Format("20060102T150405Z")
+	key := formatICalTime(expandedTime)
 	if state.occurrenceMap[key] || state.excludeMap[key] {
 		return
 	}
@@ -638,10 +638,13 @@ func ExpandEvents(data *ParsedCalendarData, start, end time.Time) (*ParsedCalend
 		Method:           data.Method,
 		Events:           []ParsedEvent{},
 		Todos:            data.Todos,
+		Journals:         data.Journals,
+		Availability:     data.Availability,
 		FreeBusy:         data.FreeBusy,
 		TimeZones:        data.TimeZones,
 		Alarms:           data.Alarms,
 		CustomProperties: data.CustomProperties,
+		ExtraProperties:  data.ExtraProperties,
 	}
 
 	// Group events by UID to handle exceptions
@@ -667,7 +670,7 @@ func ExpandEvents(data *ParsedCalendarData, start, end time.Time) (*ParsedCalend
 				masterEvent = &events[i]
 			} else if events[i].RecurrenceID != nil {
 				// This is an exception/modification
-				recIDStr := events[i].RecurrenceID.Format("20060102T150405Z")
+				recIDStr := formatICalTime(*events[i].RecurrenceID)
 				exceptions[recIDStr] = &events[i]
 			}
 		}
@@ -711,6 +714,9 @@ func ExpandEventWithExceptions(event ParsedEvent, exceptions map[string]*ParsedE
 	rdateOccurrences := processRDateForExpansion(event, exceptions, start, end, duration, occurrenceMap)
 	occurrences = append(occurrences, rdateOccurrences...)
 
+	movedOccurrences := processMovedExceptions(event, exceptions, start, end, occurrenceMap)
+	occurrences = append(occurrences, movedOccurrences...)
+
 	if len(occurrences) == 0 && event.DTStart != nil {
 		if isInDateRange(*event.DTStart, start, end) {
 			return []ParsedEvent{event}, nil
@@ -787,6 +793,27 @@ func processRDateForExpansion(event ParsedEvent, exceptions map[string]*ParsedEv
 	return occurrences
 }
 
+// processMovedExceptions returns overrides whose original occurrence lies
+// outside the range but which have been rescheduled into it.
+func processMovedExceptions(event ParsedEvent, exceptions map[string]*ParsedEvent, start, end time.Time, occurrenceMap map[string]bool) []ParsedEvent {
+	occurrences := []ParsedEvent{}
+
+	for _, recIDStr := range sortedKeys(exceptions) {
+		exception := exceptions[recIDStr]
+		if occurrenceMap[recIDStr] || exception.DTStart == nil || !isInDateRange(*exception.DTStart, start, end) {
+			continue
+		}
+		if exception.RecurrenceID != nil && isExcludedDate(*exception.RecurrenceID, event.ExceptionDates) {
+			continue
+		}
+
+		occurrenceMap[recIDStr] = true
+		occurrences = append(occurrences, *exception)
+	}
+
+	return occurrences
+}
+
 func isExcludedDate(date time.Time, exceptionDates []time.Time) bool {
 	for _, exDate := range exceptionDates {
 		if isSameDateTime(date, exDate) {
@@ -801,7 +828,7 @@ func isInDateRange(date, start, end time.Time) bool {
 }
 
 func createOccurrenceFromDate(event ParsedEvent, occurrenceDate time.Time, duration time.Duration, exceptions map[string]*ParsedEvent, occurrenceMap map[string]bool) *ParsedEvent {
-	recIDStr := occurrenceDate.Format("20060102T150405Z")
+	recIDStr := formatICalTime(occurrenceDate)
 	if occurrenceMap[recIDStr] {
 		return nil
 	}
@@ -829,6 +856,7 @@ func createOccurrenceFromDate(event ParsedEvent, occurrenceDate time.Time, durat
 
 // isSameDateTime checks if two times represent the same date/time
 func isSameDateTime(t1, t2 time.Time) bool {
+	t1, t2 = t1.UTC(), t2.UTC()
 	return t1.Year() == t2.Year() &&
 		t1.Month() == t2.Month() &&
 		t1.Day() == t2.Day() &&
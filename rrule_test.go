@@ -805,6 +805,44 @@ func TestGetNthWeekdayInMonth(t *testing.T) {
 	}
 }
 
+func TestExpandEventWithExceptions_MovedIntoRange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	master := ParsedEvent{
+		UID:            "weekly",
+		Summary:        "Weekly",
+		DTStart:        &start,
+		DTEnd:          &end,
+		RecurrenceRule: "FREQ=WEEKLY;COUNT=4",
+	}
+
+	// The 8 January occurrence was moved into the following week.
+	recurrenceID := time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)
+	movedStart := time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC)
+	movedEnd := movedStart.Add(time.Hour)
+	moved := ParsedEvent{
+		UID:          "weekly",
+		Summary:      "Moved",
+		DTStart:      &movedStart,
+		DTEnd:        &movedEnd,
+		RecurrenceID: &recurrenceID,
+	}
+	exceptions := map[string]*ParsedEvent{formatICalTime(recurrenceID): &moved}
+
+	occurrences, err := ExpandEventWithExceptions(master, exceptions,
+		time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ExpandEventWithExceptions() error = %v", err)
+	}
+
+	if len(occurrences) != 2 {
+		t.Fatalf("expected the regular occurrence and the moved override, got %d", len(occurrences))
+	}
+	if occurrences[1].Summary != "Moved" || !occurrences[1].DTStart.Equal(movedStart) {
+		t.Errorf("expected moved override, got %q at %v", occurrences[1].Summary, occurrences[1].DTStart)
+	}
+}
+
 func TestExpandEventWithInterval(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -0,0 +1,91 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxRSVPAttempts bounds how often RespondToInvitation re-reads the event
+// after its write loses a race with another change.
+const maxRSVPAttempts = 3
+
+// RespondToInvitation records attendeeEmail's answer to the invitation stored
+// at eventPath. partStat is PartStatAccepted, PartStatDeclined or
+// PartStatTentative and is set on the attendee in the master event and every
+// override; RSVP is cleared, DTSTAMP is bumped and a non-empty comment is added
+// as a COMMENT. The event is written with If-Match; when another client changed
+// it in the meantime, the event is read again and the answer reapplied.
+// Servers with implicit scheduling, such as iCloud, then notify the organizer.
+func (c *CalDAVClient) RespondToInvitation(ctx context.Context, eventPath, attendeeEmail, partStat, comment string) error {
+	partStat = strings.ToUpper(partStat)
+	switch partStat {
+	case PartStatAccepted, PartStatDeclined, PartStatTentative:
+	default:
+		return newTypedError("rsvp", ErrorTypeValidation, fmt.Sprintf("unsupported PARTSTAT %q", partStat), nil)
+	}
+
+	var err error
+	for attempt := 0; attempt < maxRSVPAttempts; attempt++ {
+		err = c.respondToInvitation(ctx, eventPath, attendeeEmail, partStat, comment)
+
+		var mismatch *ETagMismatchError
+		if !errors.As(err, &mismatch) {
+			return err
+		}
+		c.logger.Debug("event %s changed while responding, retrying", eventPath)
+	}
+	return err
+}
+
+func (c *CalDAVClient) respondToInvitation(ctx context.Context, eventPath, attendeeEmail, partStat, comment string) error {
+	resource, etag, err := c.GetEventByPath(ctx, eventPath)
+	if err != nil {
+		return fmt.Errorf("fetching event: %w", err)
+	}
+
+	data, err := ParseICalendar(resource.CalendarData)
+	if err != nil {
+		return fmt.Errorf("parsing event: %w", err)
+	}
+
+	if err := setInvitationResponse(data, attendeeEmail, partStat, comment, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	icalData, err := EncodeICalendar(data)
+	if err != nil {
+		return fmt.Errorf("generating iCalendar data: %w", err)
+	}
+
+	_, err = c.putCalendarData(ctx, resource.Href, data.Events[0].UID, string(icalData), etag)
+	return err
+}
+
+// setInvitationResponse sets attendee's PARTSTAT on every event in data that
+// lists them, clears RSVP and stamps the events with now.
+func setInvitationResponse(data *ParsedCalendarData, attendee, partStat, comment string, now time.Time) error {
+	found := false
+	for i := range data.Events {
+		event := &data.Events[i]
+		target := findAttendee(event.Attendees, attendee)
+		if target == nil {
+			continue
+		}
+		found = true
+
+		target.PartStat = partStat
+		target.RSVP = false
+		event.DTStamp = &now
+		if comment != "" {
+			event.Comments = append(event.Comments, comment)
+		}
+	}
+
+	if !found {
+		return newTypedError("rsvp", ErrorTypeValidation, fmt.Sprintf("%s is not an attendee of the event", attendee), nil)
+	}
+	return nil
+}
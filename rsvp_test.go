@@ -0,0 +1,96 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRespondToInvitation(t *testing.T) {
+	stored := meetingICS
+	etag := `"1"`
+	gets, puts := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			gets++
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write([]byte(stored))
+		case http.MethodPut:
+			puts++
+			if puts == 1 {
+				// Another client edits the event between our read and write.
+				stored = strings.Replace(stored, "SUMMARY:Planning", "SUMMARY:Planning v2", 1)
+				etag = `"2"`
+			}
+			if r.Header.Get("If-Match") != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			stored = string(body)
+			etag = `"3"`
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	err := client.RespondToInvitation(context.Background(), "/cal/meeting-1.ics", "sam@example.com", "accepted", "See you there")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gets != 2 || puts != 2 {
+		t.Errorf("expected one retry, got %d GETs and %d PUTs", gets, puts)
+	}
+
+	data, err := ParseICalendar(stored)
+	if err != nil {
+		t.Fatalf("failed to parse stored event: %v", err)
+	}
+	event := data.Events[0]
+	if event.Summary != "Planning v2" {
+		t.Errorf("expected the concurrent change to be kept, got summary %q", event.Summary)
+	}
+	sam := findAttendee(event.Attendees, "sam@example.com")
+	if sam.PartStat != PartStatAccepted || sam.RSVP {
+		t.Errorf("expected sam to have accepted without RSVP, got %+v", sam)
+	}
+	if kim := findAttendee(event.Attendees, "kim@example.com"); kim.PartStat != PartStatNeedsAction || !kim.RSVP {
+		t.Errorf("expected kim to be unchanged, got %+v", kim)
+	}
+	if len(event.Comments) != 1 || event.Comments[0] != "See you there" {
+		t.Errorf("unexpected comments %v", event.Comments)
+	}
+	if event.DTStamp == nil || !event.DTStamp.After(mustParseTime("20250101T000000Z")) {
+		t.Errorf("expected DTSTAMP to be bumped, got %v", event.DTStamp)
+	}
+}
+
+func TestRespondToInvitation_Validation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		_, _ = w.Write([]byte(meetingICS))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+	ctx := context.Background()
+
+	if err := client.RespondToInvitation(ctx, "/cal/meeting-1.ics", "sam@example.com", PartStatDelegated, ""); !IsValidationError(err) {
+		t.Errorf("expected a validation error for DELEGATED, got %v", err)
+	}
+	if err := client.RespondToInvitation(ctx, "/cal/meeting-1.ics", "nobody@example.com", PartStatDeclined, ""); !IsValidationError(err) {
+		t.Errorf("expected a validation error for an unknown attendee, got %v", err)
+	}
+}
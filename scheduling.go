@@ -0,0 +1,415 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SCHEDULE-AGENT values defined by RFC 6638.
+const (
+	ScheduleAgentServer = "SERVER"
+	ScheduleAgentClient = "CLIENT"
+	ScheduleAgentNone   = "NONE"
+)
+
+// SchedulingCollections holds the RFC 6638 scheduling collections and
+// calendar user addresses of a principal.
+type SchedulingCollections struct {
+	InboxURL              string
+	OutboxURL             string
+	CalendarUserAddresses []string
+}
+
+// InboxItem is a scheduling message delivered to a schedule inbox.
+type InboxItem struct {
+	Href     string
+	ETag     string
+	Method   string
+	Calendar *ParsedCalendarData
+}
+
+// ScheduleResponse is the outcome of a scheduling request for one recipient.
+type ScheduleResponse struct {
+	Recipient     string
+	RequestStatus string
+	CalendarData  string
+	FreeBusy      []FreeBusyPeriod
+}
+
+// Succeeded reports whether the request status is a 2.x success code.
+func (r ScheduleResponse) Succeeded() bool {
+	return strings.HasPrefix(r.RequestStatus, "2.")
+}
+
+// FindSchedulingCollections discovers the schedule inbox and outbox of the
+// principal at principalHref. Servers without CalDAV scheduling, such as
+// iCloud, return an error wrapping ErrSchedulingUnsupported.
+func (c *CalDAVClient) FindSchedulingCollections(ctx context.Context, principalHref string) (*SchedulingCollections, error) {
+	if err := c.requireScheduling("schedule.discover"); err != nil {
+		return nil, err
+	}
+
+	xmlBody, err := buildPropfindXML([]string{"schedule-inbox-URL", "schedule-outbox-URL", "calendar-user-address-set"})
+	if err != nil {
+		return nil, wrapErrorWithType("schedule.build", ErrorTypeInvalidRequest, err)
+	}
+
+	resp, err := c.propfind(ctx, principalHref, "0", xmlBody)
+	if err != nil {
+		return nil, wrapErrorWithType("schedule.request", ErrorTypeNetwork, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 207 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newCalDAVError("schedule.discover", resp.StatusCode, string(body))
+	}
+
+	msResp, err := parseMultiStatusResponse(resp.Body)
+	if err != nil {
+		return nil, wrapErrorWithType("schedule.parse", ErrorTypeInvalidResponse, err)
+	}
+
+	collections := &SchedulingCollections{}
+	for _, r := range msResp.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Status != 200 {
+				continue
+			}
+			if ps.Prop.ScheduleInboxURL != "" {
+				collections.InboxURL = ps.Prop.ScheduleInboxURL
+			}
+			if ps.Prop.ScheduleOutboxURL != "" {
+				collections.OutboxURL = ps.Prop.ScheduleOutboxURL
+			}
+			collections.CalendarUserAddresses = append(collections.CalendarUserAddresses, ps.Prop.CalendarUserAddressSet...)
+		}
+	}
+
+	if collections.InboxURL == "" && collections.OutboxURL == "" {
+		return nil, schedulingUnsupportedError("schedule.discover")
+	}
+	return collections, nil
+}
+
+// ListInboxItems returns the scheduling messages waiting in the inbox at
+// inboxURL. Items that cannot be parsed are skipped.
+func (c *CalDAVClient) ListInboxItems(ctx context.Context, inboxURL string) ([]InboxItem, error) {
+	if err := c.requireScheduling("schedule.inbox"); err != nil {
+		return nil, err
+	}
+
+	objects, err := c.QueryCalendar(ctx, inboxURL, CalendarQuery{
+		Properties: []string{"getetag", "calendar-data"},
+		Filter:     Filter{Component: "VCALENDAR"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]InboxItem, 0, len(objects))
+	for _, obj := range objects {
+		data := obj.ParsedData
+		if data == nil {
+			if data, err = ParseICalendar(obj.CalendarData); err != nil {
+				c.logger.Warn("skipping inbox item %s: %v", obj.Href, err)
+				continue
+			}
+		}
+		items = append(items, InboxItem{
+			Href:     obj.Href,
+			ETag:     obj.ETag,
+			Method:   strings.ToUpper(data.Method),
+			Calendar: data,
+		})
+	}
+
+	return items, nil
+}
+
+// ProcessInboxItem applies an inbox item to the user's calendar at
+// calendarPath and then removes it from the inbox. A REPLY updates the
+// attendee statuses on the organizer's event, a REQUEST creates or updates
+// the attendee's copy and a CANCEL marks it cancelled. Messages older than the
+// stored event are discarded.
+func (c *CalDAVClient) ProcessInboxItem(ctx context.Context, calendarPath string, item InboxItem) error {
+	if err := c.requireScheduling("schedule.process"); err != nil {
+		return err
+	}
+	if item.Calendar == nil || len(item.Calendar.Events) == 0 {
+		return newTypedError("schedule.process", ErrorTypeValidation, "inbox item holds no VEVENT", nil)
+	}
+
+	var err error
+	switch item.Method {
+	case ITIPMethodReply:
+		err = c.applyITIPMessage(ctx, calendarPath, item.Calendar, ApplyITIPReply)
+	case ITIPMethodCancel:
+		err = c.applyITIPMessage(ctx, calendarPath, item.Calendar, ApplyITIPCancel)
+		var notFound *EventNotFoundError
+		if errors.As(err, &notFound) {
+			err = nil
+		}
+	case ITIPMethodRequest:
+		err = c.applyITIPMessage(ctx, calendarPath, item.Calendar, ApplyITIPRequest)
+		var notFound *EventNotFoundError
+		if errors.As(err, &notFound) {
+			err = c.storeITIPRequest(ctx, calendarPath, item.Calendar)
+		}
+	default:
+		return newTypedError("schedule.process", ErrorTypeValidation, fmt.Sprintf("unsupported iTIP method %q", item.Method), nil)
+	}
+
+	if err != nil && !errors.Is(err, ErrStaleITIPMessage) {
+		return err
+	}
+	return c.DeleteEventWithETag(ctx, item.Href, item.ETag)
+}
+
+// applyITIPMessage applies message to the stored event it refers to and
+// writes the event back with If-Match.
+func (c *CalDAVClient) applyITIPMessage(ctx context.Context, calendarPath string, message *ParsedCalendarData, apply func(data, message *ParsedCalendarData) error) error {
+	ref := &CalendarObject{UID: message.Events[0].UID}
+	resource, data, err := c.findITIPResource(ctx, calendarPath, ref.UID)
+	if err != nil {
+		return err
+	}
+
+	if err := apply(data, message); err != nil {
+		return err
+	}
+	return c.writeRecurringResource(ctx, resource, data, ref)
+}
+
+// findITIPResource locates the resource holding the event with uid through a
+// calendar-query, since invitations accepted by other clients are not
+// necessarily stored under <UID>.ics.
+func (c *CalDAVClient) findITIPResource(ctx context.Context, calendarPath, uid string) (*CalendarObject, *ParsedCalendarData, error) {
+	query := CalendarQuery{
+		Properties: []string{"getetag", "calendar-data"},
+		Filter: Filter{
+			Component: "VEVENT",
+			Props: []PropFilter{
+				{
+					Name:      "UID",
+					TextMatch: &TextMatch{Value: uid, Collation: string(CollationOctet)},
+				},
+			},
+		},
+	}
+
+	objects, err := c.QueryCalendar(ctx, calendarPath, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finding scheduled event: %w", err)
+	}
+
+	for i := range objects {
+		resource := &objects[i]
+		data, err := ParseICalendar(resource.CalendarData)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing scheduled event: %w", err)
+		}
+		if !containsEventUID(data, uid) {
+			continue
+		}
+		if !strings.HasPrefix(resource.Href, "http://") && !strings.HasPrefix(resource.Href, "https://") {
+			resource.Href = c.baseURL + resource.Href
+		}
+		return resource, data, nil
+	}
+
+	return nil, nil, &EventNotFoundError{UID: uid}
+}
+
+// containsEventUID reports whether data holds an event with uid. The UID is
+// taken from the unfolded, unescaped parse rather than CalendarObject.UID.
+func containsEventUID(data *ParsedCalendarData, uid string) bool {
+	for _, event := range data.Events {
+		if event.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// storeITIPRequest creates the attendee's copy of a new invitation.
+func (c *CalDAVClient) storeITIPRequest(ctx context.Context, calendarPath string, request *ParsedCalendarData) error {
+	data := &ParsedCalendarData{Version: "2.0", ProdID: defaultProdID}
+	if err := ApplyITIPRequest(data, request); err != nil {
+		return err
+	}
+
+	icalData, err := EncodeICalendar(data)
+	if err != nil {
+		return fmt.Errorf("generating iCalendar data: %w", err)
+	}
+
+	uid := request.Events[0].UID
+	_, err = c.createCalendarData(ctx, buildEventURL(c.baseURL, calendarPath, uid), uid, string(icalData))
+	return err
+}
+
+// RequestFreeBusy asks the server, through the schedule outbox at outboxURL,
+// for the free/busy time of attendees between start and end on behalf of
+// organizer. It returns one response per attendee.
+func (c *CalDAVClient) RequestFreeBusy(ctx context.Context, outboxURL, organizer string, attendees []string, start, end time.Time) ([]ScheduleResponse, error) {
+	if err := c.requireScheduling("schedule.freebusy"); err != nil {
+		return nil, err
+	}
+	if len(attendees) == 0 {
+		return nil, newTypedError("schedule.freebusy", ErrorTypeValidation, "at least one attendee is required", nil)
+	}
+	if !end.After(start) {
+		return nil, newTypedError("schedule.freebusy", ErrorTypeValidation, "end must be after start", nil)
+	}
+
+	now := time.Now().UTC()
+	request := ParsedFreeBusy{
+		UID:       generateUID(),
+		DTStamp:   &now,
+		DTStart:   &start,
+		DTEnd:     &end,
+		Organizer: ParsedOrganizer{Value: calendarAddress(organizer)},
+	}
+	recipients := make([]string, 0, len(attendees))
+	for _, attendee := range attendees {
+		address := calendarAddress(attendee)
+		recipients = append(recipients, address)
+		request.Attendees = append(request.Attendees, ParsedAttendee{Value: address})
+	}
+
+	body, err := EncodeICalendar(&ParsedCalendarData{
+		Method:   ITIPMethodRequest,
+		FreeBusy: []ParsedFreeBusy{request},
+	})
+	if err != nil {
+		return nil, wrapErrorWithType("schedule.freebusy", ErrorTypeInvalidRequest, err)
+	}
+
+	resp, err := c.postToOutbox(ctx, outboxURL, request.Organizer.Value, recipients, body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return parseScheduleResponse(resp.Body)
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, schedulingUnsupportedError("schedule.freebusy")
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newCalDAVError("schedule.freebusy", resp.StatusCode, string(respBody))
+	}
+}
+
+func (c *CalDAVClient) postToOutbox(ctx context.Context, outboxURL, originator string, recipients []string, body []byte) (*http.Response, error) {
+	req, err := c.prepareRequest(ctx, http.MethodPost, outboxURL, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	// Originator and Recipient are obsolete in RFC 6638 but still read by
+	// servers that implemented the earlier drafts.
+	req.Header.Set("Originator", originator)
+	req.Header.Set("Recipient", strings.Join(recipients, ", "))
+
+	c.logRequest(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("POST request failed: %v", err)
+		return nil, wrapErrorWithType("schedule.post", ErrorTypeNetwork, err)
+	}
+
+	c.logResponse(resp)
+	return resp, nil
+}
+
+// SetAttendeeScheduleAgent sets the SCHEDULE-AGENT parameter of attendee on
+// event. With ScheduleAgentClient the server leaves delivering scheduling
+// messages to that attendee to the client.
+func SetAttendeeScheduleAgent(event *ParsedEvent, attendee, agent string) error {
+	if event == nil {
+		return newTypedError("schedule.agent", ErrorTypeValidation, "event cannot be nil", nil)
+	}
+
+	agent = strings.ToUpper(agent)
+	switch agent {
+	case ScheduleAgentServer, ScheduleAgentClient, ScheduleAgentNone:
+	default:
+		return newTypedError("schedule.agent", ErrorTypeValidation, fmt.Sprintf("invalid SCHEDULE-AGENT %q", agent), nil)
+	}
+
+	target := findAttendee(event.Attendees, attendee)
+	if target == nil {
+		return newTypedError("schedule.agent", ErrorTypeValidation, fmt.Sprintf("%s is not an attendee of event %s", attendee, event.UID), nil)
+	}
+	target.ScheduleAgent = agent
+	return nil
+}
+
+// SetAttendeeScheduleStatus sets the SCHEDULE-STATUS parameter of attendee on
+// event to one or more comma-separated request status codes such as "1.2".
+// Clients set it when they deliver scheduling messages themselves.
+func SetAttendeeScheduleStatus(event *ParsedEvent, attendee, status string) error {
+	if event == nil {
+		return newTypedError("schedule.status", ErrorTypeValidation, "event cannot be nil", nil)
+	}
+
+	for _, code := range strings.Split(status, ",") {
+		if !isRequestStatusCode(code) {
+			return newTypedError("schedule.status", ErrorTypeValidation, fmt.Sprintf("invalid SCHEDULE-STATUS %q", status), nil)
+		}
+	}
+
+	target := findAttendee(event.Attendees, attendee)
+	if target == nil {
+		return newTypedError("schedule.status", ErrorTypeValidation, fmt.Sprintf("%s is not an attendee of event %s", attendee, event.UID), nil)
+	}
+	target.ScheduleStatus = status
+	return nil
+}
+
+// isRequestStatusCode reports whether code has the "class.code" form of an
+// RFC 5545 request status, for example "2.0" or "3.7".
+func isRequestStatusCode(code string) bool {
+	parts := strings.Split(code, ".")
+	if len(parts) < 2 || len(parts[0]) != 1 || parts[0] < "1" || parts[0] > "5" {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// requireScheduling fails with ErrSchedulingUnsupported when the detected
+// server is known not to support CalDAV scheduling.
+func (c *CalDAVClient) requireScheduling(op string) error {
+	if c.serverCompat != nil && !c.serverCompat.Capabilities[CapCalendarSchedule] {
+		return schedulingUnsupportedError(op)
+	}
+	return nil
+}
+
+func schedulingUnsupportedError(op string) error {
+	return newTypedError(op, ErrorTypeClient, "server does not support CalDAV scheduling", ErrSchedulingUnsupported)
+}
+
+// calendarAddress returns address as a calendar user address URI, adding
+// mailto: to a bare email address.
+func calendarAddress(address string) string {
+	if strings.Contains(address, ":") {
+		return address
+	}
+	return "mailto:" + address
+}
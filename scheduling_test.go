@@ -0,0 +1,344 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindSchedulingCollections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "PROPFIND" || !strings.Contains(string(body), "<C:schedule-inbox-URL/>") {
+			t.Errorf("unexpected request: %s %s", r.Method, body)
+		}
+
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/principals/olivia/</D:href>
+    <D:propstat>
+      <D:prop>
+        <C:schedule-inbox-URL><D:href>/calendars/olivia/inbox/</D:href></C:schedule-inbox-URL>
+        <C:schedule-outbox-URL><D:href>/calendars/olivia/outbox/</D:href></C:schedule-outbox-URL>
+        <C:calendar-user-address-set>
+          <D:href>mailto:olivia@example.com</D:href>
+          <D:href>/principals/olivia/</D:href>
+        </C:calendar-user-address-set>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	collections, err := client.FindSchedulingCollections(context.Background(), "/principals/olivia/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &SchedulingCollections{
+		InboxURL:              "/calendars/olivia/inbox/",
+		OutboxURL:             "/calendars/olivia/outbox/",
+		CalendarUserAddresses: []string{"mailto:olivia@example.com", "/principals/olivia/"},
+	}
+	if !reflect.DeepEqual(collections, expected) {
+		t.Errorf("expected %+v, got %+v", expected, collections)
+	}
+}
+
+func TestScheduling_Unsupported(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:"><D:response><D:href>/principals/olivia/</D:href>
+<D:propstat><D:prop/><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	_, err := client.FindSchedulingCollections(context.Background(), "/principals/olivia/")
+	if !errors.Is(err, ErrSchedulingUnsupported) {
+		t.Errorf("expected ErrSchedulingUnsupported without scheduling properties, got %v", err)
+	}
+
+	client.ConfigureForICloud()
+	client.baseURL = server.URL
+	requests = 0
+
+	ctx := context.Background()
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if _, err := client.FindSchedulingCollections(ctx, "/principals/olivia/"); !errors.Is(err, ErrSchedulingUnsupported) {
+		t.Errorf("FindSchedulingCollections: expected ErrSchedulingUnsupported, got %v", err)
+	}
+	if _, err := client.ListInboxItems(ctx, "/inbox/"); !errors.Is(err, ErrSchedulingUnsupported) {
+		t.Errorf("ListInboxItems: expected ErrSchedulingUnsupported, got %v", err)
+	}
+	if _, err := client.RequestFreeBusy(ctx, "/outbox/", "olivia@example.com", []string{"sam@example.com"}, start, start.Add(time.Hour)); !errors.Is(err, ErrSchedulingUnsupported) {
+		t.Errorf("RequestFreeBusy: expected ErrSchedulingUnsupported, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests to an iCloud server, got %d", requests)
+	}
+}
+
+func TestRequestFreeBusy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/calendars/olivia/outbox/" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/calendar") {
+			t.Errorf("unexpected Content-Type %q", got)
+		}
+		for _, want := range []string{
+			"METHOD:REQUEST",
+			"BEGIN:VFREEBUSY",
+			"DTSTART:20250115T000000Z",
+			"ORGANIZER:mailto:olivia@example.com",
+			"ATTENDEE:mailto:sam@example.com",
+		} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("request body missing %q:\n%s", want, body)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<C:schedule-response xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <C:response>
+    <C:recipient><D:href>mailto:sam@example.com</D:href></C:recipient>
+    <C:request-status>2.0;Success</C:request-status>
+    <C:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test//EN
+METHOD:REPLY
+BEGIN:VFREEBUSY
+DTSTART:20250115T000000Z
+DTEND:20250116T000000Z
+FREEBUSY:20250115T090000Z/20250115T100000Z
+END:VFREEBUSY
+END:VCALENDAR
+</C:calendar-data>
+  </C:response>
+  <C:response>
+    <C:recipient><D:href>mailto:kim@elsewhere.example</D:href></C:recipient>
+    <C:request-status>3.7;Invalid calendar user</C:request-status>
+  </C:response>
+</C:schedule-response>`))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	responses, err := client.RequestFreeBusy(context.Background(), "/calendars/olivia/outbox/", "olivia@example.com",
+		[]string{"sam@example.com", "mailto:kim@elsewhere.example"}, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+	if responses[0].Recipient != "mailto:sam@example.com" || !responses[0].Succeeded() {
+		t.Errorf("unexpected first response: %+v", responses[0])
+	}
+	expected := []FreeBusyPeriod{
+		{Start: mustParseTime("20250115T090000Z"), End: mustParseTime("20250115T100000Z"), FBType: FBTypeBusy},
+	}
+	if !reflect.DeepEqual(responses[0].FreeBusy, expected) {
+		t.Errorf("expected %+v, got %+v", expected, responses[0].FreeBusy)
+	}
+	if responses[1].Succeeded() || responses[1].RequestStatus != "3.7;Invalid calendar user" {
+		t.Errorf("unexpected second response: %+v", responses[1])
+	}
+}
+
+func TestProcessInboxItem_Reply(t *testing.T) {
+	stored := meetingICS
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "REPORT":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), ">meeting-1</C:text-match>") {
+				t.Errorf("expected a calendar-query on the UID, got %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/calendars/olivia/work/4F1C9A.ics</D:href>
+    <D:propstat>
+      <D:prop>
+        <D:getetag>"1"</D:getetag>
+        <C:calendar-data>` + stored + `</C:calendar-data>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`))
+		case http.MethodPut:
+			if r.URL.Path != "/calendars/olivia/work/4F1C9A.ics" {
+				t.Errorf("expected the event to be written where it was found, got %s", r.URL.Path)
+			}
+			if r.Header.Get("If-Match") != `"1"` {
+				t.Errorf("expected If-Match \"1\", got %q", r.Header.Get("If-Match"))
+			}
+			body, _ := io.ReadAll(r.Body)
+			stored = string(body)
+			w.Header().Set("ETag", `"2"`)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			deleted = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	reply, err := ParseICalendar("BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//Test//Test//EN\r\n" +
+		"METHOD:REPLY\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:meeting-1\r\n" +
+		"DTSTAMP:20250102T000000Z\r\n" +
+		"SEQUENCE:2\r\n" +
+		"ORGANIZER:mailto:olivia@example.com\r\n" +
+		"ATTENDEE;PARTSTAT=ACCEPTED:mailto:sam@example.com\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n")
+	if err != nil {
+		t.Fatalf("failed to parse reply: %v", err)
+	}
+
+	item := InboxItem{Href: "/inbox/reply-1.ics", ETag: `"9"`, Method: ITIPMethodReply, Calendar: reply}
+	if err := client.ProcessInboxItem(context.Background(), "/calendars/olivia/work/", item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(stored, "PARTSTAT=ACCEPTED") || strings.Count(stored, "PARTSTAT=NEEDS-ACTION") != 1 {
+		t.Errorf("expected sam's reply to be applied, got:\n%s", stored)
+	}
+	if deleted != "/inbox/reply-1.ics" {
+		t.Errorf("expected inbox item to be deleted, got %q", deleted)
+	}
+}
+
+func TestProcessInboxItem_RequestFoldedUID(t *testing.T) {
+	uid := "040000008200E00074C5B7101A82E00800000000D0A1B2C3D4E5F6070000000000000000100000001A2B3C4D5E6F"
+	stored := strings.Replace(meetingICS, "UID:meeting-1\r\n", "UID:"+uid[:70]+"\r\n "+uid[70:]+"\r\n", 1)
+	var written string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "REPORT":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/calendars/olivia/work/4F1C9A.ics</D:href>
+    <D:propstat>
+      <D:prop>
+        <D:getetag>"1"</D:getetag>
+        <C:calendar-data>` + stored + `</C:calendar-data>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`))
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "" {
+				t.Errorf("expected the stored event to be updated, got a create of %s", r.URL.Path)
+			}
+			written = r.URL.Path
+			w.Header().Set("ETag", `"2"`)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	request, err := ParseICalendar(strings.NewReplacer(
+		"PRODID:-//Test//Test//EN\r\n", "PRODID:-//Test//Test//EN\r\nMETHOD:REQUEST\r\n",
+		"SEQUENCE:2", "SEQUENCE:3",
+	).Replace(stored))
+	if err != nil {
+		t.Fatalf("failed to parse request: %v", err)
+	}
+
+	item := InboxItem{Href: "/inbox/request-1.ics", ETag: `"9"`, Method: ITIPMethodRequest, Calendar: request}
+	if err := client.ProcessInboxItem(context.Background(), "/calendars/olivia/work/", item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if written != "/calendars/olivia/work/4F1C9A.ics" {
+		t.Errorf("expected the event to be written where it was found, got %q", written)
+	}
+}
+
+func TestSetAttendeeScheduleParams(t *testing.T) {
+	data := parseMeeting(t)
+	event := &data.Events[0]
+
+	if err := SetAttendeeScheduleAgent(event, "sam@example.com", "client"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetAttendeeScheduleStatus(event, "mailto:sam@example.com", "1.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := SetAttendeeScheduleAgent(event, "sam@example.com", "ROBOT"); err == nil {
+		t.Error("expected error for invalid SCHEDULE-AGENT")
+	}
+	if err := SetAttendeeScheduleStatus(event, "sam@example.com", "ok"); err == nil {
+		t.Error("expected error for invalid SCHEDULE-STATUS")
+	}
+	if err := SetAttendeeScheduleAgent(event, "nobody@example.com", ScheduleAgentNone); err == nil {
+		t.Error("expected error for unknown attendee")
+	}
+
+	encoded, err := EncodeICalendar(data)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if unfolded := strings.ReplaceAll(string(encoded), "\r\n ", ""); !strings.Contains(unfolded, `SCHEDULE-AGENT=CLIENT;SCHEDULE-STATUS="1.2":mailto:sam@example.com`) {
+		t.Errorf("expected schedule parameters on sam's ATTENDEE, got:\n%s", encoded)
+	}
+
+	reparsed, err := ParseICalendar(string(encoded))
+	if err != nil {
+		t.Fatalf("failed to reparse: %v", err)
+	}
+	sam := findAttendee(reparsed.Events[0].Attendees, "sam@example.com")
+	if sam == nil || sam.ScheduleAgent != ScheduleAgentClient || sam.ScheduleStatus != "1.2" {
+		t.Errorf("schedule parameters did not round-trip: %+v", sam)
+	}
+}
@@ -0,0 +1,290 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	defaultSlotStep       = 15 * time.Minute
+	defaultSlotMaxResults = 10
+	// slotComfortCap is the free time either side of a slot beyond which a
+	// slot gets no further ranking bonus.
+	slotComfortCap = time.Hour
+)
+
+// WorkingHours is the part of the day a participant can meet, as wall-clock
+// offsets from midnight in the participant's time zone.
+type WorkingHours struct {
+	Start time.Duration
+	End   time.Duration
+	// Days lists the working weekdays; empty means Monday to Friday.
+	Days []time.Weekday
+}
+
+// SlotParticipant is a person who must be free for a meeting.
+type SlotParticipant struct {
+	Name string
+	// CalendarHrefs are the calendars, such as those returned by
+	// DiscoverCalendars, whose events make the participant busy.
+	CalendarHrefs []string
+	// Busy holds additional busy time known to the caller.
+	Busy []FreeBusyPeriod
+	// Availability is the participant's declared availability, such as the
+	// result of GetCalendarAvailability; time outside it is unavailable.
+	Availability []ParsedAvailability
+	// Location is the participant's time zone, used for working hours and
+	// all-day events. Nil means UTC.
+	Location *time.Location
+	// WorkingHours restricts meetings to the participant's working day. Nil
+	// means any time.
+	WorkingHours *WorkingHours
+	// Buffer is the free time the participant needs before and after each of
+	// their meetings.
+	Buffer time.Duration
+}
+
+// SlotRequest describes the meeting to find time for.
+type SlotRequest struct {
+	Participants []SlotParticipant
+	Start        time.Time
+	End          time.Time
+	Duration     time.Duration
+	// Step is the spacing of candidate start times; zero means 15 minutes.
+	Step time.Duration
+	// MaxResults limits the number of slots returned; zero means 10.
+	MaxResults int
+	// AllowTentative accepts slots that overlap BUSY-TENTATIVE time, ranked
+	// below slots where everyone is free.
+	AllowTentative bool
+}
+
+// MeetingSlot is a candidate meeting time.
+type MeetingSlot struct {
+	Start time.Time
+	End   time.Time
+	// Score ranks the slot between 0 and 1. It is lowered by tentative
+	// conflicts and by back-to-back meetings or working-day edges.
+	Score float64
+	// Tentative names the participants who are tentatively busy in the slot.
+	Tentative []string
+}
+
+// FindMeetingSlots finds times between req.Start and req.End when every
+// participant is free for req.Duration. Busy time is read from each
+// participant's calendars with time-range queries, recurring events expanded,
+// and merged with SlotParticipant.Busy. See FindFreeSlots for the ranking.
+func (c *CalDAVClient) FindMeetingSlots(ctx context.Context, req SlotRequest) ([]MeetingSlot, error) {
+	if err := validateSlotRequest(&req); err != nil {
+		return nil, err
+	}
+
+	participants := make([]SlotParticipant, len(req.Participants))
+	for i, p := range req.Participants {
+		loc := slotLocation(p)
+		start, end := req.Start.Add(-p.Buffer).In(loc), req.End.Add(p.Buffer).In(loc)
+
+		p.Busy = append([]FreeBusyPeriod(nil), p.Busy...)
+		for _, href := range p.CalendarHrefs {
+			periods, err := c.freeBusyFromEvents(ctx, href, start, end)
+			if err != nil {
+				return nil, wrapError("slots.freebusy", err)
+			}
+			p.Busy = append(p.Busy, periods...)
+		}
+		participants[i] = p
+	}
+
+	req.Participants = participants
+	return FindFreeSlots(req)
+}
+
+// FindFreeSlots finds meeting slots from the participants' Busy periods
+// without contacting the server. Candidate slots start every req.Step and must
+// lie within every participant's working hours and declared availability and
+// clear of their busy time widened by their buffer. Slots are ranked by Score,
+// highest first, with ties going to the earlier slot; a slot overlapping a
+// better-ranked one is left out, so the results are distinct alternatives.
+func FindFreeSlots(req SlotRequest) ([]MeetingSlot, error) {
+	if err := validateSlotRequest(&req); err != nil {
+		return nil, err
+	}
+
+	busy := make([][]FreeBusyPeriod, len(req.Participants))
+	for i, p := range req.Participants {
+		busy[i] = append(bufferedBusy(p, req.Start, req.End), AvailabilityBusyPeriods(p.Availability, req.Start, req.End)...)
+	}
+
+	var candidates []MeetingSlot
+	for start := alignSlotStart(req.Start, req.Step); !start.Add(req.Duration).After(req.End); start = start.Add(req.Step) {
+		if slot, ok := evaluateSlot(req, busy, start); ok {
+			candidates = append(candidates, slot)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	var slots []MeetingSlot
+	for _, candidate := range candidates {
+		if len(slots) == req.MaxResults {
+			break
+		}
+		if !overlapsSlots(slots, candidate) {
+			slots = append(slots, candidate)
+		}
+	}
+	return slots, nil
+}
+
+func validateSlotRequest(req *SlotRequest) error {
+	if len(req.Participants) == 0 {
+		return newTypedError("slots", ErrorTypeValidation, "at least one participant is required", nil)
+	}
+	if req.Duration <= 0 {
+		return newTypedError("slots", ErrorTypeValidation, "duration must be positive", nil)
+	}
+	if !req.End.After(req.Start) {
+		return newTypedError("slots", ErrorTypeValidation, "end must be after start", nil)
+	}
+	for _, p := range req.Participants {
+		if wh := p.WorkingHours; wh != nil && (wh.Start < 0 || wh.End > 24*time.Hour || wh.End <= wh.Start) {
+			return newTypedError("slots", ErrorTypeValidation, fmt.Sprintf("invalid working hours for %s", p.Name), nil)
+		}
+		if p.Buffer < 0 {
+			return newTypedError("slots", ErrorTypeValidation, fmt.Sprintf("negative buffer for %s", p.Name), nil)
+		}
+	}
+
+	if req.Step <= 0 {
+		req.Step = defaultSlotStep
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultSlotMaxResults
+	}
+	return nil
+}
+
+func slotLocation(p SlotParticipant) *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+	return p.Location
+}
+
+// bufferedBusy merges the busy time of p around start to end and widens each
+// period by p's buffer.
+func bufferedBusy(p SlotParticipant, start, end time.Time) []FreeBusyPeriod {
+	merged := mergeFreeBusyPeriods(p.Busy, start.Add(-p.Buffer), end.Add(p.Buffer))
+	for i := range merged {
+		merged[i].Start = merged[i].Start.Add(-p.Buffer)
+		merged[i].End = merged[i].End.Add(p.Buffer)
+	}
+	return merged
+}
+
+// alignSlotStart returns the first multiple of step at or after start.
+func alignSlotStart(start time.Time, step time.Duration) time.Time {
+	aligned := start.Truncate(step)
+	if aligned.Before(start) {
+		aligned = aligned.Add(step)
+	}
+	return aligned.In(start.Location())
+}
+
+// evaluateSlot checks the slot beginning at start against every participant
+// and scores it. ok is false when someone cannot attend.
+func evaluateSlot(req SlotRequest, busy [][]FreeBusyPeriod, start time.Time) (MeetingSlot, bool) {
+	slot := MeetingSlot{Start: start, End: start.Add(req.Duration)}
+
+	var comfort float64
+	for i, p := range req.Participants {
+		dayStart, dayEnd, ok := workingDay(p, slot.Start, slot.End)
+		if !ok {
+			return slot, false
+		}
+
+		before, after := slot.Start.Sub(dayStart), dayEnd.Sub(slot.End)
+		tentative := false
+		for _, period := range busy[i] {
+			if period.Start.Before(slot.End) && period.End.After(slot.Start) {
+				if period.FBType != FBTypeBusyTentative || !req.AllowTentative {
+					return slot, false
+				}
+				tentative = true
+				continue
+			}
+			if gap := slot.Start.Sub(period.End); gap >= 0 && gap < before {
+				before = gap
+			}
+			if gap := period.Start.Sub(slot.End); gap >= 0 && gap < after {
+				after = gap
+			}
+		}
+		if tentative {
+			slot.Tentative = append(slot.Tentative, p.Name)
+		}
+		comfort += slotComfort(before) + slotComfort(after)
+	}
+
+	n := float64(len(req.Participants))
+	comfort /= 2 * n
+	slot.Score = 0.75*(1-float64(len(slot.Tentative))/n) + 0.25*comfort
+	return slot, true
+}
+
+// workingDay returns the bounds of p's working day holding the slot from
+// start to end, or ok false when the slot falls outside p's working hours.
+// Participants without working hours are available at any time.
+func workingDay(p SlotParticipant, start, end time.Time) (time.Time, time.Time, bool) {
+	wh := p.WorkingHours
+	if wh == nil {
+		return start.Add(-slotComfortCap), end.Add(slotComfortCap), true
+	}
+
+	local := start.In(slotLocation(p))
+	if !isWorkingDay(wh, local.Weekday()) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	// time.Date normalises the nanoseconds as wall-clock time, so working
+	// hours keep their local meaning on days with a DST transition.
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, int(wh.Start), local.Location())
+	dayEnd := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, int(wh.End), local.Location())
+	if start.Before(dayStart) || end.After(dayEnd) {
+		return time.Time{}, time.Time{}, false
+	}
+	return dayStart, dayEnd, true
+}
+
+func isWorkingDay(wh *WorkingHours, day time.Weekday) bool {
+	if len(wh.Days) == 0 {
+		return day != time.Saturday && day != time.Sunday
+	}
+	for _, d := range wh.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// slotComfort maps free time next to a slot to between 0 and 1.
+func slotComfort(gap time.Duration) float64 {
+	if gap >= slotComfortCap {
+		return 1
+	}
+	return float64(gap) / float64(slotComfortCap)
+}
+
+func overlapsSlots(slots []MeetingSlot, candidate MeetingSlot) bool {
+	for _, slot := range slots {
+		if slot.Start.Before(candidate.End) && slot.End.After(candidate.Start) {
+			return true
+		}
+	}
+	return false
+}
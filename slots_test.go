@@ -0,0 +1,167 @@
+package caldav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func slotTestParticipants(t *testing.T) []SlotParticipant {
+	t.Helper()
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	workday := &WorkingHours{Start: 9 * time.Hour, End: 17 * time.Hour}
+	return []SlotParticipant{
+		{
+			Name:         "olivia",
+			Location:     london,
+			WorkingHours: workday,
+			Buffer:       15 * time.Minute,
+			Busy: []FreeBusyPeriod{
+				{Start: mustParseTime("20250115T140000Z"), End: mustParseTime("20250115T150000Z"), FBType: FBTypeBusy},
+			},
+		},
+		{
+			Name:         "sam",
+			Location:     newYork,
+			WorkingHours: workday,
+			Busy: []FreeBusyPeriod{
+				{Start: mustParseTime("20250115T160000Z"), End: mustParseTime("20250115T163000Z"), FBType: FBTypeBusyTentative},
+			},
+		},
+	}
+}
+
+func TestFindFreeSlots(t *testing.T) {
+	req := SlotRequest{
+		Participants: slotTestParticipants(t),
+		Start:        mustParseTime("20250115T000000Z"),
+		End:          mustParseTime("20250116T000000Z"),
+		Duration:     30 * time.Minute,
+	}
+
+	slots, err := FindFreeSlots(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The working days overlap from 14:00 to 17:00 UTC; olivia is busy until
+	// 15:00 plus her buffer and sam is tentatively busy from 16:00 to 16:30.
+	expected := []time.Time{mustParseTime("20250115T151500Z"), mustParseTime("20250115T163000Z")}
+	if len(slots) != len(expected) {
+		t.Fatalf("expected %d slots, got %+v", len(expected), slots)
+	}
+	for i, slot := range slots {
+		if !slot.Start.Equal(expected[i]) || slot.End.Sub(slot.Start) != 30*time.Minute {
+			t.Errorf("slot %d: expected start %v, got %v-%v", i, expected[i], slot.Start, slot.End)
+		}
+	}
+	if slots[0].Score <= slots[1].Score {
+		t.Errorf("expected slots ranked by score, got %v then %v", slots[0].Score, slots[1].Score)
+	}
+
+	req.AllowTentative = true
+	slots, err = FindFreeSlots(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slots) != 3 {
+		t.Fatalf("expected a third, tentative slot, got %+v", slots)
+	}
+	last := slots[2]
+	if !last.Start.Equal(mustParseTime("20250115T154500Z")) || len(last.Tentative) != 1 || last.Tentative[0] != "sam" {
+		t.Errorf("unexpected tentative slot %+v", last)
+	}
+	if last.Score >= slots[1].Score {
+		t.Errorf("expected the tentative slot to rank last, got score %v", last.Score)
+	}
+}
+
+func TestFindFreeSlots_SeveralTentativePeriods(t *testing.T) {
+	slots, err := FindFreeSlots(SlotRequest{
+		Participants: []SlotParticipant{{
+			Name: "sam",
+			Busy: []FreeBusyPeriod{
+				{Start: mustParseTime("20250115T100000Z"), End: mustParseTime("20250115T101500Z"), FBType: FBTypeBusyTentative},
+				{Start: mustParseTime("20250115T103000Z"), End: mustParseTime("20250115T104500Z"), FBType: FBTypeBusyTentative},
+			},
+		}},
+		Start:          mustParseTime("20250115T100000Z"),
+		End:            mustParseTime("20250115T110000Z"),
+		Duration:       time.Hour,
+		AllowTentative: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slots) != 1 {
+		t.Fatalf("expected one slot, got %+v", slots)
+	}
+	if len(slots[0].Tentative) != 1 || slots[0].Tentative[0] != "sam" {
+		t.Errorf("expected sam to be tentative once, got %v", slots[0].Tentative)
+	}
+	if slots[0].Score < 0 {
+		t.Errorf("expected a non-negative score, got %v", slots[0].Score)
+	}
+}
+
+func TestFindFreeSlots_Validation(t *testing.T) {
+	start := mustParseTime("20250115T000000Z")
+	tests := []SlotRequest{
+		{Start: start, End: start.Add(time.Hour), Duration: time.Hour},
+		{Participants: []SlotParticipant{{}}, Start: start, End: start.Add(time.Hour)},
+		{Participants: []SlotParticipant{{}}, Start: start, End: start, Duration: time.Hour},
+		{Participants: []SlotParticipant{{WorkingHours: &WorkingHours{Start: 17 * time.Hour, End: 9 * time.Hour}}}, Start: start, End: start.Add(time.Hour), Duration: time.Hour},
+	}
+	for i, req := range tests {
+		if _, err := FindFreeSlots(req); !IsValidationError(err) {
+			t.Errorf("case %d: expected a validation error, got %v", i, err)
+		}
+	}
+}
+
+func TestFindMeetingSlots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "REPORT" {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+			`<D:response><D:href>/cal/standup.ics</D:href><D:propstat><D:status>HTTP/1.1 200 OK</D:status><D:prop><D:getetag>"1"</D:getetag><C:calendar-data>` +
+			"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\nBEGIN:VEVENT\r\nUID:standup\r\n" +
+			"DTSTART:20250113T090000Z\r\nDTEND:20250113T100000Z\r\nRRULE:FREQ=DAILY\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n" +
+			`</C:calendar-data></D:prop></D:propstat></D:response></D:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient("testuser", "testpass")
+	client.baseURL = server.URL
+
+	slots, err := client.FindMeetingSlots(context.Background(), SlotRequest{
+		Participants: []SlotParticipant{{Name: "olivia", CalendarHrefs: []string{"/cal/"}}},
+		Start:        mustParseTime("20250115T090000Z"),
+		End:          mustParseTime("20250115T110000Z"),
+		Duration:     time.Hour,
+		MaxResults:   1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slots) != 1 || !slots[0].Start.Equal(mustParseTime("20250115T100000Z")) {
+		var got []string
+		for _, s := range slots {
+			got = append(got, s.Start.String())
+		}
+		t.Errorf("expected the slot after the recurring standup, got %s", strings.Join(got, ", "))
+	}
+}
@@ -32,6 +32,23 @@ type SyncRequest struct {
 	SyncLevel   int
 	Properties  []string
 	Limit       int
+
+	// Known maps the href of every resource the caller holds to its ETag.
+	// When the server rejects SyncToken, the full listing is compared with
+	// it instead of the client's DeltaSync or SyncStateStore state. A non-nil
+	// empty map reports every resource as new.
+	Known map[string]string
+
+	// resync carries a resync across the pages of a truncated listing.
+	resync *resyncState
+}
+
+// resyncState is what a resync keeps between the pages of a full listing: the
+// known ETags it compares with and the hrefs listed so far.
+type resyncState struct {
+	known  map[string]string
+	listed map[string]bool
+	next   string
 }
 
 type SyncResponse struct {
@@ -39,6 +56,9 @@ type SyncResponse struct {
 	Changes      []SyncChange
 	MoreToSync   bool
 	TotalChanges int
+	// Resynced is set when the server rejected the sync token and Changes
+	// were derived by comparing a full listing with the last known state.
+	Resynced bool
 }
 
 type SyncChange struct {
@@ -47,7 +67,9 @@ type SyncChange struct {
 	ETag         string
 	CalendarData string
 	Deleted      bool
-	Properties   map[string]string
+	// New is set by a resync on resources missing from the known state.
+	New        bool
+	Properties map[string]string
 }
 
 type SyncChangeType int
@@ -121,39 +143,187 @@ const (
 	SyncChangeTypeDeleted
 )
 
+// ChangeType classifies the change. Resources a resync marks New are always
+// reported new.
 func (c *SyncChange) ChangeType() SyncChangeType {
 	if c.Deleted {
 		return SyncChangeTypeDeleted
 	}
+	if c.New {
+		return SyncChangeTypeNew
+	}
 	if c.CalendarData != "" && c.ETag != "" {
 		return SyncChangeTypeModified
 	}
 	return SyncChangeTypeNew
 }
 
+// SyncCalendar runs a sync-collection REPORT (RFC 6578) on req.CalendarURL.
+// When the server rejects req.SyncToken as invalid or expired, it falls back
+// to a full sync and reports the difference from the last known state, see
+// SyncResponse.Resynced. A truncated resync is continued, like any truncated
+// result, by calling SyncCalendar again with the same req and its SyncToken
+// set to the returned one, as SyncIterator does.
 func (c *CalDAVClient) SyncCalendar(ctx context.Context, req *SyncRequest) (*SyncResponse, error) {
 	if req.CalendarURL == "" {
 		return nil, newTypedError("SyncCalendar", ErrorTypeValidation, "calendar URL is required for sync", nil)
 	}
 
+	if req.resync != nil && req.resync.next != "" && req.resync.next == req.SyncToken {
+		return c.resyncPage(ctx, req)
+	}
+	req.resync = nil
+
+	resp, rejected, err := c.syncCollection(ctx, req)
+	if rejected {
+		return c.resyncCalendar(ctx, req)
+	}
+	return resp, err
+}
+
+// syncCollection runs one sync-collection REPORT. rejected is set when the
+// server refused req.SyncToken.
+func (c *CalDAVClient) syncCollection(ctx context.Context, req *SyncRequest) (*SyncResponse, bool, error) {
 	xmlBody := buildSyncCollectionXML(req)
 
 	resp, err := c.report(ctx, req.CalendarURL, []byte(xmlBody))
 	if err != nil {
-		return nil, wrapErrorWithType("SyncCalendar", ErrorTypeNetwork, err)
+		return nil, false, wrapErrorWithType("SyncCalendar", ErrorTypeNetwork, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != 207 {
-		return nil, newTypedError("SyncCalendar", ErrorTypeServer, "unexpected status code", nil)
+		body, _ := io.ReadAll(resp.Body)
+		if req.SyncToken != "" && isInvalidSyncTokenResponse(resp.StatusCode, body) {
+			return nil, true, nil
+		}
+		return nil, false, newTypedError("SyncCalendar", ErrorTypeServer, "unexpected status code", nil)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, wrapErrorWithType("SyncCalendar", ErrorTypeNetwork, err)
+		return nil, false, wrapErrorWithType("SyncCalendar", ErrorTypeNetwork, err)
 	}
 
-	return parseSyncResponse(body)
+	listing, err := parseSyncResponse(body)
+	return listing, false, err
+}
+
+// isInvalidSyncTokenResponse reports whether a sync-collection REPORT failed
+// the DAV:valid-sync-token precondition (RFC 6578 section 3.2).
+func isInvalidSyncTokenResponse(statusCode int, body []byte) bool {
+	if statusCode != http.StatusForbidden && statusCode != http.StatusConflict {
+		return false
+	}
+	return strings.Contains(string(body), "valid-sync-token")
+}
+
+// resyncCalendar recovers from a rejected sync token with a full sync. The
+// last known state comes from req.Known, DeltaSync or the SyncStateStore;
+// without one every resource is reported as new. It returns the first page of
+// the full listing.
+func (c *CalDAVClient) resyncCalendar(ctx context.Context, req *SyncRequest) (*SyncResponse, error) {
+	known := req.Known
+	if known == nil {
+		state, err := c.loadDeltaState(ctx, req.CalendarURL)
+		if err != nil {
+			return nil, err
+		}
+		known = knownETags(state)
+	}
+
+	if c.logger != nil {
+		c.logger.Info("Sync token rejected, running a full sync", "calendar", req.CalendarURL)
+	}
+
+	req.resync = &resyncState{known: known, listed: make(map[string]bool)}
+	return c.resyncPage(ctx, req)
+}
+
+// knownETags maps the href of every resource in state to its ETag, in the form
+// SyncRequest.Known takes.
+func knownETags(state *DeltaSyncState) map[string]string {
+	known := make(map[string]string, len(state.Resources))
+	for href, resource := range state.Resources {
+		known[href] = resource.ETag
+	}
+	return known
+}
+
+// resyncPage fetches the next page of the full listing of a resync and
+// compares it with the known state. Only the hrefs listed are kept between
+// pages; deletions are reported with the last page.
+func (c *CalDAVClient) resyncPage(ctx context.Context, req *SyncRequest) (*SyncResponse, error) {
+	state := req.resync
+	full := *req
+	full.SyncToken = state.next
+	page, rejected, err := c.syncCollection(ctx, &full)
+	if err != nil {
+		return nil, err
+	}
+	if rejected {
+		req.resync = nil
+		return nil, newTypedError("SyncCalendar", ErrorTypeServer, "sync token of a truncated full sync was rejected", nil)
+	}
+
+	resp := diffSyncListing(state.known, page, state.listed)
+	if resp.MoreToSync {
+		state.next = resp.SyncToken
+	} else {
+		req.resync = nil
+	}
+	return resp, nil
+}
+
+// diffSyncListing compares one page of the full listing of a calendar with its
+// last known state, recording the hrefs it shows in listed. Resources with an
+// unchanged ETag are left out, and known resources missing from every page of
+// a complete listing are reported deleted with its last page.
+func diffSyncListing(known map[string]string, listing *SyncResponse, listed map[string]bool) *SyncResponse {
+	resp := &SyncResponse{
+		SyncToken:  listing.SyncToken,
+		Changes:    make([]SyncChange, 0),
+		MoreToSync: listing.MoreToSync,
+		Resynced:   true,
+	}
+
+	for _, change := range listing.Changes {
+		if change.Href == "" {
+			continue
+		}
+		// A resource removed while the listing is paged shows up as a
+		// deletion on a later page.
+		if change.Deleted {
+			delete(listed, change.Href)
+			continue
+		}
+		listed[change.Href] = true
+
+		etag, exists := known[change.Href]
+		if !exists {
+			change.New = true
+		} else if etag != "" && etag == change.ETag {
+			continue
+		}
+		resp.Changes = append(resp.Changes, change)
+	}
+
+	// A truncated listing says nothing about the resources it does not show.
+	if !listing.MoreToSync {
+		for _, href := range sortedKeys(known) {
+			if !listed[href] {
+				resp.Changes = append(resp.Changes, SyncChange{
+					Href:       href,
+					Status:     http.StatusNotFound,
+					Deleted:    true,
+					Properties: make(map[string]string),
+				})
+			}
+		}
+	}
+
+	resp.TotalChanges = len(resp.Changes)
+	return resp
 }
 
 func (c *CalDAVClient) InitialSync(ctx context.Context, calendarURL string) (*SyncResponse, error) {
@@ -184,6 +354,10 @@ func (c *CalDAVClient) IncrementalSync(ctx context.Context, calendarURL string,
 	})
 }
 
+// SyncAllCalendars syncs every calendar, incrementally where syncTokens holds
+// a token for its href. With a SyncStateStore configured, calendars missing
+// from syncTokens resume from their stored token and each successful round is
+// saved to the store.
 func (c *CalDAVClient) SyncAllCalendars(ctx context.Context, syncTokens map[string]string) (map[string]*SyncResponse, error) {
 	return c.SyncAllCalendarsWithWorkers(ctx, syncTokens, 5)
 }
@@ -231,18 +405,7 @@ func (c *CalDAVClient) SyncAllCalendarsWithWorkers(ctx context.Context, syncToke
 				default:
 				}
 
-				var syncResp *SyncResponse
-				var err error
-
-				if job.syncToken != "" {
-					syncResp, err = c.IncrementalSync(workerCtx, job.calendar.Href, job.syncToken)
-					if err != nil {
-						syncResp, err = c.InitialSync(workerCtx, job.calendar.Href)
-					}
-				} else {
-					syncResp, err = c.InitialSync(workerCtx, job.calendar.Href)
-				}
-
+				syncResp, err := c.syncCalendarJob(workerCtx, job)
 				if err != nil && c.logger != nil {
 					c.logger.Debug("Error syncing calendar", "name", job.calendar.DisplayName, "error", err)
 				}
@@ -276,6 +439,44 @@ func (c *CalDAVClient) SyncAllCalendarsWithWorkers(ctx context.Context, syncToke
 	return syncResults, nil
 }
 
+// syncCalendarJob runs one sync round for a calendar of SyncAllCalendars.
+// With a SyncStateStore configured a calendar without a token in syncTokens
+// resumes from its stored token, and the resulting state is saved.
+func (c *CalDAVClient) syncCalendarJob(ctx context.Context, job syncJob) (*SyncResponse, error) {
+	href := job.calendar.Href
+	token := job.syncToken
+
+	var state *DeltaSyncState
+	if c.syncStore != nil {
+		var err error
+		if state, err = c.loadDeltaState(ctx, href); err != nil {
+			return nil, err
+		}
+		if token == "" {
+			token = state.SyncToken
+		}
+	}
+
+	var syncResp *SyncResponse
+	var err error
+	if token != "" {
+		syncResp, err = c.IncrementalSync(ctx, href, token)
+	}
+	if token == "" || err != nil {
+		// A full sync replaces whatever state the token belonged to.
+		state = nil
+		syncResp, err = c.InitialSync(ctx, href)
+	}
+	if err != nil || c.syncStore == nil {
+		return syncResp, err
+	}
+
+	if err := c.saveDeltaState(ctx, href, deltaStateFromSyncResponse(state, syncResp)); err != nil {
+		return nil, err
+	}
+	return syncResp, nil
+}
+
 func buildSyncCollectionXML(req *SyncRequest) string {
 	var buf strings.Builder
 
@@ -345,6 +546,17 @@ func parseSyncResponse(body []byte) (*SyncResponse, error) {
 			Properties: make(map[string]string),
 		}
 
+		// RFC 6578 reports removed members with a bare 404 status, and a
+		// truncated result with a 507 status on the collection itself.
+		switch status := parseStatusCode(response.Status); status {
+		case http.StatusNotFound:
+			change.Deleted = true
+			change.Status = status
+		case http.StatusInsufficientStorage:
+			resp.MoreToSync = true
+			continue
+		}
+
 		for _, propstat := range response.Propstats {
 			status := parseStatusCode(propstat.Status)
 
@@ -361,9 +573,9 @@ func parseSyncResponse(body []byte) (*SyncResponse, error) {
 				change.Properties["getetag"] = propstat.Prop.GetETag
 			}
 
-			if propstat.Prop.CalendarData != "" {
-				change.CalendarData = propstat.Prop.CalendarData
-				change.Properties["calendar-data"] = propstat.Prop.CalendarData
+			if calendarData := calendarDataText(propstat.Prop.CalendarData); calendarData != "" {
+				change.CalendarData = calendarData
+				change.Properties["calendar-data"] = calendarData
 			}
 
 			if propstat.Prop.DisplayName != "" {
@@ -693,53 +905,123 @@ func (c *CalDAVClient) executeSingleOperation(ctx context.Context, op BatchOpera
 	}, nil
 }
 
+// DeltaSync fetches the changes to calendarPath since its last delta sync and
+// returns the updated resource state. With a SyncStateStore configured the
+// first round resumes from the stored state and every successful round is
+// saved before it is returned.
 func (c *CalDAVClient) DeltaSync(ctx context.Context, calendarPath string) (*DeltaSyncState, error) {
-	c.syncMu.RLock()
-	state, exists := c.deltaStates[calendarPath]
-	c.syncMu.RUnlock()
-
-	if !exists {
-		state = &DeltaSyncState{
-			Resources: make(map[string]*DeltaResource),
-			LastSync:  time.Time{},
-		}
+	state, err := c.loadDeltaState(ctx, calendarPath)
+	if err != nil {
+		return nil, err
 	}
 
-	syncReq := c.buildSyncRequest(state.SyncToken)
-
-	// Use the CalDAVClient's report method which includes XML validation
-	resp, err := c.report(ctx, calendarPath, []byte(syncReq))
+	status, body, err := c.deltaSyncReport(ctx, calendarPath, state.SyncToken)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		_, _ = io.Copy(io.Discard, resp.Body)
-		_ = resp.Body.Close()
-	}()
 
-	if resp.StatusCode != http.StatusMultiStatus {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	// A rejected token is recovered from with a full listing; resources it no
+	// longer contains become pending deletes.
+	baseState := state
+	resync := state.SyncToken != "" && isInvalidSyncTokenResponse(status, body)
+	if resync {
+		if c.logger != nil {
+			c.logger.Info("Sync token rejected, running a full sync", "calendar", calendarPath)
+		}
+		baseState = nil
+		if status, body, err = c.deltaSyncReport(ctx, calendarPath, ""); err != nil {
+			return nil, err
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if status != http.StatusMultiStatus {
+		return nil, fmt.Errorf("unexpected status: %d", status)
 	}
 
-	newState, err := c.parseDeltaSyncResponse(body, state)
+	newState, truncated, err := c.parseDeltaSyncResponse(body, baseState)
 	if err != nil {
 		return nil, err
 	}
 
+	if resync {
+		if newState, truncated, err = c.continueDeltaListing(ctx, calendarPath, newState, truncated); err != nil {
+			return nil, err
+		}
+		// Only a complete listing shows which resources are gone; known
+		// resources a truncated one has not reached are kept.
+		for _, href := range sortedKeys(state.Resources) {
+			if _, exists := newState.Resources[href]; exists {
+				continue
+			}
+			if truncated {
+				newState.Resources[href] = state.Resources[href]
+			} else if !containsHref(newState.PendingDeletes, href) {
+				newState.PendingDeletes = append(newState.PendingDeletes, href)
+			}
+		}
+	}
+
 	newState.LastSync = time.Now()
 
-	c.syncMu.Lock()
-	c.deltaStates[calendarPath] = newState
-	c.syncMu.Unlock()
+	if err := c.saveDeltaState(ctx, calendarPath, newState); err != nil {
+		return nil, err
+	}
 
 	return newState, nil
 }
 
+// continueDeltaListing follows a truncated full listing with further
+// sync-collection requests from the token each page returns. It reports
+// whether the listing is still truncated, which happens when the server
+// stops handing out new tokens.
+func (c *CalDAVClient) continueDeltaListing(ctx context.Context, calendarPath string, state *DeltaSyncState, truncated bool) (*DeltaSyncState, bool, error) {
+	for truncated {
+		token := state.SyncToken
+		if token == "" {
+			break
+		}
+
+		status, body, err := c.deltaSyncReport(ctx, calendarPath, token)
+		if err != nil {
+			return nil, false, err
+		}
+		if status != http.StatusMultiStatus {
+			return nil, false, fmt.Errorf("unexpected status: %d", status)
+		}
+
+		next, more, err := c.parseDeltaSyncResponse(body, state)
+		if err != nil {
+			return nil, false, err
+		}
+		next.PendingDeletes = append(state.PendingDeletes, next.PendingDeletes...)
+		state, truncated = next, more
+		if state.SyncToken == token {
+			break
+		}
+	}
+	return state, truncated, nil
+}
+
+func (c *CalDAVClient) deltaSyncReport(ctx context.Context, calendarPath, syncToken string) (int, []byte, error) {
+	syncReq := c.buildSyncRequest(syncToken)
+
+	// Use the CalDAVClient's report method which includes XML validation
+	resp, err := c.report(ctx, calendarPath, []byte(syncReq))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
 func (c *CalDAVClient) buildSyncRequest(syncToken string) string {
 	var syncTokenXML string
 	if syncToken != "" {
@@ -759,17 +1041,22 @@ func (c *CalDAVClient) buildSyncRequest(syncToken string) string {
 </d:sync-collection>`, syncTokenXML)
 }
 
-func (c *CalDAVClient) parseDeltaSyncResponse(body []byte, oldState *DeltaSyncState) (*DeltaSyncState, error) {
+// parseDeltaSyncResponse applies a sync-collection response to oldState. It
+// also reports whether the server truncated the response.
+func (c *CalDAVClient) parseDeltaSyncResponse(body []byte, oldState *DeltaSyncState) (*DeltaSyncState, bool, error) {
 	newState := initializeDeltaSyncState(oldState)
 	bodyStr := string(body)
 
+	truncated := false
 	responses := strings.Split(bodyStr, "<response>")
 	for _, resp := range responses[1:] {
-		processDeltaSyncResponseItem(resp, newState)
+		if processDeltaSyncResponseItem(resp, newState) {
+			truncated = true
+		}
 	}
 
 	extractSyncToken(bodyStr, newState)
-	return newState, nil
+	return newState, truncated, nil
 }
 
 func initializeDeltaSyncState(oldState *DeltaSyncState) *DeltaSyncState {
@@ -787,15 +1074,31 @@ func initializeDeltaSyncState(oldState *DeltaSyncState) *DeltaSyncState {
 	return newState
 }
 
-func processDeltaSyncResponseItem(resp string, state *DeltaSyncState) {
+// processDeltaSyncResponseItem applies one response element to state and
+// reports whether it is the 507 status RFC 6578 uses to mark a truncated
+// result.
+func processDeltaSyncResponseItem(resp string, state *DeltaSyncState) bool {
 	href := extractXMLValue(resp, "<href>", "</href>")
 	status := extractHTTPStatus(resp)
 
-	if status == 200 {
+	switch {
+	case status == http.StatusOK:
 		handleSuccessfulDeltaItem(resp, href, state)
-	} else if status == 404 && href != "" {
+	case status == http.StatusNotFound && href != "":
 		handleDeletedDeltaItem(href, state)
+	case status == http.StatusInsufficientStorage:
+		return true
+	}
+	return false
+}
+
+func containsHref(hrefs []string, href string) bool {
+	for _, h := range hrefs {
+		if h == href {
+			return true
+		}
 	}
+	return false
 }
 
 func extractXMLValue(content, startTag, endTag string) string {
@@ -0,0 +1,574 @@
+package caldav
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SyncMirror is the application's local copy of calendars that a SyncEngine
+// keeps converging with the server. Objects are addressed by their server
+// href and hold iCalendar data; the application picks the href of objects it
+// creates, normally the calendar path plus the UID and ".ics".
+//
+// ListObjects must return data exactly as PutObject stored it unless the
+// application changed it, since the engine detects local changes by content.
+type SyncMirror interface {
+	// ListObjects returns every local object of the calendar, keyed by href.
+	ListObjects(ctx context.Context, calendarPath string) (map[string]string, error)
+	// PutObject stores an object received from the server.
+	PutObject(ctx context.Context, calendarPath, href, calendarData string) error
+	// DeleteObject removes an object that was deleted on the server.
+	DeleteObject(ctx context.Context, calendarPath, href string) error
+}
+
+// SyncObject is one side of a SyncConflict.
+type SyncObject struct {
+	Href         string
+	ETag         string
+	CalendarData string
+}
+
+// SyncConflict describes an object changed both locally and on the server
+// since the last sync. Local or Remote is nil when that side deleted it.
+type SyncConflict struct {
+	CalendarPath string
+	Href         string
+	Local        *SyncObject
+	Remote       *SyncObject
+}
+
+// ConflictStrategy resolves a SyncConflict by returning the object to keep on
+// both sides, which may be a merge of the two, or nil to delete it from both.
+// An error leaves the conflict for the next Sync.
+type ConflictStrategy func(ctx context.Context, conflict *SyncConflict) (*SyncObject, error)
+
+// ServerWins is a ConflictStrategy that keeps the server's version.
+func ServerWins(_ context.Context, conflict *SyncConflict) (*SyncObject, error) {
+	return conflict.Remote, nil
+}
+
+// ClientWins is a ConflictStrategy that keeps the local version.
+func ClientWins(_ context.Context, conflict *SyncConflict) (*SyncObject, error) {
+	return conflict.Local, nil
+}
+
+// LastModifiedWins is a ConflictStrategy that keeps the version with the
+// later LAST-MODIFIED, or DTSTAMP when that is missing. The server wins ties,
+// and a modification wins over a deletion, which carries no timestamp.
+func LastModifiedWins(_ context.Context, conflict *SyncConflict) (*SyncObject, error) {
+	if conflict.Local == nil || conflict.Remote == nil {
+		if conflict.Local != nil {
+			return conflict.Local, nil
+		}
+		return conflict.Remote, nil
+	}
+
+	if syncObjectModified(conflict.Local).After(syncObjectModified(conflict.Remote)) {
+		return conflict.Local, nil
+	}
+	return conflict.Remote, nil
+}
+
+// syncObjectModified returns the latest LAST-MODIFIED, or DTSTAMP, of the
+// components in obj, or the zero time when it has none.
+func syncObjectModified(obj *SyncObject) time.Time {
+	parsed, err := ParseICalendar(obj.CalendarData)
+	if err != nil {
+		return time.Time{}
+	}
+
+	var latest time.Time
+	consider := func(lastModified, dtstamp *time.Time) {
+		if lastModified == nil {
+			lastModified = dtstamp
+		}
+		if lastModified != nil && lastModified.After(latest) {
+			latest = *lastModified
+		}
+	}
+	for _, event := range parsed.Events {
+		consider(event.LastModified, event.DTStamp)
+	}
+	for _, todo := range parsed.Todos {
+		consider(todo.LastModified, todo.DTStamp)
+	}
+	for _, journal := range parsed.Journals {
+		consider(journal.LastModified, journal.DTStamp)
+	}
+	return latest
+}
+
+// SyncEngine keeps a SyncMirror and the server converging. Each Sync pulls the
+// server's changes with sync-collection and calendar-multiget, then pushes
+// local creates, updates and deletes with If-None-Match and If-Match. Objects
+// changed on both sides are settled by the ConflictStrategy.
+//
+// The engine records the sync token and the ETag and checksum of every object
+// in its SyncStateStore. That store must not be shared with the client's
+// WithSyncStateStore, as both key their states by calendar path.
+type SyncEngine struct {
+	client   *CalDAVClient
+	mirror   SyncMirror
+	store    SyncStateStore
+	strategy ConflictStrategy
+}
+
+// SyncEngineResult summarizes one Sync of a calendar.
+type SyncEngineResult struct {
+	CalendarPath string
+	// Pulled counts objects written to or deleted from the mirror.
+	Pulled int
+	// Pushed counts objects created, updated or deleted on the server.
+	Pushed    int
+	Conflicts int
+	// Errors holds the objects that could not be pushed, by href. They are
+	// retried by the next Sync.
+	Errors map[string]error
+}
+
+// NewSyncEngine creates a SyncEngine for mirror. By default state is kept in
+// a MemorySyncStateStore and conflicts are resolved with ServerWins.
+func NewSyncEngine(client *CalDAVClient, mirror SyncMirror, options ...func(*SyncEngine)) *SyncEngine {
+	engine := &SyncEngine{
+		client:   client,
+		mirror:   mirror,
+		store:    NewMemorySyncStateStore(),
+		strategy: ServerWins,
+	}
+
+	for _, opt := range options {
+		opt(engine)
+	}
+
+	return engine
+}
+
+// WithEngineStateStore sets the store in which the engine persists its state.
+func WithEngineStateStore(store SyncStateStore) func(*SyncEngine) {
+	return func(e *SyncEngine) {
+		if store != nil {
+			e.store = store
+		}
+	}
+}
+
+// WithConflictStrategy sets how the engine resolves conflicts.
+func WithConflictStrategy(strategy ConflictStrategy) func(*SyncEngine) {
+	return func(e *SyncEngine) {
+		if strategy != nil {
+			e.strategy = strategy
+		}
+	}
+}
+
+// Sync runs one round for the calendar at calendarPath. Mirror failures and
+// server failures while pulling abort the round, keeping the state of the
+// pages already applied; failures to push an object are reported in the
+// result's Errors and do not stop the round.
+func (e *SyncEngine) Sync(ctx context.Context, calendarPath string) (*SyncEngineResult, error) {
+	state, err := e.store.LoadSyncState(ctx, calendarPath)
+	if err != nil {
+		return nil, wrapErrorWithType("SyncEngine.load", ErrorTypeClient, err)
+	}
+	if state == nil {
+		state = &DeltaSyncState{}
+	}
+	if state.Resources == nil {
+		state.Resources = make(map[string]*DeltaResource)
+	}
+	state.PendingDeletes = nil
+
+	local, err := e.mirror.ListObjects(ctx, calendarPath)
+	if err != nil {
+		return nil, wrapErrorWithType("SyncEngine.mirror", ErrorTypeClient, err)
+	}
+	if local == nil {
+		local = make(map[string]string)
+	}
+
+	round := &syncEngineRound{
+		engine:       e,
+		calendarPath: calendarPath,
+		state:        state,
+		local:        local,
+		changed:      localSyncChanges(state, local),
+		result: &SyncEngineResult{
+			CalendarPath: calendarPath,
+			Errors:       make(map[string]error),
+		},
+	}
+
+	if err := round.pull(ctx); err != nil {
+		return round.result, err
+	}
+	// Pushes that succeeded are saved even when a later one aborts the round.
+	pushErr := round.push(ctx)
+	if pushErr == nil {
+		state.LastSync = time.Now()
+	}
+	if err := round.save(ctx); err != nil {
+		return round.result, err
+	}
+	return round.result, pushErr
+}
+
+// syncEngineRound holds the working state of one SyncEngine.Sync.
+type syncEngineRound struct {
+	engine       *SyncEngine
+	calendarPath string
+	state        *DeltaSyncState
+	local        map[string]string
+	// changed holds the hrefs changed locally since the last sync.
+	changed map[string]bool
+	result  *SyncEngineResult
+}
+
+func (r *syncEngineRound) save(ctx context.Context) error {
+	if err := r.engine.store.SaveSyncState(ctx, r.calendarPath, r.state); err != nil {
+		return wrapErrorWithType("SyncEngine.save", ErrorTypeClient, err)
+	}
+	return nil
+}
+
+// localSyncChanges compares the mirror with the state of the last sync.
+func localSyncChanges(state *DeltaSyncState, local map[string]string) map[string]bool {
+	changed := make(map[string]bool)
+	for href, data := range local {
+		if resource, exists := state.Resources[href]; !exists || resource.Checksum != syncChecksum(data) {
+			changed[href] = true
+		}
+	}
+	for href := range state.Resources {
+		if _, exists := local[href]; !exists {
+			changed[href] = true
+		}
+	}
+	return changed
+}
+
+// syncChecksum hashes data with CRLF line endings turned into LF, as XML
+// parsing does to the calendar-data the server returns.
+func syncChecksum(data string) string {
+	h := sha256.Sum256([]byte(strings.ReplaceAll(data, "\r\n", "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// pull applies the server's changes page by page, saving the state after each
+// page so an interrupted round resumes from there.
+func (r *syncEngineRound) pull(ctx context.Context) error {
+	it := r.engine.client.NewSyncIterator(&SyncRequest{
+		CalendarURL: r.calendarPath,
+		SyncToken:   r.state.SyncToken,
+		SyncLevel:   1,
+		Properties:  []string{"getetag"},
+		Known:       knownETags(r.state),
+	})
+
+	for it.Next(ctx) {
+		var fetch []string
+		for _, change := range it.Page().Changes {
+			if change.Href == "" || change.Href == r.calendarPath {
+				continue
+			}
+			if change.Deleted {
+				if err := r.applyRemote(ctx, change.Href, nil); err != nil {
+					return err
+				}
+				continue
+			}
+			// Our own pushes come back with the ETag we already recorded.
+			if known, exists := r.state.Resources[change.Href]; exists && known.ETag != "" && known.ETag == change.ETag {
+				continue
+			}
+			fetch = append(fetch, change.Href)
+		}
+
+		if len(fetch) > 0 {
+			remotes, err := r.fetchRemote(ctx, fetch)
+			if err != nil {
+				return err
+			}
+			for _, href := range fetch {
+				if err := r.applyRemote(ctx, href, remotes[href]); err != nil {
+					return err
+				}
+			}
+		}
+
+		r.state.SyncToken = it.SyncToken()
+		if err := r.save(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return wrapError("SyncEngine.pull", err)
+	}
+	return nil
+}
+
+// push sends the local changes that did not conflict with the server's.
+func (r *syncEngineRound) push(ctx context.Context) error {
+	for _, href := range sortedKeys(r.changed) {
+		var winner, remote *SyncObject
+		if data, exists := r.local[href]; exists {
+			winner = &SyncObject{Href: href, CalendarData: data}
+		}
+		if resource, exists := r.state.Resources[href]; exists {
+			remote = &SyncObject{Href: href, ETag: resource.ETag}
+		}
+
+		stored, err := r.pushWinner(ctx, href, winner, remote)
+		if isSyncPreconditionError(err) {
+			// The server changed since the pull; settle it as a conflict.
+			remotes, err := r.fetchRemote(ctx, []string{href})
+			if err != nil {
+				r.result.Errors[href] = err
+				continue
+			}
+			if err := r.resolve(ctx, href, remotes[href]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			r.result.Errors[href] = err
+			continue
+		}
+
+		if err := r.storeWinner(ctx, href, stored, etagOf(stored)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRemote applies the server's version of href, nil when deleted, to the
+// mirror, or resolves a conflict when href also changed locally.
+func (r *syncEngineRound) applyRemote(ctx context.Context, href string, remote *SyncObject) error {
+	if r.changed[href] {
+		delete(r.changed, href)
+		return r.resolve(ctx, href, remote)
+	}
+
+	if remote == nil {
+		if _, exists := r.state.Resources[href]; !exists {
+			return nil
+		}
+	}
+	return r.storeWinner(ctx, href, remote, etagOf(remote))
+}
+
+// resolve settles a conflict on href with the engine's ConflictStrategy and
+// makes both sides match the result.
+func (r *syncEngineRound) resolve(ctx context.Context, href string, remote *SyncObject) error {
+	var local *SyncObject
+	if data, exists := r.local[href]; exists {
+		local = &SyncObject{Href: href, CalendarData: data}
+		if resource, exists := r.state.Resources[href]; exists {
+			local.ETag = resource.ETag
+		}
+	}
+	if local == nil && remote == nil {
+		delete(r.state.Resources, href)
+		return nil
+	}
+
+	r.result.Conflicts++
+	winner, err := r.engine.strategy(ctx, &SyncConflict{
+		CalendarPath: r.calendarPath,
+		Href:         href,
+		Local:        local,
+		Remote:       remote,
+	})
+	if err != nil {
+		r.result.Errors[href] = err
+		return nil
+	}
+
+	stored, err := r.pushWinner(ctx, href, winner, remote)
+	if err != nil {
+		r.result.Errors[href] = err
+		return nil
+	}
+	return r.storeWinner(ctx, href, stored, etagOf(stored))
+}
+
+// pushWinner makes the server hold winner, nil meaning deleted, given that it
+// currently holds remote. It returns the server's copy with its ETag. Objects
+// the server already holds are only ever written with If-Match.
+func (r *syncEngineRound) pushWinner(ctx context.Context, href string, winner, remote *SyncObject) (*SyncObject, error) {
+	client := r.engine.client
+
+	var etag string
+	var err error
+	switch {
+	case winner == nil && remote == nil:
+		return nil, nil
+	case winner == nil:
+		var ifMatch string
+		if ifMatch, err = r.ifMatchETag(ctx, href, remote); err != nil {
+			return nil, err
+		}
+		if err := client.deleteCalendarData(ctx, client.baseURL+href, href, ifMatch); err != nil {
+			return nil, err
+		}
+		r.result.Pushed++
+		return nil, nil
+	case remote == nil:
+		etag, err = client.createCalendarData(ctx, client.baseURL+href, href, winner.CalendarData)
+	case remote.CalendarData != "" && remote.CalendarData == winner.CalendarData:
+		return remote, nil
+	default:
+		var ifMatch string
+		if ifMatch, err = r.ifMatchETag(ctx, href, remote); err != nil {
+			return nil, err
+		}
+		etag, err = client.putCalendarData(ctx, client.baseURL+href, href, winner.CalendarData, ifMatch)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.result.Pushed++
+	if etag == "" {
+		// A server that changes what it stores leaves the ETag out of its
+		// PUT response, so the stored copy is fetched along with its ETag.
+		return r.fetchWritten(ctx, href)
+	}
+	return &SyncObject{Href: href, ETag: etag, CalendarData: winner.CalendarData}, nil
+}
+
+// ifMatchETag returns the ETag to send with If-Match when writing over
+// remote. Without one recorded the server's copy is fetched, and its ETag is
+// only used if it still holds the content remote stands for; otherwise the
+// object changed and an ETagMismatchError has it settled as a conflict.
+func (r *syncEngineRound) ifMatchETag(ctx context.Context, href string, remote *SyncObject) (string, error) {
+	if remote.ETag != "" {
+		return remote.ETag, nil
+	}
+
+	remotes, err := r.fetchRemote(ctx, []string{href})
+	if err != nil {
+		return "", err
+	}
+	current := remotes[href]
+	if current == nil {
+		return "", &EventNotFoundError{UID: href}
+	}
+	if current.ETag == "" {
+		return "", newTypedError("SyncEngine.push", ErrorTypeInvalidResponse,
+			fmt.Sprintf("server reported no ETag for %s", href), nil)
+	}
+
+	checksum := syncChecksum(remote.CalendarData)
+	if remote.CalendarData == "" {
+		if resource, exists := r.state.Resources[href]; exists {
+			checksum = resource.Checksum
+		}
+	}
+	if syncChecksum(current.CalendarData) != checksum {
+		return "", &ETagMismatchError{}
+	}
+	return current.ETag, nil
+}
+
+// fetchWritten fetches the server's copy of href after a write that returned
+// no ETag.
+func (r *syncEngineRound) fetchWritten(ctx context.Context, href string) (*SyncObject, error) {
+	remotes, err := r.fetchRemote(ctx, []string{href})
+	if err != nil {
+		return nil, err
+	}
+	written := remotes[href]
+	if written == nil || written.ETag == "" {
+		return nil, newTypedError("SyncEngine.push", ErrorTypeInvalidResponse,
+			fmt.Sprintf("server reported no ETag for %s", href), nil)
+	}
+	return written, nil
+}
+
+// storeWinner makes the mirror hold winner, nil meaning deleted, and records
+// it in the state with the server's etag.
+func (r *syncEngineRound) storeWinner(ctx context.Context, href string, winner *SyncObject, etag string) error {
+	current, exists := r.local[href]
+
+	if winner == nil {
+		if exists {
+			if err := r.engine.mirror.DeleteObject(ctx, r.calendarPath, href); err != nil {
+				return wrapErrorWithType("SyncEngine.mirror", ErrorTypeClient, err)
+			}
+			delete(r.local, href)
+			r.result.Pulled++
+		}
+		delete(r.state.Resources, href)
+		return nil
+	}
+
+	if !exists || current != winner.CalendarData {
+		if err := r.engine.mirror.PutObject(ctx, r.calendarPath, href, winner.CalendarData); err != nil {
+			return wrapErrorWithType("SyncEngine.mirror", ErrorTypeClient, err)
+		}
+		r.local[href] = winner.CalendarData
+		r.result.Pulled++
+	}
+	r.state.Resources[href] = &DeltaResource{
+		Href:     href,
+		ETag:     etag,
+		Checksum: syncChecksum(winner.CalendarData),
+	}
+	return nil
+}
+
+// fetchRemote fetches hrefs with calendar-multiget. Hrefs the server reports
+// missing map to nil; any other failure, including an href the server leaves
+// out of its response, is returned rather than taken as a deletion.
+func (r *syncEngineRound) fetchRemote(ctx context.Context, hrefs []string) (map[string]*SyncObject, error) {
+	objects, err := r.engine.client.MultiGet(ctx, r.calendarPath, hrefs)
+	var multiErr *MultiStatusError
+	if err != nil && !errors.As(err, &multiErr) {
+		return nil, wrapError("SyncEngine.fetch", err)
+	}
+
+	remotes := make(map[string]*SyncObject, len(hrefs))
+	for _, obj := range objects {
+		remotes[obj.Href] = &SyncObject{Href: obj.Href, ETag: obj.ETag, CalendarData: obj.CalendarData}
+	}
+	missing := make(map[string]bool)
+	if multiErr != nil {
+		for _, failed := range multiErr.Responses {
+			if failed.StatusCode != 404 {
+				return nil, newTypedError("SyncEngine.fetch", ErrorTypeServer,
+					fmt.Sprintf("fetching %s failed with status %d", failed.Href, failed.StatusCode), failed.Error)
+			}
+			missing[failed.Href] = true
+		}
+	}
+
+	for _, href := range hrefs {
+		if _, found := remotes[href]; !found && !missing[href] {
+			return nil, newTypedError("SyncEngine.fetch", ErrorTypeInvalidResponse,
+				fmt.Sprintf("server did not return %s", href), nil)
+		}
+	}
+	return remotes, nil
+}
+
+func etagOf(obj *SyncObject) string {
+	if obj == nil {
+		return ""
+	}
+	return obj.ETag
+}
+
+func isSyncPreconditionError(err error) bool {
+	var mismatch *ETagMismatchError
+	var exists *EventExistsError
+	var notFound *EventNotFoundError
+	return errors.As(err, &mismatch) || errors.As(err, &exists) || errors.As(err, &notFound)
+}
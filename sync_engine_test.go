@@ -0,0 +1,560 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSyncServer is a minimal CalDAV collection at /cal/ supporting
+// sync-collection, calendar-multiget and conditional PUT and DELETE.
+type fakeSyncServer struct {
+	mu       sync.Mutex
+	objects  map[string]string
+	etags    map[string]string
+	log      []string // href changed by each version
+	puts     []string
+	deletes  []string
+	expireAt int // sync tokens older than this are rejected
+	// ifMatch holds the method, href and If-Match header of each write.
+	ifMatch     []string
+	omitPutETag bool
+}
+
+func newFakeSyncServer() *fakeSyncServer {
+	return &fakeSyncServer{objects: make(map[string]string), etags: make(map[string]string)}
+}
+
+// set changes an object on the server side, "" deleting it.
+func (s *fakeSyncServer) set(href, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setLocked(href, data)
+}
+
+func (s *fakeSyncServer) setLocked(href, data string) {
+	s.log = append(s.log, href)
+	if data == "" {
+		delete(s.objects, href)
+		delete(s.etags, href)
+		return
+	}
+	s.objects[href] = data
+	s.etags[href] = fmt.Sprintf(`"v%d"`, len(s.log))
+}
+
+var hrefPattern = regexp.MustCompile(`<D:href>([^<]*)</D:href>`)
+
+func (s *fakeSyncServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	href := r.URL.Path
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		s.ifMatch = append(s.ifMatch, r.Method+" "+href+" "+r.Header.Get("If-Match"))
+	}
+	switch {
+	case r.Method == http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && s.etags[href] != "" ||
+			r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != s.etags[href] {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		s.puts = append(s.puts, href)
+		s.setLocked(href, string(body))
+		if !s.omitPutETag {
+			w.Header().Set("ETag", s.etags[href])
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		if s.etags[href] == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != s.etags[href] {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		s.deletes = append(s.deletes, href)
+		s.setLocked(href, "")
+		w.WriteHeader(http.StatusNoContent)
+	case strings.Contains(string(body), "sync-collection"):
+		s.syncCollection(w, string(body))
+	default:
+		var sb strings.Builder
+		sb.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+		for _, match := range hrefPattern.FindAllStringSubmatch(string(body), -1) {
+			if data, ok := s.objects[match[1]]; ok {
+				sb.WriteString(`<D:response><D:href>` + match[1] + `</D:href><D:propstat><D:prop><D:getetag>` + s.etags[match[1]] +
+					`</D:getetag><C:calendar-data>` + html.EscapeString(data) + `</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+			} else {
+				sb.WriteString(`<D:response><D:href>` + match[1] + `</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>`)
+			}
+		}
+		sb.WriteString(`</D:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(sb.String()))
+	}
+}
+
+func (s *fakeSyncServer) syncCollection(w http.ResponseWriter, body string) {
+	since := 0
+	if token := extractXMLValue(body, "<D:sync-token>", "</D:sync-token>"); token != "" {
+		since, _ = strconv.Atoi(token)
+		if since < s.expireAt {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(invalidSyncTokenBody))
+			return
+		}
+	}
+
+	changed := make(map[string]bool)
+	for _, href := range s.log[since:] {
+		changed[href] = true
+	}
+	var sb strings.Builder
+	sb.WriteString(`<D:multistatus xmlns:D="DAV:">`)
+	for _, href := range sortedKeys(changed) {
+		if etag, ok := s.etags[href]; ok {
+			sb.WriteString(`<D:response><D:href>` + href + `</D:href><D:propstat><D:prop><D:getetag>` + etag +
+				`</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+		} else if since > 0 {
+			sb.WriteString(`<D:response><D:href>` + href + `</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>`)
+		}
+	}
+	sb.WriteString(`<D:sync-token>` + strconv.Itoa(len(s.log)) + `</D:sync-token></D:multistatus>`)
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write([]byte(sb.String()))
+}
+
+type memoryMirror struct {
+	objects map[string]string
+	fail    error
+}
+
+func (m *memoryMirror) ListObjects(context.Context, string) (map[string]string, error) {
+	objects := make(map[string]string, len(m.objects))
+	for href, data := range m.objects {
+		objects[href] = data
+	}
+	return objects, nil
+}
+
+func (m *memoryMirror) PutObject(_ context.Context, _, href, data string) error {
+	if m.fail != nil {
+		return m.fail
+	}
+	m.objects[href] = data
+	return nil
+}
+
+func (m *memoryMirror) DeleteObject(_ context.Context, _, href string) error {
+	if m.fail != nil {
+		return m.fail
+	}
+	delete(m.objects, href)
+	return nil
+}
+
+func syncTestEvent(uid, summary string, lastModified time.Time) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:" + uid +
+		"\r\nDTSTAMP:20240101T000000Z\r\nLAST-MODIFIED:" + formatICalTime(lastModified) +
+		"\r\nDTSTART:20240115T100000Z\r\nSUMMARY:" + summary + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+}
+
+func newSyncEngineTest(t *testing.T, options ...func(*SyncEngine)) (*SyncEngine, *fakeSyncServer, *memoryMirror, func()) {
+	fake := newFakeSyncServer()
+	client, server := setupTestClient(t, fake.ServeHTTP)
+	mirror := &memoryMirror{objects: make(map[string]string)}
+	return NewSyncEngine(client, mirror, options...), fake, mirror, server.Close
+}
+
+func TestSyncEngine_PullAndPush(t *testing.T) {
+	engine, fake, mirror, closeServer := newSyncEngineTest(t)
+	defer closeServer()
+	ctx := context.Background()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fake.set("/cal/a.ics", syncTestEvent("a", "Remote A", day))
+	fake.set("/cal/b.ics", syncTestEvent("b", "Remote B", day))
+
+	result, err := engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("initial Sync failed: %v", err)
+	}
+	if result.Pulled != 2 || result.Pushed != 0 || len(mirror.objects) != 2 {
+		t.Fatalf("expected 2 pulled objects, got %+v with mirror %v", result, mirror.objects)
+	}
+
+	// Local create, update and delete are pushed with preconditions.
+	mirror.objects["/cal/c.ics"] = syncTestEvent("c", "Local C", day)
+	mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local A", day)
+	delete(mirror.objects, "/cal/b.ics")
+
+	result, err = engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("push Sync failed: %v", err)
+	}
+	if result.Pushed != 3 || result.Pulled != 0 || result.Conflicts != 0 || len(result.Errors) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if !strings.Contains(fake.objects["/cal/a.ics"], "Local A") || fake.objects["/cal/c.ics"] == "" {
+		t.Errorf("expected local changes on the server, got %v", fake.objects)
+	}
+	if _, exists := fake.objects["/cal/b.ics"]; exists {
+		t.Error("expected b.ics to be deleted on the server")
+	}
+
+	// The pushed changes come back from the server without further work.
+	result, err = engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("idle Sync failed: %v", err)
+	}
+	if result.Pulled != 0 || result.Pushed != 0 {
+		t.Errorf("expected an idle round, got %+v", result)
+	}
+
+	// Remote update and delete reach the mirror.
+	fake.set("/cal/c.ics", syncTestEvent("c", "Remote C", day))
+	fake.set("/cal/a.ics", "")
+	result, err = engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("pull Sync failed: %v", err)
+	}
+	if result.Pulled != 2 || !strings.Contains(mirror.objects["/cal/c.ics"], "Remote C") {
+		t.Errorf("expected the remote update, got %+v with mirror %v", result, mirror.objects)
+	}
+	if _, exists := mirror.objects["/cal/a.ics"]; exists {
+		t.Error("expected a.ics to be deleted from the mirror")
+	}
+}
+
+func TestSyncEngine_PutWithoutETag(t *testing.T) {
+	engine, fake, mirror, closeServer := newSyncEngineTest(t)
+	defer closeServer()
+	ctx := context.Background()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fake.omitPutETag = true
+	fake.set("/cal/a.ics", syncTestEvent("a", "Remote A", day))
+	if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+		t.Fatalf("initial Sync failed: %v", err)
+	}
+
+	mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local A", day)
+	mirror.objects["/cal/c.ics"] = syncTestEvent("c", "Local C", day)
+	result, err := engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("push Sync failed: %v", err)
+	}
+	if result.Pushed != 2 || result.Conflicts != 0 || len(result.Errors) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// The engine's own pushes are not mistaken for remote changes.
+	result, err = engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("idle Sync failed: %v", err)
+	}
+	if result.Pulled != 0 || result.Pushed != 0 || result.Conflicts != 0 {
+		t.Errorf("expected an idle round, got %+v", result)
+	}
+
+	mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local A again", day)
+	delete(mirror.objects, "/cal/c.ics")
+	result, err = engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("second push Sync failed: %v", err)
+	}
+	if result.Pushed != 2 || result.Conflicts != 0 || len(result.Errors) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	expected := []string{
+		`PUT /cal/a.ics "v1"`,
+		`PUT /cal/c.ics `,
+		`PUT /cal/a.ics "v2"`,
+		`DELETE /cal/c.ics "v3"`,
+	}
+	if strings.Join(fake.ifMatch, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected writes %q, got %q", expected, fake.ifMatch)
+	}
+}
+
+func TestSyncEngine_StateWithoutETag(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	remote := syncTestEvent("a", "Remote A", day)
+
+	tests := []struct {
+		name      string
+		server    string
+		wantWrite []string
+		wantData  string
+	}{
+		{name: "unchanged on the server", server: remote, wantWrite: []string{`PUT /cal/a.ics "v1"`}, wantData: "Local A"},
+		{name: "changed on the server", server: syncTestEvent("a", "Changed A", day), wantData: "Changed A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemorySyncStateStore()
+			engine, fake, mirror, closeServer := newSyncEngineTest(t, WithEngineStateStore(store))
+			defer closeServer()
+
+			fake.set("/cal/a.ics", tt.server)
+			_ = store.SaveSyncState(ctx, "/cal/", &DeltaSyncState{
+				SyncToken: "1",
+				Resources: map[string]*DeltaResource{
+					"/cal/a.ics": {Href: "/cal/a.ics", Checksum: syncChecksum(remote)},
+				},
+			})
+			mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local A", day)
+
+			if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+				t.Fatalf("Sync failed: %v", err)
+			}
+			if strings.Join(fake.ifMatch, "\n") != strings.Join(tt.wantWrite, "\n") {
+				t.Errorf("expected writes %q, got %q", tt.wantWrite, fake.ifMatch)
+			}
+			if !strings.Contains(fake.objects["/cal/a.ics"], tt.wantData) || !strings.Contains(mirror.objects["/cal/a.ics"], tt.wantData) {
+				t.Errorf("expected %q on both sides, got server %q and mirror %q", tt.wantData, fake.objects["/cal/a.ics"], mirror.objects["/cal/a.ics"])
+			}
+		})
+	}
+}
+
+func TestSyncEngine_ConflictStrategies(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		strategy ConflictStrategy
+		local    time.Time
+		remote   time.Time
+		want     string
+	}{
+		{name: "server wins", strategy: ServerWins, local: newer, remote: older, want: "Remote"},
+		{name: "client wins", strategy: ClientWins, local: older, remote: newer, want: "Local"},
+		{name: "last modified, local newer", strategy: LastModifiedWins, local: newer, remote: older, want: "Local"},
+		{name: "last modified, remote newer", strategy: LastModifiedWins, local: older, remote: newer, want: "Remote"},
+		{name: "last modified, tie", strategy: LastModifiedWins, local: older, remote: older, want: "Remote"},
+		{
+			name: "callback merge",
+			strategy: func(_ context.Context, conflict *SyncConflict) (*SyncObject, error) {
+				return &SyncObject{CalendarData: syncTestEvent("a", "Merged", newer)}, nil
+			},
+			local: older, remote: older, want: "Merged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, fake, mirror, closeServer := newSyncEngineTest(t, WithConflictStrategy(tt.strategy))
+			defer closeServer()
+			ctx := context.Background()
+
+			fake.set("/cal/a.ics", syncTestEvent("a", "Original", older))
+			if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+				t.Fatalf("initial Sync failed: %v", err)
+			}
+
+			mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local", tt.local)
+			fake.set("/cal/a.ics", syncTestEvent("a", "Remote", tt.remote))
+
+			result, err := engine.Sync(ctx, "/cal/")
+			if err != nil {
+				t.Fatalf("Sync failed: %v", err)
+			}
+			if result.Conflicts != 1 {
+				t.Errorf("expected 1 conflict, got %+v", result)
+			}
+			if !strings.Contains(mirror.objects["/cal/a.ics"], "SUMMARY:"+tt.want) {
+				t.Errorf("expected the mirror to hold %s, got %q", tt.want, mirror.objects["/cal/a.ics"])
+			}
+			if !strings.Contains(fake.objects["/cal/a.ics"], "SUMMARY:"+tt.want) {
+				t.Errorf("expected the server to hold %s, got %q", tt.want, fake.objects["/cal/a.ics"])
+			}
+
+			// Both sides agree, so the next round is idle.
+			result, err = engine.Sync(ctx, "/cal/")
+			if err != nil || result.Pulled != 0 || result.Pushed != 0 || result.Conflicts != 0 {
+				t.Errorf("expected an idle round, got %+v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestSyncEngine_DeleteConflicts(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("local delete, remote update", func(t *testing.T) {
+		engine, fake, mirror, closeServer := newSyncEngineTest(t, WithConflictStrategy(LastModifiedWins))
+		defer closeServer()
+		ctx := context.Background()
+
+		fake.set("/cal/a.ics", syncTestEvent("a", "Original", day))
+		_, _ = engine.Sync(ctx, "/cal/")
+		delete(mirror.objects, "/cal/a.ics")
+		fake.set("/cal/a.ics", syncTestEvent("a", "Remote", day))
+
+		if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+			t.Fatalf("Sync failed: %v", err)
+		}
+		if !strings.Contains(mirror.objects["/cal/a.ics"], "Remote") {
+			t.Errorf("expected the modification to win over the deletion, got %v", mirror.objects)
+		}
+	})
+
+	t.Run("local update, remote delete", func(t *testing.T) {
+		engine, fake, mirror, closeServer := newSyncEngineTest(t, WithConflictStrategy(ServerWins))
+		defer closeServer()
+		ctx := context.Background()
+
+		fake.set("/cal/a.ics", syncTestEvent("a", "Original", day))
+		_, _ = engine.Sync(ctx, "/cal/")
+		mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local", day)
+		fake.set("/cal/a.ics", "")
+
+		result, err := engine.Sync(ctx, "/cal/")
+		if err != nil {
+			t.Fatalf("Sync failed: %v", err)
+		}
+		if _, exists := mirror.objects["/cal/a.ics"]; exists || result.Conflicts != 1 {
+			t.Errorf("expected the server's deletion to win, got %+v with mirror %v", result, mirror.objects)
+		}
+	})
+}
+
+func TestSyncEngine_DeleteHrefWithoutICSSuffix(t *testing.T) {
+	engine, fake, mirror, closeServer := newSyncEngineTest(t)
+	defer closeServer()
+	ctx := context.Background()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fake.set("/cal/a", syncTestEvent("a", "Remote A", day))
+	if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+		t.Fatalf("initial Sync failed: %v", err)
+	}
+	delete(mirror.objects, "/cal/a")
+
+	result, err := engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Pushed != 1 || len(result.Errors) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, exists := fake.objects["/cal/a"]; exists || len(fake.deletes) != 1 || fake.deletes[0] != "/cal/a" {
+		t.Errorf("expected /cal/a to be deleted as the server named it, got deletes %v", fake.deletes)
+	}
+}
+
+func TestSyncEngine_PushRace(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine, fake, mirror, closeServer := newSyncEngineTest(t)
+	defer closeServer()
+	ctx := context.Background()
+
+	// The server gains an object with the same href between the pull and the
+	// push, so the If-None-Match create fails and becomes a conflict.
+	mirror.objects["/cal/a.ics"] = syncTestEvent("a", "Local", day)
+	var raced bool
+	engine.strategy = func(ctx context.Context, conflict *SyncConflict) (*SyncObject, error) {
+		raced = conflict.Local != nil && conflict.Remote != nil
+		return ClientWins(ctx, conflict)
+	}
+	engine.client.httpClient.Transport = raceTransport{before: func() {
+		if len(fake.puts) == 0 && fake.objects["/cal/a.ics"] == "" {
+			fake.set("/cal/a.ics", syncTestEvent("a", "Remote", day))
+		}
+	}}
+
+	result, err := engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if !raced || result.Conflicts != 1 {
+		t.Errorf("expected the failed create to become a conflict, got %+v", result)
+	}
+	if !strings.Contains(fake.objects["/cal/a.ics"], "Local") {
+		t.Errorf("expected the local version on the server, got %q", fake.objects["/cal/a.ics"])
+	}
+}
+
+// raceTransport runs before ahead of every PUT.
+type raceTransport struct {
+	before func()
+}
+
+func (t raceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPut {
+		t.before()
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSyncEngine_ExpiredToken(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine, fake, mirror, closeServer := newSyncEngineTest(t)
+	defer closeServer()
+	ctx := context.Background()
+
+	fake.set("/cal/a.ics", syncTestEvent("a", "A", day))
+	fake.set("/cal/b.ics", syncTestEvent("b", "B", day))
+	if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+		t.Fatalf("initial Sync failed: %v", err)
+	}
+
+	fake.set("/cal/b.ics", "")
+	fake.set("/cal/c.ics", syncTestEvent("c", "C", day))
+	fake.expireAt = len(fake.log)
+
+	result, err := engine.Sync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Pulled != 2 {
+		t.Errorf("expected the resync to pull 2 changes, got %+v", result)
+	}
+	if _, exists := mirror.objects["/cal/b.ics"]; exists || mirror.objects["/cal/c.ics"] == "" || mirror.objects["/cal/a.ics"] == "" {
+		t.Errorf("unexpected mirror after resync %v", mirror.objects)
+	}
+}
+
+func TestSyncEngine_MirrorFailure(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemorySyncStateStore()
+	engine, fake, mirror, closeServer := newSyncEngineTest(t, WithEngineStateStore(store))
+	defer closeServer()
+	ctx := context.Background()
+
+	fake.set("/cal/a.ics", syncTestEvent("a", "A", day))
+	mirror.fail = errors.New("disk full")
+
+	if _, err := engine.Sync(ctx, "/cal/"); err == nil {
+		t.Fatal("expected the mirror failure to abort the round")
+	}
+	if state, _ := store.LoadSyncState(ctx, "/cal/"); state != nil && state.SyncToken != "" {
+		t.Errorf("expected the sync token not to advance, got %q", state.SyncToken)
+	}
+
+	mirror.fail = nil
+	if _, err := engine.Sync(ctx, "/cal/"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if mirror.objects["/cal/a.ics"] == "" {
+		t.Error("expected the object to be pulled on retry")
+	}
+}
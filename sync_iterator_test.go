@@ -0,0 +1,113 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func syncPageResponse(token string, truncated bool, hrefs ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?><D:multistatus xmlns:D="DAV:">`)
+	for _, href := range hrefs {
+		sb.WriteString(`<D:response><D:href>` + href + `</D:href><D:propstat><D:prop><D:getetag>"1"</D:getetag></D:prop>` +
+			`<D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+	}
+	if truncated {
+		sb.WriteString(`<D:response><D:href>/cal/</D:href><D:status>HTTP/1.1 507 Insufficient Storage</D:status></D:response>`)
+	}
+	sb.WriteString(`<D:sync-token>` + token + `</D:sync-token></D:multistatus>`)
+	return sb.String()
+}
+
+func TestSyncIterator(t *testing.T) {
+	pages := map[string]string{
+		"":   syncPageResponse("p1", true, "/cal/1.ics", "/cal/2.ics"),
+		"p1": syncPageResponse("p2", true, "/cal/3.ics"),
+		"p2": syncPageResponse("final", false, "/cal/4.ics"),
+	}
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "<D:nresults>2</D:nresults>") {
+			t.Errorf("expected the page size in the request, got %s", body)
+		}
+		token := extractXMLValue(string(body), "<D:sync-token>", "</D:sync-token>")
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprint(w, pages[token])
+	})
+	defer server.Close()
+
+	it := client.NewSyncIterator(&SyncRequest{
+		CalendarURL: "/cal/",
+		SyncLevel:   1,
+		Properties:  []string{"getetag"},
+		Limit:       2,
+	})
+
+	var pageSizes []int
+	var hrefs []string
+	for it.Next(context.Background()) {
+		pageSizes = append(pageSizes, len(it.Page().Changes))
+		for _, change := range it.Page().Changes {
+			hrefs = append(hrefs, change.Href)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(pageSizes) != "[2 1 1]" {
+		t.Errorf("expected pages of 2, 1 and 1 changes, got %v", pageSizes)
+	}
+	if len(hrefs) != 4 || hrefs[3] != "/cal/4.ics" {
+		t.Errorf("unexpected hrefs %v", hrefs)
+	}
+	if it.SyncToken() != "final" {
+		t.Errorf("expected final token, got %q", it.SyncToken())
+	}
+	if it.Next(context.Background()) {
+		t.Error("expected no more pages")
+	}
+}
+
+func TestSyncIterator_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "truncated without progress",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusMultiStatus)
+				_, _ = fmt.Fprint(w, syncPageResponse("same", true))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := setupTestClient(t, tt.handler)
+			defer server.Close()
+
+			it := client.NewSyncIterator(&SyncRequest{CalendarURL: "/cal/", SyncToken: "same", SyncLevel: 1})
+			if it.Next(context.Background()) {
+				t.Fatal("expected Next to fail")
+			}
+			if it.Err() == nil {
+				t.Error("expected an error")
+			}
+			if it.Page() != nil {
+				t.Error("expected no page after an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package caldav
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncStateStore persists delta sync state, the sync token plus the href and
+// ETag of every resource, per calendar so that a restarted process can resume
+// with an incremental sync instead of a full one. Implementations must be safe
+// for concurrent use, as SyncAllCalendars saves calendars from several workers.
+type SyncStateStore interface {
+	// LoadSyncState returns the saved state of calendarPath, or nil if there
+	// is none.
+	LoadSyncState(ctx context.Context, calendarPath string) (*DeltaSyncState, error)
+	// SaveSyncState replaces the saved state of calendarPath.
+	SaveSyncState(ctx context.Context, calendarPath string, state *DeltaSyncState) error
+}
+
+// WithSyncStateStore makes DeltaSync and SyncAllCalendars load their starting
+// state from store and save it after every successful sync round.
+func WithSyncStateStore(store SyncStateStore) ClientOption {
+	return func(c *CalDAVClient) {
+		c.syncStore = store
+	}
+}
+
+// MemorySyncStateStore is a SyncStateStore that keeps states in memory. It is
+// useful for tests and for sharing state between clients in one process.
+type MemorySyncStateStore struct {
+	mu     sync.RWMutex
+	states map[string]*DeltaSyncState
+}
+
+// NewMemorySyncStateStore creates an empty MemorySyncStateStore.
+func NewMemorySyncStateStore() *MemorySyncStateStore {
+	return &MemorySyncStateStore{
+		states: make(map[string]*DeltaSyncState),
+	}
+}
+
+func (s *MemorySyncStateStore) LoadSyncState(_ context.Context, calendarPath string) (*DeltaSyncState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return cloneDeltaSyncState(s.states[calendarPath]), nil
+}
+
+func (s *MemorySyncStateStore) SaveSyncState(_ context.Context, calendarPath string, state *DeltaSyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[calendarPath] = cloneDeltaSyncState(state)
+	return nil
+}
+
+// FileSyncStateStore is a SyncStateStore that keeps the states of all
+// calendars in one JSON file. Each save writes a temporary file next to it and
+// renames it into place, so the file always holds a complete set of states.
+type FileSyncStateStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSyncStateStore creates a FileSyncStateStore backed by the file at
+// path. The file is created on the first save.
+func NewFileSyncStateStore(path string) *FileSyncStateStore {
+	return &FileSyncStateStore{path: path}
+}
+
+func (s *FileSyncStateStore) LoadSyncState(_ context.Context, calendarPath string) (*DeltaSyncState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return states[calendarPath], nil
+}
+
+func (s *FileSyncStateStore) SaveSyncState(_ context.Context, calendarPath string, state *DeltaSyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.read()
+	if err != nil {
+		return err
+	}
+	states[calendarPath] = state
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return wrapErrorWithType("sync-store.encode", ErrorTypeClient, err)
+	}
+	return s.write(data)
+}
+
+func (s *FileSyncStateStore) read() (map[string]*DeltaSyncState, error) {
+	states := make(map[string]*DeltaSyncState)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, wrapErrorWithType("sync-store.read", ErrorTypeClient, err)
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, wrapErrorWithType("sync-store.decode", ErrorTypeClient, err)
+	}
+	return states, nil
+}
+
+func (s *FileSyncStateStore) write(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return wrapErrorWithType("sync-store.write", ErrorTypeClient, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return wrapErrorWithType("sync-store.write", ErrorTypeClient, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return wrapErrorWithType("sync-store.write", ErrorTypeClient, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return wrapErrorWithType("sync-store.write", ErrorTypeClient, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return wrapErrorWithType("sync-store.write", ErrorTypeClient, err)
+	}
+	return nil
+}
+
+// loadDeltaState returns the state DeltaSync last left for calendarPath,
+// falling back to the sync state store, or an empty state.
+func (c *CalDAVClient) loadDeltaState(ctx context.Context, calendarPath string) (*DeltaSyncState, error) {
+	c.syncMu.RLock()
+	state, exists := c.deltaStates[calendarPath]
+	c.syncMu.RUnlock()
+	if exists {
+		return state, nil
+	}
+
+	if c.syncStore != nil {
+		stored, err := c.syncStore.LoadSyncState(ctx, calendarPath)
+		if err != nil {
+			return nil, wrapErrorWithType("sync-store.load", ErrorTypeClient, err)
+		}
+		if stored != nil {
+			if stored.Resources == nil {
+				stored.Resources = make(map[string]*DeltaResource)
+			}
+			return stored, nil
+		}
+	}
+
+	return &DeltaSyncState{
+		Resources: make(map[string]*DeltaResource),
+		LastSync:  time.Time{},
+	}, nil
+}
+
+// saveDeltaState records state for calendarPath, persisting it first so that
+// the in-memory state never runs ahead of the store.
+func (c *CalDAVClient) saveDeltaState(ctx context.Context, calendarPath string, state *DeltaSyncState) error {
+	if c.syncStore != nil {
+		if err := c.syncStore.SaveSyncState(ctx, calendarPath, state); err != nil {
+			return wrapErrorWithType("sync-store.save", ErrorTypeClient, err)
+		}
+	}
+
+	c.syncMu.Lock()
+	c.deltaStates[calendarPath] = state
+	c.syncMu.Unlock()
+	return nil
+}
+
+// deltaStateFromSyncResponse applies the changes of a sync-collection round
+// to oldState, which is nil after an initial sync.
+func deltaStateFromSyncResponse(oldState *DeltaSyncState, resp *SyncResponse) *DeltaSyncState {
+	state := initializeDeltaSyncState(oldState)
+	for _, change := range resp.Changes {
+		if change.Href == "" {
+			continue
+		}
+		if change.Deleted {
+			handleDeletedDeltaItem(change.Href, state)
+			continue
+		}
+		state.Resources[change.Href] = &DeltaResource{
+			Href: change.Href,
+			ETag: change.ETag,
+		}
+	}
+	state.SyncToken = resp.SyncToken
+	state.LastSync = time.Now()
+	return state
+}
+
+func cloneDeltaSyncState(state *DeltaSyncState) *DeltaSyncState {
+	if state == nil {
+		return nil
+	}
+
+	clone := &DeltaSyncState{
+		SyncToken:      state.SyncToken,
+		LastSync:       state.LastSync,
+		Resources:      make(map[string]*DeltaResource, len(state.Resources)),
+		PendingDeletes: append([]string(nil), state.PendingDeletes...),
+	}
+	for href, resource := range state.Resources {
+		r := *resource
+		clone.Resources[href] = &r
+	}
+	return clone
+}
@@ -0,0 +1,230 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemorySyncStateStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySyncStateStore()
+
+	state, err := store.LoadSyncState(ctx, "/cal/")
+	if err != nil || state != nil {
+		t.Fatalf("expected no state, got %+v, %v", state, err)
+	}
+
+	saved := &DeltaSyncState{
+		SyncToken: "token-1",
+		Resources: map[string]*DeltaResource{"/cal/a.ics": {Href: "/cal/a.ics", ETag: `"1"`}},
+	}
+	if err := store.SaveSyncState(ctx, "/cal/", saved); err != nil {
+		t.Fatalf("SaveSyncState failed: %v", err)
+	}
+	saved.Resources["/cal/a.ics"].ETag = `"changed"`
+
+	loaded, err := store.LoadSyncState(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("LoadSyncState failed: %v", err)
+	}
+	if loaded.SyncToken != "token-1" || loaded.Resources["/cal/a.ics"].ETag != `"1"` {
+		t.Errorf("expected the saved copy to be unaffected by later changes, got %+v", loaded.Resources["/cal/a.ics"])
+	}
+}
+
+func TestFileSyncStateStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sync.json")
+
+	store := NewFileSyncStateStore(path)
+	if state, err := store.LoadSyncState(ctx, "/cal/"); err != nil || state != nil {
+		t.Fatalf("expected no state before the first save, got %+v, %v", state, err)
+	}
+
+	for i, calendar := range []string{"/cal/", "/other/"} {
+		state := &DeltaSyncState{
+			SyncToken: fmt.Sprintf("token-%d", i),
+			Resources: map[string]*DeltaResource{calendar + "a.ics": {Href: calendar + "a.ics", ETag: `"1"`}},
+		}
+		if err := store.SaveSyncState(ctx, calendar, state); err != nil {
+			t.Fatalf("SaveSyncState failed: %v", err)
+		}
+	}
+
+	// A new store on the same file sees the states of every calendar.
+	reopened := NewFileSyncStateStore(path)
+	state, err := reopened.LoadSyncState(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("LoadSyncState failed: %v", err)
+	}
+	if state == nil || state.SyncToken != "token-0" || state.Resources["/cal/a.ics"].ETag != `"1"` {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if state, _ := reopened.LoadSyncState(ctx, "/other/"); state == nil || state.SyncToken != "token-1" {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the state file to remain, got %d entries", len(entries))
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.LoadSyncState(ctx, "/cal/"); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
+
+func TestDeltaSync_ResumesFromStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.json")
+	var requests []string
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		w.WriteHeader(http.StatusMultiStatus)
+		if strings.Contains(string(body), "sync-token-v1") {
+			_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<multistatus xmlns="DAV:">
+  <response>
+    <href>/cal/a.ics</href>
+    <status>HTTP/1.1 404 Not Found</status>
+  </response>
+  <sync-token>sync-token-v2</sync-token>
+</multistatus>`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<multistatus xmlns="DAV:">
+  <response>
+    <href>/cal/a.ics</href>
+    <propstat><prop><getetag>"a1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat>
+  </response>
+  <response>
+    <href>/cal/b.ics</href>
+    <propstat><prop><getetag>"b1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat>
+  </response>
+  <sync-token>sync-token-v1</sync-token>
+</multistatus>`)
+	}
+
+	client, server := setupTestClient(t, handler)
+	defer server.Close()
+	WithSyncStateStore(NewFileSyncStateStore(path))(client)
+
+	if _, err := client.DeltaSync(context.Background(), "/cal/"); err != nil {
+		t.Fatalf("DeltaSync failed: %v", err)
+	}
+
+	// A new client, as after a restart, continues from the stored token.
+	restarted := NewClientWithOptions("user", "pass", WithSyncStateStore(NewFileSyncStateStore(path)))
+	restarted.SetBaseURL(server.URL)
+	state, err := restarted.DeltaSync(context.Background(), "/cal/")
+	if err != nil {
+		t.Fatalf("DeltaSync failed: %v", err)
+	}
+
+	if len(requests) != 2 || !strings.Contains(requests[1], "<sync-token>sync-token-v1</sync-token>") {
+		t.Fatalf("expected the second sync to send the stored token, got %q", requests)
+	}
+	if state.SyncToken != "sync-token-v2" || len(state.Resources) != 1 || state.Resources["/cal/b.ics"] == nil {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	stored, err := NewFileSyncStateStore(path).LoadSyncState(context.Background(), "/cal/")
+	if err != nil {
+		t.Fatalf("LoadSyncState failed: %v", err)
+	}
+	if stored.SyncToken != "sync-token-v2" || len(stored.Resources) != 1 {
+		t.Errorf("expected the second round to be persisted, got %+v", stored)
+	}
+}
+
+func TestDeltaSync_StoreError(t *testing.T) {
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:"><sync-token>sync-token-v1</sync-token></multistatus>`)
+	})
+	defer server.Close()
+
+	// A directory is not a readable state file.
+	WithSyncStateStore(NewFileSyncStateStore(t.TempDir()))(client)
+
+	if _, err := client.DeltaSync(context.Background(), "/cal/"); err == nil {
+		t.Fatal("expected an error when the state cannot be loaded")
+	}
+	if _, err := client.GetDeltaResources("/cal/"); err == nil {
+		t.Error("expected no in-memory state after a failed round")
+	}
+}
+
+func TestSyncAllCalendars_Store(t *testing.T) {
+	var reports []string
+	client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusMultiStatus)
+		switch {
+		case r.Method == "PROPFIND" && strings.Contains(string(body), "current-user-principal"):
+			_, _ = w.Write([]byte(`<D:multistatus xmlns:D="DAV:"><D:response><D:href>/</D:href><D:propstat><D:prop>` +
+				`<D:current-user-principal><D:href>/principal/</D:href></D:current-user-principal>` +
+				`</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+		case r.Method == "PROPFIND" && strings.Contains(string(body), "calendar-home-set"):
+			_, _ = w.Write([]byte(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:response><D:href>/principal/</D:href><D:propstat><D:prop>` +
+				`<C:calendar-home-set><D:href>/calendars/</D:href></C:calendar-home-set>` +
+				`</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+		case r.Method == "PROPFIND":
+			_, _ = w.Write([]byte(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:response><D:href>/calendars/work/</D:href><D:propstat><D:prop>` +
+				`<D:displayname>Work</D:displayname><D:resourcetype><D:collection/><C:calendar/></D:resourcetype>` +
+				`</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+		default:
+			reports = append(reports, string(body))
+			_, _ = w.Write([]byte(`<D:multistatus xmlns:D="DAV:">` +
+				`<D:response><D:href>/calendars/work/a.ics</D:href><D:propstat><D:prop><D:getetag>"a1"</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>` +
+				`<D:response><D:href>/calendars/work/old.ics</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>` +
+				`<D:sync-token>token-2</D:sync-token></D:multistatus>`))
+		}
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewMemorySyncStateStore()
+	_ = store.SaveSyncState(ctx, "/calendars/work/", &DeltaSyncState{
+		SyncToken: "token-1",
+		Resources: map[string]*DeltaResource{
+			"/calendars/work/old.ics":  {Href: "/calendars/work/old.ics", ETag: `"o1"`},
+			"/calendars/work/kept.ics": {Href: "/calendars/work/kept.ics", ETag: `"k1"`},
+		},
+	})
+	WithSyncStateStore(store)(client)
+
+	results, err := client.SyncAllCalendars(ctx, nil)
+	if err != nil {
+		t.Fatalf("SyncAllCalendars failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if len(reports) != 1 || !strings.Contains(reports[0], "<D:sync-token>token-1</D:sync-token>") {
+		t.Errorf("expected an incremental sync from the stored token, got %q", reports)
+	}
+
+	state, _ := store.LoadSyncState(ctx, "/calendars/work/")
+	if state.SyncToken != "token-2" {
+		t.Errorf("expected stored token token-2, got %q", state.SyncToken)
+	}
+	if len(state.Resources) != 2 || state.Resources["/calendars/work/a.ics"].ETag != `"a1"` || state.Resources["/calendars/work/kept.ics"] == nil {
+		t.Errorf("unexpected stored resources %+v", state.Resources)
+	}
+}
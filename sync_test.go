@@ -3,8 +3,10 @@ package caldav
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -1408,3 +1410,410 @@ func TestGetDeltaResources(t *testing.T) {
 		t.Errorf("Expected ETag 'etag1', got '%s'", resource.ETag)
 	}
 }
+
+const invalidSyncTokenBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:error xmlns:D="DAV:"><D:valid-sync-token/></D:error>`
+
+func TestSyncCalendar_InvalidTokenResync(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+
+		if strings.Contains(request, "<D:sync-token>expired</D:sync-token>") {
+			tokens = append(tokens, "expired")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, invalidSyncTokenBody)
+			return
+		}
+
+		tokens = append(tokens, "")
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response><D:href>/cal/same.ics</D:href><D:propstat><D:prop><D:getetag>"s1"</D:getetag><C:calendar-data>same</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:response><D:href>/cal/changed.ics</D:href><D:propstat><D:prop><D:getetag>"c2"</D:getetag><C:calendar-data>changed</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:response><D:href>/cal/added.ics</D:href><D:propstat><D:prop><D:getetag>"a1"</D:getetag><C:calendar-data>added</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:sync-token>fresh</D:sync-token>
+</D:multistatus>`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewMemorySyncStateStore()
+	_ = store.SaveSyncState(ctx, "/cal/", &DeltaSyncState{
+		SyncToken: "expired",
+		Resources: map[string]*DeltaResource{
+			"/cal/same.ics":    {Href: "/cal/same.ics", ETag: `"s1"`},
+			"/cal/changed.ics": {Href: "/cal/changed.ics", ETag: `"c1"`},
+			"/cal/removed.ics": {Href: "/cal/removed.ics", ETag: `"r1"`},
+		},
+	})
+	client := NewClientWithOptions("user", "pass", WithSyncStateStore(store))
+	client.SetBaseURL(server.URL)
+
+	resp, err := client.IncrementalSync(ctx, "/cal/", "expired")
+	if err != nil {
+		t.Fatalf("IncrementalSync failed: %v", err)
+	}
+
+	if len(tokens) != 2 || tokens[0] != "expired" || tokens[1] != "" {
+		t.Errorf("expected the rejected sync to be followed by a full sync, got %q", tokens)
+	}
+	if !resp.Resynced || resp.SyncToken != "fresh" {
+		t.Errorf("expected a resync with a fresh token, got %+v", resp)
+	}
+
+	changes := make(map[string]SyncChangeType)
+	for _, change := range resp.Changes {
+		changes[change.Href] = change.ChangeType()
+	}
+	expected := map[string]SyncChangeType{
+		"/cal/changed.ics": SyncChangeTypeModified,
+		"/cal/added.ics":   SyncChangeTypeNew,
+		"/cal/removed.ics": SyncChangeTypeDeleted,
+	}
+	if len(changes) != len(expected) || resp.TotalChanges != len(expected) {
+		t.Errorf("expected changes %v, got %v", expected, changes)
+	}
+	for href, changeType := range expected {
+		if got, ok := changes[href]; !ok || got != changeType {
+			t.Errorf("expected %s to be change type %v, got %v (present: %v)", href, changeType, got, ok)
+		}
+	}
+}
+
+func TestSyncCalendar_TruncatedResync(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		if !strings.Contains(request, "<D:nresults>2</D:nresults>") {
+			t.Errorf("expected every page to keep the limit, got %s", request)
+		}
+
+		token := extractXMLValue(request, "<D:sync-token>", "</D:sync-token>")
+		tokens = append(tokens, token)
+		switch token {
+		case "expired":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, invalidSyncTokenBody)
+		case "":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response><D:href>/cal/same.ics</D:href><D:propstat><D:prop><D:getetag>"s1"</D:getetag><C:calendar-data>same</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:response><D:href>/cal/changed.ics</D:href><D:propstat><D:prop><D:getetag>"c2"</D:getetag><C:calendar-data>changed</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:response><D:href>/cal/</D:href><D:status>HTTP/1.1 507 Insufficient Storage</D:status></D:response>
+  <D:sync-token>page-1</D:sync-token>
+</D:multistatus>`)
+		case "page-1":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response><D:href>/cal/later.ics</D:href><D:propstat><D:prop><D:getetag>"l1"</D:getetag><C:calendar-data>later</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:sync-token>fresh</D:sync-token>
+</D:multistatus>`)
+		default:
+			t.Errorf("unexpected sync token %q", token)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewMemorySyncStateStore()
+	_ = store.SaveSyncState(ctx, "/cal/", &DeltaSyncState{
+		SyncToken: "expired",
+		Resources: map[string]*DeltaResource{
+			"/cal/same.ics":    {Href: "/cal/same.ics", ETag: `"s1"`},
+			"/cal/changed.ics": {Href: "/cal/changed.ics", ETag: `"c1"`},
+			"/cal/later.ics":   {Href: "/cal/later.ics", ETag: `"l1"`},
+			"/cal/removed.ics": {Href: "/cal/removed.ics", ETag: `"r1"`},
+		},
+	})
+	client := NewClientWithOptions("user", "pass", WithSyncStateStore(store))
+	client.SetBaseURL(server.URL)
+
+	it := client.NewSyncIterator(&SyncRequest{
+		CalendarURL: "/cal/",
+		SyncToken:   "expired",
+		Properties:  []string{"getetag", "calendar-data"},
+		Limit:       2,
+	})
+	var pages []map[string]SyncChangeType
+	for it.Next(ctx) {
+		if !it.Page().Resynced {
+			t.Errorf("expected every page to be marked resynced, got %+v", it.Page())
+		}
+		changes := make(map[string]SyncChangeType)
+		for _, change := range it.Page().Changes {
+			changes[change.Href] = change.ChangeType()
+		}
+		pages = append(pages, changes)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("SyncIterator failed: %v", err)
+	}
+
+	if want := []string{"expired", "", "page-1"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("expected requests with tokens %q, got %q", want, tokens)
+	}
+	if it.SyncToken() != "fresh" {
+		t.Errorf("expected a fresh token, got %q", it.SyncToken())
+	}
+
+	// Deletions are only known once the last page is in.
+	expected := []map[string]SyncChangeType{
+		{"/cal/changed.ics": SyncChangeTypeModified},
+		{"/cal/removed.ics": SyncChangeTypeDeleted},
+	}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got %v", expected, pages)
+	}
+}
+
+func TestSyncCalendar_ResyncWithKnownETags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "<D:sync-token>expired</D:sync-token>") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, invalidSyncTokenBody)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<D:multistatus xmlns:D="DAV:">
+  <D:response><D:href>/cal/same.ics</D:href><D:propstat><D:prop><D:getetag>"s1"</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:response><D:href>/cal/added.ics</D:href><D:propstat><D:prop><D:getetag>"a1"</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:sync-token>fresh</D:sync-token>
+</D:multistatus>`)
+	}))
+	defer server.Close()
+
+	client := NewClient("user", "pass")
+	client.SetBaseURL(server.URL)
+
+	resp, err := client.SyncCalendar(context.Background(), &SyncRequest{
+		CalendarURL: "/cal/",
+		SyncToken:   "expired",
+		Properties:  []string{"getetag"},
+		Known: map[string]string{
+			"/cal/same.ics":    `"s1"`,
+			"/cal/removed.ics": `"r1"`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+
+	changes := make(map[string]SyncChangeType)
+	for _, change := range resp.Changes {
+		changes[change.Href] = change.ChangeType()
+		if change.Status == http.StatusCreated {
+			t.Errorf("expected the server's status for %s, got %d", change.Href, change.Status)
+		}
+	}
+	expected := map[string]SyncChangeType{
+		"/cal/added.ics":   SyncChangeTypeNew,
+		"/cal/removed.ics": SyncChangeTypeDeleted,
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %v, got %v", expected, changes)
+	}
+}
+
+func TestSyncCalendar_ForbiddenWithoutSyncTokenPrecondition(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `<D:error xmlns:D="DAV:"><D:need-privileges/></D:error>`)
+	}))
+	defer server.Close()
+
+	client := NewClient("user", "pass")
+	client.SetBaseURL(server.URL)
+
+	if _, err := client.IncrementalSync(context.Background(), "/cal/", "token"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if requests != 1 {
+		t.Errorf("expected no full sync fallback, got %d requests", requests)
+	}
+}
+
+func TestDiffSyncListing_Truncated(t *testing.T) {
+	known := map[string]string{
+		"/cal/a.ics": `"1"`,
+		"/cal/b.ics": `"1"`,
+	}
+	listing, err := parseSyncResponse([]byte(`<D:multistatus xmlns:D="DAV:">
+  <D:response><D:href>/cal/a.ics</D:href><D:propstat><D:prop><D:getetag>"1"</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+  <D:response><D:href>/cal/</D:href><D:status>HTTP/1.1 507 Insufficient Storage</D:status></D:response>
+  <D:sync-token>partial</D:sync-token>
+</D:multistatus>`))
+	if err != nil {
+		t.Fatalf("parseSyncResponse failed: %v", err)
+	}
+	if !listing.MoreToSync || len(listing.Changes) != 1 {
+		t.Fatalf("expected a truncated listing with 1 change, got %+v", listing)
+	}
+
+	resp := diffSyncListing(known, listing, make(map[string]bool))
+	if len(resp.Changes) != 0 {
+		t.Errorf("expected no deletes from a truncated listing, got %+v", resp.Changes)
+	}
+}
+
+func TestDeltaSync_InvalidTokenResync(t *testing.T) {
+	round := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		round++
+		switch round {
+		case 1:
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:">
+  <response><href>/cal/a.ics</href><propstat><prop><getetag>"a1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <response><href>/cal/b.ics</href><propstat><prop><getetag>"b1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <sync-token>token-1</sync-token>
+</multistatus>`)
+		case 2:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = fmt.Fprint(w, invalidSyncTokenBody)
+		default:
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:">
+  <response><href>/cal/b.ics</href><propstat><prop><getetag>"b2"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <sync-token>token-2</sync-token>
+</multistatus>`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("user", "pass")
+	client.SetBaseURL(server.URL)
+	ctx := context.Background()
+
+	if _, err := client.DeltaSync(ctx, "/cal/"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.DeltaSync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("DeltaSync failed: %v", err)
+	}
+
+	if round != 3 {
+		t.Errorf("expected a full sync after the rejected token, got %d requests", round)
+	}
+	if state.SyncToken != "token-2" || len(state.Resources) != 1 || state.Resources["/cal/b.ics"].ETag != `"b2"` {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if len(state.PendingDeletes) != 1 || state.PendingDeletes[0] != "/cal/a.ics" {
+		t.Errorf("expected /cal/a.ics to be pending delete, got %v", state.PendingDeletes)
+	}
+}
+
+func TestDeltaSync_TruncatedResync(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		token := extractXMLValue(string(body), "<sync-token>", "</sync-token>")
+		requested = append(requested, token)
+
+		switch token {
+		case "":
+			if len(requested) == 1 {
+				w.WriteHeader(http.StatusMultiStatus)
+				_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:">
+  <response><href>/cal/a.ics</href><propstat><prop><getetag>"a1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <response><href>/cal/b.ics</href><propstat><prop><getetag>"b1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <response><href>/cal/c.ics</href><propstat><prop><getetag>"c1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <sync-token>token-1</sync-token>
+</multistatus>`)
+				return
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:">
+  <response><href>/cal/b.ics</href><propstat><prop><getetag>"b2"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <response><href>/cal/</href><status>HTTP/1.1 507 Insufficient Storage</status></response>
+  <sync-token>page-1</sync-token>
+</multistatus>`)
+		case "token-1":
+			w.WriteHeader(http.StatusConflict)
+			_, _ = fmt.Fprint(w, invalidSyncTokenBody)
+		case "page-1":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:">
+  <response><href>/cal/c.ics</href><propstat><prop><getetag>"c1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <sync-token>token-2</sync-token>
+</multistatus>`)
+		default:
+			t.Errorf("unexpected sync token %q", token)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("user", "pass")
+	client.SetBaseURL(server.URL)
+	ctx := context.Background()
+
+	if _, err := client.DeltaSync(ctx, "/cal/"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.DeltaSync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("DeltaSync failed: %v", err)
+	}
+
+	if want := []string{"", "token-1", "", "page-1"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("expected requests with tokens %q, got %q", want, requested)
+	}
+	if state.SyncToken != "token-2" || len(state.Resources) != 2 || state.Resources["/cal/b.ics"].ETag != `"b2"` || state.Resources["/cal/c.ics"] == nil {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if !reflect.DeepEqual(state.PendingDeletes, []string{"/cal/a.ics"}) {
+		t.Errorf("expected only /cal/a.ics to be pending delete, got %v", state.PendingDeletes)
+	}
+}
+
+func TestDeltaSync_IncompleteResyncKeepsKnownResources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "<sync-token>expired</sync-token>") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, invalidSyncTokenBody)
+			return
+		}
+		// The server keeps truncating without moving the token on.
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprint(w, `<multistatus xmlns="DAV:">
+  <response><href>/cal/b.ics</href><propstat><prop><getetag>"b1"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+  <response><href>/cal/</href><status>HTTP/1.1 507 Insufficient Storage</status></response>
+  <sync-token>stuck</sync-token>
+</multistatus>`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewMemorySyncStateStore()
+	_ = store.SaveSyncState(ctx, "/cal/", &DeltaSyncState{
+		SyncToken: "expired",
+		Resources: map[string]*DeltaResource{
+			"/cal/a.ics": {Href: "/cal/a.ics", ETag: `"a1"`},
+			"/cal/b.ics": {Href: "/cal/b.ics", ETag: `"b1"`},
+		},
+	})
+	client := NewClientWithOptions("user", "pass", WithSyncStateStore(store))
+	client.SetBaseURL(server.URL)
+
+	state, err := client.DeltaSync(ctx, "/cal/")
+	if err != nil {
+		t.Fatalf("DeltaSync failed: %v", err)
+	}
+	if len(state.PendingDeletes) != 0 {
+		t.Errorf("expected no deletes from an incomplete listing, got %v", state.PendingDeletes)
+	}
+	if len(state.Resources) != 2 || state.Resources["/cal/a.ics"] == nil {
+		t.Errorf("expected the unlisted resource to be kept, got %+v", state.Resources)
+	}
+}
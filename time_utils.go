@@ -47,21 +47,69 @@ func ParseCalDAVTimePtr(value string) *time.Time {
 }
 
 // ParseICalPropertyTime parses a time from an iCalendar property line.
-// It expects format "PROPERTY:VALUE" and extracts the VALUE part.
+// It expects format "PROPERTY;PARAMS:VALUE" and honours the TZID parameter.
 func ParseICalPropertyTime(line string) *time.Time {
-	parts := strings.SplitN(line, ":", 2)
-	if len(parts) != 2 {
+	value, params, ok := splitPropertyLine(line)
+	if !ok {
 		return nil
 	}
+	return ParseCalDAVTimeWithParams(value, params)
+}
+
+// splitPropertyLine splits a "PROPERTY;PARAMS:VALUE" line into its value and
+// parameters.
+func splitPropertyLine(line string) (string, map[string]string, bool) {
+	colonIndex := findValueSeparator(line)
+	if colonIndex == -1 {
+		return "", nil, false
+	}
+
+	params := make(map[string]string)
+	for _, param := range splitOutsideQuotes(line[:colonIndex], ';')[1:] {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			params[kv[0]] = parseParamValue(kv[1])
+		}
+	}
 
-	return ParseCalDAVTimePtr(parts[1])
+	return line[colonIndex+1:], params, true
 }
 
 // ParseCalDAVTimeWithParams parses time considering TZID and other parameters.
+// Local times with a TZID that Go's tzdata knows are returned in that location;
+// UTC values and unknown TZIDs are parsed as UTC. DATE values and floating
+// times have no time zone, so their wall-clock reading is returned in UTC.
 func ParseCalDAVTimeWithParams(value string, params map[string]string) *time.Time {
-	// For now, just parse the time value
-	// In the future, we could handle TZID parameter to parse in specific timezone
-	return ParseCalDAVTimePtr(value)
+	tzid := params["TZID"]
+	if tzid == "" || strings.HasSuffix(value, "Z") || isDateValue(value, params) {
+		return ParseCalDAVTimePtr(value)
+	}
+
+	loc, err := LoadLocationFromTZID(tzid)
+	if err != nil {
+		return ParseCalDAVTimePtr(value)
+	}
+
+	for _, format := range calDAVTimeFormats {
+		if t, err := time.ParseInLocation(format, value, loc); err == nil {
+			return &t
+		}
+	}
+
+	return nil
+}
+
+// isDateValue reports whether value is a DATE rather than a DATE-TIME.
+func isDateValue(value string, params map[string]string) bool {
+	if params["VALUE"] == "DATE" {
+		return true
+	}
+	return !strings.Contains(value, "T")
+}
+
+// isFloatingValue reports whether value is a DATE-TIME with neither a UTC
+// designator nor a TZID, which RFC 5545 calls floating time.
+func isFloatingValue(value string, params map[string]string) bool {
+	return params["TZID"] == "" && !strings.HasSuffix(value, "Z") && !isDateValue(value, params)
 }
 
 // ParseCalDAVTimeDates parses multiple comma-separated date/time values.
@@ -72,7 +120,7 @@ func ParseCalDAVTimeDates(value string, params map[string]string) []time.Time {
 	parts := strings.Split(value, ",")
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
-		if t := ParseCalDAVTimePtr(part); t != nil {
+		if t := ParseCalDAVTimeWithParams(part, params); t != nil {
 			times = append(times, *t)
 		}
 	}
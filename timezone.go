@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -246,3 +247,215 @@ func LoadLocationFromTZID(tzid string) (*time.Location, error) {
 
 	return nil, fmt.Errorf("cannot load timezone for TZID: %s", tzid)
 }
+
+// TimeZoneInfoFromLocation builds a TimeZoneInfo from Go's tzdata, collecting
+// the UTC offset transitions of loc between start and end. As with transitions
+// read from a VTIMEZONE, each DateTime holds the local wall-clock time of the
+// transition in the offset in effect before it.
+func TimeZoneInfoFromLocation(loc *time.Location, start, end time.Time) *TimeZoneInfo {
+	tzInfo := &TimeZoneInfo{
+		TZID:        GetTZIDFromGoLocation(loc),
+		Location:    loc,
+		Transitions: make([]TimeZoneTransition, 0),
+	}
+	if loc == nil {
+		return tzInfo
+	}
+
+	current := start.In(loc)
+	for current.Before(end) {
+		next := current.Add(24 * time.Hour)
+		_, currentOffset := current.Zone()
+		_, nextOffset := next.Zone()
+		if currentOffset != nextOffset {
+			tzInfo.Transitions = append(tzInfo.Transitions, findTransition(current, next))
+		}
+		current = next
+	}
+
+	if len(tzInfo.Transitions) == 0 {
+		abbreviation, offset := start.In(loc).Zone()
+		tzInfo.Transitions = append(tzInfo.Transitions, TimeZoneTransition{
+			DateTime:     wallClock(start.In(loc)),
+			OffsetFrom:   time.Duration(offset) * time.Second,
+			OffsetTo:     time.Duration(offset) * time.Second,
+			Abbreviation: abbreviation,
+			IsDST:        start.In(loc).IsDST(),
+		})
+	}
+
+	return tzInfo
+}
+
+// findTransition bisects the interval (before, after] to the first second
+// using the new offset.
+func findTransition(before, after time.Time) TimeZoneTransition {
+	_, offsetFrom := before.Zone()
+	for after.Sub(before) > time.Second {
+		mid := before.Add(after.Sub(before) / 2)
+		if _, offset := mid.Zone(); offset == offsetFrom {
+			before = mid
+		} else {
+			after = mid
+		}
+	}
+
+	abbreviation, offsetTo := after.Zone()
+	from := time.Duration(offsetFrom) * time.Second
+
+	return TimeZoneTransition{
+		DateTime:     wallClock(after.UTC().Add(from)),
+		OffsetFrom:   from,
+		OffsetTo:     time.Duration(offsetTo) * time.Second,
+		Abbreviation: abbreviation,
+		IsDST:        after.IsDST(),
+	}
+}
+
+// wallClock returns the wall-clock reading of t as a UTC time, matching how
+// VTIMEZONE observance start times are represented after parsing.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// wallClockIn returns the time in loc with the same wall-clock reading as t.
+func wallClockIn(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// GenerateVTimeZone builds a VTIMEZONE definition for loc from Go's tzdata.
+// The observances start in the year before ref and, where the transitions
+// follow an annual pattern, repeat yearly through an RRULE; otherwise the
+// individual transitions up to ten years after ref are listed as RDATEs.
+func GenerateVTimeZone(loc *time.Location, ref time.Time) (*ParsedTimeZone, error) {
+	tzid := tzidForLocation(loc)
+	if tzid == "" {
+		return nil, newTypedError("timezone.generate", ErrorTypeValidation, "location has no IANA time zone identifier", nil)
+	}
+
+	yearStart := time.Date(ref.In(loc).Year()-1, time.January, 1, 0, 0, 0, 0, loc)
+	tzInfo := TimeZoneInfoFromLocation(loc, yearStart, yearStart.AddDate(11, 0, 0))
+
+	tz := &ParsedTimeZone{
+		TZID:             tzid,
+		StandardTime:     ParsedTimeZoneComponent{CustomProperties: make(map[string]string)},
+		DaylightTime:     ParsedTimeZoneComponent{CustomProperties: make(map[string]string)},
+		CustomProperties: make(map[string]string),
+	}
+
+	var standard, daylight []TimeZoneTransition
+	for _, transition := range tzInfo.Transitions {
+		if transition.IsDST {
+			daylight = append(daylight, transition)
+		} else {
+			standard = append(standard, transition)
+		}
+	}
+
+	if len(standard) > 0 {
+		tz.StandardTime = observanceFromTransitions(standard)
+	}
+	if len(daylight) > 0 {
+		tz.DaylightTime = observanceFromTransitions(daylight)
+	}
+
+	return tz, nil
+}
+
+// observanceFromTransitions converts transitions of a single kind into a
+// STANDARD or DAYLIGHT observance.
+func observanceFromTransitions(transitions []TimeZoneTransition) ParsedTimeZoneComponent {
+	first := transitions[0]
+	dtStart := first.DateTime
+
+	comp := ParsedTimeZoneComponent{
+		DTStart:          &dtStart,
+		TZOffsetFrom:     formatOffset(first.OffsetFrom),
+		TZOffsetTo:       formatOffset(first.OffsetTo),
+		TZName:           first.Abbreviation,
+		CustomProperties: make(map[string]string),
+	}
+
+	if len(transitions) == 1 {
+		return comp
+	}
+
+	rule := annualTransitionRule(first.DateTime)
+	for _, transition := range transitions[1:] {
+		if annualTransitionRule(transition.DateTime) != rule ||
+			transition.DateTime.Format("150405") != first.DateTime.Format("150405") ||
+			transition.OffsetFrom != first.OffsetFrom || transition.OffsetTo != first.OffsetTo {
+			rule = ""
+			break
+		}
+	}
+
+	if rule != "" {
+		comp.RecurrenceRule = rule
+		return comp
+	}
+
+	for _, transition := range transitions[1:] {
+		comp.RecurrenceDates = append(comp.RecurrenceDates, transition.DateTime)
+	}
+	return comp
+}
+
+// annualTransitionRule describes the date of a transition as a yearly RRULE,
+// for example FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU.
+func annualTransitionRule(wall time.Time) string {
+	weekdays := []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+	daysInMonth := time.Date(wall.Year(), wall.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	position := strconv.Itoa((wall.Day()-1)/7 + 1)
+	if wall.Day()+7 > daysInMonth {
+		position = "-1"
+	}
+
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%s%s", int(wall.Month()), position, weekdays[wall.Weekday()])
+}
+
+// formatOffset formats a UTC offset as used by TZOFFSETFROM and TZOFFSETTO,
+// for example +0100 or -0330.
+func formatOffset(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	seconds := int(offset / time.Second)
+	result := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, (seconds%3600)/60)
+	if seconds%60 != 0 {
+		result += fmt.Sprintf("%02d", seconds%60)
+	}
+	return result
+}
+
+var tzidCache sync.Map
+
+// tzidForLocation returns the TZID to write for times in loc, or an empty
+// string when loc is UTC or cannot be identified by an IANA name, in which
+// case times are written in UTC.
+func tzidForLocation(loc *time.Location) string {
+	if loc == nil {
+		return ""
+	}
+
+	name := loc.String()
+	if name == "" || name == "UTC" || name == "Local" {
+		return ""
+	}
+
+	if cached, ok := tzidCache.Load(name); ok {
+		return cached.(string)
+	}
+
+	tzid := ""
+	if _, err := time.LoadLocation(name); err == nil {
+		tzid = name
+	}
+	tzidCache.Store(name, tzid)
+
+	return tzid
+}
@@ -305,3 +305,80 @@ func TestParsedTimeZone_EnhancedStructure(t *testing.T) {
 		t.Error("CustomProperties not properly initialized")
 	}
 }
+
+func TestGenerateVTimeZone(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	tz, err := GenerateVTimeZone(london, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GenerateVTimeZone failed: %v", err)
+	}
+
+	if tz.TZID != "Europe/London" {
+		t.Errorf("TZID: got %q", tz.TZID)
+	}
+	if got := tz.DaylightTime.RecurrenceRule; got != "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU" {
+		t.Errorf("daylight RRULE: got %q", got)
+	}
+	if got := tz.StandardTime.RecurrenceRule; got != "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU" {
+		t.Errorf("standard RRULE: got %q", got)
+	}
+	if tz.DaylightTime.TZOffsetFrom != "+0000" || tz.DaylightTime.TZOffsetTo != "+0100" || tz.DaylightTime.TZName != "BST" {
+		t.Errorf("unexpected daylight observance: %+v", tz.DaylightTime)
+	}
+	if tz.StandardTime.TZOffsetFrom != "+0100" || tz.StandardTime.TZOffsetTo != "+0000" || tz.StandardTime.TZName != "GMT" {
+		t.Errorf("unexpected standard observance: %+v", tz.StandardTime)
+	}
+	if want := time.Date(2023, 3, 26, 1, 0, 0, 0, time.UTC); !tz.DaylightTime.DTStart.Equal(want) {
+		t.Errorf("daylight DTSTART: expected %v, got %v", want, tz.DaylightTime.DTStart)
+	}
+
+	// The generated definition must agree with Go's tzdata when converting.
+	info := CreateTimeZoneInfo(*tz)
+	local := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	if got, want := info.ConvertToUTC(local), time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ConvertToUTC: expected %v, got %v", want, got)
+	}
+}
+
+func TestGenerateVTimeZone_NoDST(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	tz, err := GenerateVTimeZone(tokyo, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GenerateVTimeZone failed: %v", err)
+	}
+
+	if tz.StandardTime.TZOffsetTo != "+0900" || tz.StandardTime.TZName != "JST" {
+		t.Errorf("unexpected standard observance: %+v", tz.StandardTime)
+	}
+	if tz.DaylightTime.DTStart != nil {
+		t.Errorf("expected no daylight observance, got %+v", tz.DaylightTime)
+	}
+}
+
+func TestGenerateVTimeZone_UTC(t *testing.T) {
+	if _, err := GenerateVTimeZone(time.UTC, time.Now()); err == nil {
+		t.Error("expected error for UTC location")
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                               "+0000",
+		time.Hour:                       "+0100",
+		-(3*time.Hour + 30*time.Minute): "-0330",
+		5*time.Hour + 15*time.Minute + 30*time.Second: "+051530",
+	}
+	for offset, want := range tests {
+		if got := formatOffset(offset); got != want {
+			t.Errorf("formatOffset(%v): expected %q, got %q", offset, want, got)
+		}
+	}
+}
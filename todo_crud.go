@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -331,105 +332,123 @@ func validateTodo(todo *ParsedTodo) error {
 }
 
 func generateTodoICalendar(todo *ParsedTodo) string {
-	var builder strings.Builder
+	var w contentLineWriter
 
-	builder.WriteString("BEGIN:VCALENDAR\r\n")
-	builder.WriteString("VERSION:2.0\r\n")
-	builder.WriteString("PRODID:-//go-icloud-caldav//EN\r\n")
-	builder.WriteString("BEGIN:VTODO\r\n")
+	w.begin("VCALENDAR")
+	w.writeProperty("VERSION", nil, "2.0")
+	w.writeProperty("PRODID", nil, defaultProdID)
+	w.begin("VTODO")
 
-	fmt.Fprintf(&builder, "UID:%s\r\n", escapeICalText(todo.UID))
+	w.writeText("UID", nil, todo.UID)
 
-	writeTodoDateProperties(&builder, todo)
-	writeTodoMainProperties(&builder, todo)
-	writeTodoListProperties(&builder, todo)
-	writeTodoRelationships(&builder, todo)
-	writeTodoAttachments(&builder, todo)
-	writeTodoRequestStatus(&builder, todo)
+	writeTodoDateProperties(&w, todo)
+	writeTodoMainProperties(&w, todo)
+	writeTodoListProperties(&w, todo)
+	writeTodoRelationships(&w, todo)
+	writeTodoAttachments(&w, todo)
+	writeTodoRequestStatus(&w, todo)
 
-	builder.WriteString("END:VTODO\r\n")
-	builder.WriteString("END:VCALENDAR\r\n")
+	w.end("VTODO")
+	w.end("VCALENDAR")
 
-	return builder.String()
+	return w.String()
 }
 
-func writeTodoDateProperties(builder *strings.Builder, todo *ParsedTodo) {
+func writeTodoDateProperties(w *contentLineWriter, todo *ParsedTodo) {
 	if todo.DTStamp != nil {
-		fmt.Fprintf(builder, "DTSTAMP:%s\r\n", formatICalTime(*todo.DTStamp))
+		w.writeProperty("DTSTAMP", nil, formatICalTime(*todo.DTStamp))
 	}
 	if todo.Created != nil {
-		fmt.Fprintf(builder, "CREATED:%s\r\n", formatICalTime(*todo.Created))
+		w.writeProperty("CREATED", nil, formatICalTime(*todo.Created))
 	}
 	if todo.LastModified != nil {
-		fmt.Fprintf(builder, "LAST-MODIFIED:%s\r\n", formatICalTime(*todo.LastModified))
+		w.writeProperty("LAST-MODIFIED", nil, formatICalTime(*todo.LastModified))
 	}
+	form := eventTimeForm(todo.AllDay, todo.Floating)
 	if todo.DTStart != nil {
-		fmt.Fprintf(builder, "DTSTART:%s\r\n", formatICalTime(*todo.DTStart))
+		writeTodoTime(w, "DTSTART", *todo.DTStart, form)
 	}
 	if todo.Due != nil {
-		fmt.Fprintf(builder, "DUE:%s\r\n", formatICalTime(*todo.Due))
+		writeTodoTime(w, "DUE", *todo.Due, form)
 	}
 	if todo.Completed != nil {
-		fmt.Fprintf(builder, "COMPLETED:%s\r\n", formatICalTime(*todo.Completed))
+		w.writeProperty("COMPLETED", nil, formatICalTime(*todo.Completed))
 	}
 }
 
-func writeTodoMainProperties(builder *strings.Builder, todo *ParsedTodo) {
-	fmt.Fprintf(builder, "SUMMARY:%s\r\n", escapeICalText(todo.Summary))
+// writeTodoTime writes DTSTART or DUE as a date, a floating time or a UTC
+// time.
+func writeTodoTime(w *contentLineWriter, name string, t time.Time, form timeForm) {
+	if form == formDateTime {
+		w.writeProperty(name, nil, formatICalTime(t))
+		return
+	}
+	value, params := formatICalDateTime(t, form)
+	w.writeProperty(name, params, value)
+}
+
+func writeTodoMainProperties(w *contentLineWriter, todo *ParsedTodo) {
+	w.writeText("SUMMARY", nil, todo.Summary)
 
 	if todo.Description != "" {
-		fmt.Fprintf(builder, "DESCRIPTION:%s\r\n", escapeICalText(todo.Description))
+		w.writeText("DESCRIPTION", nil, todo.Description)
 	}
 	if todo.Status != "" {
-		fmt.Fprintf(builder, "STATUS:%s\r\n", todo.Status)
+		w.writeProperty("STATUS", nil, todo.Status)
 	}
 	if todo.Priority > 0 {
-		fmt.Fprintf(builder, "PRIORITY:%d\r\n", todo.Priority)
+		w.writeProperty("PRIORITY", nil, strconv.Itoa(todo.Priority))
 	}
 	if todo.PercentComplete > 0 {
-		fmt.Fprintf(builder, "PERCENT-COMPLETE:%d\r\n", todo.PercentComplete)
+		w.writeProperty("PERCENT-COMPLETE", nil, strconv.Itoa(todo.PercentComplete))
 	}
 	if todo.Sequence > 0 {
-		fmt.Fprintf(builder, "SEQUENCE:%d\r\n", todo.Sequence)
+		w.writeProperty("SEQUENCE", nil, strconv.Itoa(todo.Sequence))
 	}
 	if todo.Class != "" {
-		fmt.Fprintf(builder, "CLASS:%s\r\n", todo.Class)
+		w.writeProperty("CLASS", nil, todo.Class)
+	}
+	if todo.Color != "" {
+		w.writeProperty("COLOR", nil, todo.Color)
 	}
 }
 
-func writeTodoListProperties(builder *strings.Builder, todo *ParsedTodo) {
+func writeTodoListProperties(w *contentLineWriter, todo *ParsedTodo) {
 	for _, category := range todo.Categories {
-		fmt.Fprintf(builder, "CATEGORIES:%s\r\n", escapeICalText(category))
+		w.writeText("CATEGORIES", nil, category)
 	}
 	for _, contact := range todo.Contacts {
-		fmt.Fprintf(builder, "CONTACT:%s\r\n", escapeICalText(contact))
+		w.writeText("CONTACT", nil, contact)
 	}
 	for _, comment := range todo.Comments {
-		fmt.Fprintf(builder, "COMMENT:%s\r\n", escapeICalText(comment))
+		w.writeText("COMMENT", nil, comment)
 	}
 }
 
-func writeTodoRelationships(builder *strings.Builder, todo *ParsedTodo) {
+func writeTodoRelationships(w *contentLineWriter, todo *ParsedTodo) {
 	for _, related := range todo.RelatedTo {
-		if related.RelationType != "" {
-			fmt.Fprintf(builder, "RELATED-TO;RELTYPE=%s:%s\r\n", related.RelationType, escapeICalText(related.UID))
-		} else {
-			fmt.Fprintf(builder, "RELATED-TO:%s\r\n", escapeICalText(related.UID))
-		}
+		w.writeText("RELATED-TO", []icalParam{{"RELTYPE", related.RelationType}}, related.UID)
 	}
 }
 
-func writeTodoAttachments(builder *strings.Builder, todo *ParsedTodo) {
+func writeTodoAttachments(w *contentLineWriter, todo *ParsedTodo) {
 	for _, attachment := range todo.Attachments {
 		if attachment.URI != "" {
-			fmt.Fprintf(builder, "ATTACH:%s\r\n", attachment.URI)
+			w.writeProperty("ATTACH", nil, attachment.URI)
 		} else if attachment.Value != "" {
-			fmt.Fprintf(builder, "ATTACH;ENCODING=BASE64;VALUE=BINARY:%s\r\n", attachment.Value)
+			w.writeProperty("ATTACH", []icalParam{{"ENCODING", "BASE64"}, {"VALUE", "BINARY"}}, attachment.Value)
 		}
 	}
+	for _, image := range todo.Images {
+		params, value := imageParams(image)
+		w.writeProperty("IMAGE", params, value)
+	}
+	for _, conference := range todo.Conferences {
+		w.writeProperty("CONFERENCE", conferenceParams(conference), conference.URI)
+	}
 }
 
-func writeTodoRequestStatus(builder *strings.Builder, todo *ParsedTodo) {
+func writeTodoRequestStatus(w *contentLineWriter, todo *ParsedTodo) {
 	for _, rs := range todo.RequestStatus {
 		reqStat := rs.Code
 		if rs.Description != "" {
@@ -438,7 +457,7 @@ func writeTodoRequestStatus(builder *strings.Builder, todo *ParsedTodo) {
 				reqStat += ";" + escapeICalText(rs.ExtraData)
 			}
 		}
-		fmt.Fprintf(builder, "REQUEST-STATUS:%s\r\n", reqStat)
+		w.writeProperty("REQUEST-STATUS", nil, reqStat)
 	}
 }
 
@@ -682,6 +682,7 @@ func TestGenerateTodoICalendar(t *testing.T) {
 		Completed:       nil,
 		Sequence:        1,
 		Class:           "PRIVATE",
+		Color:           "orange",
 		Categories:      []string{"Work", "Important"},
 		Contacts:        []string{"John Doe", "Jane Smith"},
 		Comments:        []string{"First comment", "Second comment"},
@@ -693,6 +694,9 @@ func TestGenerateTodoICalendar(t *testing.T) {
 			{URI: "https://example.com/file.pdf"},
 			{Value: "base64data", FormatType: "image/png"},
 		},
+		Conferences: []Conference{
+			{URI: "https://video.example.com/123", Features: []string{"AUDIO", "VIDEO"}},
+		},
 		RequestStatus: []RequestStatus{
 			{Code: "2.0", Description: "Success"},
 		},
@@ -713,6 +717,7 @@ func TestGenerateTodoICalendar(t *testing.T) {
 		"PERCENT-COMPLETE:50",
 		"SEQUENCE:1",
 		"CLASS:PRIVATE",
+		"COLOR:orange",
 		"CATEGORIES:Work",
 		"CATEGORIES:Important",
 		"CONTACT:John Doe",
@@ -723,6 +728,7 @@ func TestGenerateTodoICalendar(t *testing.T) {
 		"RELATED-TO:sibling-uid",
 		"ATTACH:https://example.com/file.pdf",
 		"ATTACH;ENCODING=BASE64;VALUE=BINARY:base64data",
+		"CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO:https://video.example.com/123",
 		"REQUEST-STATUS:2.0;Success",
 		"END:VTODO",
 		"END:VCALENDAR",
@@ -735,6 +741,20 @@ func TestGenerateTodoICalendar(t *testing.T) {
 	}
 }
 
+func TestGenerateTodoICalendar_DateForms(t *testing.T) {
+	due := time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC)
+	ical := generateTodoICalendar(&ParsedTodo{UID: "dated", Summary: "Taxes", Due: &due, AllDay: true})
+	if !strings.Contains(ical, "DUE;VALUE=DATE:20240415\r\n") {
+		t.Errorf("expected a DATE due, got:\n%s", ical)
+	}
+
+	due = time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+	ical = generateTodoICalendar(&ParsedTodo{UID: "floating", Summary: "Plants", Due: &due, Floating: true})
+	if !strings.Contains(ical, "DUE:20240310T180000\r\n") {
+		t.Errorf("expected a floating due, got:\n%s", ical)
+	}
+}
+
 func TestBuildTodoURL(t *testing.T) {
 	tests := []struct {
 		name         string
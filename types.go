@@ -9,78 +9,216 @@ import (
 // ParsedCalendarData contains structured calendar data parsed from iCalendar format.
 // This provides easier access to calendar components without manual parsing.
 type ParsedCalendarData struct {
-	Version          string
-	ProdID           string
-	CalScale         string
-	Method           string
+	Version  string
+	ProdID   string
+	CalScale string
+	Method   string
+	// Name, Description, Color, RefreshInterval and Images are the RFC 7986
+	// calendar properties. RefreshInterval is an ISO 8601 duration.
+	Name             string
+	Description      string
+	Color            string
+	RefreshInterval  string
+	Images           []Image
 	Events           []ParsedEvent
 	Todos            []ParsedTodo
+	Journals         []ParsedJournal
+	Availability     []ParsedAvailability
 	FreeBusy         []ParsedFreeBusy
 	TimeZones        []ParsedTimeZone
 	Alarms           []ParsedAlarm
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
+}
+
+// ICalProperty is a property the parser has no field for. Every component
+// lists these in ExtraProperties in their original order, repeats included,
+// together with their parameters, so that EncodeICalendar writes them back
+// unchanged. CustomProperties holds the last value of each name for lookups;
+// changing or deleting a name there takes precedence over ExtraProperties.
+type ICalProperty struct {
+	Name       string
+	Parameters []ICalParameter
+	// Value is the raw property value, still escaped.
+	Value string
+}
+
+// ICalParameter is a property parameter. Values holds the unquoted values of
+// a multi-valued parameter in order.
+type ICalParameter struct {
+	Name   string
+	Values []string
 }
 
 // ParsedEvent represents a parsed VEVENT component.
 type ParsedEvent struct {
-	UID              string
-	DTStamp          *time.Time
-	DTStart          *time.Time
-	DTEnd            *time.Time
-	Duration         string
+	UID             string
+	DTStamp         *time.Time
+	DTStart         *time.Time
+	DTEnd           *time.Time
+	AllDay          bool
+	Floating        bool
+	Duration        string
+	Summary         string
+	Description     string
+	Location        string
+	Status          string
+	Transparency    string
+	Categories      []string
+	Organizer       ParsedOrganizer
+	Attendees       []ParsedAttendee
+	RecurrenceID    *time.Time
+	RecurrenceRange string
+	RecurrenceRule  string
+	RecurrenceDates []time.Time
+	ExceptionDates  []time.Time
+	ExceptionRule   string
+	RelatedTo       []RelatedEvent
+	Attachments     []Attachment
+	Contacts        []string
+	Comments        []string
+	RequestStatus   []RequestStatus
+	Created         *time.Time
+	LastModified    *time.Time
+	Sequence        int
+	Priority        int
+	Class           string
+	URL             string
+	Color           string
+	Images          []Image
+	Conferences     []Conference
+	GeoLocation     *GeoLocation
+	// StructuredLocation is Apple's X-APPLE-STRUCTURED-LOCATION, which iCloud
+	// stores alongside the free-text Location.
+	StructuredLocation *ParsedLocation
+	// Locations and Participants are the RFC 9073 VLOCATION and PARTICIPANT
+	// subcomponents.
+	Locations    []ParsedLocation
+	Participants []ParsedParticipant
+	Alarms       []ParsedAlarm
+	// SummaryLanguage and DescriptionLanguage are the LANGUAGE parameters
+	// of SUMMARY and DESCRIPTION, such as "de-CH".
+	SummaryLanguage     string
+	DescriptionLanguage string
+	CustomProperties    map[string]string
+	ExtraProperties     []ICalProperty
+}
+
+// ParsedTodo represents a parsed VTODO component.
+type ParsedTodo struct {
+	UID             string
+	DTStamp         *time.Time
+	DTStart         *time.Time
+	Due             *time.Time
+	AllDay          bool
+	Floating        bool
+	Completed       *time.Time
+	Summary         string
+	Description     string
+	Status          string
+	PercentComplete int
+	Priority        int
+	Categories      []string
+	RelatedTo       []RelatedEvent
+	Attachments     []Attachment
+	Contacts        []string
+	Comments        []string
+	RequestStatus   []RequestStatus
+	Created         *time.Time
+	LastModified    *time.Time
+	Sequence        int
+	Class           string
+	URL             string
+	Color           string
+	Images          []Image
+	Conferences     []Conference
+	Alarms          []ParsedAlarm
+	// SummaryLanguage and DescriptionLanguage are the LANGUAGE parameters
+	// of SUMMARY and DESCRIPTION.
+	SummaryLanguage     string
+	DescriptionLanguage string
+	CustomProperties    map[string]string
+	ExtraProperties     []ICalProperty
+}
+
+// ParsedJournal represents a parsed VJOURNAL component.
+type ParsedJournal struct {
+	UID     string
+	DTStamp *time.Time
+	DTStart *time.Time
+	// AllDay is set when DTSTART is a DATE value, as is usual for journals.
+	AllDay  bool
+	Summary string
+	// Descriptions holds every DESCRIPTION property; unlike other components
+	// a journal may have several.
+	Descriptions []string
+	Status       string
+	Categories   []string
+	RelatedTo    []RelatedEvent
+	Attachments  []Attachment
+	Comments     []string
+	Created      *time.Time
+	LastModified *time.Time
+	Sequence     int
+	Class        string
+	URL          string
+	// SummaryLanguage and DescriptionLanguage are the LANGUAGE parameters
+	// of SUMMARY and of the DESCRIPTION properties.
+	SummaryLanguage     string
+	DescriptionLanguage string
+	CustomProperties    map[string]string
+	ExtraProperties     []ICalProperty
+}
+
+// ParsedAvailability represents a parsed VAVAILABILITY component (RFC 7953).
+// Within the time it covers, time outside its Available periods is busy with
+// BusyType, which defaults to BUSY-UNAVAILABLE. A nil DTStart or DTEnd leaves
+// that side unbounded.
+type ParsedAvailability struct {
+	UID      string
+	DTStamp  *time.Time
+	DTStart  *time.Time
+	DTEnd    *time.Time
+	Duration string
+	BusyType string
+	// Priority orders overlapping components: 1 is the highest and 0, the
+	// default, the lowest.
+	Priority         int
 	Summary          string
 	Description      string
 	Location         string
-	Status           string
-	Transparency     string
-	Categories       []string
 	Organizer        ParsedOrganizer
-	Attendees        []ParsedAttendee
-	RecurrenceID     *time.Time
-	RecurrenceRule   string
-	RecurrenceDates  []time.Time
-	ExceptionDates   []time.Time
-	ExceptionRule    string
-	RelatedTo        []RelatedEvent
-	Attachments      []Attachment
-	Contacts         []string
-	Comments         []string
-	RequestStatus    []RequestStatus
+	Sequence         int
 	Created          *time.Time
 	LastModified     *time.Time
-	Sequence         int
-	Priority         int
 	Class            string
 	URL              string
-	GeoLocation      *GeoLocation
-	Alarms           []ParsedAlarm
+	Categories       []string
+	Available        []ParsedAvailable
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
 }
 
-// ParsedTodo represents a parsed VTODO component.
-type ParsedTodo struct {
+// ParsedAvailable represents an AVAILABLE subcomponent of a VAVAILABILITY:
+// a period, possibly recurring, during which the calendar user is available.
+type ParsedAvailable struct {
 	UID              string
 	DTStamp          *time.Time
 	DTStart          *time.Time
-	Due              *time.Time
-	Completed        *time.Time
+	DTEnd            *time.Time
+	Duration         string
+	RecurrenceID     *time.Time
+	RecurrenceRule   string
+	RecurrenceDates  []time.Time
+	ExceptionDates   []time.Time
 	Summary          string
 	Description      string
-	Status           string
-	PercentComplete  int
-	Priority         int
-	Categories       []string
-	RelatedTo        []RelatedEvent
-	Attachments      []Attachment
-	Contacts         []string
-	Comments         []string
-	RequestStatus    []RequestStatus
+	Location         string
 	Created          *time.Time
 	LastModified     *time.Time
-	Sequence         int
-	Class            string
-	URL              string
+	Categories       []string
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
 }
 
 // ParsedFreeBusy represents a parsed VFREEBUSY component.
@@ -93,6 +231,7 @@ type ParsedFreeBusy struct {
 	Attendees        []ParsedAttendee
 	FreeBusy         []FreeBusyPeriod
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
 }
 
 // ParsedTimeZone represents a parsed VTIMEZONE component.
@@ -101,6 +240,7 @@ type ParsedTimeZone struct {
 	StandardTime     ParsedTimeZoneComponent
 	DaylightTime     ParsedTimeZoneComponent
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
 }
 
 // ParsedTimeZoneComponent represents standard or daylight time information.
@@ -114,6 +254,51 @@ type ParsedTimeZoneComponent struct {
 	ExceptionDates   []time.Time
 	Comment          []string
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
+}
+
+// ParsedLocation represents a structured location, either a VLOCATION
+// component (RFC 9073) or Apple's X-APPLE-STRUCTURED-LOCATION property.
+type ParsedLocation struct {
+	UID string
+	// Name is the VLOCATION NAME or the Apple X-TITLE parameter.
+	Name        string
+	Description string
+	// LocationType lists the LOCATION-TYPE values (RFC 4589), such as office
+	// or parking.
+	LocationType []string
+	// Address is the Apple X-ADDRESS parameter; VLOCATION has no equivalent.
+	Address string
+	// Geo is the VLOCATION GEO property or the Apple geo: URI.
+	Geo *GeoLocation
+	// Radius is the accuracy of Geo in metres, from the Apple X-APPLE-RADIUS
+	// parameter.
+	Radius float64
+	URL    string
+	// CustomParams holds the other parameters of an Apple structured location,
+	// such as X-APPLE-REFERENCEFRAME and X-APPLE-MAPKIT-HANDLE.
+	CustomParams map[string]string
+	// CustomProperties holds the other properties of a VLOCATION.
+	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
+}
+
+// ParsedParticipant represents a PARTICIPANT component (RFC 9073), a person or
+// resource taking part in an event beyond the attendee list, such as a speaker
+// or sponsor.
+type ParsedParticipant struct {
+	UID string
+	// ParticipantType is the PARTICIPANT-TYPE, such as ACTIVE, SPEAKER or
+	// SPONSOR.
+	ParticipantType  string
+	CalendarAddress  string
+	Summary          string
+	Description      string
+	Geo              *GeoLocation
+	URL              string
+	Locations        []ParsedLocation
+	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
 }
 
 // TimeZoneTransition represents a timezone transition point.
@@ -134,14 +319,18 @@ type TimeZoneInfo struct {
 
 // ParsedAlarm represents a parsed VALARM component.
 type ParsedAlarm struct {
-	Action           string
-	Trigger          string
+	Action  string
+	Trigger string
+	// TriggerRelated is the RELATED parameter of a relative Trigger, "START"
+	// or "END"; empty means START.
+	TriggerRelated   string
 	Duration         string
 	Repeat           int
 	Description      string
 	Summary          string
 	Attendees        []ParsedAttendee
 	CustomProperties map[string]string
+	ExtraProperties  []ICalProperty
 }
 
 // ParsedOrganizer represents event/todo organizer information.
@@ -168,7 +357,11 @@ type ParsedAttendee struct {
 	DelegatedFrom string
 	Dir           string
 	SentBy        string
-	CustomParams  map[string]string
+	// ScheduleAgent and ScheduleStatus are the RFC 6638 SCHEDULE-AGENT and
+	// SCHEDULE-STATUS parameters.
+	ScheduleAgent  string
+	ScheduleStatus string
+	CustomParams   map[string]string
 }
 
 // GeoLocation represents geographic coordinates.
@@ -201,6 +394,30 @@ type Attachment struct {
 	CustomParams map[string]string
 }
 
+// Image represents an image from the IMAGE property (RFC 7986), either a URI
+// or inline binary data.
+type Image struct {
+	URI        string
+	Encoding   string
+	Value      string
+	FormatType string
+	// Display lists the DISPLAY parameter values, such as BADGE or THUMBNAIL.
+	Display      []string
+	AltRep       string
+	CustomParams map[string]string
+}
+
+// Conference represents a way to join a meeting from the CONFERENCE property
+// (RFC 7986), such as a video call link or dial-in number.
+type Conference struct {
+	URI string
+	// Features lists the FEATURE parameter values, such as AUDIO, VIDEO or
+	// PHONE.
+	Features     []string
+	Label        string
+	CustomParams map[string]string
+}
+
 // RequestStatus represents meeting request status information from REQUEST-STATUS property.
 type RequestStatus struct {
 	Code        string
@@ -274,10 +491,17 @@ type Calendar struct {
 	CurrentUserPrivilegeSet []string
 	Source                  string
 	SupportedReports        []string
-	Quota                   CalendarQuota
-	ACL                     ACL
+	// SupportedCalendarData lists the media types of the calendar's
+	// supported-calendar-data property. Empty means text/calendar only.
+	SupportedCalendarData []string
+	Quota                 CalendarQuota
+	ACL                   ACL
 }
 
+// CalendarObject is a calendar resource with the main properties of its event.
+// AllDay marks VALUE=DATE start and end times and Floating marks local times
+// without a time zone; for both only the wall-clock fields of StartTime and
+// EndTime are meaningful, and parsed values hold them in UTC.
 type CalendarObject struct {
 	Href             string
 	ETag             string
@@ -288,6 +512,8 @@ type CalendarObject struct {
 	Location         string
 	StartTime        *time.Time
 	EndTime          *time.Time
+	AllDay           bool
+	Floating         bool
 	Organizer        string
 	Attendees        []string
 	Status           string
@@ -308,9 +534,24 @@ type CalendarObject struct {
 }
 
 type CalendarQuery struct {
+	// Properties lists WebDAV properties such as "getetag" and
+	// "calendar-data". Upper-case iCalendar property names ("SUMMARY", or
+	// "VTODO.DUE" for another component) limit calendar-data to those
+	// properties of the queried component.
 	Properties []string
 	Filter     Filter
 	TimeRange  *TimeRange
+	// Expand asks the server to return recurring events as individual
+	// instances within the range, in UTC and without RRULEs.
+	Expand *TimeRange
+	// LimitRecurrenceSet asks the server to return the master event and only
+	// the overrides that overlap the range.
+	LimitRecurrenceSet *TimeRange
+	// ContentType asks for calendar-data in another media type, such as
+	// ContentTypeXCal. It is only requested when the calendar lists it in its
+	// supported-calendar-data property. Responses are converted back to
+	// iCalendar, so CalendarData and ParsedData work as usual.
+	ContentType string
 }
 
 type Filter struct {
@@ -373,12 +614,17 @@ type PropstatProp struct {
 	CurrentUserPrivilegeSet       []string
 	Source                        string
 	SupportedReports              []string
+	SupportedCalendarData         []string
 	QuotaUsedBytes                int64
 	QuotaAvailableBytes           int64
 	ContentType                   string
 	ContentLength                 int64
 	CreationDate                  string
 	LastModified                  string
+	ScheduleInboxURL              string
+	ScheduleOutboxURL             string
+	CalendarUserAddressSet        []string
+	CalendarAvailability          string
 }
 
 // CalendarHomeSet represents the calendar home collection URL.
@@ -0,0 +1,345 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// XCalNamespace is the XML namespace of xCal (RFC 6321).
+	XCalNamespace = "urn:ietf:params:xml:ns:icalendar-2.0"
+	// ContentTypeXCal is the media type of xCal calendar data.
+	ContentTypeXCal = "application/calendar+xml"
+	// ContentTypeICalendar is the media type of iCalendar data.
+	ContentTypeICalendar = "text/calendar"
+)
+
+// xcalParamTypes maps parameters to their xCal value type (RFC 6321 section
+// 3.5). Parameters not listed here are text.
+var xcalParamTypes = map[string]string{
+	"ALTREP":         "uri",
+	"DIR":            "uri",
+	"DELEGATED-FROM": "cal-address",
+	"DELEGATED-TO":   "cal-address",
+	"MEMBER":         "cal-address",
+	"SENT-BY":        "cal-address",
+	"RSVP":           "boolean",
+}
+
+// xcalNode is an element of an xCal document.
+type xcalNode struct {
+	XMLName  xml.Name
+	Children []xcalNode `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+// MarshalXCal encodes data as xCal (RFC 6321). Like MarshalJCal it serializes
+// the calendar with EncodeICalendar first, so the XML carries exactly the
+// properties and parameters of the iCalendar form with the same value types.
+func MarshalXCal(data *ParsedCalendarData) ([]byte, error) {
+	icalData, err := EncodeICalendar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	component, err := icalToJCal(string(icalData))
+	if err != nil {
+		return nil, err
+	}
+
+	builder := NewXMLBuilder(2 * len(icalData))
+	builder.WriteHeader().WriteStartElement("icalendar", "xmlns", XCalNamespace)
+	writeXCalComponent(builder, component)
+	builder.WriteEndElement("icalendar")
+	return builder.Bytes(), nil
+}
+
+// UnmarshalXCal decodes an xCal (RFC 6321) document by converting its first
+// vcalendar to iCalendar and parsing that with ParseICalendar. Elements
+// outside the xCal namespace are ignored.
+func UnmarshalXCal(data []byte) (*ParsedCalendarData, error) {
+	icalData, err := xcalToICal(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseICalendar(icalData)
+}
+
+func xcalToICal(data []byte) (string, error) {
+	var root xcalNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return "", newTypedError("xcal.unmarshal", ErrorTypeValidation, "invalid xCal XML", err)
+	}
+	if root.XMLName.Space != XCalNamespace || root.XMLName.Local != "icalendar" {
+		return "", newTypedError("xcal.unmarshal", ErrorTypeValidation, "root element must be icalendar in the xCal namespace", nil)
+	}
+
+	for _, child := range root.Children {
+		if child.XMLName.Space == XCalNamespace && child.XMLName.Local == "vcalendar" {
+			var w contentLineWriter
+			if err := writeICalFromXCal(&w, child); err != nil {
+				return "", err
+			}
+			return w.String(), nil
+		}
+	}
+	return "", newTypedError("xcal.unmarshal", ErrorTypeValidation, "no vcalendar element", nil)
+}
+
+// writeXCalComponent writes a component of the jCal tree built by icalToJCal
+// as xCal. The two formats share their value representations.
+func writeXCalComponent(builder *XMLBuilder, component []interface{}) {
+	name := component[0].(string)
+	properties := component[1].([]interface{})
+	components := component[2].([]interface{})
+
+	builder.WriteStartElement(name)
+	if len(properties) > 0 {
+		builder.WriteStartElement("properties")
+		for _, property := range properties {
+			writeXCalProperty(builder, property.([]interface{}))
+		}
+		builder.WriteEndElement("properties")
+	}
+	if len(components) > 0 {
+		builder.WriteStartElement("components")
+		for _, sub := range components {
+			writeXCalComponent(builder, sub.([]interface{}))
+		}
+		builder.WriteEndElement("components")
+	}
+	builder.WriteEndElement(name)
+}
+
+func writeXCalProperty(builder *XMLBuilder, property []interface{}) {
+	name := property[0].(string)
+	params := property[1].(map[string]interface{})
+	valueType := property[2].(string)
+
+	builder.WriteStartElement(name)
+	if len(params) > 0 {
+		builder.WriteStartElement("parameters")
+		for _, key := range sortedKeys(params) {
+			writeXCalParam(builder, key, params[key])
+		}
+		builder.WriteEndElement("parameters")
+	}
+
+	for _, value := range property[3:] {
+		switch v := value.(type) {
+		case []interface{}:
+			// GEO and REQUEST-STATUS are structured values.
+			parts := []string{"latitude", "longitude"}
+			if name == "request-status" {
+				parts = []string{"code", "description", "data"}
+			}
+			for i, part := range v {
+				if i < len(parts) {
+					writeXCalValue(builder, parts[i], part)
+				}
+			}
+		case jcalRecur:
+			builder.WriteStartElement("recur")
+			for _, part := range v {
+				for _, partValue := range part.values {
+					writeXCalValue(builder, part.name, partValue)
+				}
+			}
+			builder.WriteEndElement("recur")
+		default:
+			if valueType == "period" {
+				writeXCalPeriod(builder, xcalText(v))
+				continue
+			}
+			writeXCalValue(builder, valueType, v)
+		}
+	}
+	builder.WriteEndElement(name)
+}
+
+func writeXCalParam(builder *XMLBuilder, key string, value interface{}) {
+	valueType := xcalParamTypes[strings.ToUpper(key)]
+	if valueType == "" {
+		valueType = "text"
+	}
+
+	builder.WriteStartElement(key)
+	switch v := value.(type) {
+	case []string:
+		for _, item := range v {
+			writeXCalValue(builder, valueType, item)
+		}
+	case string:
+		if valueType == "boolean" {
+			v = strings.ToLower(v)
+		}
+		writeXCalValue(builder, valueType, v)
+	}
+	builder.WriteEndElement(key)
+}
+
+// writeXCalPeriod writes a PERIOD value, whose end is either a date-time or
+// a duration.
+func writeXCalPeriod(builder *XMLBuilder, value string) {
+	parts := strings.SplitN(value, "/", 2)
+	builder.WriteStartElement("period")
+	builder.WriteStartElement("start").WriteText(parts[0]).WriteEndElement("start")
+	if len(parts) == 2 {
+		end := "end"
+		if isDurationValue(parts[1]) {
+			end = "duration"
+		}
+		builder.WriteStartElement(end).WriteText(parts[1]).WriteEndElement(end)
+	}
+	builder.WriteEndElement("period")
+}
+
+func writeXCalValue(builder *XMLBuilder, element string, value interface{}) {
+	builder.WriteStartElement(element).WriteText(xcalText(value)).WriteEndElement(element)
+}
+
+func xcalText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+func writeICalFromXCal(w *contentLineWriter, component xcalNode) error {
+	name := strings.ToUpper(component.XMLName.Local)
+	w.begin(name)
+	for _, section := range xcalChildren(component) {
+		switch section.XMLName.Local {
+		case "properties":
+			for _, property := range xcalChildren(section) {
+				if err := writeICalPropertyFromXCal(w, property); err != nil {
+					return err
+				}
+			}
+		case "components":
+			for _, sub := range xcalChildren(section) {
+				if err := writeICalFromXCal(w, sub); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	w.end(name)
+	return nil
+}
+
+func writeICalPropertyFromXCal(w *contentLineWriter, property xcalNode) error {
+	name := strings.ToUpper(property.XMLName.Local)
+
+	var params []ICalParameter
+	var values []xcalNode
+	for _, child := range xcalChildren(property) {
+		if child.XMLName.Local != "parameters" {
+			values = append(values, child)
+			continue
+		}
+		for _, param := range xcalChildren(child) {
+			key := strings.ToUpper(param.XMLName.Local)
+			var parts []string
+			for _, v := range xcalChildren(param) {
+				text := v.Text
+				if v.XMLName.Local == "boolean" {
+					text = strings.ToUpper(text)
+				}
+				parts = append(parts, text)
+			}
+			params = append(params, ICalParameter{Name: key, Values: parts})
+		}
+	}
+	if len(values) == 0 {
+		return newTypedError("xcal.unmarshal", ErrorTypeValidation, fmt.Sprintf("property %s has no value", name), nil)
+	}
+
+	valueType := values[0].XMLName.Local
+	var value string
+	switch valueType {
+	case "latitude", "code":
+		var parts []string
+		for _, part := range values {
+			text := part.Text
+			if valueType == "code" {
+				text = escapeICalText(text)
+			}
+			parts = append(parts, text)
+		}
+		value = strings.Join(parts, ";")
+		valueType = icalValueTypes[name]
+	case "recur":
+		value = icalRecurFromXCal(values[0])
+	case "period":
+		var periods []string
+		for _, period := range values {
+			var parts []string
+			for _, part := range xcalChildren(period) {
+				parts = append(parts, icalStringFromJCal("period", part.Text))
+			}
+			periods = append(periods, strings.Join(parts, "/"))
+		}
+		value = strings.Join(periods, ",")
+	default:
+		var parts []string
+		for _, v := range values {
+			if valueType == "boolean" {
+				parts = append(parts, strings.ToUpper(v.Text))
+			} else {
+				parts = append(parts, icalStringFromJCal(valueType, v.Text))
+			}
+		}
+		value = strings.Join(parts, ",")
+	}
+
+	if valueParam, ok := icalValueParam(name, valueType); ok {
+		params = append([]ICalParameter{{Name: valueParam.Name, Values: []string{valueParam.Value}}}, params...)
+	}
+	w.writeICalProperty(ICalProperty{Name: name, Parameters: params, Value: value})
+	return nil
+}
+
+// icalRecurFromXCal converts a recur element to an RRULE value. Repeated
+// parts, such as several byday elements, become one comma-separated part.
+func icalRecurFromXCal(recur xcalNode) string {
+	var order []string
+	values := make(map[string][]string)
+	for _, part := range xcalChildren(recur) {
+		key := strings.ToUpper(part.XMLName.Local)
+		if _, seen := values[key]; !seen {
+			order = append(order, key)
+		}
+		value := part.Text
+		if key == "UNTIL" {
+			value = icalStringFromJCal("date-time", value)
+		}
+		values[key] = append(values[key], value)
+	}
+
+	parts := make([]string, len(order))
+	for i, key := range order {
+		parts[i] = key + "=" + strings.Join(values[key], ",")
+	}
+	return strings.Join(parts, ";")
+}
+
+// xcalChildren returns the child elements of node in the xCal namespace.
+func xcalChildren(node xcalNode) []xcalNode {
+	var children []xcalNode
+	for _, child := range node.Children {
+		if child.XMLName.Space == XCalNamespace {
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -0,0 +1,250 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"html"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXCal_RoundTrip(t *testing.T) {
+	original, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+
+	encoded, err := MarshalXCal(original)
+	if err != nil {
+		t.Fatalf("MarshalXCal failed: %v", err)
+	}
+	if err := xml.Unmarshal(encoded, new(xcalNode)); err != nil {
+		t.Fatalf("MarshalXCal produced invalid XML: %v\n%s", err, encoded)
+	}
+
+	decoded, err := UnmarshalXCal(encoded)
+	if err != nil {
+		t.Fatalf("UnmarshalXCal failed: %v", err)
+	}
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("round trip mismatch\noriginal: %+v\ndecoded:  %+v\nxCal: %s", original, decoded, encoded)
+	}
+}
+
+func TestMarshalXCal_Values(t *testing.T) {
+	parsed, err := ParseICalendar(roundTripICalendar)
+	if err != nil {
+		t.Fatalf("ParseICalendar failed: %v", err)
+	}
+	encoded, err := MarshalXCal(parsed)
+	if err != nil {
+		t.Fatalf("MarshalXCal failed: %v", err)
+	}
+	output := string(encoded)
+
+	expected := []string{
+		`<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0"><vcalendar><properties><version><text>2.0</text></version>`,
+		`<dtstart><date-time>2024-01-15T10:00:00Z</date-time></dtstart>`,
+		`<dtstart><parameters><tzid><text>Europe/London</text></tzid></parameters><date-time>2024-01-01T00:00:00</date-time></dtstart>`,
+		`<dtstart><date>2024-01-15</date></dtstart>`,
+		`<sequence><integer>2</integer></sequence>`,
+		`<geo><latitude>51.5074</latitude><longitude>-0.1278</longitude></geo>`,
+		`<rrule><recur><freq>WEEKLY</freq><byday>MO</byday><count>10</count></recur></rrule>`,
+		`<categories><text>Work</text><text>Finance,Budget</text></categories>`,
+		`<delegated-from><cal-address>mailto:a@example.com</cal-address><cal-address>mailto:b@example.com</cal-address></delegated-from>`,
+		`<request-status><code>3.1</code><description>Invalid property value</description><data>DTSTART:96-Apr-01</data></request-status>`,
+		`<trigger><duration>-PT15M</duration></trigger>`,
+		`<period><start>2024-01-15T14:00:00Z</start><end>2024-01-15T15:00:00Z</end></period>`,
+		`</vtimezone><vevent><properties><uid><text>round-trip-1</text></uid>`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("xCal output missing %s\n%s", want, output)
+		}
+	}
+}
+
+func TestUnmarshalXCal(t *testing.T) {
+	// Adapted from the example in RFC 6321 appendix B.
+	input := `<?xml version="1.0" encoding="utf-8"?>
+<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0">
+  <vcalendar>
+    <properties>
+      <prodid><text>-//Example Inc.//Example Calendar//EN</text></prodid>
+      <version><text>2.0</text></version>
+    </properties>
+    <components>
+      <vevent>
+        <properties>
+          <dtstamp><date-time>2008-02-05T19:12:24Z</date-time></dtstamp>
+          <dtstart>
+            <parameters><tzid><text>America/New_York</text></tzid></parameters>
+            <date-time>2008-02-11T12:00:00</date-time>
+          </dtstart>
+          <dtend><date>2008-02-12</date></dtend>
+          <summary><text>Lunch; with friends</text></summary>
+          <uid><text>4088E990AD89CB3DBB484909</text></uid>
+          <rrule><recur><freq>WEEKLY</freq><byday>MO</byday><byday>WE</byday><until>2008-03-01T00:00:00Z</until></recur></rrule>
+          <geo><latitude>37.386013</latitude><longitude>-122.082932</longitude></geo>
+          <attendee>
+            <parameters><rsvp><boolean>true</boolean></rsvp></parameters>
+            <cal-address>mailto:c@example.com</cal-address>
+          </attendee>
+          <x-example xmlns="http://example.com/ns">ignored</x-example>
+        </properties>
+        <components>
+          <valarm>
+            <properties>
+              <action><text>DISPLAY</text></action>
+              <trigger><duration>-PT15M</duration></trigger>
+            </properties>
+          </valarm>
+        </components>
+      </vevent>
+    </components>
+  </vcalendar>
+</icalendar>`
+
+	parsed, err := UnmarshalXCal([]byte(input))
+	if err != nil {
+		t.Fatalf("UnmarshalXCal failed: %v", err)
+	}
+	if parsed.ProdID != "-//Example Inc.//Example Calendar//EN" || len(parsed.Events) != 1 {
+		t.Fatalf("unexpected calendar %+v", parsed)
+	}
+
+	event := parsed.Events[0]
+	if event.UID != "4088E990AD89CB3DBB484909" || event.Summary != "Lunch; with friends" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.DTStart == nil || event.DTStart.Location().String() != "America/New_York" || event.DTStart.Hour() != 12 {
+		t.Errorf("unexpected DTSTART %v", event.DTStart)
+	}
+	if event.DTEnd == nil || event.DTEnd.Day() != 12 {
+		t.Errorf("unexpected DTEND %v", event.DTEnd)
+	}
+	if event.RecurrenceRule != "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20080301T000000Z" {
+		t.Errorf("unexpected RRULE %q", event.RecurrenceRule)
+	}
+	if event.GeoLocation == nil || event.GeoLocation.Longitude != -122.082932 {
+		t.Errorf("unexpected GEO %v", event.GeoLocation)
+	}
+	if len(event.Attendees) != 1 || !event.Attendees[0].RSVP {
+		t.Errorf("unexpected attendees %+v", event.Attendees)
+	}
+	if _, ok := event.CustomProperties["X-EXAMPLE"]; ok {
+		t.Error("expected elements outside the xCal namespace to be ignored")
+	}
+	if len(event.Alarms) != 1 || event.Alarms[0].Trigger != "-PT15M" {
+		t.Errorf("unexpected alarms %+v", event.Alarms)
+	}
+}
+
+func TestUnmarshalXCal_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not XML", `<icalendar`},
+		{"wrong namespace", `<icalendar xmlns="http://example.com/"><vcalendar/></icalendar>`},
+		{"no vcalendar", `<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0"></icalendar>`},
+		{"property without value", `<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0"><vcalendar><properties><version/></properties></vcalendar></icalendar>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalXCal([]byte(tt.input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestQueryCalendar_XCal(t *testing.T) {
+	xcalEvent, err := MarshalXCal(&ParsedCalendarData{
+		Version: "2.0",
+		ProdID:  "-//Test//EN",
+		Events:  []ParsedEvent{{UID: "xcal-1", Summary: "From xCal"}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalXCal failed: %v", err)
+	}
+	// The xCal document is embedded as escaped text, with its XML declaration
+	// stripped, as servers return it inside calendar-data.
+	calendarData := html.EscapeString(strings.TrimPrefix(string(xcalEvent), xml.Header))
+
+	tests := []struct {
+		name       string
+		advertised string
+		expectXCal bool
+	}{
+		{name: "advertised", advertised: `<C:calendar-data content-type="application/calendar+xml" version="2.0"/>`, expectXCal: true},
+		{name: "not advertised", advertised: "", expectXCal: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusMultiStatus)
+				if r.Method == "PROPFIND" {
+					_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+						`<D:response><D:href>/cal/</D:href><D:propstat><D:prop><C:supported-calendar-data>` +
+						`<C:calendar-data content-type="text/calendar" version="2.0"/>` + tt.advertised +
+						`</C:supported-calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+					return
+				}
+
+				body, _ := io.ReadAll(r.Body)
+				got := strings.Contains(string(body), `content-type="application/calendar+xml"`)
+				if got != tt.expectXCal {
+					t.Errorf("expected xCal request: %v, got body %s", tt.expectXCal, body)
+				}
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+					`<D:response><D:href>/cal/xcal-1.ics</D:href><D:propstat><D:prop><D:getetag>"1"</D:getetag>` +
+					`<C:calendar-data>` + calendarData + `</C:calendar-data>` +
+					`</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`))
+			})
+			defer server.Close()
+			client.autoParsing = true
+
+			objects, err := client.QueryCalendar(context.Background(), "/cal/", CalendarQuery{
+				Properties:  []string{"getetag", "calendar-data"},
+				ContentType: ContentTypeXCal,
+			})
+			if err != nil {
+				t.Fatalf("QueryCalendar failed: %v", err)
+			}
+			if len(objects) != 1 {
+				t.Fatalf("expected 1 object, got %d", len(objects))
+			}
+			if !strings.HasPrefix(objects[0].CalendarData, "BEGIN:VCALENDAR") {
+				t.Errorf("expected CalendarData converted to iCalendar, got %q", objects[0].CalendarData)
+			}
+			if objects[0].ParsedData == nil || len(objects[0].ParsedData.Events) != 1 || objects[0].ParsedData.Events[0].Summary != "From xCal" {
+				t.Errorf("unexpected parsed data %+v", objects[0].ParsedData)
+			}
+		})
+	}
+}
+
+func TestSupportsContentType(t *testing.T) {
+	tests := []struct {
+		types       []string
+		contentType string
+		want        bool
+	}{
+		{nil, ContentTypeICalendar, true},
+		{nil, ContentTypeXCal, false},
+		{[]string{"text/calendar", "application/calendar+xml"}, "Application/Calendar+XML", true},
+		{[]string{"text/calendar"}, "text/calendar; charset=utf-8", true},
+		{[]string{"text/calendar"}, "application/calendar+json", false},
+	}
+	for _, tt := range tests {
+		if got := supportsContentType(tt.types, tt.contentType); got != tt.want {
+			t.Errorf("supportsContentType(%v, %q) = %v, want %v", tt.types, tt.contentType, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package caldav
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -41,6 +42,8 @@ var propElementMap = map[string]string{
 	"calendar-user-address-set":        `<C:calendar-user-address-set/>`,
 	"schedule-inbox-URL":               `<C:schedule-inbox-URL/>`,
 	"schedule-outbox-URL":              `<C:schedule-outbox-URL/>`,
+	"calendar-availability":            `<C:calendar-availability/>`,
+	"supported-calendar-data":          `<C:supported-calendar-data/>`,
 }
 
 const (
@@ -208,19 +211,29 @@ func needsFilter(query CalendarQuery) bool {
 	return query.Filter.Component != "" || query.TimeRange != nil || len(query.Filter.CompFilters) > 0
 }
 
-func writeQueryProperties(builder *XMLBuilder, properties []string) {
-	for _, prop := range properties {
-		switch prop {
-		case "getetag":
+// writeQueryProperties writes the properties requested by query. Upper-case
+// iCalendar property names are not WebDAV properties; they select the parts of
+// calendar-data to return (see writeCalendarDataElement).
+func writeQueryProperties(builder *XMLBuilder, query CalendarQuery) {
+	wroteCalendarData := false
+	for _, prop := range query.Properties {
+		switch {
+		case prop == "getetag":
 			builder.WriteSelfClosingElement("D:getetag")
-		case "calendar-data":
-			builder.WriteSelfClosingElement("C:calendar-data")
+		case prop == "calendar-data":
+			writeCalendarDataElement(builder, query)
+			wroteCalendarData = true
+		case isICalendarPropertyName(prop):
 		default:
 			if element, exists := propElementMap[prop]; exists {
 				builder.WriteRawString(element)
 			}
 		}
 	}
+
+	if !wroteCalendarData && len(calendarDataProjection(query)) > 0 {
+		writeCalendarDataElement(builder, query)
+	}
 }
 
 func writeQueryFilter(builder *XMLBuilder, query CalendarQuery) {
@@ -266,6 +279,10 @@ func writeEventTimeRange(builder *XMLBuilder, timeRange *TimeRange) {
 }
 
 func buildCalendarQueryXML(query CalendarQuery) ([]byte, error) {
+	if query.Expand != nil && query.LimitRecurrenceSet != nil {
+		return nil, fmt.Errorf("expand and limit-recurrence-set cannot be combined")
+	}
+
 	estimatedSize := calculateQueryXMLSize(query)
 	builder := NewXMLBuilder(estimatedSize)
 
@@ -275,7 +292,7 @@ func buildCalendarQueryXML(query CalendarQuery) ([]byte, error) {
 			"xmlns:C", "urn:ietf:params:xml:ns:caldav").
 		WriteStartElement("D:prop")
 
-	writeQueryProperties(builder, query.Properties)
+	writeQueryProperties(builder, query)
 	builder.WriteEndElement("D:prop")
 
 	if needsFilter(query) {
@@ -287,6 +304,136 @@ func buildCalendarQueryXML(query CalendarQuery) ([]byte, error) {
 	return builder.Bytes(), nil
 }
 
+func writeCalendarDataElement(builder *XMLBuilder, query CalendarQuery) {
+	var attrs []string
+	if query.ContentType != "" {
+		attrs = []string{"content-type", query.ContentType, "version", "2.0"}
+	}
+
+	projection := calendarDataProjection(query)
+	if len(projection) == 0 && query.Expand == nil && query.LimitRecurrenceSet == nil {
+		builder.WriteSelfClosingElement("C:calendar-data", attrs...)
+		return
+	}
+
+	builder.WriteStartElement("C:calendar-data", attrs...)
+	if len(projection) > 0 {
+		writeCalendarDataComp(builder, projection)
+	}
+	if query.Expand != nil {
+		builder.WriteSelfClosingElement("C:expand",
+			"start", formatTimeForCalDAV(query.Expand.Start),
+			"end", formatTimeForCalDAV(query.Expand.End))
+	}
+	if query.LimitRecurrenceSet != nil {
+		builder.WriteSelfClosingElement("C:limit-recurrence-set",
+			"start", formatTimeForCalDAV(query.LimitRecurrenceSet.Start),
+			"end", formatTimeForCalDAV(query.LimitRecurrenceSet.End))
+	}
+	builder.WriteEndElement("C:calendar-data")
+}
+
+// writeCalendarDataComp writes a <C:comp> tree that returns the VCALENDAR
+// properties, complete VTIMEZONEs and only the listed properties of each
+// projected component. Sub-components such as VALARM are left out.
+func writeCalendarDataComp(builder *XMLBuilder, projection []componentProjection) {
+	builder.WriteStartElement("C:comp", "name", "VCALENDAR").
+		WriteSelfClosingElement("C:allprop").
+		WriteStartElement("C:comp", "name", "VTIMEZONE").
+		WriteSelfClosingElement("C:allprop").
+		WriteSelfClosingElement("C:allcomp").
+		WriteEndElement("C:comp")
+
+	for _, comp := range projection {
+		builder.WriteStartElement("C:comp", "name", comp.name)
+		for _, prop := range comp.props {
+			builder.WriteSelfClosingElement("C:prop", "name", prop)
+		}
+		builder.WriteEndElement("C:comp")
+	}
+
+	builder.WriteEndElement("C:comp")
+}
+
+type componentProjection struct {
+	name  string
+	props []string
+}
+
+// calendarDataProjection groups the iCalendar property names in
+// query.Properties by component. A name may be qualified with its component,
+// as in "VTODO.DUE"; otherwise it applies to the component being queried,
+// VEVENT by default. Each component always includes the properties returned
+// by projectionKeyProps.
+func calendarDataProjection(query CalendarQuery) []componentProjection {
+	var projection []componentProjection
+	for _, prop := range query.Properties {
+		if !isICalendarPropertyName(prop) {
+			continue
+		}
+
+		component := queriedComponent(query.Filter)
+		if i := strings.Index(prop, "."); i > 0 {
+			component, prop = prop[:i], prop[i+1:]
+		}
+
+		index := -1
+		for i := range projection {
+			if projection[i].name == component {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			projection = append(projection, componentProjection{name: component, props: projectionKeyProps(component)})
+			index = len(projection) - 1
+		}
+		if !containsProp(projection[index].props, prop) {
+			projection[index].props = append(projection[index].props, prop)
+		}
+	}
+	return projection
+}
+
+// projectionKeyProps returns the properties a projection of component always
+// includes: UID so results can be matched up and, for recurring components,
+// RECURRENCE-ID and DTSTART so that overrides can be told from their master
+// and placed in the series.
+func projectionKeyProps(component string) []string {
+	switch component {
+	case "VEVENT", "VTODO":
+		return []string{"UID", "RECURRENCE-ID", "DTSTART"}
+	default:
+		return []string{"UID"}
+	}
+}
+
+func containsProp(props []string, prop string) bool {
+	for _, p := range props {
+		if p == prop {
+			return true
+		}
+	}
+	return false
+}
+
+// queriedComponent returns the component a filter selects, VEVENT if none.
+func queriedComponent(filter Filter) string {
+	if filter.Component != "" && filter.Component != "VCALENDAR" {
+		return filter.Component
+	}
+	if len(filter.CompFilters) > 0 && filter.CompFilters[0].Component != "" {
+		return filter.CompFilters[0].Component
+	}
+	return "VEVENT"
+}
+
+// isICalendarPropertyName reports whether prop is written in upper case, as
+// iCalendar property names are, rather than as a WebDAV property name.
+func isICalendarPropertyName(prop string) bool {
+	return prop != "" && prop == strings.ToUpper(prop) && strings.ContainsAny(prop, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+}
+
 func writeComponentFilter(builder *XMLBuilder, filter Filter) {
 	builder.WriteStartElement("C:comp-filter", "name", filter.Component)
 
@@ -335,3 +482,41 @@ func writeTimeRange(builder *XMLBuilder, tr *TimeRange) {
 	endStr := formatTimeForCalDAV(tr.End)
 	builder.WriteSelfClosingElement("C:time-range", "start", startStr, "end", endStr)
 }
+
+func buildCalendarMultigetXML(hrefs []string, properties []string) []byte {
+	builder := NewXMLBuilder(baseXMLOverhead + len(properties)*avgPropElementSize + len(hrefs)*80)
+
+	builder.WriteHeader().
+		WriteStartElement("C:calendar-multiget",
+			"xmlns:D", "DAV:",
+			"xmlns:C", "urn:ietf:params:xml:ns:caldav",
+			"xmlns:CS", "http://calendarserver.org/ns/",
+			"xmlns:A", "http://apple.com/ns/ical/").
+		WriteStartElement("D:prop")
+
+	writeQueryProperties(builder, CalendarQuery{Properties: properties})
+	builder.WriteEndElement("D:prop")
+
+	for _, href := range hrefs {
+		builder.WriteStartElement("D:href").
+			WriteText(href).
+			WriteEndElement("D:href")
+	}
+
+	builder.WriteEndElement("C:calendar-multiget")
+
+	return builder.Bytes()
+}
+
+func buildFreeBusyQueryXML(start, end time.Time) []byte {
+	builder := NewXMLBuilder(baseXMLOverhead)
+
+	builder.WriteHeader().
+		WriteStartElement("C:free-busy-query",
+			"xmlns:D", "DAV:",
+			"xmlns:C", "urn:ietf:params:xml:ns:caldav")
+	writeTimeRange(builder, &TimeRange{Start: start, End: end})
+	builder.WriteEndElement("C:free-busy-query")
+
+	return builder.Bytes()
+}
@@ -316,6 +316,69 @@ func TestBuildCalendarQueryXML(t *testing.T) {
 				`</C:comp-filter>`,
 			},
 		},
+		{
+			name: "query with expand",
+			query: CalendarQuery{
+				Properties: []string{"getetag", "calendar-data"},
+				TimeRange:  &TimeRange{Start: startTime, End: endTime},
+				Expand:     &TimeRange{Start: startTime, End: endTime},
+			},
+			expected: []string{
+				`<C:calendar-data><C:expand start="20250101T000000Z" end="20250131T235959Z"/></C:calendar-data>`,
+			},
+			notExpected: []string{
+				`<C:calendar-data/>`,
+			},
+		},
+		{
+			name: "query with calendar-data projection",
+			query: CalendarQuery{
+				Properties: []string{"getetag", "SUMMARY", "DTSTART", "DTEND", "RRULE"},
+				Filter:     Filter{Component: "VEVENT"},
+				Expand:     &TimeRange{Start: startTime, End: endTime},
+			},
+			expected: []string{
+				`<D:getetag/>`,
+				`<C:calendar-data><C:comp name="VCALENDAR"><C:allprop/>` +
+					`<C:comp name="VTIMEZONE"><C:allprop/><C:allcomp/></C:comp>` +
+					`<C:comp name="VEVENT"><C:prop name="UID"/><C:prop name="RECURRENCE-ID"/><C:prop name="DTSTART"/><C:prop name="SUMMARY"/><C:prop name="DTEND"/><C:prop name="RRULE"/></C:comp>` +
+					`</C:comp><C:expand start="20250101T000000Z" end="20250131T235959Z"/></C:calendar-data>`,
+			},
+			notExpected: []string{
+				`<C:calendar-data/>`,
+			},
+		},
+		{
+			name: "query with qualified projection",
+			query: CalendarQuery{
+				Properties: []string{"calendar-data", "VTODO.SUMMARY", "VTODO.DUE"},
+			},
+			expected: []string{
+				`<C:comp name="VTODO"><C:prop name="UID"/><C:prop name="RECURRENCE-ID"/><C:prop name="DTSTART"/><C:prop name="SUMMARY"/><C:prop name="DUE"/></C:comp>`,
+			},
+			notExpected: []string{
+				`<C:comp name="VEVENT">`,
+			},
+		},
+		{
+			name: "query with journal projection",
+			query: CalendarQuery{
+				Properties: []string{"calendar-data", "VJOURNAL.SUMMARY"},
+			},
+			expected: []string{
+				`<C:comp name="VJOURNAL"><C:prop name="UID"/><C:prop name="SUMMARY"/></C:comp>`,
+			},
+		},
+		{
+			name: "query with limit-recurrence-set",
+			query: CalendarQuery{
+				Properties:         []string{"calendar-data"},
+				LimitRecurrenceSet: &TimeRange{Start: startTime, End: endTime},
+			},
+			expected: []string{
+				`<C:calendar-data><C:limit-recurrence-set start="20250101T000000Z" end="20250131T235959Z"/></C:calendar-data>`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -350,6 +413,13 @@ func TestBuildCalendarQueryXML(t *testing.T) {
 	}
 }
 
+func TestBuildCalendarQueryXML_ExpandAndLimit(t *testing.T) {
+	tr := &TimeRange{Start: time.Now(), End: time.Now().Add(time.Hour)}
+	if _, err := buildCalendarQueryXML(CalendarQuery{Expand: tr, LimitRecurrenceSet: tr}); err == nil {
+		t.Error("expected an error when combining expand and limit-recurrence-set")
+	}
+}
+
 func TestXMLEscape(t *testing.T) {
 	tests := []struct {
 		input    string